@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	cli "github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+// adoptCommand configures the adopt subcommand, which tags as managed the backup snapshots
+// created before the app tagged its snapshots, so that the cleanup applies the retention to them again.
+// Its dry run prints the snapshots it would adopt.
+func adoptCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Action = func() {
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		results, err := svc.Adopt(ctx, *env.dryRun)
+		if printErr := printAdoption(os.Stdout, results, *env.dryRun); printErr != nil {
+			log.WithError(printErr).Error("Error in printing the adopted snapshots")
+		}
+		if code := runExitCode(ctx, err); code != exitCodeSuccess {
+			log.WithError(err).WithField("exitCode", code).Error("PAC aurora snapshot adoption failed")
+			cli.Exit(code)
+		}
+	}
+}
+
+// printAdoption writes a human-readable table of the snapshots adopted, or that would be adopted, and skipped.
+func printAdoption(w io.Writer, results []*backup.AdoptResult, dryRun bool) error {
+	adopted := "adopted"
+	if dryRun {
+		adopted = "adopt"
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "REGION\tSNAPSHOT\tACTION")
+	for _, r := range results {
+		if r.Err != nil && len(r.Adopted) == 0 && len(r.Failed) == 0 {
+			fmt.Fprintf(tw, "%v\t%v*\terror: %v\n", r.Region, r.SnapshotIDPrefix, r.Err)
+			continue
+		}
+		for _, snapshotID := range r.Adopted {
+			fmt.Fprintf(tw, "%v\t%v\t%v\n", r.Region, snapshotID, adopted)
+		}
+		for _, snapshotID := range r.Skipped {
+			fmt.Fprintf(tw, "%v\t%v\tskipped, not named like a backup\n", r.Region, snapshotID)
+		}
+		for _, f := range r.Failed {
+			fmt.Fprintf(tw, "%v\t%v\terror: %v\n", r.Region, f.SnapshotID, f.Err)
+		}
+	}
+	return tw.Flush()
+}
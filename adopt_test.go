@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintAdoption(t *testing.T) {
+	results := []*backup.AdoptResult{{
+		Region:           "eu-west-1",
+		SnapshotIDPrefix: "pac-aurora-prod-backup",
+		Adopted:          []string{"pac-aurora-prod-backup-2024-01-05-03-00-00"},
+		Skipped:          []string{"pac-aurora-prod-backup-before-migration"},
+	}, {
+		Region:           "us-east-1",
+		SnapshotIDPrefix: "pac-aurora-prod-backup",
+		Err:              errors.New("rate exceeded"),
+	}}
+
+	var out bytes.Buffer
+	require.NoError(t, printAdoption(&out, results, true))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"REGION", "SNAPSHOT", "ACTION"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"eu-west-1", "pac-aurora-prod-backup-2024-01-05-03-00-00", "adopt"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"eu-west-1", "pac-aurora-prod-backup-before-migration", "skipped,", "not", "named", "like", "a", "backup"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"us-east-1", "pac-aurora-prod-backup*", "error:", "rate", "exceeded"}, strings.Fields(lines[3]))
+
+	out.Reset()
+	require.NoError(t, printAdoption(&out, results[:1], false))
+	assert.Contains(t, out.String(), "pac-aurora-prod-backup-2024-01-05-03-00-00  adopted")
+}
@@ -1,18 +1,53 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/metrics"
+	"github.com/Financial-Times/pac-aurora-backup/report"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
 	cli "github.com/jawher/mow.cli"
 	log "github.com/sirupsen/logrus"
 )
 
 const pacAuroraPrefix = "pac-aurora-"
 
+// version is the version of the app, tagged on the snapshots it creates.
+// It is set at build time with -ldflags "-X main.version=<version>".
+var version = "dev"
+
+// metricsPushTimeout bounds the push of the metrics at the end of a run.
+const metricsPushTimeout = 30 * time.Second
+
+// Exit codes reported to the Kubernetes CronJob, so that failed runs are recorded as failed Jobs.
+const (
+	exitCodeSuccess = iota
+	exitCodeError
+	exitCodeClusterNotFound
+	exitCodeSnapshotTimeout
+	exitCodeUnexpectedStatus
+	exitCodeDeletionFailure
+	exitCodeCopyFailure
+	exitCodeShareFailure
+	exitCodePreflightFailure
+	exitCodeInterrupted
+	exitCodeVerificationFailure
+	exitCodeExportFailure
+)
+
 func main() {
 	app := cli.App("pac-aurora-backup", "A backup app for PAC Aurora clusters")
 
@@ -49,6 +84,50 @@ func main() {
 		EnvVar: "BACKUPS_RETENTION",
 	})
 
+	retentionPolicy := app.String(cli.StringOpt{
+		Name:   "retention-policy",
+		Desc:   "The snapshots preserved by the cleanup as <bucket>=<count> pairs of keep-last, keep-hourly, keep-daily, keep-weekly, keep-monthly and keep-yearly, overriding backups-retention",
+		EnvVar: "RETENTION_POLICY",
+	})
+
+	minBackupAge := app.String(cli.StringOpt{
+		Name:   "min-backup-age",
+		Value:  "24h",
+		Desc:   "The age under which snapshots are never deleted by the cleanup, whatever the retention, e.g. 36h or 2d",
+		EnvVar: "MIN_BACKUP_AGE",
+	})
+
+	maxBackupAge := app.String(cli.StringOpt{
+		Name:   "max-backup-age",
+		Desc:   "The age over which snapshots are always deleted by the cleanup, whatever the retention, e.g. 90d",
+		EnvVar: "MAX_BACKUP_AGE",
+	})
+
+	minBackupInterval := app.String(cli.StringOpt{
+		Name:   "min-backup-interval",
+		Desc:   "The age under which an available or creating backup snapshot of a cluster is reused by the backup instead of making a new one, e.g. 12h; by default a snapshot is always made",
+		EnvVar: "MIN_BACKUP_INTERVAL",
+	})
+
+	snapshotIDTemplate := app.String(cli.StringOpt{
+		Name:   "snapshot-id-template",
+		Value:  backup.DefaultSnapshotIDTemplate,
+		Desc:   "The identifier of the new snapshots, starting with {prefix} and with at least {yyyy}, {MM} and {dd}, among {cluster}, {env}, {run-id}, {label} and {HH}, {mm} and {ss} in UTC",
+		EnvVar: "SNAPSHOT_ID_TEMPLATE",
+	})
+
+	snapshotLabel := app.String(cli.StringOpt{
+		Name:   "snapshot-label",
+		Desc:   "The {label} of the snapshot identifier template, e.g. pre-migration",
+		EnvVar: "SNAPSHOT_LABEL",
+	})
+
+	runID := app.String(cli.StringOpt{
+		Name:   "run-id",
+		Desc:   "The {run-id} of the snapshot identifier template, e.g. the name of the Kubernetes Job, by default random",
+		EnvVar: "RUN_ID",
+	})
+
 	statusCheckIntervalString := app.String(cli.StringOpt{
 		Name:   "status-check-interval",
 		Value:  "30s",
@@ -63,39 +142,456 @@ func main() {
 		EnvVar: "STATUS_CHECK_ATTEMPTS",
 	})
 
+	discoverClusters := app.Bool(cli.BoolOpt{
+		Name:   "discover-clusters",
+		Value:  false,
+		Desc:   "Back up every Aurora cluster of the PAC environment instead of only the first one found, applying retention per cluster",
+		EnvVar: "DISCOVER_CLUSTERS",
+	})
+
+	backupConcurrency := app.Int(cli.IntOpt{
+		Name:   "backup-concurrency",
+		Value:  2,
+		Desc:   "The maximum number of clusters backed up or cleaned up at the same time when discovering clusters",
+		EnvVar: "BACKUP_CONCURRENCY",
+	})
+
+	deletionConcurrency := app.Int(cli.IntOpt{
+		Name:   "deletion-concurrency",
+		Value:  4,
+		Desc:   "The maximum number of snapshot deletions requested at the same time by the cleanup of a cluster in a region",
+		EnvVar: "DELETION_CONCURRENCY",
+	})
+
+	deletionRate := app.Int(cli.IntOpt{
+		Name:   "deletion-rate",
+		Value:  2,
+		Desc:   "The maximum number of snapshot deletions requested per second by the cleanup of a cluster in a region; 0 disables the limit",
+		EnvVar: "DELETION_RATE",
+	})
+
+	copyRegions := app.Strings(cli.StringsOpt{
+		Name:   "copy-regions",
+		Value:  []string{},
+		Desc:   "The AWS regions where every new snapshot is copied for disaster recovery",
+		EnvVar: "COPY_REGIONS",
+	})
+
+	copyKMSKeyIDs := app.Strings(cli.StringsOpt{
+		Name:   "copy-kms-key-ids",
+		Value:  []string{},
+		Desc:   "The KMS keys used to encrypt the snapshot copies of encrypted clusters, as <region>=<key-id> pairs",
+		EnvVar: "COPY_KMS_KEY_IDS",
+	})
+
+	copyBackupsRetention := app.Int(cli.IntOpt{
+		Name:   "copy-backups-retention",
+		Value:  7,
+		Desc:   "The number of most recent snapshot copies that needed to be preserved in each copy region",
+		EnvVar: "COPY_BACKUPS_RETENTION",
+	})
+
+	shareWithAccounts := app.Strings(cli.StringsOpt{
+		Name:   "share-with-accounts",
+		Value:  []string{},
+		Desc:   "The AWS account IDs every new snapshot is shared with",
+		EnvVar: "SHARE_WITH_ACCOUNTS",
+	})
+
+	vaultRoleARN := app.String(cli.StringOpt{
+		Name:   "vault-role-arn",
+		Desc:   "The ARN of the IAM role assumed in the backup vault account to copy every new snapshot there",
+		EnvVar: "VAULT_ROLE_ARN",
+	})
+
+	vaultRegion := app.String(cli.StringOpt{
+		Name:   "vault-region",
+		Desc:   "The AWS region of the snapshot copies in the backup vault account, by default the region of the Aurora cluster",
+		EnvVar: "VAULT_REGION",
+	})
+
+	vaultKMSKeyID := app.String(cli.StringOpt{
+		Name:   "vault-kms-key-id",
+		Desc:   "The KMS key owned by the backup vault account used to encrypt the snapshot copies of encrypted clusters",
+		EnvVar: "VAULT_KMS_KEY_ID",
+	})
+
+	exportBucket := app.String(cli.StringOpt{
+		Name:   "export-bucket",
+		Desc:   "The S3 bucket where every new snapshot is exported in Parquet, in the region of the Aurora cluster",
+		EnvVar: "EXPORT_BUCKET",
+	})
+
+	exportPrefix := app.String(cli.StringOpt{
+		Name:   "export-prefix",
+		Desc:   "The prefix of the keys of the snapshot exports in the export bucket",
+		EnvVar: "EXPORT_PREFIX",
+	})
+
+	exportRoleARN := app.String(cli.StringOpt{
+		Name:   "export-role-arn",
+		Desc:   "The ARN of the IAM role RDS assumes to write the snapshot exports into the export bucket",
+		EnvVar: "EXPORT_ROLE_ARN",
+	})
+
+	exportKMSKeyID := app.String(cli.StringOpt{
+		Name:   "export-kms-key-id",
+		Desc:   "The KMS key used to encrypt the snapshot exports",
+		EnvVar: "EXPORT_KMS_KEY_ID",
+	})
+
+	exportOnly := app.Strings(cli.StringsOpt{
+		Name:   "export-only",
+		Value:  []string{},
+		Desc:   "The databases, schemas or tables exported, e.g. annotations or annotations.annotation, by default all of them",
+		EnvVar: "EXPORT_ONLY",
+	})
+
+	exportRetention := app.Int(cli.IntOpt{
+		Name:   "export-retention",
+		Value:  0,
+		Desc:   "The number of most recent snapshot exports of each cluster preserved in the export bucket; 0 keeps them all",
+		EnvVar: "EXPORT_RETENTION",
+	})
+
+	preflightWait := app.String(cli.StringOpt{
+		Name:   "preflight-wait",
+		Value:  "0s",
+		Desc:   "How long to wait for a cluster to be available, outside its backup and maintenance windows and without snapshots in progress, before failing",
+		EnvVar: "PREFLIGHT_WAIT",
+	})
+
+	timeoutString := app.String(cli.StringOpt{
+		Name:   "timeout",
+		Value:  "2h",
+		Desc:   "The maximum duration of a run, after which waiting for snapshots is interrupted; 0 disables it",
+		EnvVar: "TIMEOUT",
+	})
+
+	statusCheckMaxIntervalString := app.String(cli.StringOpt{
+		Name:   "status-check-max-interval",
+		Value:  "2m",
+		Desc:   "The maximum time elapsed between each check of a status, as the interval doubles after every check",
+		EnvVar: "STATUS_CHECK_MAX_INTERVAL",
+	})
+
+	pushgatewayURL := app.String(cli.StringOpt{
+		Name:   "pushgateway-url",
+		Desc:   "The URL of the Prometheus Pushgateway where the metrics of each run are pushed, e.g. http://pushgateway:9091",
+		EnvVar: "PUSHGATEWAY_URL",
+	})
+
+	notifySpecs := app.Strings(cli.StringsOpt{
+		Name:   "notify",
+		Value:  []string{},
+		Desc:   "Where the outcome of the backups and the cleanup failures are notified: webhook:<url> for a generic JSON webhook, slack:<url> for a Slack incoming webhook or sns:<topic arn>",
+		EnvVar: "NOTIFY",
+	})
+
+	slowCreationString := app.String(cli.StringOpt{
+		Name:   "slow-creation",
+		Value:  "1h",
+		Desc:   "The snapshot creation duration over which a slow creation is notified besides the success of the backup; 0 disables it",
+		EnvVar: "SLOW_CREATION",
+	})
+
+	reportSpec := app.String(cli.StringOpt{
+		Name:   "report",
+		Desc:   "Where the JSON report of each run, backup and cleanup command is written: - for stdout, a file path or s3://<bucket>/<key>; by default no report is written",
+		EnvVar: "REPORT",
+	})
+
+	dryRun := app.Bool(cli.BoolOpt{
+		Name:   "dry-run",
+		Value:  false,
+		Desc:   "Print which snapshots the run, backup or cleanup command would create and delete without creating or deleting any",
+		EnvVar: "DRY_RUN",
+	})
+
+	configFile := app.String(cli.StringOpt{
+		Name:   "config",
+		Desc:   "The YAML or JSON file listing the backup targets, each with its clusters, region, retention, copies and tags, replacing the options of the PAC environment",
+		EnvVar: "CONFIG_FILE",
+	})
+
+	targetName := app.String(cli.StringOpt{
+		Name:   "target",
+		Desc:   "The name of the target of the config file the command applies to, by default all of them",
+		EnvVar: "TARGET",
+	})
+
+	lockSpec := app.String(cli.StringOpt{
+		Name:   "lock",
+		Desc:   "Where the run, backup and cleanup commands take a lock so that only one of them runs at a time: dynamodb:[<region>/]<table>, cluster-tag:[<region>/]<cluster id> or file:<directory>; by default runs are not locked",
+		EnvVar: "LOCK",
+	})
+
+	lockName := app.String(cli.StringOpt{
+		Name:   "lock-name",
+		Desc:   "The name of the lock, by default the cluster identifier prefix of the PAC environment, or the target and the name of the config file",
+		EnvVar: "LOCK_NAME",
+	})
+
+	lockTTLString := app.String(cli.StringOpt{
+		Name:   "lock-ttl",
+		Value:  "5m",
+		Desc:   "How long the lock of a run outlives it if the run dies, as the lease of the lock is renewed every third of it",
+		EnvVar: "LOCK_TTL",
+	})
+
 	log.SetFormatter(&log.JSONFormatter{TimestampFormat: time.RFC3339Nano})
 	log.SetLevel(log.InfoLevel)
 
-	log.Infof("[Startup] %v is starting", *appSystemCode)
+	log.Infof("[Startup] %v %v is starting", *appSystemCode, version)
 
-	app.Action = func() {
+	// newContext returns the context of a command, cancelled on SIGTERM or SIGINT and after the timeout.
+	newContext := func() (context.Context, context.CancelFunc) {
+		timeout, err := time.ParseDuration(*timeoutString)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing timeout parameter")
+			cli.Exit(exitCodeError)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		if timeout <= 0 {
+			return ctx, stop
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		return ctx, func() {
+			cancel()
+			stop()
+		}
+	}
 
-		log.Infof("System code: %s, App Name: %s, Pac environment: %s", *appSystemCode, *appName, *pacEnvironment)
+	// awsRegion returns the region of the lock and of the report: the rds-region option,
+	// or else the region of the targets of the config file.
+	awsRegion := func() string {
+		if *rdsRegion != "" || *configFile == "" {
+			return *rdsRegion
+		}
+		region, err := configRegion(*configFile, *targetName)
+		if err != nil {
+			log.WithError(err).WithField("config", *configFile).Error("Error in reading the region of the config file")
+			cli.Exit(exitCodeError)
+		}
+		return region
+	}
 
+	// newService returns the backup service configured by the options of the app,
+	// or by the targets of the config file if one is given.
+	newService := func() backup.Service {
 		statusCheckInterval, err := time.ParseDuration(*statusCheckIntervalString)
 		if err != nil {
 			log.WithError(err).Warn("Error in parsing status-check-interval parameter. Setting the value as 30s")
 			statusCheckInterval = 30 * time.Second
 		}
+		statusCheckMaxInterval, err := time.ParseDuration(*statusCheckMaxIntervalString)
+		if err != nil {
+			log.WithError(err).Warn("Error in parsing status-check-max-interval parameter. Setting the value as the status check interval")
+			statusCheckMaxInterval = statusCheckInterval
+		}
+		wait, err := time.ParseDuration(*preflightWait)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing preflight-wait parameter")
+			cli.Exit(exitCodeError)
+		}
+		interval, err := backup.ParseBackupAge(*minBackupInterval)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing min-backup-interval parameter")
+			cli.Exit(exitCodeError)
+		}
+		template, err := backup.ParseSnapshotIDTemplate(*snapshotIDTemplate)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing snapshot-id-template parameter")
+			cli.Exit(exitCodeError)
+		}
+		slowCreation, err := time.ParseDuration(*slowCreationString)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing slow-creation parameter")
+			cli.Exit(exitCodeError)
+		}
+		if *reportSpec != "" {
+			destination, err := report.ParseDestination(*reportSpec)
+			if err != nil {
+				log.WithError(err).Error("Error in parsing report parameter")
+				cli.Exit(exitCodeError)
+			}
+			if destination.IsS3() {
+				if _, err := newRegionSession(awsRegion()); err != nil {
+					log.WithError(err).Error("Error in configuring the report upload")
+					cli.Exit(exitCodeError)
+				}
+			}
+		}
+		if _, err := newLocker(*lockSpec, awsRegion()); err != nil {
+			log.WithError(err).Error("Error in configuring the run lock")
+			cli.Exit(exitCodeError)
+		}
+		notifications, err := parseNotifications(*notifySpecs)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing notify parameter")
+			cli.Exit(exitCodeError)
+		}
+		if *runID == "" {
+			*runID = newRunID()
+		}
+		opts := []backup.Option{
+			backup.WithSnapshotIDTemplate(template),
+			backup.WithSnapshotIDFields(*runID, *snapshotLabel),
+			backup.WithMinBackupInterval(interval),
+			backup.WithStatusCheckBackoff(statusCheckMaxInterval),
+			backup.WithPreflightWait(wait),
+			backup.WithDeletionConcurrency(*deletionConcurrency, float64(*deletionRate)),
+			backup.WithManagedTags(*pacEnvironment, *appSystemCode, version),
+		}
+
+		if *configFile != "" {
+			log.Infof("System code: %s, App Name: %s, Config file: %s", *appSystemCode, *appName, *configFile)
+			svc, err := newConfigService(*configFile, *targetName, statusCheckInterval, *statusCheckAttempts, *backupConcurrency, slowCreation, notifications, opts...)
+			if err != nil {
+				log.WithError(err).WithField("config", *configFile).Error("Error in creating the backup services of the config file")
+				cli.Exit(exitCodeError)
+			}
+			return svc
+		}
+		log.Infof("System code: %s, App Name: %s, Pac environment: %s", *appSystemCode, *appName, *pacEnvironment)
 
 		envLevel, err := extractEnvironmentLevel(*pacEnvironment)
 		if err != nil {
 			log.WithError(err).Error("Error in extracting environment level")
-			return
+			cli.Exit(exitCodeError)
 		}
 
 		clusterIDPrefix := pacAuroraPrefix + envLevel
 		snapshotIDPrefix := clusterIDPrefix + "-backup"
 
-		svc, err := backup.NewBackupService(*rdsRegion, clusterIDPrefix, snapshotIDPrefix, statusCheckInterval, *statusCheckAttempts, *backupsRetention)
+		if *retentionPolicy != "" {
+			policy, err := backup.ParseRetentionPolicy(*retentionPolicy)
+			if err != nil {
+				log.WithError(err).Error("Error in parsing retention-policy parameter")
+				cli.Exit(exitCodeError)
+			}
+			opts = append(opts, backup.WithRetentionPolicy(policy))
+		}
+		minAge, err := backup.ParseBackupAge(*minBackupAge)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing min-backup-age parameter")
+			cli.Exit(exitCodeError)
+		}
+		maxAge, err := backup.ParseBackupAge(*maxBackupAge)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing max-backup-age parameter")
+			cli.Exit(exitCodeError)
+		}
+		opts = append(opts, backup.WithBackupAgeLimits(minAge, maxAge))
+		if *discoverClusters {
+			opts = append(opts, backup.WithClusterDiscovery(*backupConcurrency))
+		}
+
+		copyDestinations, err := parseCopyDestinations(*copyRegions, *copyKMSKeyIDs, *copyBackupsRetention)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing copy destinations")
+			cli.Exit(exitCodeError)
+		}
+		opts = append(opts, backup.WithCopyDestinations(copyDestinations...))
+		opts = append(opts, backup.WithSnapshotSharing(*shareWithAccounts...))
+
+		if *vaultRoleARN != "" {
+			vault, err := newVaultCopy(*vaultRoleARN, *vaultRegion, *vaultKMSKeyID, *rdsRegion)
+			if err != nil {
+				log.WithError(err).Error("Error in configuring the backup vault account")
+				cli.Exit(exitCodeError)
+			}
+			opts = append(opts, backup.WithVaultCopy(vault))
+		}
+
+		if *exportBucket != "" {
+			opts = append(opts, backup.WithExport(backup.ExportDestination{
+				Bucket:     *exportBucket,
+				Prefix:     *exportPrefix,
+				IAMRoleARN: *exportRoleARN,
+				KMSKeyID:   *exportKMSKeyID,
+				ExportOnly: *exportOnly,
+				Retention:  *exportRetention,
+			}))
+		}
+
+		notifier, err := newNotifier(notifications)
+		if err != nil {
+			log.WithError(err).Error("Error in configuring the notifications")
+			cli.Exit(exitCodeError)
+		}
+
+		svc, err := backup.NewBackupService(*rdsRegion, clusterIDPrefix, snapshotIDPrefix, statusCheckInterval, *statusCheckAttempts, *backupsRetention, opts...)
 		if err != nil {
 			log.WithError(err).Error("Error in creating a new backup service")
-			return
+			cli.Exit(exitCodeError)
 		}
+		return withNotifications(svc, notifier, *pacEnvironment, slowCreation)
+	}
+
+	// lockRun takes the lock of the run, if one is configured.
+	lockRun := func(ctx context.Context) (context.Context, func(), bool) {
+		locker, err := newLocker(*lockSpec, awsRegion())
+		if err != nil {
+			log.WithError(err).Error("Error in configuring the run lock")
+			cli.Exit(exitCodeError)
+		}
+		ttl, err := time.ParseDuration(*lockTTLString)
+		if err != nil {
+			log.WithError(err).Error("Error in parsing lock-ttl parameter")
+			cli.Exit(exitCodeError)
+		}
+		name := *lockName
+		if name == "" {
+			name, err = defaultLockName(*configFile, *targetName, *pacEnvironment)
+			if err != nil {
+				log.WithError(err).Error("Error in naming the run lock")
+				cli.Exit(exitCodeError)
+			}
+		}
+		return newRunLock(locker, name, lockOwner(), ttl)(ctx)
+	}
 
-		svc.MakeBackup()
-		svc.CleanUpOldBackups()
+	env := &commandEnv{
+		newContext: newContext,
+		newService: newService,
+		lockRun:    lockRun,
+		dryRun:     dryRun,
+		configFile: configFile,
+		pushRun: func(command string, run *metrics.Run) {
+			pushMetrics(*pushgatewayURL, *appSystemCode, *pacEnvironment, command, run)
+		},
+		writeReport: func(command string, run *metrics.Run, err error) {
+			if command == "" {
+				command = "run"
+			}
+			environment := *pacEnvironment
+			if *configFile != "" {
+				environment = ""
+			}
+			writeRunReport(*reportSpec, awsRegion(), report.Run{
+				Command:     command,
+				Version:     version,
+				Environment: environment,
+				ConfigFile:  *configFile,
+				Target:      *targetName,
+				StartTime:   run.StartTime,
+				Duration:    run.Duration,
+				ExitCode:    run.ExitCode,
+				Backups:     run.Backups,
+				Cleanups:    run.Cleanups,
+				Err:         err,
+			})
+		},
+		pushVerification: func(verification *metrics.Verification) {
+			pushVerificationMetrics(*pushgatewayURL, *appSystemCode, *pacEnvironment, verification)
+		},
+	}
+	registerCommands(app, env)
 
+	app.Action = func() {
+		log.Warn("Running without a command is deprecated, use the run command to back up and clean up")
+		runBackupAndCleanup(env)
 	}
 
 	err := app.Run(os.Args)
@@ -106,6 +602,140 @@ func main() {
 	log.Infof("[Shutdown] %v is stopping", *appSystemCode)
 }
 
+// pushMetrics pushes the metrics of a run to the Pushgateway, if one is configured.
+// The metrics of the backup and cleanup commands are grouped under a command label.
+func pushMetrics(url, job, environment, command string, run *metrics.Run) {
+	if url == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), metricsPushTimeout)
+	defer cancel()
+	opts := []metrics.PusherOption{metrics.WithGrouping("environment", environment)}
+	if command != "" {
+		opts = append(opts, metrics.WithGrouping("command", command))
+	}
+	if err := metrics.NewPusher(url, job, opts...).Push(ctx, run); err != nil {
+		log.WithError(err).WithField("pushgatewayURL", url).Warn("Error in pushing metrics to the Pushgateway")
+		return
+	}
+	log.WithField("pushgatewayURL", url).Info("Metrics pushed to the Pushgateway")
+}
+
+// pushVerificationMetrics pushes the metrics of a verification to the Pushgateway, if one is configured,
+// with the same deadline and error handling as pushMetrics.
+func pushVerificationMetrics(url, job, environment string, verification *metrics.Verification) {
+	if url == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), metricsPushTimeout)
+	defer cancel()
+	if err := metrics.NewPusher(url, job, metrics.WithGrouping("environment", environment)).PushVerification(ctx, verification); err != nil {
+		log.WithError(err).WithField("pushgatewayURL", url).Warn("Error in pushing verification metrics to the Pushgateway")
+		return
+	}
+	log.WithField("pushgatewayURL", url).Info("Verification metrics pushed to the Pushgateway")
+}
+
+// runExitCode maps the errors of a run to the exit code of the process,
+// reporting any failure of a run whose context was cancelled or expired as an interruption.
+func runExitCode(ctx context.Context, errs ...error) int {
+	code := exitCode(errs...)
+	if code != exitCodeSuccess && ctx.Err() != nil {
+		return exitCodeInterrupted
+	}
+	return code
+}
+
+// exitCode maps the first non-nil error to the exit code of the process.
+func exitCode(errs ...error) int {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var interrupted *backup.InterruptedError
+		var clusterNotFound *backup.ClusterNotFoundError
+		var preflight *backup.PreflightError
+		var timeout *backup.SnapshotTimeoutError
+		var clusterTimeout *backup.ClusterTimeoutError
+		var unexpectedStatus *backup.UnexpectedStatusError
+		var unexpectedClusterStatus *backup.UnexpectedClusterStatusError
+		var deletion *backup.DeletionError
+		var copyErr *backup.CopyError
+		var shareErr *backup.ShareError
+		var exportErr *backup.ExportError
+		switch {
+		case errors.As(err, &interrupted):
+			return exitCodeInterrupted
+		case errors.As(err, &clusterNotFound):
+			return exitCodeClusterNotFound
+		case errors.As(err, &preflight):
+			return exitCodePreflightFailure
+		case errors.As(err, &timeout), errors.As(err, &clusterTimeout):
+			return exitCodeSnapshotTimeout
+		case errors.As(err, &unexpectedStatus), errors.As(err, &unexpectedClusterStatus):
+			return exitCodeUnexpectedStatus
+		case errors.As(err, &deletion):
+			return exitCodeDeletionFailure
+		case errors.As(err, &copyErr):
+			return exitCodeCopyFailure
+		case errors.As(err, &shareErr):
+			return exitCodeShareFailure
+		case errors.As(err, &exportErr):
+			return exitCodeExportFailure
+		default:
+			return exitCodeError
+		}
+	}
+	return exitCodeSuccess
+}
+
+// parseCopyDestinations builds the copy destinations from the list of regions
+// and the list of <region>=<kms-key-id> pairs.
+func parseCopyDestinations(regions, kmsKeyIDs []string, retention int) ([]backup.CopyDestination, error) {
+	keys := make(map[string]string)
+	for _, pair := range kmsKeyIDs {
+		region, keyID, found := strings.Cut(pair, "=")
+		if !found || region == "" || keyID == "" {
+			return nil, fmt.Errorf("KMS key ID is not in the <region>=<key-id> format: %v", pair)
+		}
+		keys[region] = keyID
+	}
+
+	var destinations []backup.CopyDestination
+	for _, region := range regions {
+		if region == "" {
+			continue
+		}
+		destinations = append(destinations, backup.CopyDestination{
+			Region:    region,
+			KMSKeyID:  keys[region],
+			Retention: retention,
+		})
+		delete(keys, region)
+	}
+	for region := range keys {
+		return nil, fmt.Errorf("KMS key ID provided for region %v which is not a copy region", region)
+	}
+	return destinations, nil
+}
+
+// newVaultCopy configures the copy into the backup vault account owning the given IAM role.
+func newVaultCopy(roleARN, region, kmsKeyID, defaultRegion string) (backup.VaultCopy, error) {
+	parsed, err := arn.Parse(roleARN)
+	if err != nil || parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+		return backup.VaultCopy{}, fmt.Errorf("vault role ARN is invalid: %v", roleARN)
+	}
+	if region == "" {
+		region = defaultRegion
+	}
+	return backup.VaultCopy{
+		AccountID: parsed.AccountID,
+		RoleARN:   roleARN,
+		Region:    region,
+		KMSKeyID:  kmsKeyID,
+	}, nil
+}
+
 func extractEnvironmentLevel(env string) (string, error) {
 	firstHyphenIndex := strings.Index(env, "-")
 	lastHyphenIndex := strings.LastIndex(env, "-")
@@ -118,3 +748,28 @@ func extractEnvironmentLevel(env string) (string, error) {
 	}
 	return envLevel, nil
 }
+
+// newRunID returns a random identifier of the run, 8 lowercase hexadecimal digits.
+func newRunID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().Unix(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// newRegionSession returns an AWS session in the given region, or else in the region of the environment, e.g. $AWS_REGION.
+func newRegionSession(region string) (*session.Session, error) {
+	config := aws.NewConfig()
+	if region != "" {
+		config = config.WithRegion(region)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(sess.Config.Region) == "" {
+		return nil, errors.New("no AWS region: set --rds-region, a single region for the targets of the config file, or $AWS_REGION")
+	}
+	return sess, nil
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/metrics"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExtractEnvironmentLevel(t *testing.T) {
@@ -28,3 +38,111 @@ func TestExtractEnvironmentLevelError(t *testing.T) {
 	_, err = extractEnvironmentLevel("pac--us")
 	assert.Error(t, err)
 }
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitCodeSuccess, exitCode())
+	assert.Equal(t, exitCodeSuccess, exitCode(nil, nil))
+	assert.Equal(t, exitCodeError, exitCode(errors.New("an AWS error")))
+	assert.Equal(t, exitCodeClusterNotFound, exitCode(&backup.ClusterNotFoundError{ClusterIDPrefix: "pac-aurora-staging"}))
+	assert.Equal(t, exitCodeSnapshotTimeout, exitCode(&backup.SnapshotTimeoutError{SnapshotID: "a-snapshot", Operation: "creation"}))
+	assert.Equal(t, exitCodeUnexpectedStatus, exitCode(fmt.Errorf("wrapped: %w", &backup.UnexpectedStatusError{Status: "failed"})))
+	assert.Equal(t, exitCodeSnapshotTimeout, exitCode(&backup.ClusterTimeoutError{ClusterID: "pac-aurora-restored", Operation: "restore"}))
+	assert.Equal(t, exitCodeUnexpectedStatus, exitCode(&backup.UnexpectedClusterStatusError{ResourceID: "pac-aurora-restored", Status: "incompatible-restore"}))
+	assert.Equal(t, exitCodeDeletionFailure, exitCode(nil, &backup.DeletionError{}))
+	assert.Equal(t, exitCodeCopyFailure, exitCode(&backup.ClusterError{Err: &backup.CopyError{Region: "us-east-1"}}))
+	assert.Equal(t, exitCodeShareFailure, exitCode(errors.Join(&backup.ShareError{}, errors.New("an AWS error"))))
+	assert.Equal(t, exitCodeExportFailure, exitCode(&backup.ClusterError{Err: &backup.ExportError{SnapshotID: "a-snapshot", Err: errors.New("export task failed")}}))
+	assert.Equal(t, exitCodeSnapshotTimeout, exitCode(&backup.ExportError{SnapshotID: "a-snapshot", Err: &backup.SnapshotTimeoutError{SnapshotID: "an-export", Operation: "export"}}))
+	assert.Equal(t, exitCodePreflightFailure, exitCode(&backup.ClusterError{Err: &backup.PreflightError{Conditions: []string{"cluster status is modifying"}}}))
+	assert.Equal(t, exitCodeClusterNotFound, exitCode(&backup.ClusterNotFoundError{}, &backup.DeletionError{}))
+	assert.Equal(t, exitCodeInterrupted, exitCode(errors.Join(&backup.DeletionError{}, &backup.InterruptedError{SnapshotID: "a-snapshot", Operation: "deletion", Err: context.Canceled})))
+}
+
+func TestRunExitCode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Equal(t, exitCodeSuccess, runExitCode(ctx, nil))
+	assert.Equal(t, exitCodeDeletionFailure, runExitCode(ctx, nil, &backup.DeletionError{}))
+
+	cancel()
+	assert.Equal(t, exitCodeSuccess, runExitCode(ctx, nil))
+	assert.Equal(t, exitCodeInterrupted, runExitCode(ctx, errors.New("RequestCanceled: request context canceled")))
+}
+
+func TestParseCopyDestinations(t *testing.T) {
+	destinations, err := parseCopyDestinations([]string{"eu-central-1", "us-east-1"}, []string{"us-east-1=arn:aws:kms:us-east-1:123456789012:key/abc"}, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, []backup.CopyDestination{
+		{Region: "eu-central-1", Retention: 7},
+		{Region: "us-east-1", KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/abc", Retention: 7},
+	}, destinations)
+
+	destinations, err = parseCopyDestinations([]string{}, []string{}, 7)
+	assert.NoError(t, err)
+	assert.Empty(t, destinations)
+}
+
+func TestParseCopyDestinationsError(t *testing.T) {
+	_, err := parseCopyDestinations([]string{"us-east-1"}, []string{"arn:aws:kms:us-east-1:123456789012:key/abc"}, 7)
+	assert.Error(t, err)
+
+	_, err = parseCopyDestinations([]string{"us-east-1"}, []string{"eu-central-1=a-key"}, 7)
+	assert.EqualError(t, err, "KMS key ID provided for region eu-central-1 which is not a copy region")
+}
+
+func TestNewVaultCopy(t *testing.T) {
+	vault, err := newVaultCopy("arn:aws:iam::210987654321:role/pac-aurora-backup-vault", "", "a-vault-key", "eu-west-1")
+	assert.NoError(t, err)
+	assert.Equal(t, backup.VaultCopy{
+		AccountID: "210987654321",
+		RoleARN:   "arn:aws:iam::210987654321:role/pac-aurora-backup-vault",
+		Region:    "eu-west-1",
+		KMSKeyID:  "a-vault-key",
+	}, vault)
+
+	vault, err = newVaultCopy("arn:aws:iam::210987654321:role/pac-aurora-backup-vault", "eu-central-1", "", "eu-west-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-central-1", vault.Region)
+}
+
+func TestNewVaultCopyError(t *testing.T) {
+	_, err := newVaultCopy("pac-aurora-backup-vault", "", "", "eu-west-1")
+	assert.Error(t, err)
+
+	_, err = newVaultCopy("arn:aws:s3:::a-bucket", "", "", "eu-west-1")
+	assert.Error(t, err)
+}
+
+func TestNewRunID(t *testing.T) {
+	runID := newRunID()
+	assert.Regexp(t, `^[0-9a-f]{8}$`, runID)
+	assert.NotEqual(t, runID, newRunID())
+}
+
+func TestPushMetrics(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer server.Close()
+
+	pushMetrics("", "pac-aurora-backup", "pac-prod-eu", "", &metrics.Run{StartTime: time.Now()})
+	assert.Empty(t, paths)
+
+	pushMetrics(server.URL, "pac-aurora-backup", "pac-prod-eu", "", &metrics.Run{StartTime: time.Now()})
+	pushMetrics(server.URL, "pac-aurora-backup", "pac-prod-eu", "cleanup", &metrics.Run{StartTime: time.Now()})
+	// The Pushgateway client adds the grouping labels to the URL in no particular order.
+	require.Len(t, paths, 2)
+	assert.Equal(t, map[string]string{"job": "pac-aurora-backup", "environment": "pac-prod-eu"}, groupingLabels(t, paths[0]))
+	assert.Equal(t, map[string]string{"job": "pac-aurora-backup", "environment": "pac-prod-eu", "command": "cleanup"}, groupingLabels(t, paths[1]))
+}
+
+// groupingLabels returns the labels of a Pushgateway URL path, /metrics/<label>/<value>/...
+func groupingLabels(t *testing.T, path string) map[string]string {
+	segments := strings.Split(strings.TrimPrefix(path, "/metrics/"), "/")
+	require.True(t, len(segments)%2 == 0, "unexpected path: %v", path)
+	labels := make(map[string]string)
+	for i := 0; i < len(segments); i += 2 {
+		labels[segments[i]] = segments[i+1]
+	}
+	return labels
+}
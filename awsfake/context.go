@@ -0,0 +1,96 @@
+package awsfake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// The WithContext variants behave like the AWS SDK ones: a call made with a cancelled
+// or expired context fails with a RequestCanceled error wrapping the context error.
+// Request options are ignored.
+
+func (f *RDS) DescribeDBClustersWithContext(ctx aws.Context, input *rds.DescribeDBClustersInput, _ ...request.Option) (*rds.DescribeDBClustersOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.DescribeDBClusters(input)
+}
+
+func (f *RDS) CreateDBClusterSnapshotWithContext(ctx aws.Context, input *rds.CreateDBClusterSnapshotInput, _ ...request.Option) (*rds.CreateDBClusterSnapshotOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.CreateDBClusterSnapshot(input)
+}
+
+func (f *RDS) DescribeDBClusterSnapshotsWithContext(ctx aws.Context, input *rds.DescribeDBClusterSnapshotsInput, _ ...request.Option) (*rds.DescribeDBClusterSnapshotsOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.DescribeDBClusterSnapshots(input)
+}
+
+func (f *RDS) DeleteDBClusterSnapshotWithContext(ctx aws.Context, input *rds.DeleteDBClusterSnapshotInput, _ ...request.Option) (*rds.DeleteDBClusterSnapshotOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.DeleteDBClusterSnapshot(input)
+}
+
+func (f *RDS) CopyDBClusterSnapshotWithContext(ctx aws.Context, input *rds.CopyDBClusterSnapshotInput, _ ...request.Option) (*rds.CopyDBClusterSnapshotOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.CopyDBClusterSnapshot(input)
+}
+
+func (f *RDS) ModifyDBClusterSnapshotAttributeWithContext(ctx aws.Context, input *rds.ModifyDBClusterSnapshotAttributeInput, _ ...request.Option) (*rds.ModifyDBClusterSnapshotAttributeOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.ModifyDBClusterSnapshotAttribute(input)
+}
+
+func canceled(ctx aws.Context) error {
+	if err := ctx.Err(); err != nil {
+		return awserr.New(request.CanceledErrorCode, "request context canceled", err)
+	}
+	return nil
+}
+
+func (f *RDS) RestoreDBClusterFromSnapshotWithContext(ctx aws.Context, input *rds.RestoreDBClusterFromSnapshotInput, _ ...request.Option) (*rds.RestoreDBClusterFromSnapshotOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.RestoreDBClusterFromSnapshot(input)
+}
+
+func (f *RDS) CreateDBInstanceWithContext(ctx aws.Context, input *rds.CreateDBInstanceInput, _ ...request.Option) (*rds.CreateDBInstanceOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.CreateDBInstance(input)
+}
+
+func (f *RDS) DescribeDBInstancesWithContext(ctx aws.Context, input *rds.DescribeDBInstancesInput, _ ...request.Option) (*rds.DescribeDBInstancesOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.DescribeDBInstances(input)
+}
+
+func (f *RDS) DeleteDBInstanceWithContext(ctx aws.Context, input *rds.DeleteDBInstanceInput, _ ...request.Option) (*rds.DeleteDBInstanceOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.DeleteDBInstance(input)
+}
+
+func (f *RDS) DeleteDBClusterWithContext(ctx aws.Context, input *rds.DeleteDBClusterInput, _ ...request.Option) (*rds.DeleteDBClusterOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.DeleteDBCluster(input)
+}
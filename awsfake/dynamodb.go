@@ -0,0 +1,262 @@
+package awsfake
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DynamoDB is an in-memory fake of the single item operations of the AWS DynamoDB API,
+// for tables with a string partition key and no sort key.
+// Condition expressions are evaluated, limited to comparisons and the attribute_exists and
+// attribute_not_exists functions, combined with AND and OR without parentheses.
+type DynamoDB struct {
+	mu       sync.Mutex
+	tables   map[string]*fakeTable
+	failures map[string][]error
+	calls    map[string]int
+}
+
+type fakeTable struct {
+	partitionKey string
+	items        map[string]map[string]*dynamodb.AttributeValue
+}
+
+// NewDynamoDB returns a fake DynamoDB without any table.
+func NewDynamoDB() *DynamoDB {
+	return &DynamoDB{
+		tables:   make(map[string]*fakeTable),
+		failures: make(map[string][]error),
+		calls:    make(map[string]int),
+	}
+}
+
+// AddTable creates an empty table with the given string partition key.
+func (f *DynamoDB) AddTable(table, partitionKey string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tables[table] = &fakeTable{partitionKey: partitionKey, items: make(map[string]map[string]*dynamodb.AttributeValue)}
+}
+
+// Item returns the item of the table with the given partition key, or nil if it does not exist.
+func (f *DynamoDB) Item(table, key string) map[string]*dynamodb.AttributeValue {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t := f.tables[table]; t != nil {
+		return t.items[key]
+	}
+	return nil
+}
+
+// FailNext makes the next call to the named operation (e.g. "PutItem")
+// return err. Multiple failures for the same operation are returned in order.
+func (f *DynamoDB) FailNext(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[operation] = append(f.failures[operation], err)
+}
+
+// Calls returns how many times the named operation has been called.
+func (f *DynamoDB) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[operation]
+}
+
+// PutItem stores an item, replacing the item with the same key, if the condition expression holds for the existing item.
+func (f *DynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("PutItem"); err != nil {
+		return nil, err
+	}
+
+	table, key, err := f.findItem(aws.StringValue(input.TableName), input.Item)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCondition(input.ConditionExpression, table.items[key], input.ExpressionAttributeNames, input.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+	item := make(map[string]*dynamodb.AttributeValue, len(input.Item))
+	for name, value := range input.Item {
+		item[name] = value
+	}
+	table.items[key] = item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// GetItem returns the item with the given key, or no item if it does not exist.
+func (f *DynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("GetItem"); err != nil {
+		return nil, err
+	}
+
+	table, key, err := f.findItem(aws.StringValue(input.TableName), input.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: table.items[key]}, nil
+}
+
+// DeleteItem deletes the item with the given key if the condition expression holds for it.
+func (f *DynamoDB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DeleteItem"); err != nil {
+		return nil, err
+	}
+
+	table, key, err := f.findItem(aws.StringValue(input.TableName), input.Key)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCondition(input.ConditionExpression, table.items[key], input.ExpressionAttributeNames, input.ExpressionAttributeValues); err != nil {
+		return nil, err
+	}
+	delete(table.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *DynamoDB) PutItemWithContext(ctx aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.PutItem(input)
+}
+
+func (f *DynamoDB) GetItemWithContext(ctx aws.Context, input *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.GetItem(input)
+}
+
+func (f *DynamoDB) DeleteItemWithContext(ctx aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.DeleteItem(input)
+}
+
+func (f *DynamoDB) call(operation string) error {
+	f.calls[operation]++
+	if failures := f.failures[operation]; len(failures) > 0 {
+		f.failures[operation] = failures[1:]
+		return failures[0]
+	}
+	return nil
+}
+
+func (f *DynamoDB) findItem(tableName string, item map[string]*dynamodb.AttributeValue) (*fakeTable, string, error) {
+	table := f.tables[tableName]
+	if table == nil {
+		return nil, "", awserr.New(dynamodb.ErrCodeResourceNotFoundException, fmt.Sprintf("Requested resource not found: Table: %v not found", tableName), nil)
+	}
+	key := item[table.partitionKey]
+	if key == nil || key.S == nil {
+		return nil, "", awserr.New("ValidationException", fmt.Sprintf("One of the required keys was not given a value: %v", table.partitionKey), nil)
+	}
+	return table, *key.S, nil
+}
+
+// checkCondition evaluates a condition expression against an item, nil if it does not exist.
+func checkCondition(expression *string, item map[string]*dynamodb.AttributeValue, names map[string]*string, values map[string]*dynamodb.AttributeValue) error {
+	if expression == nil {
+		return nil
+	}
+	for _, conjunction := range strings.Split(*expression, " OR ") {
+		holds := true
+		for _, term := range strings.Split(conjunction, " AND ") {
+			ok, err := evaluateTerm(strings.TrimSpace(term), item, names, values)
+			if err != nil {
+				return err
+			}
+			holds = holds && ok
+		}
+		if holds {
+			return nil
+		}
+	}
+	return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+}
+
+func evaluateTerm(term string, item map[string]*dynamodb.AttributeValue, names map[string]*string, values map[string]*dynamodb.AttributeValue) (bool, error) {
+	for function, exists := range map[string]bool{"attribute_exists": true, "attribute_not_exists": false} {
+		if argument, found := strings.CutPrefix(term, function+"("); found {
+			name := resolveName(strings.TrimSuffix(argument, ")"), names)
+			return (item[name] != nil) == exists, nil
+		}
+	}
+
+	fields := strings.Fields(term)
+	if len(fields) != 3 {
+		return false, awserr.New("ValidationException", fmt.Sprintf("Invalid ConditionExpression: unsupported term %q", term), nil)
+	}
+	left, right := operand(fields[0], item, names, values), operand(fields[2], item, names, values)
+	if left == nil || right == nil {
+		return false, nil
+	}
+	cmp, err := compareAttributes(left, right)
+	if err != nil {
+		return false, err
+	}
+	switch fields[1] {
+	case "=":
+		return cmp == 0, nil
+	case "<>":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	}
+	return false, awserr.New("ValidationException", fmt.Sprintf("Invalid ConditionExpression: unsupported operator %q", fields[1]), nil)
+}
+
+func resolveName(name string, names map[string]*string) string {
+	if strings.HasPrefix(name, "#") {
+		return aws.StringValue(names[name])
+	}
+	return name
+}
+
+func operand(s string, item map[string]*dynamodb.AttributeValue, names map[string]*string, values map[string]*dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if strings.HasPrefix(s, ":") {
+		return values[s]
+	}
+	return item[resolveName(s, names)]
+}
+
+func compareAttributes(a, b *dynamodb.AttributeValue) (int, error) {
+	switch {
+	case a.N != nil && b.N != nil:
+		x, errX := strconv.ParseFloat(*a.N, 64)
+		y, errY := strconv.ParseFloat(*b.N, 64)
+		if errX != nil || errY != nil {
+			return 0, awserr.New("ValidationException", "Invalid number", nil)
+		}
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		}
+		return 0, nil
+	case a.S != nil && b.S != nil:
+		return strings.Compare(*a.S, *b.S), nil
+	}
+	return 0, awserr.New("ValidationException", "Invalid ConditionExpression: operands of different types", nil)
+}
@@ -0,0 +1,68 @@
+package awsfake
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutItemWithCondition(t *testing.T) {
+	fake := NewDynamoDB()
+	fake.AddTable("locks", "lock")
+	put := func(owner, expires, now string) error {
+		_, err := fake.PutItem(&dynamodb.PutItemInput{
+			TableName: aws.String("locks"),
+			Item: map[string]*dynamodb.AttributeValue{
+				"lock":    {S: aws.String("pac-aurora-prod")},
+				"owner":   {S: aws.String(owner)},
+				"expires": {N: aws.String(expires)},
+			},
+			ConditionExpression:      aws.String("attribute_not_exists(#lock) OR #expires < :now OR #owner = :owner"),
+			ExpressionAttributeNames: map[string]*string{"#lock": aws.String("lock"), "#expires": aws.String("expires"), "#owner": aws.String("owner")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":now":   {N: aws.String(now)},
+				":owner": {S: aws.String(owner)},
+			},
+		})
+		return err
+	}
+
+	require.NoError(t, put("pod-a", "1100", "1000"))
+	assertAWSErrorCode(t, dynamodb.ErrCodeConditionalCheckFailedException, put("pod-b", "1150", "1050"))
+	require.NoError(t, put("pod-a", "1200", "1100"), "the owner should be able to renew")
+	require.NoError(t, put("pod-b", "1300", "1201"), "an expired item should be replaced")
+	assert.Equal(t, "pod-b", aws.StringValue(fake.Item("locks", "pac-aurora-prod")["owner"].S))
+}
+
+func TestDeleteItemWithCondition(t *testing.T) {
+	fake := NewDynamoDB()
+	fake.AddTable("locks", "lock")
+	key := map[string]*dynamodb.AttributeValue{"lock": {S: aws.String("pac-aurora-prod")}}
+	_, err := fake.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String("locks"),
+		Item:      map[string]*dynamodb.AttributeValue{"lock": key["lock"], "owner": {S: aws.String("pod-a")}},
+	})
+	require.NoError(t, err)
+
+	input := &dynamodb.DeleteItemInput{
+		TableName:                 aws.String("locks"),
+		Key:                       key,
+		ConditionExpression:       aws.String("owner = :owner"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":owner": {S: aws.String("pod-b")}},
+	}
+	_, err = fake.DeleteItem(input)
+	assertAWSErrorCode(t, dynamodb.ErrCodeConditionalCheckFailedException, err)
+
+	input.ExpressionAttributeValues[":owner"] = &dynamodb.AttributeValue{S: aws.String("pod-a")}
+	_, err = fake.DeleteItem(input)
+	require.NoError(t, err)
+	out, err := fake.GetItem(&dynamodb.GetItemInput{TableName: aws.String("locks"), Key: key})
+	require.NoError(t, err)
+	assert.Nil(t, out.Item)
+
+	_, err = fake.GetItem(&dynamodb.GetItemInput{TableName: aws.String("missing"), Key: key})
+	assertAWSErrorCode(t, dynamodb.ErrCodeResourceNotFoundException, err)
+}
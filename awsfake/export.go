@@ -0,0 +1,218 @@
+package awsfake
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// Statuses of the export tasks.
+const (
+	ExportStatusStarting   = "STARTING"
+	ExportStatusInProgress = "IN_PROGRESS"
+	ExportStatusComplete   = "COMPLETE"
+	ExportStatusFailed     = "FAILED"
+)
+
+// maxExportTaskIDLength is the maximum length of an export task identifier.
+const maxExportTaskIDLength = 60
+
+type fakeExportTask struct {
+	*rds.ExportTask
+	pendingPolls int
+}
+
+// ExportTasks returns a copy of all the export tasks, without advancing their lifecycle.
+func (f *RDS) ExportTasks() []*rds.ExportTask {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var tasks []*rds.ExportTask
+	for _, t := range f.exportTasks {
+		task := *t.ExportTask
+		tasks = append(tasks, &task)
+	}
+	return tasks
+}
+
+// StartExportTask starts the export of an available snapshot of the fake.
+// The task is STARTING, IN_PROGRESS for ExportPolls calls to DescribeExportTasks, and then COMPLETE,
+// or FAILED with ExportFailureCause if it is set. A complete task writes an export info object
+// and a Parquet object per exported table under the prefix of the task in S3, if set.
+func (f *RDS) StartExportTask(input *rds.StartExportTaskInput) (*rds.StartExportTaskOutput, error) {
+	f.mu.Lock()
+	err := f.call("StartExportTask")
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	taskID := aws.StringValue(input.ExportTaskIdentifier)
+	if err := validateParameterIdentifier("ExportTaskIdentifier", taskID); err != nil {
+		return nil, err
+	}
+	if len(taskID) > maxExportTaskIDLength {
+		return nil, awserr.New("InvalidParameterValue", fmt.Sprintf("The parameter ExportTaskIdentifier is longer than %d characters", maxExportTaskIDLength), nil)
+	}
+	for _, parameter := range []struct {
+		name  string
+		value *string
+	}{
+		{"S3BucketName", input.S3BucketName},
+		{"IamRoleArn", input.IamRoleArn},
+		{"KmsKeyId", input.KmsKeyId},
+	} {
+		if aws.StringValue(parameter.value) == "" {
+			return nil, awserr.New("MissingParameter", fmt.Sprintf("The request must contain the parameter %v", parameter.name), nil)
+		}
+	}
+	source, owner := f.sourceSnapshot(aws.StringValue(input.SourceArn))
+	if source == nil || owner != f {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot not found: %v", aws.StringValue(input.SourceArn)), nil)
+	}
+	if aws.StringValue(source.Status) != statusAvailable {
+		return nil, awserr.New(rds.ErrCodeInvalidExportSourceStateFault, fmt.Sprintf("Snapshot %v is not available", aws.StringValue(source.DBClusterSnapshotIdentifier)), nil)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.findExportTask(taskID) != nil {
+		return nil, awserr.New(rds.ErrCodeExportTaskAlreadyExistsFault, fmt.Sprintf("Export task %v already exists", taskID), nil)
+	}
+	task := &fakeExportTask{
+		ExportTask: &rds.ExportTask{
+			ExportTaskIdentifier: aws.String(taskID),
+			SourceArn:            source.DBClusterSnapshotArn,
+			SourceType:           aws.String(rds.ExportSourceTypeSnapshot),
+			SnapshotTime:         source.SnapshotCreateTime,
+			S3Bucket:             input.S3BucketName,
+			S3Prefix:             input.S3Prefix,
+			IamRoleArn:           input.IamRoleArn,
+			KmsKeyId:             input.KmsKeyId,
+			ExportOnly:           input.ExportOnly,
+			Status:               aws.String(ExportStatusStarting),
+			PercentProgress:      aws.Int64(0),
+			TaskStartTime:        aws.Time(f.Now().UTC()),
+		},
+		pendingPolls: f.ExportPolls,
+	}
+	f.exportTasks = append(f.exportTasks, task)
+	t := *task.ExportTask
+	return &rds.StartExportTaskOutput{
+		ExportTaskIdentifier: t.ExportTaskIdentifier,
+		SourceArn:            t.SourceArn,
+		SourceType:           t.SourceType,
+		SnapshotTime:         t.SnapshotTime,
+		S3Bucket:             t.S3Bucket,
+		S3Prefix:             t.S3Prefix,
+		IamRoleArn:           t.IamRoleArn,
+		KmsKeyId:             t.KmsKeyId,
+		ExportOnly:           t.ExportOnly,
+		Status:               t.Status,
+		PercentProgress:      t.PercentProgress,
+		TaskStartTime:        t.TaskStartTime,
+	}, nil
+}
+
+// DescribeExportTasks advances the lifecycle of every export task by one status check
+// and returns the tasks matching the identifier or the source ARN of the input.
+func (f *RDS) DescribeExportTasks(input *rds.DescribeExportTasksInput) (*rds.DescribeExportTasksOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DescribeExportTasks"); err != nil {
+		return nil, err
+	}
+
+	f.observeExportTasks()
+	var tasks []*rds.ExportTask
+	for _, t := range f.exportTasks {
+		if input.ExportTaskIdentifier != nil && *input.ExportTaskIdentifier != *t.ExportTaskIdentifier {
+			continue
+		}
+		if input.SourceArn != nil && *input.SourceArn != *t.SourceArn {
+			continue
+		}
+		task := *t.ExportTask
+		tasks = append(tasks, &task)
+	}
+	if input.ExportTaskIdentifier != nil && len(tasks) == 0 {
+		return nil, awserr.New(rds.ErrCodeExportTaskNotFoundFault, fmt.Sprintf("Export task %v not found", *input.ExportTaskIdentifier), nil)
+	}
+
+	start, end, marker, err := f.page(len(tasks), input.Marker, input.MaxRecords)
+	if err != nil {
+		return nil, err
+	}
+	return &rds.DescribeExportTasksOutput{ExportTasks: tasks[start:end], Marker: marker}, nil
+}
+
+func (f *RDS) StartExportTaskWithContext(ctx aws.Context, input *rds.StartExportTaskInput, _ ...request.Option) (*rds.StartExportTaskOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.StartExportTask(input)
+}
+
+func (f *RDS) DescribeExportTasksWithContext(ctx aws.Context, input *rds.DescribeExportTasksInput, _ ...request.Option) (*rds.DescribeExportTasksOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.DescribeExportTasks(input)
+}
+
+func (f *RDS) observeExportTasks() {
+	for _, t := range f.exportTasks {
+		switch *t.Status {
+		case ExportStatusStarting, ExportStatusInProgress:
+			if t.pendingPolls > 0 {
+				t.pendingPolls--
+				t.Status = aws.String(ExportStatusInProgress)
+				continue
+			}
+			t.TaskEndTime = aws.Time(f.Now().UTC())
+			if f.ExportFailureCause != "" {
+				t.Status = aws.String(ExportStatusFailed)
+				t.FailureCause = aws.String(f.ExportFailureCause)
+				continue
+			}
+			t.Status = aws.String(ExportStatusComplete)
+			t.PercentProgress = aws.Int64(100)
+			t.TotalExtractedDataInGB = aws.Int64(1)
+			f.writeExport(t.ExportTask)
+		}
+	}
+}
+
+// writeExport stores the objects of a complete export task in the S3 fake, as AWS lays them out:
+// <prefix>/<task>/export_info_<task>.json and <prefix>/<task>/<database>/<table>/part-00000.parquet.
+func (f *RDS) writeExport(task *rds.ExportTask) {
+	if f.S3 == nil {
+		return
+	}
+	folder := aws.StringValue(task.ExportTaskIdentifier) + "/"
+	if prefix := strings.TrimSuffix(aws.StringValue(task.S3Prefix), "/"); prefix != "" {
+		folder = prefix + "/" + folder
+	}
+	tables := aws.StringValueSlice(task.ExportOnly)
+	if len(tables) == 0 {
+		tables = []string{"database.table"}
+	}
+	f.S3.mu.Lock()
+	defer f.S3.mu.Unlock()
+	f.S3.putObject(*task.S3Bucket, folder+"export_info_"+*task.ExportTaskIdentifier+".json", []byte("{}"))
+	for _, table := range tables {
+		f.S3.putObject(*task.S3Bucket, folder+strings.ReplaceAll(table, ".", "/")+"/part-00000.parquet", []byte("PAR1"))
+	}
+}
+
+func (f *RDS) findExportTask(taskID string) *fakeExportTask {
+	for _, t := range f.exportTasks {
+		if *t.ExportTaskIdentifier == taskID {
+			return t
+		}
+	}
+	return nil
+}
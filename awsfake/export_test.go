@@ -0,0 +1,91 @@
+package awsfake
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportInput(taskID, snapshotARN string) *rds.StartExportTaskInput {
+	input := new(rds.StartExportTaskInput)
+	input.SetExportTaskIdentifier(taskID)
+	input.SetSourceArn(snapshotARN)
+	input.SetS3BucketName("pac-exports")
+	input.SetS3Prefix("aurora/")
+	input.SetIamRoleArn("arn:aws:iam::123456789012:role/export")
+	input.SetKmsKeyId("arn:aws:kms:eu-west-1:123456789012:key/export")
+	return input
+}
+
+func TestExportLifecycle(t *testing.T) {
+	fake := NewRDS()
+	fake.ExportPolls = 2
+	fake.S3 = NewS3()
+	fake.AddSnapshot(&rds.DBClusterSnapshot{DBClusterSnapshotIdentifier: aws.String("a-snapshot"), DBClusterIdentifier: aws.String("pac-aurora-staging")})
+	snapshotARN := "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:a-snapshot"
+
+	input := exportInput("a-snapshot-export", snapshotARN)
+	input.SetExportOnly(aws.StringSlice([]string{"annotations.annotation"}))
+	out, err := fake.StartExportTask(input)
+	require.NoError(t, err)
+	assert.Equal(t, ExportStatusStarting, *out.Status)
+
+	describeInput := new(rds.DescribeExportTasksInput)
+	describeInput.SetExportTaskIdentifier("a-snapshot-export")
+	for _, expectedStatus := range []string{ExportStatusInProgress, ExportStatusInProgress, ExportStatusComplete} {
+		tasks, err := fake.DescribeExportTasks(describeInput)
+		require.NoError(t, err)
+		require.Len(t, tasks.ExportTasks, 1)
+		assert.Equal(t, expectedStatus, *tasks.ExportTasks[0].Status)
+	}
+	assert.Equal(t, []string{
+		"aurora/a-snapshot-export/annotations/annotation/part-00000.parquet",
+		"aurora/a-snapshot-export/export_info_a-snapshot-export.json",
+	}, fake.S3.Keys("pac-exports", ""))
+
+	_, err = fake.StartExportTask(exportInput("a-snapshot-export", snapshotARN))
+	assertAWSErrorCode(t, rds.ErrCodeExportTaskAlreadyExistsFault, err)
+}
+
+func TestExportFailure(t *testing.T) {
+	fake := NewRDS()
+	fake.ExportPolls = 0
+	fake.ExportFailureCause = "S3 bucket not accessible"
+	fake.AddSnapshot(&rds.DBClusterSnapshot{DBClusterSnapshotIdentifier: aws.String("a-snapshot")})
+
+	_, err := fake.StartExportTask(exportInput("a-snapshot-export", "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:a-snapshot"))
+	require.NoError(t, err)
+	tasks, err := fake.DescribeExportTasks(new(rds.DescribeExportTasksInput))
+	require.NoError(t, err)
+	require.Len(t, tasks.ExportTasks, 1)
+	assert.Equal(t, ExportStatusFailed, *tasks.ExportTasks[0].Status)
+	assert.Equal(t, "S3 bucket not accessible", *tasks.ExportTasks[0].FailureCause)
+}
+
+func TestStartExportTaskErrors(t *testing.T) {
+	fake := NewRDS()
+	fake.AddSnapshot(&rds.DBClusterSnapshot{DBClusterSnapshotIdentifier: aws.String("a-snapshot"), Status: aws.String(statusCreating)})
+	snapshotARN := "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:a-snapshot"
+
+	_, err := fake.StartExportTask(exportInput("a-snapshot-export", snapshotARN))
+	assertAWSErrorCode(t, rds.ErrCodeInvalidExportSourceStateFault, err)
+
+	_, err = fake.StartExportTask(exportInput("a-snapshot-export", "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:missing"))
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterSnapshotNotFoundFault, err)
+
+	input := exportInput("a-snapshot-export", snapshotARN)
+	input.KmsKeyId = nil
+	_, err = fake.StartExportTask(input)
+	assertAWSErrorCode(t, "MissingParameter", err)
+
+	_, err = fake.StartExportTask(exportInput("an-export-task-identifier-longer-than-sixty-characters-allowed", snapshotARN))
+	assertAWSErrorCode(t, "InvalidParameterValue", err)
+
+	describeInput := new(rds.DescribeExportTasksInput)
+	describeInput.SetExportTaskIdentifier("missing")
+	_, err = fake.DescribeExportTasks(describeInput)
+	assertAWSErrorCode(t, rds.ErrCodeExportTaskNotFoundFault, err)
+}
@@ -0,0 +1,582 @@
+// Package awsfake provides stateful, in-memory fakes of the AWS APIs used by
+// pac-aurora-backup, so that the backup logic can be tested without an AWS account.
+package awsfake
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+const (
+	statusAvailable = "available"
+	statusCreating  = "creating"
+	statusDeleting  = "deleting"
+
+	snapshotTypeManual = "manual"
+)
+
+const (
+	defaultRegion    = "eu-west-1"
+	defaultAccountID = "123456789012"
+	defaultPageSize  = 100
+)
+
+// RDS is an in-memory fake of the AWS RDS API.
+//
+// Every call to DescribeDBClusterSnapshots is a status check that moves the
+// snapshots through the same lifecycle as in AWS: a new snapshot stays
+// "creating" for CreationPolls checks and then becomes "available"; a deleted
+// snapshot stays "deleting" for DeletionPolls checks and then disappears.
+// Describe calls are paginated with at most PageSize records per page.
+//
+// Likewise, a cluster restored from a snapshot stays "creating" for RestorePolls
+// calls to DescribeDBClusters, and a new instance for RestorePolls calls to DescribeDBInstances.
+// A deleted cluster or instance stays "deleting" for DeletionPolls of those calls and then disappears,
+// a deleted cluster with its instances.
+//
+// An export task of a snapshot stays in progress for ExportPolls calls to DescribeExportTasks
+// and then completes, writing its objects into S3 if set, or fails with ExportFailureCause if set.
+type RDS struct {
+	Region             string
+	AccountID          string
+	PageSize           int
+	CreationPolls      int
+	DeletionPolls      int
+	RestorePolls       int
+	ExportPolls        int
+	ExportFailureCause string
+	S3                 *S3
+	Now                func() time.Time
+
+	mu               sync.Mutex
+	clusters         []*rds.DBCluster
+	snapshots        []*fakeSnapshot
+	instances        []*rds.DBInstance
+	pendingClusters  map[string]int
+	pendingInstances map[string]int
+	failures         map[string][]error
+	calls            map[string]int
+	peers            map[string]*RDS
+	exportTasks      []*fakeExportTask
+}
+
+type fakeSnapshot struct {
+	*rds.DBClusterSnapshot
+	pendingPolls int
+	sharedWith   []string
+}
+
+// NewRDS returns an empty fake RDS where snapshots need one status check
+// to be created and one to be deleted, restored clusters and instances one to be created,
+// and export tasks one to complete.
+func NewRDS() *RDS {
+	return &RDS{
+		Region:           defaultRegion,
+		AccountID:        defaultAccountID,
+		PageSize:         defaultPageSize,
+		CreationPolls:    1,
+		DeletionPolls:    1,
+		RestorePolls:     1,
+		ExportPolls:      1,
+		Now:              time.Now,
+		pendingClusters:  make(map[string]int),
+		pendingInstances: make(map[string]int),
+		failures:         make(map[string][]error),
+		calls:            make(map[string]int),
+		peers:            make(map[string]*RDS),
+	}
+}
+
+// AddPeer makes the snapshots of another fake, usually for a different region or account,
+// available as source of CopyDBClusterSnapshot through their ARN.
+func (f *RDS) AddPeer(peer *RDS) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.peers[peer.Region+":"+peer.AccountID] = peer
+}
+
+// AddCluster registers an available Aurora cluster and returns it,
+// so that the caller can adjust its attributes.
+func (f *RDS) AddCluster(clusterID string) *rds.DBCluster {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cluster := &rds.DBCluster{
+		DBClusterIdentifier: aws.String(clusterID),
+		DBClusterArn:        aws.String(f.arn("cluster", clusterID)),
+		Engine:              aws.String("aurora-mysql"),
+		Status:              aws.String(statusAvailable),
+		StorageEncrypted:    aws.Bool(false),
+	}
+	f.clusters = append(f.clusters, cluster)
+	return cluster
+}
+
+// AddSnapshot registers an existing snapshot. Missing attributes are set to
+// the values of an available manual snapshot taken now.
+// A snapshot added in the creating or deleting status goes through the rest of its lifecycle.
+func (f *RDS) AddSnapshot(snapshot *rds.DBClusterSnapshot) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := *snapshot
+	if s.DBClusterSnapshotArn == nil {
+		s.DBClusterSnapshotArn = aws.String(f.arn("cluster-snapshot", *s.DBClusterSnapshotIdentifier))
+	}
+	if s.Status == nil {
+		s.Status = aws.String(statusAvailable)
+	}
+	if s.SnapshotType == nil {
+		s.SnapshotType = aws.String(snapshotTypeManual)
+	}
+	if s.SnapshotCreateTime == nil {
+		s.SnapshotCreateTime = aws.Time(f.Now().UTC())
+	}
+	added := &fakeSnapshot{DBClusterSnapshot: &s}
+	switch *s.Status {
+	case statusCreating:
+		added.pendingPolls = f.CreationPolls
+	case statusDeleting:
+		added.pendingPolls = f.DeletionPolls
+	}
+	f.snapshots = append(f.snapshots, added)
+}
+
+// Snapshot returns a copy of the snapshot with the given identifier,
+// without advancing its lifecycle, or nil if it does not exist.
+func (f *RDS) Snapshot(snapshotID string) *rds.DBClusterSnapshot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if s := f.findSnapshot(snapshotID); s != nil {
+		return copySnapshot(s)
+	}
+	return nil
+}
+
+// UpdateSnapshot calls update with the snapshot with the given identifier, e.g. to remove its creation time,
+// and reports whether the snapshot exists.
+func (f *RDS) UpdateSnapshot(snapshotID string, update func(*rds.DBClusterSnapshot)) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.findSnapshot(snapshotID)
+	if s == nil {
+		return false
+	}
+	update(s.DBClusterSnapshot)
+	return true
+}
+
+// Snapshots returns a copy of all the snapshots, without advancing their lifecycle.
+func (f *RDS) Snapshots() []*rds.DBClusterSnapshot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var snapshots []*rds.DBClusterSnapshot
+	for _, s := range f.snapshots {
+		snapshots = append(snapshots, copySnapshot(s))
+	}
+	return snapshots
+}
+
+// SharedWith returns the AWS accounts allowed to restore or copy the snapshot.
+func (f *RDS) SharedWith(snapshotID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if s := f.findSnapshot(snapshotID); s != nil {
+		return append([]string(nil), s.sharedWith...)
+	}
+	return nil
+}
+
+// FailNext makes the next call to the named operation (e.g. "CreateDBClusterSnapshot")
+// return err. Multiple failures for the same operation are returned in order.
+func (f *RDS) FailNext(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[operation] = append(f.failures[operation], err)
+}
+
+// Calls returns how many times the named operation has been called.
+func (f *RDS) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[operation]
+}
+
+func (f *RDS) DescribeDBClusters(input *rds.DescribeDBClustersInput) (*rds.DescribeDBClustersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DescribeDBClusters"); err != nil {
+		return nil, err
+	}
+
+	f.observeClusters()
+	var clusters []*rds.DBCluster
+	for _, c := range f.clusters {
+		if input.DBClusterIdentifier != nil && *input.DBClusterIdentifier != *c.DBClusterIdentifier {
+			continue
+		}
+		cluster := *c
+		clusters = append(clusters, &cluster)
+	}
+	if input.DBClusterIdentifier != nil && len(clusters) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBClusterNotFoundFault, fmt.Sprintf("DBCluster %v not found.", *input.DBClusterIdentifier), nil)
+	}
+
+	start, end, marker, err := f.page(len(clusters), input.Marker, input.MaxRecords)
+	if err != nil {
+		return nil, err
+	}
+	return &rds.DescribeDBClustersOutput{DBClusters: clusters[start:end], Marker: marker}, nil
+}
+
+func (f *RDS) CreateDBClusterSnapshot(input *rds.CreateDBClusterSnapshotInput) (*rds.CreateDBClusterSnapshotOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("CreateDBClusterSnapshot"); err != nil {
+		return nil, err
+	}
+	if err := validateIdentifier(aws.StringValue(input.DBClusterSnapshotIdentifier)); err != nil {
+		return nil, err
+	}
+
+	cluster := f.findCluster(aws.StringValue(input.DBClusterIdentifier))
+	if cluster == nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterNotFoundFault, fmt.Sprintf("DBCluster %v not found.", aws.StringValue(input.DBClusterIdentifier)), nil)
+	}
+	if *cluster.Status != statusAvailable {
+		return nil, awserr.New(rds.ErrCodeInvalidDBClusterStateFault, fmt.Sprintf("DBCluster %v is not in available state.", *cluster.DBClusterIdentifier), nil)
+	}
+	for _, s := range f.snapshots {
+		if *s.DBClusterIdentifier == *cluster.DBClusterIdentifier && *s.Status == statusCreating {
+			return nil, awserr.New(rds.ErrCodeInvalidDBClusterStateFault, fmt.Sprintf("Cannot create a snapshot because another snapshot of DBCluster %v is in progress.", *cluster.DBClusterIdentifier), nil)
+		}
+	}
+	if f.findSnapshot(*input.DBClusterSnapshotIdentifier) != nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotAlreadyExistsFault, fmt.Sprintf("DBClusterSnapshot %v already exists.", *input.DBClusterSnapshotIdentifier), nil)
+	}
+
+	snapshot := &fakeSnapshot{
+		DBClusterSnapshot: &rds.DBClusterSnapshot{
+			DBClusterIdentifier:         cluster.DBClusterIdentifier,
+			DBClusterSnapshotIdentifier: input.DBClusterSnapshotIdentifier,
+			DBClusterSnapshotArn:        aws.String(f.arn("cluster-snapshot", *input.DBClusterSnapshotIdentifier)),
+			Engine:                      cluster.Engine,
+			EngineVersion:               cluster.EngineVersion,
+			KmsKeyId:                    cluster.KmsKeyId,
+			StorageEncrypted:            cluster.StorageEncrypted,
+			AllocatedStorage:            cluster.AllocatedStorage,
+			ClusterCreateTime:           cluster.ClusterCreateTime,
+			SnapshotCreateTime:          aws.Time(f.Now().UTC()),
+			SnapshotType:                aws.String(snapshotTypeManual),
+			Status:                      aws.String(statusCreating),
+			PercentProgress:             aws.Int64(0),
+			TagList:                     input.Tags,
+		},
+		pendingPolls: f.CreationPolls,
+	}
+	f.snapshots = append(f.snapshots, snapshot)
+	return &rds.CreateDBClusterSnapshotOutput{DBClusterSnapshot: copySnapshot(snapshot)}, nil
+}
+
+func (f *RDS) DescribeDBClusterSnapshots(input *rds.DescribeDBClusterSnapshotsInput) (*rds.DescribeDBClusterSnapshotsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DescribeDBClusterSnapshots"); err != nil {
+		return nil, err
+	}
+
+	var snapshots []*rds.DBClusterSnapshot
+	for _, s := range f.observeSnapshots() {
+		if input.DBClusterSnapshotIdentifier != nil && *input.DBClusterSnapshotIdentifier != *s.DBClusterSnapshotIdentifier {
+			continue
+		}
+		if input.DBClusterIdentifier != nil && *input.DBClusterIdentifier != *s.DBClusterIdentifier {
+			continue
+		}
+		if input.SnapshotType != nil && *input.SnapshotType != *s.SnapshotType {
+			continue
+		}
+		snapshots = append(snapshots, copySnapshot(s))
+	}
+	if input.DBClusterSnapshotIdentifier != nil && len(snapshots) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %v not found.", *input.DBClusterSnapshotIdentifier), nil)
+	}
+
+	start, end, marker, err := f.page(len(snapshots), input.Marker, input.MaxRecords)
+	if err != nil {
+		return nil, err
+	}
+	return &rds.DescribeDBClusterSnapshotsOutput{DBClusterSnapshots: snapshots[start:end], Marker: marker}, nil
+}
+
+func (f *RDS) DeleteDBClusterSnapshot(input *rds.DeleteDBClusterSnapshotInput) (*rds.DeleteDBClusterSnapshotOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DeleteDBClusterSnapshot"); err != nil {
+		return nil, err
+	}
+
+	snapshot := f.findSnapshot(aws.StringValue(input.DBClusterSnapshotIdentifier))
+	if snapshot == nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %v not found.", aws.StringValue(input.DBClusterSnapshotIdentifier)), nil)
+	}
+	if *snapshot.Status != statusAvailable {
+		return nil, awserr.New(rds.ErrCodeInvalidDBClusterSnapshotStateFault, fmt.Sprintf("Cannot delete the snapshot because it is in %v state.", *snapshot.Status), nil)
+	}
+
+	snapshot.Status = aws.String(statusDeleting)
+	snapshot.pendingPolls = f.DeletionPolls
+	return &rds.DeleteDBClusterSnapshotOutput{DBClusterSnapshot: copySnapshot(snapshot)}, nil
+}
+
+func (f *RDS) CopyDBClusterSnapshot(input *rds.CopyDBClusterSnapshotInput) (*rds.CopyDBClusterSnapshotOutput, error) {
+	f.mu.Lock()
+	err := f.call("CopyDBClusterSnapshot")
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sourceID := aws.StringValue(input.SourceDBClusterSnapshotIdentifier)
+	source, sourceOwner := f.sourceSnapshot(sourceID)
+	if source == nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %v not found.", sourceID), nil)
+	}
+	if *source.Status != statusAvailable {
+		return nil, awserr.New(rds.ErrCodeInvalidDBClusterSnapshotStateFault, fmt.Sprintf("DBClusterSnapshot %v is not in available state.", sourceID), nil)
+	}
+	if aws.BoolValue(source.StorageEncrypted) && sourceOwner.Region != f.Region && input.KmsKeyId == nil {
+		return nil, awserr.New("InvalidParameterCombination", "KmsKeyId is required to copy an encrypted snapshot across regions.", nil)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	targetID := aws.StringValue(input.TargetDBClusterSnapshotIdentifier)
+	if err := validateIdentifier(targetID); err != nil {
+		return nil, err
+	}
+	if f.findSnapshot(targetID) != nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotAlreadyExistsFault, fmt.Sprintf("DBClusterSnapshot %v already exists.", targetID), nil)
+	}
+
+	snapshot := &fakeSnapshot{DBClusterSnapshot: source, pendingPolls: f.CreationPolls}
+	snapshot.SourceDBClusterSnapshotArn = source.DBClusterSnapshotArn
+	snapshot.DBClusterSnapshotIdentifier = aws.String(targetID)
+	snapshot.DBClusterSnapshotArn = aws.String(f.arn("cluster-snapshot", targetID))
+	snapshot.SnapshotType = aws.String(snapshotTypeManual)
+	snapshot.Status = aws.String(statusCreating)
+	snapshot.PercentProgress = aws.Int64(0)
+	if input.KmsKeyId != nil {
+		snapshot.KmsKeyId = input.KmsKeyId
+	}
+	var tags []*rds.Tag
+	if aws.BoolValue(input.CopyTags) {
+		tags = append(tags, source.TagList...)
+	}
+	snapshot.TagList = append(tags, input.Tags...)
+	f.snapshots = append(f.snapshots, snapshot)
+	return &rds.CopyDBClusterSnapshotOutput{DBClusterSnapshot: copySnapshot(snapshot)}, nil
+}
+
+func (f *RDS) ModifyDBClusterSnapshotAttribute(input *rds.ModifyDBClusterSnapshotAttributeInput) (*rds.ModifyDBClusterSnapshotAttributeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ModifyDBClusterSnapshotAttribute"); err != nil {
+		return nil, err
+	}
+
+	snapshot := f.findSnapshot(aws.StringValue(input.DBClusterSnapshotIdentifier))
+	if snapshot == nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %v not found.", aws.StringValue(input.DBClusterSnapshotIdentifier)), nil)
+	}
+	if aws.StringValue(input.AttributeName) != "restore" {
+		return nil, awserr.New("InvalidParameterValue", fmt.Sprintf("Invalid attribute name: %v", aws.StringValue(input.AttributeName)), nil)
+	}
+	if *snapshot.SnapshotType != snapshotTypeManual || *snapshot.Status != statusAvailable {
+		return nil, awserr.New(rds.ErrCodeInvalidDBClusterSnapshotStateFault, "Only available manual snapshots can be shared.", nil)
+	}
+
+	for _, accountID := range aws.StringValueSlice(input.ValuesToAdd) {
+		if !contains(snapshot.sharedWith, accountID) {
+			snapshot.sharedWith = append(snapshot.sharedWith, accountID)
+		}
+	}
+	var sharedWith []string
+	for _, accountID := range snapshot.sharedWith {
+		if !contains(aws.StringValueSlice(input.ValuesToRemove), accountID) {
+			sharedWith = append(sharedWith, accountID)
+		}
+	}
+	snapshot.sharedWith = sharedWith
+
+	return &rds.ModifyDBClusterSnapshotAttributeOutput{
+		DBClusterSnapshotAttributesResult: &rds.DBClusterSnapshotAttributesResult{
+			DBClusterSnapshotIdentifier: snapshot.DBClusterSnapshotIdentifier,
+			DBClusterSnapshotAttributes: []*rds.DBClusterSnapshotAttribute{{
+				AttributeName:   aws.String("restore"),
+				AttributeValues: aws.StringSlice(sharedWith),
+			}},
+		},
+	}, nil
+}
+
+// sourceSnapshot resolves a snapshot identifier or ARN to a copy of the snapshot
+// and the fake owning it, which is either this fake or one of its peers.
+// Snapshots of another account are only visible when they are shared with this account.
+func (f *RDS) sourceSnapshot(id string) (*rds.DBClusterSnapshot, *RDS) {
+	owner := f
+	if strings.HasPrefix(id, "arn:") {
+		parts := strings.SplitN(id, ":", 7)
+		if len(parts) != 7 || parts[5] != "cluster-snapshot" {
+			return nil, nil
+		}
+		region, accountID := parts[3], parts[4]
+		id = parts[6]
+		if region != f.Region || accountID != f.AccountID {
+			f.mu.Lock()
+			owner = f.peers[region+":"+accountID]
+			f.mu.Unlock()
+			if owner == nil {
+				return nil, nil
+			}
+		}
+	}
+	if owner.AccountID != f.AccountID {
+		sharedWith := owner.SharedWith(id)
+		if !contains(sharedWith, f.AccountID) && !contains(sharedWith, "all") {
+			return nil, nil
+		}
+	}
+	return owner.Snapshot(id), owner
+}
+
+// observeSnapshots advances the lifecycle of every snapshot by one status check
+// and returns the snapshots that still exist.
+func (f *RDS) observeSnapshots() []*fakeSnapshot {
+	var remaining []*fakeSnapshot
+	for _, s := range f.snapshots {
+		switch *s.Status {
+		case statusCreating:
+			if s.pendingPolls <= 0 {
+				s.Status = aws.String(statusAvailable)
+				s.PercentProgress = aws.Int64(100)
+			}
+			s.pendingPolls--
+		case statusDeleting:
+			if s.pendingPolls <= 0 {
+				continue
+			}
+			s.pendingPolls--
+		}
+		remaining = append(remaining, s)
+	}
+	f.snapshots = remaining
+	return remaining
+}
+
+func (f *RDS) call(operation string) error {
+	f.calls[operation]++
+	if failures := f.failures[operation]; len(failures) > 0 {
+		f.failures[operation] = failures[1:]
+		return failures[0]
+	}
+	return nil
+}
+
+func (f *RDS) page(total int, marker *string, maxRecords *int64) (int, int, *string, error) {
+	start := 0
+	if marker != nil {
+		var err error
+		start, err = strconv.Atoi(*marker)
+		if err != nil || start < 0 || start > total {
+			return 0, 0, nil, awserr.New("InvalidParameterValue", fmt.Sprintf("Invalid marker: %v", *marker), nil)
+		}
+	}
+	size := f.PageSize
+	if maxRecords != nil && int(*maxRecords) < size {
+		size = int(*maxRecords)
+	}
+	end := start + size
+	if end >= total {
+		return start, total, nil, nil
+	}
+	return start, end, aws.String(strconv.Itoa(end)), nil
+}
+
+func (f *RDS) findCluster(clusterID string) *rds.DBCluster {
+	for _, c := range f.clusters {
+		if *c.DBClusterIdentifier == clusterID {
+			return c
+		}
+	}
+	return nil
+}
+
+func (f *RDS) findSnapshot(snapshotID string) *fakeSnapshot {
+	for _, s := range f.snapshots {
+		if *s.DBClusterSnapshotIdentifier == snapshotID {
+			return s
+		}
+	}
+	return nil
+}
+
+func (f *RDS) arn(resourceType, id string) string {
+	return fmt.Sprintf("arn:aws:rds:%v:%v:%v:%v", f.Region, f.AccountID, resourceType, id)
+}
+
+// validateIdentifier applies the RDS rules for snapshot identifiers.
+func validateIdentifier(id string) error {
+	return validateParameterIdentifier("DBClusterSnapshotIdentifier", id)
+}
+
+// validateParameterIdentifier applies the RDS rules for identifiers to the given parameter.
+func validateParameterIdentifier(parameter, id string) error {
+	invalid := func(reason string) error {
+		return awserr.New("InvalidParameterValue", fmt.Sprintf("The parameter %v is not a valid identifier: %v", parameter, reason), nil)
+	}
+	if len(id) < 1 || len(id) > 255 {
+		return invalid("it must contain from 1 to 255 characters")
+	}
+	for i, r := range id {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		switch {
+		case i == 0 && !isLetter:
+			return invalid("first character must be a letter")
+		case !isLetter && !isDigit && r != '-':
+			return invalid("identifiers must contain only ASCII letters, digits and hyphens")
+		case r == '-' && i > 0 && id[i-1] == '-':
+			return invalid("identifiers cannot contain two consecutive hyphens")
+		}
+	}
+	if id[len(id)-1] == '-' {
+		return invalid("identifiers cannot end with a hyphen")
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func copySnapshot(s *fakeSnapshot) *rds.DBClusterSnapshot {
+	snapshot := *s.DBClusterSnapshot
+	return &snapshot
+}
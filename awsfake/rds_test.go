@@ -0,0 +1,321 @@
+package awsfake
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotCreationLifecycle(t *testing.T) {
+	fake := NewRDS()
+	fake.CreationPolls = 2
+	fake.AddCluster("pac-aurora-staging")
+
+	out, err := fake.CreateDBClusterSnapshot(createInput("pac-aurora-staging", "a-snapshot"))
+	require.NoError(t, err)
+	assert.Equal(t, statusCreating, *out.DBClusterSnapshot.Status)
+	assert.Equal(t, "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:a-snapshot", *out.DBClusterSnapshot.DBClusterSnapshotArn)
+
+	input := new(rds.DescribeDBClusterSnapshotsInput)
+	input.SetDBClusterSnapshotIdentifier("a-snapshot")
+	for _, expectedStatus := range []string{statusCreating, statusCreating, statusAvailable, statusAvailable} {
+		result, err := fake.DescribeDBClusterSnapshots(input)
+		require.NoError(t, err)
+		require.Len(t, result.DBClusterSnapshots, 1)
+		assert.Equal(t, expectedStatus, *result.DBClusterSnapshots[0].Status)
+	}
+}
+
+func TestAddedSnapshotCreationLifecycle(t *testing.T) {
+	fake := NewRDS()
+	fake.CreationPolls = 1
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+		DBClusterSnapshotIdentifier: aws.String("rds:pac-aurora-staging-2024-01-01-03-00"),
+		SnapshotType:                aws.String("automated"),
+		Status:                      aws.String(statusCreating),
+	})
+
+	input := new(rds.DescribeDBClusterSnapshotsInput)
+	input.SetDBClusterIdentifier("pac-aurora-staging")
+	for _, expectedStatus := range []string{statusCreating, statusAvailable} {
+		result, err := fake.DescribeDBClusterSnapshots(input)
+		require.NoError(t, err)
+		require.Len(t, result.DBClusterSnapshots, 1)
+		assert.Equal(t, expectedStatus, *result.DBClusterSnapshots[0].Status)
+	}
+}
+
+func TestUpdateSnapshot(t *testing.T) {
+	fake := NewRDS()
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+		DBClusterSnapshotIdentifier: aws.String("pac-aurora-staging-backup-20180112"),
+	})
+
+	assert.True(t, fake.UpdateSnapshot("pac-aurora-staging-backup-20180112", func(s *rds.DBClusterSnapshot) { s.SnapshotCreateTime = nil }))
+	assert.False(t, fake.UpdateSnapshot("pac-aurora-staging-backup-20180113", func(s *rds.DBClusterSnapshot) { t.Error("unexpected update") }))
+
+	result, err := fake.DescribeDBClusterSnapshots(&rds.DescribeDBClusterSnapshotsInput{DBClusterIdentifier: aws.String("pac-aurora-staging")})
+	require.NoError(t, err)
+	require.Len(t, result.DBClusterSnapshots, 1)
+	assert.Nil(t, result.DBClusterSnapshots[0].SnapshotCreateTime)
+}
+
+func TestSnapshotDeletionLifecycle(t *testing.T) {
+	fake := NewRDS()
+	fake.DeletionPolls = 2
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+		DBClusterSnapshotIdentifier: aws.String("a-snapshot"),
+	})
+
+	deleteInput := new(rds.DeleteDBClusterSnapshotInput)
+	deleteInput.SetDBClusterSnapshotIdentifier("a-snapshot")
+	out, err := fake.DeleteDBClusterSnapshot(deleteInput)
+	require.NoError(t, err)
+	assert.Equal(t, statusDeleting, *out.DBClusterSnapshot.Status)
+
+	_, err = fake.DeleteDBClusterSnapshot(deleteInput)
+	assertAWSErrorCode(t, rds.ErrCodeInvalidDBClusterSnapshotStateFault, err)
+
+	input := new(rds.DescribeDBClusterSnapshotsInput)
+	input.SetDBClusterSnapshotIdentifier("a-snapshot")
+	for i := 0; i < 2; i++ {
+		result, err := fake.DescribeDBClusterSnapshots(input)
+		require.NoError(t, err)
+		assert.Equal(t, statusDeleting, *result.DBClusterSnapshots[0].Status)
+	}
+	_, err = fake.DescribeDBClusterSnapshots(input)
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterSnapshotNotFoundFault, err)
+	assert.Nil(t, fake.Snapshot("a-snapshot"))
+}
+
+func TestDescribeDBClusterSnapshotsPagination(t *testing.T) {
+	fake := NewRDS()
+	fake.PageSize = 3
+	for i := 0; i < 7; i++ {
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+			DBClusterSnapshotIdentifier: aws.String("snapshot-" + string(rune('a'+i))),
+		})
+	}
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+		DBClusterSnapshotIdentifier: aws.String("rds-automated"),
+		SnapshotType:                aws.String("automated"),
+	})
+
+	var ids []string
+	var pages int
+	input := new(rds.DescribeDBClusterSnapshotsInput)
+	input.SetSnapshotType(snapshotTypeManual)
+	for {
+		result, err := fake.DescribeDBClusterSnapshots(input)
+		require.NoError(t, err)
+		pages++
+		for _, s := range result.DBClusterSnapshots {
+			ids = append(ids, *s.DBClusterSnapshotIdentifier)
+		}
+		if result.Marker == nil {
+			break
+		}
+		input.SetMarker(*result.Marker)
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"snapshot-a", "snapshot-b", "snapshot-c", "snapshot-d", "snapshot-e", "snapshot-f", "snapshot-g"}, ids)
+
+	input.SetMarker("not-a-marker")
+	_, err := fake.DescribeDBClusterSnapshots(input)
+	assertAWSErrorCode(t, "InvalidParameterValue", err)
+}
+
+func TestDescribeDBClustersMaxRecords(t *testing.T) {
+	fake := NewRDS()
+	for _, id := range []string{"cluster-a", "cluster-b", "cluster-c"} {
+		fake.AddCluster(id)
+	}
+
+	input := new(rds.DescribeDBClustersInput)
+	input.SetMaxRecords(2)
+	result, err := fake.DescribeDBClusters(input)
+	require.NoError(t, err)
+	assert.Len(t, result.DBClusters, 2)
+	assert.NotNil(t, result.Marker)
+
+	input = new(rds.DescribeDBClustersInput)
+	input.SetDBClusterIdentifier("cluster-z")
+	_, err = fake.DescribeDBClusters(input)
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterNotFoundFault, err)
+}
+
+func TestCreateDBClusterSnapshotErrors(t *testing.T) {
+	fake := NewRDS()
+	fake.Now = func() time.Time { return time.Date(2018, 1, 12, 12, 0, 0, 0, time.UTC) }
+	fake.AddCluster("pac-aurora-staging")
+	fake.AddCluster("pac-aurora-prod").SetStatus("modifying")
+
+	_, err := fake.CreateDBClusterSnapshot(createInput("pac-aurora-dev", "a-snapshot"))
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterNotFoundFault, err)
+
+	_, err = fake.CreateDBClusterSnapshot(createInput("pac-aurora-prod", "a-snapshot"))
+	assertAWSErrorCode(t, rds.ErrCodeInvalidDBClusterStateFault, err)
+
+	for _, invalidID := range []string{"", "-a-snapshot", "1-snapshot", "a-snapshot-", "a--snapshot", "a_snapshot"} {
+		_, err = fake.CreateDBClusterSnapshot(createInput("pac-aurora-staging", invalidID))
+		assertAWSErrorCode(t, "InvalidParameterValue", err)
+	}
+
+	out, err := fake.CreateDBClusterSnapshot(createInput("pac-aurora-staging", "a-snapshot"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2018, 1, 12, 12, 0, 0, 0, time.UTC), *out.DBClusterSnapshot.SnapshotCreateTime)
+
+	_, err = fake.CreateDBClusterSnapshot(createInput("pac-aurora-staging", "another-snapshot"))
+	assertAWSErrorCode(t, rds.ErrCodeInvalidDBClusterStateFault, err)
+
+	_, err = fake.DescribeDBClusterSnapshots(new(rds.DescribeDBClusterSnapshotsInput))
+	require.NoError(t, err)
+	_, err = fake.DescribeDBClusterSnapshots(new(rds.DescribeDBClusterSnapshotsInput))
+	require.NoError(t, err)
+
+	_, err = fake.CreateDBClusterSnapshot(createInput("pac-aurora-staging", "a-snapshot"))
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterSnapshotAlreadyExistsFault, err)
+}
+
+func TestCopyDBClusterSnapshotAcrossRegions(t *testing.T) {
+	source := NewRDS()
+	source.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+		DBClusterSnapshotIdentifier: aws.String("a-snapshot"),
+		StorageEncrypted:            aws.Bool(true),
+		TagList:                     []*rds.Tag{{Key: aws.String("environment"), Value: aws.String("staging")}},
+	})
+	destination := NewRDS()
+	destination.Region = "us-east-1"
+	destination.CreationPolls = 0
+
+	input := new(rds.CopyDBClusterSnapshotInput)
+	input.SetSourceDBClusterSnapshotIdentifier("arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:a-snapshot")
+	input.SetTargetDBClusterSnapshotIdentifier("a-snapshot")
+	input.SetCopyTags(true)
+	_, err := destination.CopyDBClusterSnapshot(input)
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterSnapshotNotFoundFault, err)
+
+	destination.AddPeer(source)
+	_, err = destination.CopyDBClusterSnapshot(input)
+	assertAWSErrorCode(t, "InvalidParameterCombination", err)
+
+	input.SetKmsKeyId("a-key")
+	out, err := destination.CopyDBClusterSnapshot(input)
+	require.NoError(t, err)
+	assert.Equal(t, statusCreating, *out.DBClusterSnapshot.Status)
+	assert.Equal(t, "arn:aws:rds:us-east-1:123456789012:cluster-snapshot:a-snapshot", *out.DBClusterSnapshot.DBClusterSnapshotArn)
+	assert.Equal(t, "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:a-snapshot", *out.DBClusterSnapshot.SourceDBClusterSnapshotArn)
+	assert.Equal(t, "a-key", *out.DBClusterSnapshot.KmsKeyId)
+	assert.Len(t, out.DBClusterSnapshot.TagList, 1)
+
+	result, err := destination.DescribeDBClusterSnapshots(new(rds.DescribeDBClusterSnapshotsInput))
+	require.NoError(t, err)
+	assert.Equal(t, statusAvailable, *result.DBClusterSnapshots[0].Status)
+	assert.Equal(t, "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:a-snapshot", *source.Snapshot("a-snapshot").DBClusterSnapshotArn)
+
+	_, err = destination.CopyDBClusterSnapshot(input)
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterSnapshotAlreadyExistsFault, err)
+}
+
+func TestCopyDBClusterSnapshotAcrossAccounts(t *testing.T) {
+	source := NewRDS()
+	source.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+		DBClusterSnapshotIdentifier: aws.String("a-snapshot"),
+	})
+	vault := NewRDS()
+	vault.AccountID = "210987654321"
+	vault.AddPeer(source)
+
+	input := new(rds.CopyDBClusterSnapshotInput)
+	input.SetSourceDBClusterSnapshotIdentifier("arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:a-snapshot")
+	input.SetTargetDBClusterSnapshotIdentifier("a-snapshot")
+	_, err := vault.CopyDBClusterSnapshot(input)
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterSnapshotNotFoundFault, err)
+
+	share := new(rds.ModifyDBClusterSnapshotAttributeInput)
+	share.SetDBClusterSnapshotIdentifier("a-snapshot")
+	share.SetAttributeName("restore")
+	share.SetValuesToAdd(aws.StringSlice([]string{"210987654321", "111111111111"}))
+	out, err := source.ModifyDBClusterSnapshotAttribute(share)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"210987654321", "111111111111"}, aws.StringValueSlice(out.DBClusterSnapshotAttributesResult.DBClusterSnapshotAttributes[0].AttributeValues))
+
+	_, err = vault.CopyDBClusterSnapshot(input)
+	require.NoError(t, err)
+	assert.NotNil(t, vault.Snapshot("a-snapshot"))
+
+	unshare := new(rds.ModifyDBClusterSnapshotAttributeInput)
+	unshare.SetDBClusterSnapshotIdentifier("a-snapshot")
+	unshare.SetAttributeName("restore")
+	unshare.SetValuesToRemove(aws.StringSlice([]string{"111111111111"}))
+	_, err = source.ModifyDBClusterSnapshotAttribute(unshare)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"210987654321"}, source.SharedWith("a-snapshot"))
+
+	unshare.SetAttributeName("copy")
+	_, err = source.ModifyDBClusterSnapshotAttribute(unshare)
+	assertAWSErrorCode(t, "InvalidParameterValue", err)
+}
+
+func TestFailNext(t *testing.T) {
+	fake := NewRDS()
+	fake.FailNext("DescribeDBClusters", errors.New("first"))
+	fake.FailNext("DescribeDBClusters", errors.New("second"))
+
+	_, err := fake.DescribeDBClusters(new(rds.DescribeDBClustersInput))
+	assert.EqualError(t, err, "first")
+	_, err = fake.DescribeDBClusters(new(rds.DescribeDBClustersInput))
+	assert.EqualError(t, err, "second")
+	_, err = fake.DescribeDBClusters(new(rds.DescribeDBClustersInput))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, fake.Calls("DescribeDBClusters"))
+}
+
+func createInput(clusterID, snapshotID string) *rds.CreateDBClusterSnapshotInput {
+	input := new(rds.CreateDBClusterSnapshotInput)
+	input.SetDBClusterIdentifier(clusterID)
+	input.SetDBClusterSnapshotIdentifier(snapshotID)
+	return input
+}
+
+func assertAWSErrorCode(t *testing.T, expectedCode string, err error) {
+	require.Error(t, err)
+	awsErr, ok := err.(awserr.Error)
+	require.True(t, ok, "expected an AWS error, got %v", err)
+	assert.Equal(t, expectedCode, awsErr.Code())
+}
+
+func TestWithContextCanceled(t *testing.T) {
+	fake := NewRDS()
+	fake.AddCluster("pac-aurora-staging")
+
+	_, err := fake.CreateDBClusterSnapshotWithContext(context.Background(), createInput("pac-aurora-staging", "a-snapshot"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = fake.DescribeDBClusterSnapshotsWithContext(ctx, new(rds.DescribeDBClusterSnapshotsInput))
+
+	var awsErr awserr.Error
+	require.True(t, errors.As(err, &awsErr), "unexpected error: %v", err)
+	assert.Equal(t, request.CanceledErrorCode, awsErr.Code())
+	assert.Equal(t, context.Canceled, awsErr.OrigErr())
+	assert.Equal(t, 1, fake.Calls("CreateDBClusterSnapshot"))
+	assert.Equal(t, 0, fake.Calls("DescribeDBClusterSnapshots"))
+}
@@ -0,0 +1,350 @@
+package awsfake
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// AddInstance registers an available DB instance as a member of an existing cluster and returns it,
+// so that the caller can adjust its attributes.
+func (f *RDS) AddInstance(clusterID, instanceID, instanceClass string) *rds.DBInstance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cluster := f.findCluster(clusterID)
+	if cluster == nil {
+		panic(fmt.Sprintf("awsfake: cluster %v not found", clusterID))
+	}
+	instance := &rds.DBInstance{
+		DBInstanceIdentifier: aws.String(instanceID),
+		DBInstanceArn:        aws.String(f.arn("db", instanceID)),
+		DBClusterIdentifier:  aws.String(clusterID),
+		DBInstanceClass:      aws.String(instanceClass),
+		Engine:               cluster.Engine,
+		DBInstanceStatus:     aws.String(statusAvailable),
+	}
+	f.instances = append(f.instances, instance)
+	addClusterMember(cluster, instanceID)
+	return instance
+}
+
+// Cluster returns a copy of the cluster with the given identifier, or nil if it does not exist.
+func (f *RDS) Cluster(clusterID string) *rds.DBCluster {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := f.findCluster(clusterID)
+	if c == nil {
+		return nil
+	}
+	cluster := *c
+	return &cluster
+}
+
+// Instance returns a copy of the instance with the given identifier, or nil if it does not exist.
+func (f *RDS) Instance(instanceID string) *rds.DBInstance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.findInstance(instanceID)
+	if i == nil {
+		return nil
+	}
+	instance := *i
+	return &instance
+}
+
+func (f *RDS) RestoreDBClusterFromSnapshot(input *rds.RestoreDBClusterFromSnapshotInput) (*rds.RestoreDBClusterFromSnapshotOutput, error) {
+	f.mu.Lock()
+	err := f.call("RestoreDBClusterFromSnapshot")
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if input.Engine == nil {
+		return nil, awserr.New("InvalidParameterValue", "The parameter Engine must be provided.", nil)
+	}
+
+	snapshotID := aws.StringValue(input.SnapshotIdentifier)
+	snapshot, _ := f.sourceSnapshot(snapshotID)
+	if snapshot == nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %v not found.", snapshotID), nil)
+	}
+	if *snapshot.Status != statusAvailable {
+		return nil, awserr.New(rds.ErrCodeInvalidDBClusterSnapshotStateFault, fmt.Sprintf("DBClusterSnapshot %v is not in available state.", snapshotID), nil)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clusterID := aws.StringValue(input.DBClusterIdentifier)
+	if err := validateParameterIdentifier("DBClusterIdentifier", clusterID); err != nil {
+		return nil, err
+	}
+	if f.findCluster(clusterID) != nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterAlreadyExistsFault, fmt.Sprintf("DBCluster %v already exists.", clusterID), nil)
+	}
+
+	cluster := &rds.DBCluster{
+		DBClusterIdentifier:     aws.String(clusterID),
+		DBClusterArn:            aws.String(f.arn("cluster", clusterID)),
+		Engine:                  input.Engine,
+		EngineVersion:           input.EngineVersion,
+		Status:                  aws.String(statusCreating),
+		StorageEncrypted:        snapshot.StorageEncrypted,
+		KmsKeyId:                snapshot.KmsKeyId,
+		AllocatedStorage:        snapshot.AllocatedStorage,
+		DBSubnetGroup:           input.DBSubnetGroupName,
+		DBClusterParameterGroup: input.DBClusterParameterGroupName,
+		Endpoint:                aws.String(fmt.Sprintf("%v.cluster-fake.%v.rds.amazonaws.com", clusterID, f.Region)),
+		ReaderEndpoint:          aws.String(fmt.Sprintf("%v.cluster-ro-fake.%v.rds.amazonaws.com", clusterID, f.Region)),
+		ClusterCreateTime:       aws.Time(f.Now().UTC()),
+	}
+	if cluster.EngineVersion == nil {
+		cluster.EngineVersion = snapshot.EngineVersion
+	}
+	if input.KmsKeyId != nil {
+		cluster.KmsKeyId = input.KmsKeyId
+		cluster.StorageEncrypted = aws.Bool(true)
+	}
+	for _, id := range input.VpcSecurityGroupIds {
+		cluster.VpcSecurityGroups = append(cluster.VpcSecurityGroups, &rds.VpcSecurityGroupMembership{
+			VpcSecurityGroupId: id,
+			Status:             aws.String("active"),
+		})
+	}
+	f.clusters = append(f.clusters, cluster)
+	f.pendingClusters[clusterID] = f.RestorePolls
+	restored := *cluster
+	return &rds.RestoreDBClusterFromSnapshotOutput{DBCluster: &restored}, nil
+}
+
+func (f *RDS) CreateDBInstance(input *rds.CreateDBInstanceInput) (*rds.CreateDBInstanceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("CreateDBInstance"); err != nil {
+		return nil, err
+	}
+
+	instanceID := aws.StringValue(input.DBInstanceIdentifier)
+	if err := validateParameterIdentifier("DBInstanceIdentifier", instanceID); err != nil {
+		return nil, err
+	}
+	if f.findInstance(instanceID) != nil {
+		return nil, awserr.New(rds.ErrCodeDBInstanceAlreadyExistsFault, fmt.Sprintf("DB instance %v already exists.", instanceID), nil)
+	}
+	if input.DBInstanceClass == nil || input.Engine == nil {
+		return nil, awserr.New("InvalidParameterValue", "The parameters DBInstanceClass and Engine must be provided.", nil)
+	}
+	cluster := f.findCluster(aws.StringValue(input.DBClusterIdentifier))
+	if cluster == nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterNotFoundFault, fmt.Sprintf("DBCluster %v not found.", aws.StringValue(input.DBClusterIdentifier)), nil)
+	}
+
+	instance := &rds.DBInstance{
+		DBInstanceIdentifier: aws.String(instanceID),
+		DBInstanceArn:        aws.String(f.arn("db", instanceID)),
+		DBClusterIdentifier:  cluster.DBClusterIdentifier,
+		DBInstanceClass:      input.DBInstanceClass,
+		Engine:               input.Engine,
+		DBInstanceStatus:     aws.String(statusCreating),
+	}
+	if input.DBParameterGroupName != nil {
+		instance.DBParameterGroups = []*rds.DBParameterGroupStatus{{
+			DBParameterGroupName: input.DBParameterGroupName,
+			ParameterApplyStatus: aws.String("in-sync"),
+		}}
+	}
+	f.instances = append(f.instances, instance)
+	f.pendingInstances[instanceID] = f.RestorePolls
+	addClusterMember(cluster, instanceID)
+	created := *instance
+	return &rds.CreateDBInstanceOutput{DBInstance: &created}, nil
+}
+
+// DeleteDBInstance moves the instance to the deleting status; it disappears after DeletionPolls
+// calls to DescribeDBInstances. Final snapshots of Aurora instances are not supported, as in AWS.
+func (f *RDS) DeleteDBInstance(input *rds.DeleteDBInstanceInput) (*rds.DeleteDBInstanceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DeleteDBInstance"); err != nil {
+		return nil, err
+	}
+
+	instanceID := aws.StringValue(input.DBInstanceIdentifier)
+	instance := f.findInstance(instanceID)
+	if instance == nil {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, fmt.Sprintf("DBInstance %v not found.", instanceID), nil)
+	}
+	if *instance.DBInstanceStatus == statusDeleting {
+		return nil, awserr.New(rds.ErrCodeInvalidDBInstanceStateFault, fmt.Sprintf("Instance %v is already being deleted.", instanceID), nil)
+	}
+
+	instance.DBInstanceStatus = aws.String(statusDeleting)
+	f.pendingInstances[instanceID] = f.DeletionPolls
+	deleted := *instance
+	return &rds.DeleteDBInstanceOutput{DBInstance: &deleted}, nil
+}
+
+// DeleteDBCluster moves the cluster to the deleting status; it disappears after DeletionPolls
+// calls to DescribeDBClusters. As in AWS, the instances of the cluster must be deleted first.
+func (f *RDS) DeleteDBCluster(input *rds.DeleteDBClusterInput) (*rds.DeleteDBClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DeleteDBCluster"); err != nil {
+		return nil, err
+	}
+
+	clusterID := aws.StringValue(input.DBClusterIdentifier)
+	cluster := f.findCluster(clusterID)
+	if cluster == nil {
+		return nil, awserr.New(rds.ErrCodeDBClusterNotFoundFault, fmt.Sprintf("DBCluster %v not found.", clusterID), nil)
+	}
+	if !aws.BoolValue(input.SkipFinalSnapshot) {
+		return nil, awserr.New("InvalidParameterCombination", "FinalDBSnapshotIdentifier is required unless SkipFinalSnapshot is specified.", nil)
+	}
+	if *cluster.Status != statusAvailable {
+		return nil, awserr.New(rds.ErrCodeInvalidDBClusterStateFault, fmt.Sprintf("DBCluster %v is not in available state.", clusterID), nil)
+	}
+	for _, i := range f.instances {
+		if aws.StringValue(i.DBClusterIdentifier) == clusterID && *i.DBInstanceStatus != statusDeleting {
+			return nil, awserr.New(rds.ErrCodeInvalidDBClusterStateFault, "Cluster cannot be deleted, it still contains DB instances in non-deleting state.", nil)
+		}
+	}
+
+	cluster.Status = aws.String(statusDeleting)
+	f.pendingClusters[clusterID] = f.DeletionPolls
+	deleted := *cluster
+	return &rds.DeleteDBClusterOutput{DBCluster: &deleted}, nil
+}
+
+// DescribeDBInstances supports filtering by instance identifier and by the db-cluster-id filter.
+func (f *RDS) DescribeDBInstances(input *rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DescribeDBInstances"); err != nil {
+		return nil, err
+	}
+
+	var clusterIDs []string
+	for _, filter := range input.Filters {
+		if aws.StringValue(filter.Name) != "db-cluster-id" {
+			return nil, awserr.New("InvalidParameterValue", fmt.Sprintf("Unrecognized filter name: %v", aws.StringValue(filter.Name)), nil)
+		}
+		clusterIDs = append(clusterIDs, aws.StringValueSlice(filter.Values)...)
+	}
+
+	f.observeInstances()
+	var instances []*rds.DBInstance
+	for _, i := range f.instances {
+		if input.DBInstanceIdentifier != nil && *input.DBInstanceIdentifier != *i.DBInstanceIdentifier {
+			continue
+		}
+		if input.Filters != nil && !contains(clusterIDs, aws.StringValue(i.DBClusterIdentifier)) {
+			continue
+		}
+		instance := *i
+		instances = append(instances, &instance)
+	}
+	if input.DBInstanceIdentifier != nil && len(instances) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, fmt.Sprintf("DBInstance %v not found.", *input.DBInstanceIdentifier), nil)
+	}
+
+	start, end, marker, err := f.page(len(instances), input.Marker, input.MaxRecords)
+	if err != nil {
+		return nil, err
+	}
+	return &rds.DescribeDBInstancesOutput{DBInstances: instances[start:end], Marker: marker}, nil
+}
+
+// observeClusters advances the creation and deletion of the clusters by one status check.
+func (f *RDS) observeClusters() {
+	clusters := f.clusters[:0]
+	for _, c := range f.clusters {
+		polls, pending := f.pendingClusters[*c.DBClusterIdentifier]
+		if !pending {
+			clusters = append(clusters, c)
+			continue
+		}
+		if polls > 0 {
+			f.pendingClusters[*c.DBClusterIdentifier] = polls - 1
+			clusters = append(clusters, c)
+			continue
+		}
+		delete(f.pendingClusters, *c.DBClusterIdentifier)
+		if *c.Status == statusDeleting {
+			f.removeClusterInstances(*c.DBClusterIdentifier)
+			continue
+		}
+		c.Status = aws.String(statusAvailable)
+		clusters = append(clusters, c)
+	}
+	f.clusters = clusters
+}
+
+// observeInstances advances the creation and deletion of the instances by one status check.
+func (f *RDS) observeInstances() {
+	instances := f.instances[:0]
+	for _, i := range f.instances {
+		polls, pending := f.pendingInstances[*i.DBInstanceIdentifier]
+		if !pending {
+			instances = append(instances, i)
+			continue
+		}
+		if polls > 0 {
+			f.pendingInstances[*i.DBInstanceIdentifier] = polls - 1
+			instances = append(instances, i)
+			continue
+		}
+		delete(f.pendingInstances, *i.DBInstanceIdentifier)
+		if *i.DBInstanceStatus == statusDeleting {
+			if cluster := f.findCluster(aws.StringValue(i.DBClusterIdentifier)); cluster != nil {
+				removeClusterMember(cluster, *i.DBInstanceIdentifier)
+			}
+			continue
+		}
+		i.DBInstanceStatus = aws.String(statusAvailable)
+		instances = append(instances, i)
+	}
+	f.instances = instances
+}
+
+// removeClusterInstances removes the instances of a deleted cluster, which are all being deleted.
+func (f *RDS) removeClusterInstances(clusterID string) {
+	instances := f.instances[:0]
+	for _, i := range f.instances {
+		if aws.StringValue(i.DBClusterIdentifier) == clusterID {
+			delete(f.pendingInstances, *i.DBInstanceIdentifier)
+			continue
+		}
+		instances = append(instances, i)
+	}
+	f.instances = instances
+}
+
+func (f *RDS) findInstance(instanceID string) *rds.DBInstance {
+	for _, i := range f.instances {
+		if *i.DBInstanceIdentifier == instanceID {
+			return i
+		}
+	}
+	return nil
+}
+
+// addClusterMember adds an instance to the members of a cluster, the first one being the writer.
+func addClusterMember(cluster *rds.DBCluster, instanceID string) {
+	cluster.DBClusterMembers = append(cluster.DBClusterMembers, &rds.DBClusterMember{
+		DBInstanceIdentifier: aws.String(instanceID),
+		IsClusterWriter:      aws.Bool(len(cluster.DBClusterMembers) == 0),
+	})
+}
+
+func removeClusterMember(cluster *rds.DBCluster, instanceID string) {
+	members := cluster.DBClusterMembers[:0]
+	for _, member := range cluster.DBClusterMembers {
+		if aws.StringValue(member.DBInstanceIdentifier) != instanceID {
+			members = append(members, member)
+		}
+	}
+	cluster.DBClusterMembers = members
+}
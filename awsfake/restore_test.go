@@ -0,0 +1,118 @@
+package awsfake
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreLifecycle(t *testing.T) {
+	fake := NewRDS()
+	fake.RestorePolls = 1
+	fake.AddCluster("pac-aurora-staging")
+	_, err := fake.CreateDBClusterSnapshot(createInput("pac-aurora-staging", "a-snapshot"))
+	require.NoError(t, err)
+	_, err = fake.DescribeDBClusterSnapshots(new(rds.DescribeDBClusterSnapshotsInput))
+	require.NoError(t, err)
+	_, err = fake.DescribeDBClusterSnapshots(new(rds.DescribeDBClusterSnapshotsInput))
+	require.NoError(t, err)
+
+	restoreInput := new(rds.RestoreDBClusterFromSnapshotInput)
+	restoreInput.SetDBClusterIdentifier("pac-aurora-restored")
+	restoreInput.SetSnapshotIdentifier("arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:a-snapshot")
+	restoreInput.SetEngine("aurora-mysql")
+	restoreInput.SetVpcSecurityGroupIds(aws.StringSlice([]string{"sg-1"}))
+	out, err := fake.RestoreDBClusterFromSnapshot(restoreInput)
+	require.NoError(t, err)
+	assert.Equal(t, statusCreating, *out.DBCluster.Status)
+	assert.Equal(t, "sg-1", *out.DBCluster.VpcSecurityGroups[0].VpcSecurityGroupId)
+
+	instanceInput := new(rds.CreateDBInstanceInput)
+	instanceInput.SetDBClusterIdentifier("pac-aurora-restored")
+	instanceInput.SetDBInstanceIdentifier("pac-aurora-restored-1")
+	instanceInput.SetDBInstanceClass("db.r6g.large")
+	instanceInput.SetEngine("aurora-mysql")
+	_, err = fake.CreateDBInstance(instanceInput)
+	require.NoError(t, err)
+
+	clusterInput := new(rds.DescribeDBClustersInput)
+	clusterInput.SetDBClusterIdentifier("pac-aurora-restored")
+	instancesInput := new(rds.DescribeDBInstancesInput)
+	instancesInput.SetFilters([]*rds.Filter{{Name: aws.String("db-cluster-id"), Values: aws.StringSlice([]string{"pac-aurora-restored"})}})
+	for _, expectedStatus := range []string{statusCreating, statusAvailable} {
+		clusters, err := fake.DescribeDBClusters(clusterInput)
+		require.NoError(t, err)
+		assert.Equal(t, expectedStatus, *clusters.DBClusters[0].Status)
+		instances, err := fake.DescribeDBInstances(instancesInput)
+		require.NoError(t, err)
+		require.Len(t, instances.DBInstances, 1)
+		assert.Equal(t, expectedStatus, *instances.DBInstances[0].DBInstanceStatus)
+	}
+	members := fake.Cluster("pac-aurora-restored").DBClusterMembers
+	require.Len(t, members, 1)
+	assert.True(t, *members[0].IsClusterWriter)
+}
+
+func TestRestoreErrors(t *testing.T) {
+	fake := NewRDS()
+	fake.AddCluster("pac-aurora-staging")
+
+	input := new(rds.RestoreDBClusterFromSnapshotInput)
+	input.SetDBClusterIdentifier("pac-aurora-restored")
+	input.SetSnapshotIdentifier("missing")
+	input.SetEngine("aurora-mysql")
+	_, err := fake.RestoreDBClusterFromSnapshot(input)
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterSnapshotNotFoundFault, err)
+
+	instanceInput := new(rds.CreateDBInstanceInput)
+	instanceInput.SetDBClusterIdentifier("missing")
+	instanceInput.SetDBInstanceIdentifier("missing-1")
+	instanceInput.SetDBInstanceClass("db.r6g.large")
+	instanceInput.SetEngine("aurora-mysql")
+	_, err = fake.CreateDBInstance(instanceInput)
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterNotFoundFault, err)
+
+	instanceInput.SetDBInstanceIdentifier("invalid--id")
+	_, err = fake.CreateDBInstance(instanceInput)
+	assertAWSErrorCode(t, "InvalidParameterValue", err)
+}
+
+func TestDeleteClusterLifecycle(t *testing.T) {
+	fake := NewRDS()
+	fake.AddCluster("pac-aurora-restored")
+	fake.AddInstance("pac-aurora-restored", "pac-aurora-restored-1", "db.r6g.large")
+
+	clusterInput := new(rds.DeleteDBClusterInput)
+	clusterInput.SetDBClusterIdentifier("pac-aurora-restored")
+	clusterInput.SetSkipFinalSnapshot(true)
+	_, err := fake.DeleteDBCluster(clusterInput)
+	assertAWSErrorCode(t, rds.ErrCodeInvalidDBClusterStateFault, err)
+
+	instanceInput := new(rds.DeleteDBInstanceInput)
+	instanceInput.SetDBInstanceIdentifier("pac-aurora-restored-1")
+	out, err := fake.DeleteDBInstance(instanceInput)
+	require.NoError(t, err)
+	assert.Equal(t, statusDeleting, *out.DBInstance.DBInstanceStatus)
+	_, err = fake.DeleteDBCluster(clusterInput)
+	require.NoError(t, err)
+
+	describeInput := new(rds.DescribeDBClustersInput)
+	describeInput.SetDBClusterIdentifier("pac-aurora-restored")
+	clusters, err := fake.DescribeDBClusters(describeInput)
+	require.NoError(t, err)
+	assert.Equal(t, statusDeleting, *clusters.DBClusters[0].Status)
+	_, err = fake.DescribeDBClusters(describeInput)
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterNotFoundFault, err)
+
+	_, err = fake.DescribeDBInstances(new(rds.DescribeDBInstancesInput))
+	require.NoError(t, err)
+	instances, err := fake.DescribeDBInstances(new(rds.DescribeDBInstancesInput))
+	require.NoError(t, err)
+	assert.Empty(t, instances.DBInstances)
+
+	_, err = fake.DeleteDBInstance(instanceInput)
+	assertAWSErrorCode(t, rds.ErrCodeDBInstanceNotFoundFault, err)
+}
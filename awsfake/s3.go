@@ -0,0 +1,241 @@
+package awsfake
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// defaultS3PageSize is the maximum number of keys returned by a ListObjectsV2 call, as in AWS.
+const defaultS3PageSize = 1000
+
+// S3 is an in-memory fake of the upload, listing and deletion of objects of the AWS S3 API.
+// Listings are paginated with at most PageSize keys and common prefixes per page.
+type S3 struct {
+	PageSize int
+
+	mu       sync.Mutex
+	buckets  map[string]map[string][]byte
+	failures map[string][]error
+	calls    map[string]int
+}
+
+// NewS3 returns a fake S3 without any bucket.
+func NewS3() *S3 {
+	return &S3{
+		PageSize: defaultS3PageSize,
+		buckets:  make(map[string]map[string][]byte),
+		failures: make(map[string][]error),
+		calls:    make(map[string]int),
+	}
+}
+
+// AddBucket creates an empty bucket.
+func (f *S3) AddBucket(bucket string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.buckets[bucket] == nil {
+		f.buckets[bucket] = make(map[string][]byte)
+	}
+}
+
+// AddObject stores an object, creating its bucket if needed.
+func (f *S3) AddObject(bucket, key string, body []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.putObject(bucket, key, body)
+}
+
+// Object returns the content of an object, or nil if it does not exist.
+func (f *S3) Object(bucket, key string) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buckets[bucket][key]
+}
+
+// Keys returns the sorted keys of the objects of the bucket starting with prefix.
+func (f *S3) Keys(bucket, prefix string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for key := range f.buckets[bucket] {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FailNext makes the next call to the named operation (e.g. "DeleteObjects")
+// return err. Multiple failures for the same operation are returned in order.
+func (f *S3) FailNext(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[operation] = append(f.failures[operation], err)
+}
+
+// Calls returns how many times the named operation has been called.
+func (f *S3) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[operation]
+}
+
+func (f *S3) ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("ListObjectsV2"); err != nil {
+		return nil, err
+	}
+	objects, err := f.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys and common prefixes are listed together in lexicographic order, as in AWS.
+	prefix, delimiter := aws.StringValue(input.Prefix), aws.StringValue(input.Delimiter)
+	var entries []string
+	isCommonPrefix := make(map[string]bool)
+	for key := range objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common := key[:len(prefix)+i+len(delimiter)]
+				if !isCommonPrefix[common] {
+					isCommonPrefix[common] = true
+					entries = append(entries, common)
+				}
+				continue
+			}
+		}
+		entries = append(entries, key)
+	}
+	sort.Strings(entries)
+
+	start := 0
+	if input.ContinuationToken != nil {
+		start, err = strconv.Atoi(*input.ContinuationToken)
+		if err != nil || start < 0 || start > len(entries) {
+			return nil, awserr.New("InvalidArgument", fmt.Sprintf("The continuation token provided is incorrect: %v", *input.ContinuationToken), nil)
+		}
+	}
+	size := f.PageSize
+	if input.MaxKeys != nil && int(*input.MaxKeys) < size {
+		size = int(*input.MaxKeys)
+	}
+	end := start + size
+	output := &s3.ListObjectsV2Output{Name: input.Bucket, Prefix: input.Prefix, Delimiter: input.Delimiter, IsTruncated: aws.Bool(false)}
+	if end < len(entries) {
+		output.IsTruncated = aws.Bool(true)
+		output.NextContinuationToken = aws.String(strconv.Itoa(end))
+	} else {
+		end = len(entries)
+	}
+	for _, entry := range entries[start:end] {
+		if isCommonPrefix[entry] {
+			output.CommonPrefixes = append(output.CommonPrefixes, &s3.CommonPrefix{Prefix: aws.String(entry)})
+		} else {
+			output.Contents = append(output.Contents, &s3.Object{Key: aws.String(entry), Size: aws.Int64(int64(len(objects[entry])))})
+		}
+	}
+	output.KeyCount = aws.Int64(int64(end - start))
+	return output, nil
+}
+
+func (f *S3) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("DeleteObjects"); err != nil {
+		return nil, err
+	}
+	objects, err := f.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if input.Delete == nil || len(input.Delete.Objects) == 0 || len(input.Delete.Objects) > 1000 {
+		return nil, awserr.New("MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", nil)
+	}
+	output := new(s3.DeleteObjectsOutput)
+	for _, object := range input.Delete.Objects {
+		delete(objects, aws.StringValue(object.Key))
+		output.Deleted = append(output.Deleted, &s3.DeletedObject{Key: object.Key})
+	}
+	return output, nil
+}
+
+// PutObject stores an object in an existing bucket, replacing the object with the same key.
+func (f *S3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("PutObject"); err != nil {
+		return nil, err
+	}
+	if _, err := f.bucket(input.Bucket); err != nil {
+		return nil, err
+	}
+	var body []byte
+	if input.Body != nil {
+		var err error
+		if body, err = io.ReadAll(input.Body); err != nil {
+			return nil, err
+		}
+	}
+	f.putObject(aws.StringValue(input.Bucket), aws.StringValue(input.Key), body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *S3) ListObjectsV2WithContext(ctx aws.Context, input *s3.ListObjectsV2Input, _ ...request.Option) (*s3.ListObjectsV2Output, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.ListObjectsV2(input)
+}
+
+func (f *S3) DeleteObjectsWithContext(ctx aws.Context, input *s3.DeleteObjectsInput, _ ...request.Option) (*s3.DeleteObjectsOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.DeleteObjects(input)
+}
+
+func (f *S3) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.PutObject(input)
+}
+
+func (f *S3) putObject(bucket, key string, body []byte) {
+	if f.buckets[bucket] == nil {
+		f.buckets[bucket] = make(map[string][]byte)
+	}
+	f.buckets[bucket][key] = body
+}
+
+func (f *S3) bucket(name *string) (map[string][]byte, error) {
+	objects, found := f.buckets[aws.StringValue(name)]
+	if !found {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "The specified bucket does not exist", nil)
+	}
+	return objects, nil
+}
+
+func (f *S3) call(operation string) error {
+	f.calls[operation]++
+	if failures := f.failures[operation]; len(failures) > 0 {
+		f.failures[operation] = failures[1:]
+		return failures[0]
+	}
+	return nil
+}
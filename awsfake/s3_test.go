@@ -0,0 +1,69 @@
+package awsfake
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListObjectsV2WithDelimiterAndPages(t *testing.T) {
+	fake := NewS3()
+	fake.PageSize = 2
+	for _, key := range []string{"exports/a/1.parquet", "exports/a/2.parquet", "exports/b/1.parquet", "exports/c.json", "other/d"} {
+		fake.AddObject("pac-exports", key, []byte("x"))
+	}
+
+	input := &s3.ListObjectsV2Input{Bucket: aws.String("pac-exports"), Prefix: aws.String("exports/"), Delimiter: aws.String("/")}
+	var prefixes, keys []string
+	for {
+		out, err := fake.ListObjectsV2(input)
+		require.NoError(t, err)
+		for _, p := range out.CommonPrefixes {
+			prefixes = append(prefixes, *p.Prefix)
+		}
+		for _, o := range out.Contents {
+			keys = append(keys, *o.Key)
+		}
+		if !aws.BoolValue(out.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+	assert.Equal(t, []string{"exports/a/", "exports/b/"}, prefixes)
+	assert.Equal(t, []string{"exports/c.json"}, keys)
+	assert.Equal(t, 2, fake.Calls("ListObjectsV2"))
+}
+
+func TestDeleteObjects(t *testing.T) {
+	fake := NewS3()
+	fake.AddObject("pac-exports", "a", []byte("x"))
+	fake.AddObject("pac-exports", "b", []byte("x"))
+
+	out, err := fake.DeleteObjects(&s3.DeleteObjectsInput{
+		Bucket: aws.String("pac-exports"),
+		Delete: &s3.Delete{Objects: []*s3.ObjectIdentifier{{Key: aws.String("a")}}},
+	})
+	require.NoError(t, err)
+	assert.Len(t, out.Deleted, 1)
+	assert.Equal(t, []string{"b"}, fake.Keys("pac-exports", ""))
+
+	_, err = fake.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String("missing")})
+	assertAWSErrorCode(t, s3.ErrCodeNoSuchBucket, err)
+}
+
+func TestPutObject(t *testing.T) {
+	fake := NewS3()
+	fake.AddBucket("pac-reports")
+
+	_, err := fake.PutObject(&s3.PutObjectInput{Bucket: aws.String("pac-reports"), Key: aws.String("runs/latest.json"), Body: strings.NewReader(`{"exitCode":0}`)})
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"exitCode":0}`), fake.Object("pac-reports", "runs/latest.json"))
+
+	_, err = fake.PutObject(&s3.PutObjectInput{Bucket: aws.String("other"), Key: aws.String("runs/latest.json")})
+	assertAWSErrorCode(t, s3.ErrCodeNoSuchBucket, err)
+	assert.Equal(t, 2, fake.Calls("PutObject"))
+}
@@ -0,0 +1,100 @@
+package awsfake
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// SNS is an in-memory fake of the publication of messages to topics of the AWS SNS API,
+// recording the messages published to each topic.
+type SNS struct {
+	mu       sync.Mutex
+	topics   map[string][]*sns.PublishInput
+	failures map[string][]error
+	calls    map[string]int
+}
+
+// NewSNS returns a fake SNS without any topic.
+func NewSNS() *SNS {
+	return &SNS{
+		topics:   make(map[string][]*sns.PublishInput),
+		failures: make(map[string][]error),
+		calls:    make(map[string]int),
+	}
+}
+
+// AddTopic creates a topic with the given ARN.
+func (f *SNS) AddTopic(topicARN string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.topics[topicARN]; !ok {
+		f.topics[topicARN] = nil
+	}
+}
+
+// Messages returns the messages published to the topic, in order.
+func (f *SNS) Messages(topicARN string) []*sns.PublishInput {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*sns.PublishInput(nil), f.topics[topicARN]...)
+}
+
+// FailNext makes the next call to the named operation (e.g. "Publish")
+// return err. Multiple failures for the same operation are returned in order.
+func (f *SNS) FailNext(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[operation] = append(f.failures[operation], err)
+}
+
+// Calls returns how many times the named operation has been called.
+func (f *SNS) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[operation]
+}
+
+// Publish records a message published to an existing topic.
+func (f *SNS) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("Publish"); err != nil {
+		return nil, err
+	}
+
+	topicARN := aws.StringValue(input.TopicArn)
+	messages, ok := f.topics[topicARN]
+	if !ok {
+		return nil, awserr.New(sns.ErrCodeNotFoundException, fmt.Sprintf("Topic does not exist: %v", topicARN), nil)
+	}
+	if aws.StringValue(input.Message) == "" {
+		return nil, awserr.New(sns.ErrCodeInvalidParameterException, "Invalid parameter: Empty message", nil)
+	}
+	if len(aws.StringValue(input.Subject)) > 100 {
+		return nil, awserr.New(sns.ErrCodeInvalidParameterException, "Invalid parameter: Subject", nil)
+	}
+	f.topics[topicARN] = append(messages, input)
+	return &sns.PublishOutput{MessageId: aws.String(strconv.Itoa(len(messages) + 1))}, nil
+}
+
+func (f *SNS) PublishWithContext(ctx aws.Context, input *sns.PublishInput, _ ...request.Option) (*sns.PublishOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.Publish(input)
+}
+
+func (f *SNS) call(operation string) error {
+	f.calls[operation]++
+	if failures := f.failures[operation]; len(failures) > 0 {
+		f.failures[operation] = failures[1:]
+		return failures[0]
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package awsfake
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish(t *testing.T) {
+	const topicARN = "arn:aws:sns:eu-west-1:123456789012:pac-aurora-backup"
+	fake := NewSNS()
+	fake.AddTopic(topicARN)
+
+	out, err := fake.Publish(&sns.PublishInput{TopicArn: aws.String(topicARN), Message: aws.String("backup failed")})
+	require.NoError(t, err)
+	assert.Equal(t, "1", aws.StringValue(out.MessageId))
+
+	_, err = fake.Publish(&sns.PublishInput{TopicArn: aws.String(topicARN + "-other"), Message: aws.String("backup failed")})
+	assertAWSErrorCode(t, sns.ErrCodeNotFoundException, err)
+	_, err = fake.Publish(&sns.PublishInput{TopicArn: aws.String(topicARN)})
+	assertAWSErrorCode(t, sns.ErrCodeInvalidParameterException, err)
+	_, err = fake.Publish(&sns.PublishInput{TopicArn: aws.String(topicARN), Message: aws.String("backup failed"), Subject: aws.String(strings.Repeat("a", 101))})
+	assertAWSErrorCode(t, sns.ErrCodeInvalidParameterException, err)
+
+	messages := fake.Messages(topicARN)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "backup failed", aws.StringValue(messages[0].Message))
+	assert.Equal(t, 4, fake.Calls("Publish"))
+}
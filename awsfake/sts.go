@@ -0,0 +1,75 @@
+package awsfake
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// STS is an in-memory fake of the caller identity of the AWS STS API.
+type STS struct {
+	mu       sync.Mutex
+	arn      string
+	userID   string
+	failures map[string][]error
+	calls    map[string]int
+}
+
+// NewSTS returns a fake STS whose caller is the IAM principal with the given ARN and unique ID.
+func NewSTS(arn, userID string) *STS {
+	return &STS{
+		arn:      arn,
+		userID:   userID,
+		failures: make(map[string][]error),
+		calls:    make(map[string]int),
+	}
+}
+
+// FailNext makes the next call to the named operation (e.g. "GetCallerIdentity")
+// return err. Multiple failures for the same operation are returned in order.
+func (f *STS) FailNext(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[operation] = append(f.failures[operation], err)
+}
+
+// Calls returns how many times the named operation has been called.
+func (f *STS) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[operation]
+}
+
+// GetCallerIdentity returns the caller, whose account is read from its ARN.
+func (f *STS) GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("GetCallerIdentity"); err != nil {
+		return nil, err
+	}
+
+	account := ""
+	if parts := strings.SplitN(f.arn, ":", 6); len(parts) == 6 {
+		account = parts[4]
+	}
+	return &sts.GetCallerIdentityOutput{Account: aws.String(account), Arn: aws.String(f.arn), UserId: aws.String(f.userID)}, nil
+}
+
+func (f *STS) GetCallerIdentityWithContext(ctx aws.Context, input *sts.GetCallerIdentityInput, _ ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.GetCallerIdentity(input)
+}
+
+func (f *STS) call(operation string) error {
+	f.calls[operation]++
+	if failures := f.failures[operation]; len(failures) > 0 {
+		f.failures[operation] = failures[1:]
+		return failures[0]
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package awsfake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCallerIdentity(t *testing.T) {
+	fake := NewSTS("arn:aws:sts::123456789012:assumed-role/pac-aurora-backup/pod", "AROAEXAMPLE:pod")
+
+	out, err := fake.GetCallerIdentityWithContext(context.Background(), &sts.GetCallerIdentityInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "123456789012", aws.StringValue(out.Account))
+	assert.Equal(t, "arn:aws:sts::123456789012:assumed-role/pac-aurora-backup/pod", aws.StringValue(out.Arn))
+	assert.Equal(t, "AROAEXAMPLE:pod", aws.StringValue(out.UserId))
+
+	fake.FailNext("GetCallerIdentity", awserr.New("ExpiredToken", "The security token included in the request is expired", nil))
+	_, err = fake.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	assertAWSErrorCode(t, "ExpiredToken", err)
+	assert.Equal(t, 2, fake.Calls("GetCallerIdentity"))
+}
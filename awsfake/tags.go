@@ -0,0 +1,115 @@
+package awsfake
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// tagPattern matches the characters allowed in RDS tag keys and values.
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// AddTagsToResource adds tags to a snapshot or a cluster of the fake identified by its ARN,
+// replacing the value of the tags it already has.
+func (f *RDS) AddTagsToResource(input *rds.AddTagsToResourceInput) (*rds.AddTagsToResourceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("AddTagsToResource"); err != nil {
+		return nil, err
+	}
+
+	tagList, err := f.findTagList(aws.StringValue(input.ResourceName))
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range input.Tags {
+		if strings.HasPrefix(aws.StringValue(tag.Key), "aws:") {
+			return nil, awserr.New("InvalidParameterValue", fmt.Sprintf("Tag keys cannot start with aws: %v", aws.StringValue(tag.Key)), nil)
+		}
+		if !tagPattern.MatchString(aws.StringValue(tag.Key)) || !tagPattern.MatchString(aws.StringValue(tag.Value)) {
+			return nil, awserr.New("InvalidParameterValue", fmt.Sprintf("Invalid characters in tag %v", aws.StringValue(tag.Key)), nil)
+		}
+	}
+
+	// The tag list is rebuilt rather than updated in place, as the copies returned by the fake share it.
+	tags := make([]*rds.Tag, 0, len(*tagList)+len(input.Tags))
+	for _, tag := range *tagList {
+		if !hasTag(input.Tags, aws.StringValue(tag.Key)) {
+			tags = append(tags, tag)
+		}
+	}
+	for _, tag := range input.Tags {
+		tags = append(tags, &rds.Tag{Key: tag.Key, Value: tag.Value})
+	}
+	*tagList = tags
+	return &rds.AddTagsToResourceOutput{}, nil
+}
+
+// RemoveTagsFromResource removes the tags with the given keys from a snapshot or a cluster of the fake identified by its ARN.
+// Keys the snapshot does not have are ignored.
+func (f *RDS) RemoveTagsFromResource(input *rds.RemoveTagsFromResourceInput) (*rds.RemoveTagsFromResourceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call("RemoveTagsFromResource"); err != nil {
+		return nil, err
+	}
+
+	tagList, err := f.findTagList(aws.StringValue(input.ResourceName))
+	if err != nil {
+		return nil, err
+	}
+	var tags []*rds.Tag
+	for _, tag := range *tagList {
+		if !contains(aws.StringValueSlice(input.TagKeys), aws.StringValue(tag.Key)) {
+			tags = append(tags, tag)
+		}
+	}
+	*tagList = tags
+	return &rds.RemoveTagsFromResourceOutput{}, nil
+}
+
+func (f *RDS) AddTagsToResourceWithContext(ctx aws.Context, input *rds.AddTagsToResourceInput, _ ...request.Option) (*rds.AddTagsToResourceOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.AddTagsToResource(input)
+}
+
+func (f *RDS) RemoveTagsFromResourceWithContext(ctx aws.Context, input *rds.RemoveTagsFromResourceInput, _ ...request.Option) (*rds.RemoveTagsFromResourceOutput, error) {
+	if err := canceled(ctx); err != nil {
+		return nil, err
+	}
+	return f.RemoveTagsFromResource(input)
+}
+
+// findTagList returns the tag list of the snapshot or the cluster with the given ARN.
+func (f *RDS) findTagList(arn string) (*[]*rds.Tag, error) {
+	for _, s := range f.snapshots {
+		if aws.StringValue(s.DBClusterSnapshotArn) == arn {
+			return &s.TagList, nil
+		}
+	}
+	for _, c := range f.clusters {
+		if aws.StringValue(c.DBClusterArn) == arn {
+			return &c.TagList, nil
+		}
+	}
+	if strings.Contains(arn, ":cluster:") {
+		return nil, awserr.New(rds.ErrCodeDBClusterNotFoundFault, fmt.Sprintf("DBCluster %v not found.", arn), nil)
+	}
+	return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %v not found.", arn), nil)
+}
+
+func hasTag(tags []*rds.Tag, key string) bool {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package awsfake
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddTagsToResource(t *testing.T) {
+	fake := NewRDS()
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+		DBClusterSnapshotIdentifier: aws.String("a-snapshot"),
+		TagList:                     []*rds.Tag{{Key: aws.String("team"), Value: aws.String("pac")}, {Key: aws.String("managed-by"), Value: aws.String("someone")}},
+	})
+	before := fake.Snapshot("a-snapshot")
+
+	_, err := fake.AddTagsToResource(&rds.AddTagsToResourceInput{
+		ResourceName: before.DBClusterSnapshotArn,
+		Tags:         []*rds.Tag{{Key: aws.String("managed-by"), Value: aws.String("pac-aurora-backup")}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []*rds.Tag{
+		{Key: aws.String("team"), Value: aws.String("pac")},
+		{Key: aws.String("managed-by"), Value: aws.String("pac-aurora-backup")},
+	}, fake.Snapshot("a-snapshot").TagList)
+	assert.Equal(t, "someone", *before.TagList[1].Value)
+}
+
+func TestAddTagsToResourceErrors(t *testing.T) {
+	fake := NewRDS()
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+		DBClusterSnapshotIdentifier: aws.String("a-snapshot"),
+	})
+
+	_, err := fake.AddTagsToResource(&rds.AddTagsToResourceInput{
+		ResourceName: aws.String("arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:another-snapshot"),
+		Tags:         []*rds.Tag{{Key: aws.String("team"), Value: aws.String("pac")}},
+	})
+	var awsErr awserr.Error
+	require.True(t, errors.As(err, &awsErr), "unexpected error: %v", err)
+	assert.Equal(t, rds.ErrCodeDBClusterSnapshotNotFoundFault, awsErr.Code())
+
+	_, err = fake.AddTagsToResource(&rds.AddTagsToResourceInput{
+		ResourceName: fake.Snapshot("a-snapshot").DBClusterSnapshotArn,
+		Tags:         []*rds.Tag{{Key: aws.String("aws:team"), Value: aws.String("pac")}},
+	})
+	require.True(t, errors.As(err, &awsErr), "unexpected error: %v", err)
+	assert.Equal(t, "InvalidParameterValue", awsErr.Code())
+
+	_, err = fake.AddTagsToResource(&rds.AddTagsToResourceInput{
+		ResourceName: fake.Snapshot("a-snapshot").DBClusterSnapshotArn,
+		Tags:         []*rds.Tag{{Key: aws.String("hold-reason"), Value: aws.String("incident, do not delete")}},
+	})
+	require.True(t, errors.As(err, &awsErr), "unexpected error: %v", err)
+	assert.Equal(t, "InvalidParameterValue", awsErr.Code())
+	assert.Empty(t, fake.Snapshot("a-snapshot").TagList)
+}
+
+func TestRemoveTagsFromResource(t *testing.T) {
+	fake := NewRDS()
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("pac-aurora-staging"),
+		DBClusterSnapshotIdentifier: aws.String("a-snapshot"),
+		TagList:                     []*rds.Tag{{Key: aws.String("team"), Value: aws.String("pac")}, {Key: aws.String("hold-reason"), Value: aws.String("incident")}},
+	})
+
+	_, err := fake.RemoveTagsFromResource(&rds.RemoveTagsFromResourceInput{
+		ResourceName: fake.Snapshot("a-snapshot").DBClusterSnapshotArn,
+		TagKeys:      aws.StringSlice([]string{"hold-reason", "hold-owner"}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*rds.Tag{{Key: aws.String("team"), Value: aws.String("pac")}}, fake.Snapshot("a-snapshot").TagList)
+}
+
+func TestAddTagsToCluster(t *testing.T) {
+	fake := NewRDS()
+	cluster := fake.AddCluster("pac-aurora-prod-eu")
+
+	_, err := fake.AddTagsToResource(&rds.AddTagsToResourceInput{
+		ResourceName: cluster.DBClusterArn,
+		Tags:         []*rds.Tag{{Key: aws.String("team"), Value: aws.String("pac")}},
+	})
+	require.NoError(t, err)
+	out, err := fake.DescribeDBClusters(&rds.DescribeDBClustersInput{DBClusterIdentifier: aws.String("pac-aurora-prod-eu")})
+	require.NoError(t, err)
+	assert.Equal(t, []*rds.Tag{{Key: aws.String("team"), Value: aws.String("pac")}}, out.DBClusters[0].TagList)
+
+	_, err = fake.RemoveTagsFromResource(&rds.RemoveTagsFromResourceInput{
+		ResourceName: aws.String("arn:aws:rds:eu-west-1:123456789012:cluster:missing"),
+		TagKeys:      aws.StringSlice([]string{"team"}),
+	})
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterNotFoundFault, err)
+}
@@ -0,0 +1,47 @@
+package backup
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// statusCheckJitter is the fraction by which status check delays are randomised.
+const statusCheckJitter = 0.2
+
+// WithStatusCheckBackoff makes the delay between status checks start at the status check interval
+// and double after every check, up to maxInterval.
+// By default the delay grows up to 4 times the status check interval.
+func WithStatusCheckBackoff(maxInterval time.Duration) Option {
+	return func(svc *auroraBackupService) {
+		svc.maxStatusCheckInterval = maxInterval
+	}
+}
+
+// statusCheckDelay returns how long to wait before the given status check attempt, starting from 0.
+func (svc *auroraBackupService) statusCheckDelay(attempt int) time.Duration {
+	delay := svc.statusCheckInterval
+	for i := 0; i < attempt && delay < svc.maxStatusCheckInterval; i++ {
+		delay *= 2
+	}
+	if delay > svc.maxStatusCheckInterval {
+		delay = svc.maxStatusCheckInterval
+	}
+	return time.Duration(float64(delay) * (1 + statusCheckJitter*(2*rand.Float64()-1)))
+}
+
+// pause waits before the given status check attempt, returning the context error
+// if the context is cancelled or expires in the meantime.
+func (svc *auroraBackupService) pause(ctx context.Context, attempt int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	timer := time.NewTimer(svc.statusCheckDelay(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
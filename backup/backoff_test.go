@@ -0,0 +1,89 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusCheckDelay(t *testing.T) {
+	svc := &auroraBackupService{statusCheckInterval: 10 * time.Second, maxStatusCheckInterval: time.Minute}
+
+	for attempt, expected := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute} {
+		for i := 0; i < 20; i++ {
+			delay := svc.statusCheckDelay(attempt)
+			assert.True(t, delay >= time.Duration(float64(expected)*(1-statusCheckJitter)), "attempt %v: %v", attempt, delay)
+			assert.True(t, delay <= time.Duration(float64(expected)*(1+statusCheckJitter)), "attempt %v: %v", attempt, delay)
+		}
+	}
+}
+
+func TestStatusCheckDelayDefaultBackoff(t *testing.T) {
+	svc, err := NewBackupService("eu-west-1", testClusterIDPrefix, testSnapshotIDPrefix, 10*time.Second, testStatusCheckAttempts, 0, WithRDSClient(awsfake.NewRDS()))
+	require.NoError(t, err)
+
+	delay := svc.(*auroraBackupService).statusCheckDelay(100)
+	assert.True(t, delay <= time.Duration(float64(40*time.Second)*(1+statusCheckJitter)), delay)
+	assert.True(t, delay >= time.Duration(float64(40*time.Second)*(1-statusCheckJitter)), delay)
+}
+
+func TestPauseInterrupted(t *testing.T) {
+	svc := &auroraBackupService{statusCheckInterval: time.Hour, maxStatusCheckInterval: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, svc.pause(ctx, 0))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, svc.pause(ctx, 0))
+}
+
+func TestWaitForSnapshotCreationInterrupted(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.CreationPolls = 100
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	input := new(rds.CreateDBClusterSnapshotInput)
+	input.SetDBClusterIdentifier(testClusterIDPrefix + "-eu")
+	input.SetDBClusterSnapshotIdentifier("a-snapshot")
+	_, err := fake.CreateDBClusterSnapshot(input)
+	require.NoError(t, err)
+	svc := newFakeBackupService(t, fake, 0)
+	svc.statusCheckInterval = time.Hour
+	svc.maxStatusCheckInterval = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = svc.checkSnapshotCreation(ctx, "a-snapshot")
+
+	var interrupted *InterruptedError
+	require.True(t, errors.As(err, &interrupted), "unexpected error: %v", err)
+	assert.Equal(t, "interrupted while waiting for snapshot a-snapshot creation: context deadline exceeded", err.Error())
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestMakeBackupWithFakeRDSInterruptedPreflightWait(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu").SetStatus("backing-up")
+	svc := newFakeBackupService(t, fake, 0, WithPreflightWait(time.Hour))
+	svc.statusCheckInterval = time.Hour
+	svc.maxStatusCheckInterval = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	results, err := svc.MakeBackup(ctx)
+
+	var interrupted *InterruptedError
+	require.True(t, errors.As(err, &interrupted), "unexpected error: %v", err)
+	assert.Equal(t, testClusterIDPrefix+"-eu", interrupted.ClusterID)
+	assert.Contains(t, err.Error(), "interrupted while waiting for pre-flight checks of cluster "+testClusterIDPrefix+"-eu")
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].SnapshotID)
+	assert.Equal(t, 0, fake.Calls("CreateDBClusterSnapshot"))
+}
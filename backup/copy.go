@@ -0,0 +1,158 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+// ClientFactory creates an RDS client for an AWS region.
+// When roleARN is not empty, the client acts on behalf of the assumed IAM role.
+type ClientFactory func(region, roleARN string) (RDSClient, error)
+
+// CopyDestination is a disaster recovery region where every new snapshot is copied.
+// KMSKeyID is the key of the destination region used to encrypt copies of encrypted snapshots.
+// Retention is the number of most recent copies preserved in the destination region.
+type CopyDestination struct {
+	Region    string
+	KMSKeyID  string
+	Retention int
+}
+
+// CopyResult describes the copy of a snapshot into a destination region.
+// AccountID is only set for copies into another AWS account.
+type CopyResult struct {
+	Region      string
+	AccountID   string
+	SnapshotID  string
+	SnapshotARN string
+	Duration    time.Duration
+	Err         error
+}
+
+// WithCopyDestinations makes the service copy every new snapshot into the given regions,
+// waiting for each copy to become available, and apply the retention of each region during the cleanup.
+func WithCopyDestinations(destinations ...CopyDestination) Option {
+	return func(svc *auroraBackupService) {
+		svc.copyDestinations = append(svc.copyDestinations, destinations...)
+	}
+}
+
+// WithClientFactory makes the service use the given factory to create the RDS clients
+// of the copy destination regions and of the vault account.
+func WithClientFactory(factory ClientFactory) Option {
+	return func(svc *auroraBackupService) {
+		svc.newClient = factory
+	}
+}
+
+// CopySnapshot copies an existing backup snapshot of the source region into every copy region and,
+// when configured, shares it with the vault account and copies it there, e.g. to retry the copies of a failed run.
+// snapshotID is LatestSnapshot for the most recent available backup snapshot of the first cluster.
+func (svc *auroraBackupService) CopySnapshot(ctx context.Context, snapshotID string) ([]*CopyResult, error) {
+	if len(svc.copyDestinations) == 0 && svc.vault == nil {
+		return nil, errors.New("no copy region nor vault account configured")
+	}
+	var clusterID string
+	snapshot, err := svc.availableSnapshot(ctx, snapshotID, &clusterID)
+	if err != nil {
+		return nil, err
+	}
+	snapshotID = *snapshot.DBClusterSnapshotIdentifier
+
+	var errs []error
+	shared := false
+	if svc.vault != nil {
+		accountIDs := svc.sharingAccountIDs()
+		if err := svc.shareSnapshot(ctx, snapshotID, accountIDs); err != nil {
+			errs = append(errs, &ShareError{SnapshotID: snapshotID, AccountIDs: accountIDs, Err: err})
+		} else {
+			shared = true
+		}
+	}
+
+	results := svc.copySnapshotToDestinations(ctx, snapshot, shared)
+	for _, c := range results {
+		if c.Err != nil {
+			errs = append(errs, &CopyError{Region: c.Region, AccountID: c.AccountID, SnapshotID: snapshotID, Err: c.Err})
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// copySnapshotToDestinations copies the snapshot into every destination region
+// and, when configured, into the vault account. The copies are made concurrently.
+func (svc *auroraBackupService) copySnapshotToDestinations(ctx context.Context, snapshot *rds.DBClusterSnapshot, copyToVault bool) []*CopyResult {
+	var results []*CopyResult
+	var wg sync.WaitGroup
+	copyTo := func(client RDSClient, region, accountID, kmsKeyID string) {
+		result := new(CopyResult)
+		results = append(results, result)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			*result = *svc.copySnapshot(ctx, snapshot, client, region, accountID, kmsKeyID)
+		}()
+	}
+	for _, destination := range svc.copyDestinations {
+		copyTo(svc.copyClients[destination.Region], destination.Region, "", destination.KMSKeyID)
+	}
+	if copyToVault {
+		copyTo(svc.vaultClient, svc.vault.Region, svc.vault.AccountID, svc.vault.KMSKeyID)
+	}
+	wg.Wait()
+	return results
+}
+
+func (svc *auroraBackupService) copySnapshot(ctx context.Context, snapshot *rds.DBClusterSnapshot, client RDSClient, region, accountID, kmsKeyID string) *CopyResult {
+	start := time.Now()
+	snapshotID := *snapshot.DBClusterSnapshotIdentifier
+	result := &CopyResult{Region: region, AccountID: accountID, SnapshotID: snapshotID}
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	logEntry := log.WithField("snapshotID", snapshotID).WithField("region", region)
+	if accountID != "" {
+		logEntry = logEntry.WithField("accountID", accountID)
+	}
+
+	logEntry.Info("Copying snapshot to destination")
+	input := new(rds.CopyDBClusterSnapshotInput)
+	input.SetSourceDBClusterSnapshotIdentifier(*snapshot.DBClusterSnapshotArn)
+	input.SetTargetDBClusterSnapshotIdentifier(snapshotID)
+	if region != svc.region {
+		input.SetSourceRegion(svc.region)
+	}
+	input.SetCopyTags(true)
+	if kmsKeyID != "" {
+		input.SetKmsKeyId(kmsKeyID)
+	}
+	_, err := client.CopyDBClusterSnapshotWithContext(ctx, input)
+	if isAWSErrorCode(err, rds.ErrCodeDBClusterSnapshotAlreadyExistsFault) {
+		// The snapshot was already copied, or is being copied, by an earlier run reusing the same snapshot.
+		logEntry.Info("Snapshot copy already exists in destination")
+		err = nil
+	}
+	if err != nil {
+		logEntry.WithError(err).Error("Error in copying snapshot to destination")
+		result.Err = err
+		return result
+	}
+
+	logEntry.Info("Checking for snapshot copy successfully created")
+	snapshotCopy, err := svc.waitForSnapshotCreation(ctx, client, snapshotID)
+	if err != nil {
+		logEntry.WithError(err).Error("Error in snapshot copy creation check")
+		result.Err = err
+		return result
+	}
+	result.SnapshotARN = *snapshotCopy.DBClusterSnapshotArn
+
+	logEntry.Info("Snapshot successfully copied to destination")
+	return result
+}
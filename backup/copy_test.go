@@ -0,0 +1,162 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeBackupCopiesSnapshotToDestinationRegions(t *testing.T) {
+	source, destinations := newFakeRegions("eu-central-1", "us-east-1")
+	cluster := source.AddCluster(testClusterIDPrefix + "-eu")
+	cluster.SetStorageEncrypted(true)
+	cluster.SetKmsKeyId("arn:aws:kms:eu-west-1:123456789012:key/source")
+	svc := newFakeBackupService(t, source, 0,
+		WithClientFactory(fakeClientFactory(destinations)),
+		WithCopyDestinations(
+			CopyDestination{Region: "eu-central-1", KMSKeyID: "arn:aws:kms:eu-central-1:123456789012:key/dr", Retention: 3},
+			CopyDestination{Region: "us-east-1", KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/dr", Retention: 3},
+		))
+
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Len(t, results[0].Copies, 2)
+
+	for i, region := range []string{"eu-central-1", "us-east-1"} {
+		c := results[0].Copies[i]
+		assert.NoError(t, c.Err)
+		assert.Equal(t, region, c.Region)
+		assert.Equal(t, results[0].SnapshotID, c.SnapshotID)
+
+		snapshotCopy := destinations[region].Snapshot(results[0].SnapshotID)
+		require.NotNil(t, snapshotCopy)
+		assert.Equal(t, statusAvailable, *snapshotCopy.Status)
+		assert.Equal(t, c.SnapshotARN, *snapshotCopy.DBClusterSnapshotArn)
+		assert.Equal(t, results[0].SnapshotARN, *snapshotCopy.SourceDBClusterSnapshotArn)
+		assert.Equal(t, "arn:aws:kms:"+region+":123456789012:key/dr", *snapshotCopy.KmsKeyId)
+	}
+}
+
+func TestMakeBackupCopyFailure(t *testing.T) {
+	source, destinations := newFakeRegions("us-east-1")
+	source.AddCluster(testClusterIDPrefix + "-eu").SetStorageEncrypted(true)
+	svc := newFakeBackupService(t, source, 0,
+		WithClientFactory(fakeClientFactory(destinations)),
+		WithCopyDestinations(CopyDestination{Region: "us-east-1", Retention: 3}))
+
+	results, err := svc.MakeBackup(context.Background())
+
+	var copyErr *CopyError
+	require.True(t, errors.As(err, &copyErr), "unexpected error: %v", err)
+	assert.Equal(t, "us-east-1", copyErr.Region)
+	require.Len(t, results, 1)
+	assert.NotEmpty(t, results[0].SnapshotARN)
+	assertAWSErrorCode(t, "InvalidParameterCombination", results[0].Copies[0].Err)
+	assert.Empty(t, destinations["us-east-1"].Snapshots())
+}
+
+func TestCleanUpOldBackupsAppliesRetentionInDestinationRegions(t *testing.T) {
+	source, destinations := newFakeRegions("us-east-1")
+	now := time.Now().UTC()
+	for i := 0; i < 6; i++ {
+		snapshot := &rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+			TagList:                     testManagedTags,
+		}
+		source.AddSnapshot(snapshot)
+		destinations["us-east-1"].AddSnapshot(snapshot)
+	}
+	svc := newFakeBackupService(t, source, 4,
+		WithClientFactory(fakeClientFactory(destinations)),
+		WithCopyDestinations(CopyDestination{Region: "us-east-1", Retention: 2}))
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, "eu-west-1", results[0].Region)
+	assert.Len(t, results[0].Retained, 4)
+	assert.Len(t, results[0].Deleted, 2)
+	assert.Len(t, source.Snapshots(), 4)
+
+	assert.Equal(t, "us-east-1", results[1].Region)
+	assert.Len(t, results[1].Retained, 2)
+	assert.Len(t, results[1].Deleted, 4)
+	assert.Len(t, destinations["us-east-1"].Snapshots(), 2)
+}
+
+func TestCopySnapshotCopiesLatestSnapshot(t *testing.T) {
+	source, destinations := newFakeRegions("us-east-1")
+	source.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	for i := 1; i <= 2; i++ {
+		source.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+		})
+	}
+	svc := newFakeBackupService(t, source, 0,
+		WithClientFactory(fakeClientFactory(destinations)),
+		WithCopyDestinations(CopyDestination{Region: "us-east-1", Retention: 3}))
+
+	results, err := svc.CopySnapshot(context.Background(), LatestSnapshot)
+	require.NoError(t, err)
+
+	latestID := testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -1).Format(snapshotIDDateFormat)
+	require.Len(t, results, 1)
+	assert.Equal(t, latestID, results[0].SnapshotID)
+	require.Len(t, destinations["us-east-1"].Snapshots(), 1)
+	assert.NotNil(t, destinations["us-east-1"].Snapshot(latestID))
+	assert.Equal(t, 0, source.Calls("CreateDBClusterSnapshot"))
+}
+
+func TestCopySnapshotWithoutDestinations(t *testing.T) {
+	fake := awsfake.NewRDS()
+	svc := newFakeBackupService(t, fake, 0)
+
+	_, err := svc.CopySnapshot(context.Background(), LatestSnapshot)
+
+	require.Error(t, err)
+	assert.Equal(t, 0, fake.Calls("DescribeDBClusterSnapshots"))
+}
+
+func TestNewBackupServiceClientFactoryError(t *testing.T) {
+	_, err := NewBackupService("eu-west-1", testClusterIDPrefix, testSnapshotIDPrefix, 0, 1, 1,
+		WithRDSClient(awsfake.NewRDS()),
+		WithClientFactory(func(region, roleARN string) (RDSClient, error) {
+			return nil, errors.New("no credentials")
+		}),
+		WithCopyDestinations(CopyDestination{Region: "us-east-1"}))
+	assert.EqualError(t, err, "no credentials")
+}
+
+// newFakeRegions returns a fake for the source region eu-west-1 and
+// the fakes of the given destination regions, which can copy from the source.
+func newFakeRegions(regions ...string) (*awsfake.RDS, map[string]*awsfake.RDS) {
+	source := awsfake.NewRDS()
+	destinations := make(map[string]*awsfake.RDS)
+	for _, region := range regions {
+		destination := awsfake.NewRDS()
+		destination.Region = region
+		destination.AddPeer(source)
+		destinations[region] = destination
+	}
+	return source, destinations
+}
+
+func fakeClientFactory(fakes map[string]*awsfake.RDS) ClientFactory {
+	return func(region, roleARN string) (RDSClient, error) {
+		return fakes[region], nil
+	}
+}
@@ -0,0 +1,235 @@
+package backup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+// WithDeletionConcurrency makes the cleanup issue the deletions of a region from concurrency workers,
+// at most ratePerSecond deletion requests per second across the workers, 0 leaving the rate unlimited.
+// Whatever the concurrency, the deletions in progress are checked together at each status check.
+// By default the deletions are requested one at a time without rate limit.
+func WithDeletionConcurrency(concurrency int, ratePerSecond float64) Option {
+	return func(svc *auroraBackupService) {
+		svc.deletionConcurrency = concurrency
+		svc.deletionRate = ratePerSecond
+	}
+}
+
+// rateLimiter spaces out calls evenly, at most one per interval.
+// A nil limiter does not limit the calls.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(ratePerSecond float64) *rateLimiter {
+	if ratePerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / ratePerSecond)}
+}
+
+// wait blocks until the next call is allowed, returning the context error
+// if the context is cancelled or expires in the meantime.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil || ctx.Err() != nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// deletionRequest is the outcome of a deletion request issued by a worker of deleteSnapshots.
+type deletionRequest struct {
+	snapshotID string
+	err        error
+}
+
+// deleteSnapshots deletes the snapshots with the given identifiers and prefix in a region, and waits for them to disappear.
+// The deletions are requested by a pool of workers and checked by a single polling loop,
+// listing the snapshots with the prefix at each status check, so a deletion is confirmed
+// after statusCheckAttempts checks at most whatever the number of snapshots.
+// The snapshots of the identifiers also in deleting are already being deleted, and are only waited for.
+// It returns the deleted snapshots and the failures in the order of the given identifiers.
+func (svc *auroraBackupService) deleteSnapshots(ctx context.Context, client RDSClient, region, snapshotIDPrefix string, snapshotIDs, deleting []string) ([]string, []SnapshotFailure) {
+	concurrency := svc.deletionConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	limiter := newRateLimiter(svc.deletionRate)
+
+	// pending maps the snapshots being deleted to the number of status checks they went through.
+	pending := make(map[string]int)
+	for _, snapshotID := range deleting {
+		log.WithField("snapshotID", snapshotID).
+			WithField("region", region).
+			Info("Checking for snapshot already being deleted")
+		pending[snapshotID] = 0
+	}
+	queue := make(chan string, len(snapshotIDs))
+	for _, snapshotID := range snapshotIDs {
+		if _, ok := pending[snapshotID]; !ok {
+			queue <- snapshotID
+		}
+	}
+	close(queue)
+	// The requests are buffered so that the workers never block once the polling loop is interrupted.
+	requests := make(chan deletionRequest, len(queue))
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < cap(requests); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for snapshotID := range queue {
+				requests <- deletionRequest{snapshotID: snapshotID, err: svc.requestSnapshotDeletion(ctx, client, limiter, snapshotID)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(requests)
+	}()
+
+	errs := make(map[string]error, len(snapshotIDs))
+	var timer *time.Timer
+	var timerC <-chan time.Time
+	done := ctx.Done()
+	for requests != nil || (len(pending) > 0 && ctx.Err() == nil) {
+		if timerC == nil && len(pending) > 0 && ctx.Err() == nil {
+			timer = time.NewTimer(svc.statusCheckDelay(minAttempts(pending)))
+			timerC = timer.C
+		}
+		select {
+		case req, ok := <-requests:
+			if !ok {
+				requests = nil
+				continue
+			}
+			if req.err != nil {
+				errs[req.snapshotID] = req.err
+				continue
+			}
+			log.WithField("snapshotID", req.snapshotID).
+				WithField("region", region).
+				Info("Checking for snapshot successfully deleted")
+			pending[req.snapshotID] = 0
+		case <-timerC:
+			timerC = nil
+			svc.checkSnapshotDeletions(ctx, client, region, snapshotIDPrefix, pending, errs)
+		case <-done:
+			// The workers stop as soon as the context is done, and the remaining requests are drained above.
+			done = nil
+			if timer != nil {
+				timer.Stop()
+			}
+			timerC = nil
+		}
+	}
+	for snapshotID := range pending {
+		errs[snapshotID] = &InterruptedError{SnapshotID: snapshotID, Operation: "deletion", Err: ctx.Err()}
+	}
+
+	var deleted []string
+	var failed []SnapshotFailure
+	for _, snapshotID := range snapshotIDs {
+		if err, ok := errs[snapshotID]; ok && err != nil {
+			failed = append(failed, SnapshotFailure{SnapshotID: snapshotID, Err: err})
+		} else if ok {
+			deleted = append(deleted, snapshotID)
+		}
+	}
+	return deleted, failed
+}
+
+// requestSnapshotDeletion waits for the rate limiter and then requests the deletion of a snapshot.
+func (svc *auroraBackupService) requestSnapshotDeletion(ctx context.Context, client RDSClient, limiter *rateLimiter, snapshotID string) error {
+	if err := limiter.wait(ctx); err != nil {
+		return &InterruptedError{SnapshotID: snapshotID, Operation: "deletion", Err: err}
+	}
+	input := new(rds.DeleteDBClusterSnapshotInput)
+	input.SetDBClusterSnapshotIdentifier(snapshotID)
+	if _, err := client.DeleteDBClusterSnapshotWithContext(ctx, input); err != nil {
+		log.WithError(err).
+			WithField("snapshotID", snapshotID).
+			Error("Error in deleting DB cluster snapshot for cleanup")
+		return err
+	}
+	return nil
+}
+
+// checkSnapshotDeletions lists the snapshots with the prefix once to check every pending deletion,
+// moving the deletions completed, failed or timed out from pending to errs with a nil error on success.
+// An error listing the snapshots counts as a check of each pending deletion, which is retried at the next check.
+func (svc *auroraBackupService) checkSnapshotDeletions(ctx context.Context, client RDSClient, region, snapshotIDPrefix string, pending map[string]int, errs map[string]error) {
+	snapshots, err := svc.listSnapshotsByPrefix(ctx, client, snapshotIDPrefix)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		log.WithError(err).
+			WithField("region", region).
+			Warn("Error in checking the DB cluster snapshot deletions for cleanup")
+	}
+	statuses := make(map[string]string, len(snapshots))
+	for _, snapshot := range snapshots {
+		statuses[aws.StringValue(snapshot.DBClusterSnapshotIdentifier)] = aws.StringValue(snapshot.Status)
+	}
+
+	for snapshotID, attempts := range pending {
+		status, found := statuses[snapshotID]
+		switch {
+		case err == nil && (!found || status == statusDeleted):
+			log.WithField("snapshotID", snapshotID).
+				WithField("region", region).
+				Info("Deleted old snapshot for cleanup")
+			errs[snapshotID] = nil
+		case err == nil && status != statusDeleting:
+			errs[snapshotID] = &UnexpectedStatusError{SnapshotID: snapshotID, Status: status}
+		case attempts+1 >= svc.statusCheckAttempts:
+			errs[snapshotID] = &SnapshotTimeoutError{SnapshotID: snapshotID, Operation: "deletion"}
+		default:
+			pending[snapshotID] = attempts + 1
+			continue
+		}
+		if errs[snapshotID] != nil {
+			log.WithError(errs[snapshotID]).
+				WithField("snapshotID", snapshotID).
+				Error("Error in checking DB cluster snapshot deletion for cleanup")
+		}
+		delete(pending, snapshotID)
+	}
+}
+
+// minAttempts returns the fewest status checks among the pending deletions,
+// so that the latest deletion requested is checked without the delay of the older ones.
+func minAttempts(pending map[string]int) int {
+	attempts := -1
+	for _, a := range pending {
+		if attempts < 0 || a < attempts {
+			attempts = a
+		}
+	}
+	return attempts
+}
@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanUpDeletesSnapshotsConcurrently(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.DeletionPolls = 3
+	now := time.Now().UTC()
+	snapshotIDs := addManagedSnapshots(fake, now, 1, 2, 3, 4, 5, 6, 7, 8)
+	fake.FailNext("DeleteDBClusterSnapshot", nil)
+	fake.FailNext("DeleteDBClusterSnapshot", awserr.New("InvalidDBClusterSnapshotStateFault", "Snapshot is in use", nil))
+	svc := newFakeBackupService(t, fake, 2, WithDeletionConcurrency(3, 0))
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+
+	var deletionErr *DeletionError
+	require.True(t, errors.As(err, &deletionErr), "unexpected error: %v", err)
+	require.Len(t, results, 1)
+	assert.Equal(t, snapshotIDs[:2], results[0].Retained)
+	require.Len(t, results[0].Failed, 1)
+	assert.Len(t, results[0].Deleted, 5)
+	assert.NotContains(t, results[0].Deleted, results[0].Failed[0].SnapshotID)
+	assert.Equal(t, 6, fake.Calls("DeleteDBClusterSnapshot"))
+	assert.Len(t, fake.Snapshots(), 3)
+}
+
+func TestCleanUpDeletionTimeout(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.DeletionPolls = 100
+	now := time.Now().UTC()
+	snapshotIDs := addManagedSnapshots(fake, now, 1, 2, 3)
+	svc := newFakeBackupService(t, fake, 1, WithDeletionConcurrency(2, 0))
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+
+	var deletionErr *DeletionError
+	require.True(t, errors.As(err, &deletionErr), "unexpected error: %v", err)
+	require.Len(t, results[0].Failed, 2)
+	for i, failure := range results[0].Failed {
+		assert.Equal(t, snapshotIDs[1+i], failure.SnapshotID)
+		var timeoutErr *SnapshotTimeoutError
+		assert.True(t, errors.As(failure.Err, &timeoutErr), "unexpected error: %v", failure.Err)
+	}
+	assert.LessOrEqual(t, fake.Calls("DescribeDBClusterSnapshots"), 1+2*testStatusCheckAttempts,
+		"the deletions should be checked together")
+}
+
+func TestCleanUpWaitsForSnapshotsAlreadyBeingDeleted(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.DeletionPolls = 2
+	now := time.Now().UTC()
+	snapshotIDs := addManagedSnapshots(fake, now, 1, 2)
+	for i, status := range []string{statusDeleting, "failed"} {
+		snapshotID := testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -3-i).Format(snapshotIDDateFormat)
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(snapshotID),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -3-i)),
+			Status:                      aws.String(status),
+			TagList:                     testManagedTags,
+		})
+		snapshotIDs = append(snapshotIDs, snapshotID)
+	}
+	svc := newFakeBackupService(t, fake, 1)
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, snapshotIDs[:1], results[0].Retained)
+	assert.Equal(t, snapshotIDs[1:3], results[0].Deleted)
+	assert.Equal(t, snapshotIDs[3:], results[0].Skipped)
+	assert.Equal(t, 1, fake.Calls("DeleteDBClusterSnapshot"), "the snapshot already being deleted should not be deleted again")
+	require.Len(t, fake.Snapshots(), 2)
+	assert.Equal(t, "failed", aws.StringValue(fake.Snapshots()[1].Status))
+}
+
+func TestDeleteSnapshotsInterrupted(t *testing.T) {
+	fake := awsfake.NewRDS()
+	snapshotIDs := addManagedSnapshots(fake, time.Now().UTC(), 1, 2, 3)
+	svc := newFakeBackupService(t, fake, 1, WithDeletionConcurrency(2, 1))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	deleted, failed := svc.deleteSnapshots(ctx, fake, fake.Region, testSnapshotIDPrefix, snapshotIDs, nil)
+
+	assert.Empty(t, deleted)
+	require.Len(t, failed, 3)
+	for _, failure := range failed {
+		var interruptedErr *InterruptedError
+		assert.True(t, errors.As(failure.Err, &interruptedErr), "unexpected error: %v", failure.Err)
+	}
+	assert.Equal(t, 0, fake.Calls("DeleteDBClusterSnapshot"))
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(100)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		require.NoError(t, limiter.wait(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	var unlimited *rateLimiter
+	assert.Nil(t, newRateLimiter(0))
+	assert.NoError(t, unlimited.wait(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, newRateLimiter(0.1).wait(ctx), context.Canceled)
+}
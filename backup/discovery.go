@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+func (svc *auroraBackupService) getDBClusterID(ctx context.Context) (string, error) {
+	clusterIDs, err := svc.getDBClusterIDs(ctx)
+	if err != nil {
+		return "", err
+	}
+	return clusterIDs[0], nil
+}
+
+// getDBClusterIDs returns the identifiers of all the DB clusters matching the cluster identifier prefix,
+// except the temporary verification clusters.
+func (svc *auroraBackupService) getDBClusterIDs(ctx context.Context) ([]string, error) {
+	var clusterIDs []string
+	isLastPage := false
+	input := new(rds.DescribeDBClustersInput)
+	for !isLastPage {
+		result, err := svc.DescribeDBClustersWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range result.DBClusters {
+			if matchesClusterIDPrefix(*cluster.DBClusterIdentifier, svc.clusterIDPrefix) && !svc.isVerificationCluster(*cluster.DBClusterIdentifier) {
+				clusterIDs = append(clusterIDs, *cluster.DBClusterIdentifier)
+			}
+		}
+		if result.Marker != nil {
+			input.SetMarker(*result.Marker)
+		} else {
+			isLastPage = true
+		}
+	}
+	if len(clusterIDs) == 0 {
+		return nil, &ClusterNotFoundError{ClusterIDPrefix: svc.clusterIDPrefix}
+	}
+	return clusterIDs, nil
+}
+
+func (svc *auroraBackupService) getBackupClusterIDs(ctx context.Context) ([]string, error) {
+	if svc.discoverClusters {
+		return svc.getDBClusterIDs(ctx)
+	}
+	clusterID, err := svc.getDBClusterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []string{clusterID}, nil
+}
+
+// getCleanupClusterIDs returns the clusters whose snapshots are cleaned up separately.
+// Without discovery, a single empty cluster ID stands for all the snapshots with the configured prefix.
+func (svc *auroraBackupService) getCleanupClusterIDs(ctx context.Context) ([]string, error) {
+	if svc.discoverClusters {
+		return svc.getDBClusterIDs(ctx)
+	}
+	return []string{""}, nil
+}
+
+// matchesClusterIDPrefix reports whether the cluster identifier is the prefix itself
+// or the prefix followed by a hyphen, so that pac-aurora-prod does not match pac-aurora-prod2.
+func matchesClusterIDPrefix(clusterID, prefix string) bool {
+	return clusterID == prefix || strings.HasPrefix(clusterID, prefix+"-")
+}
+
+// snapshotIDPrefixFor returns the snapshot identifier prefix used for the given cluster.
+// With cluster discovery the cluster identifier prefix at the start of the configured
+// snapshot prefix is replaced by the cluster identifier, e.g. pac-aurora-prod-backup
+// becomes pac-aurora-prod-eu-backup for cluster pac-aurora-prod-eu.
+func (svc *auroraBackupService) snapshotIDPrefixFor(clusterID string) string {
+	if !svc.discoverClusters || clusterID == "" {
+		return svc.snapshotIDPrefix
+	}
+	if strings.HasPrefix(svc.snapshotIDPrefix, svc.clusterIDPrefix) {
+		return clusterID + strings.TrimPrefix(svc.snapshotIDPrefix, svc.clusterIDPrefix)
+	}
+	return clusterID + "-" + svc.snapshotIDPrefix
+}
+
+func filterSnapshotsByCluster(snapshots []*rds.DBClusterSnapshot, clusterID string) []*rds.DBClusterSnapshot {
+	var filtered []*rds.DBClusterSnapshot
+	for _, snapshot := range snapshots {
+		if snapshot.DBClusterIdentifier != nil && *snapshot.DBClusterIdentifier == clusterID {
+			filtered = append(filtered, snapshot)
+		}
+	}
+	return filtered
+}
+
+// forEachCluster calls fn for every cluster, running at most svc.concurrency calls at a time.
+func (svc *auroraBackupService) forEachCluster(clusterIDs []string, fn func(i int, clusterID string)) {
+	concurrency := svc.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, clusterID := range clusterIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, clusterID string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i, clusterID)
+		}(i, clusterID)
+	}
+	wg.Wait()
+}
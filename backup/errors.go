@@ -0,0 +1,197 @@
+package backup
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ClusterNotFoundError is returned when no DB cluster matches the cluster identifier prefix.
+type ClusterNotFoundError struct {
+	ClusterIDPrefix string
+}
+
+func (e *ClusterNotFoundError) Error() string {
+	return fmt.Sprintf("DB cluster not found with identifier prefix %v", e.ClusterIDPrefix)
+}
+
+// SnapshotTimeoutError is returned when a snapshot does not reach the expected
+// state within the configured number of status checks.
+type SnapshotTimeoutError struct {
+	SnapshotID string
+	Operation  string
+}
+
+func (e *SnapshotTimeoutError) Error() string {
+	return fmt.Sprintf("check for snapshot %v time out: %v", e.Operation, e.SnapshotID)
+}
+
+// UnexpectedStatusError is returned when a snapshot moves to a status
+// that is neither the transitional nor the expected final one.
+type UnexpectedStatusError struct {
+	SnapshotID string
+	Status     string
+}
+
+func (e *UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("unexpected snapshot status %v", e.Status)
+}
+
+// UnsupportedEngineError is returned when the snapshot to restore is of none of the requested engines.
+type UnsupportedEngineError struct {
+	SnapshotID string
+	Engine     string
+	Engines    []string
+}
+
+func (e *UnsupportedEngineError) Error() string {
+	return fmt.Sprintf("snapshot %v has engine %v, not %v", e.SnapshotID, e.Engine, strings.Join(e.Engines, " or "))
+}
+
+// ClusterTimeoutError is returned when a DB cluster does not reach the expected
+// state within the configured number of status checks.
+type ClusterTimeoutError struct {
+	ClusterID string
+	Operation string
+}
+
+func (e *ClusterTimeoutError) Error() string {
+	return fmt.Sprintf("check for cluster %v time out: %v", e.Operation, e.ClusterID)
+}
+
+// UnexpectedClusterStatusError is returned when a restored DB cluster or one of its instances
+// moves to a status it does not recover from, e.g. inaccessible-encryption-credentials.
+type UnexpectedClusterStatusError struct {
+	ResourceID string
+	Status     string
+}
+
+func (e *UnexpectedClusterStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %v of %v", e.Status, e.ResourceID)
+}
+
+// DeletionError is returned by the cleanup when at least one snapshot could not be deleted.
+type DeletionError struct {
+	Failures []SnapshotFailure
+}
+
+func (e *DeletionError) Error() string {
+	var ids []string
+	for _, f := range e.Failures {
+		ids = append(ids, f.SnapshotID)
+	}
+	return fmt.Sprintf("failed to delete %d snapshot(s): %v", len(e.Failures), strings.Join(ids, ", "))
+}
+
+// AdoptionError is returned by Adopt when at least one snapshot could not be tagged as managed.
+type AdoptionError struct {
+	Failures []SnapshotFailure
+}
+
+func (e *AdoptionError) Error() string {
+	var ids []string
+	for _, f := range e.Failures {
+		ids = append(ids, f.SnapshotID)
+	}
+	return fmt.Sprintf("failed to adopt %d snapshot(s): %v", len(e.Failures), strings.Join(ids, ", "))
+}
+
+// SnapshotFailure records why an operation on a snapshot failed.
+type SnapshotFailure struct {
+	SnapshotID string
+	Err        error
+}
+
+// ClusterError associates an error with the DB cluster it occurred on.
+type ClusterError struct {
+	ClusterID string
+	Err       error
+}
+
+func (e *ClusterError) Error() string {
+	return fmt.Sprintf("cluster %v: %v", e.ClusterID, e.Err)
+}
+
+func (e *ClusterError) Unwrap() error {
+	return e.Err
+}
+
+// CopyError is returned when a snapshot could not be copied into a destination region or account.
+type CopyError struct {
+	Region     string
+	AccountID  string
+	SnapshotID string
+	Err        error
+}
+
+func (e *CopyError) Error() string {
+	if e.AccountID != "" {
+		return fmt.Sprintf("copy of snapshot %v to account %v in region %v failed: %v", e.SnapshotID, e.AccountID, e.Region, e.Err)
+	}
+	return fmt.Sprintf("copy of snapshot %v to region %v failed: %v", e.SnapshotID, e.Region, e.Err)
+}
+
+func (e *CopyError) Unwrap() error {
+	return e.Err
+}
+
+// ShareError is returned when a snapshot could not be shared with other AWS accounts.
+type ShareError struct {
+	SnapshotID string
+	AccountIDs []string
+	Err        error
+}
+
+func (e *ShareError) Error() string {
+	return fmt.Sprintf("sharing of snapshot %v with accounts %v failed: %v", e.SnapshotID, strings.Join(e.AccountIDs, ", "), e.Err)
+}
+
+func (e *ShareError) Unwrap() error {
+	return e.Err
+}
+
+// ExportError is returned when a snapshot could not be exported to S3,
+// or when the older exports could not be deleted after the export.
+type ExportError struct {
+	SnapshotID string
+	TaskID     string
+	Err        error
+}
+
+func (e *ExportError) Error() string {
+	return fmt.Sprintf("export %v of snapshot %v failed: %v", e.TaskID, e.SnapshotID, e.Err)
+}
+
+func (e *ExportError) Unwrap() error {
+	return e.Err
+}
+
+// PreflightError is returned when a snapshot of a DB cluster cannot be created
+// because of the listed conditions, e.g. the cluster is in its maintenance window.
+type PreflightError struct {
+	ClusterID  string
+	Conditions []string
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("pre-flight checks failed for cluster %v: %v", e.ClusterID, strings.Join(e.Conditions, ", "))
+}
+
+// InterruptedError is returned when the context of a run is cancelled or expires,
+// e.g. on SIGTERM or after the run timeout, while waiting for a snapshot or a cluster.
+type InterruptedError struct {
+	SnapshotID string
+	ClusterID  string
+	Operation  string
+	Err        error
+}
+
+func (e *InterruptedError) Error() string {
+	if e.SnapshotID != "" {
+		return fmt.Sprintf("interrupted while waiting for snapshot %v %v: %v", e.SnapshotID, e.Operation, e.Err)
+	}
+	return fmt.Sprintf("interrupted while waiting for %v of cluster %v: %v", e.Operation, e.ClusterID, e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,347 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	exportStatusComplete = "COMPLETE"
+	exportStatusFailed   = "FAILED"
+	exportStatusCanceled = "CANCELED"
+)
+
+// maxExportTaskIDLength is the maximum length of an export task identifier.
+const maxExportTaskIDLength = 60
+
+const exportTaskIDDateFormat = "20060102150405"
+
+// maxDeleteObjects is the maximum number of objects deleted by a DeleteObjects call.
+const maxDeleteObjects = 1000
+
+// S3Client is the subset of the AWS S3 API used to apply the retention of the snapshot exports.
+// It is satisfied by *s3.S3 and by the in-memory fake in the awsfake package.
+type S3Client interface {
+	ListObjectsV2WithContext(aws.Context, *s3.ListObjectsV2Input, ...request.Option) (*s3.ListObjectsV2Output, error)
+	DeleteObjectsWithContext(aws.Context, *s3.DeleteObjectsInput, ...request.Option) (*s3.DeleteObjectsOutput, error)
+}
+
+// ExportDestination is the S3 location where the snapshots are exported in Parquet by RDS export tasks,
+// each export under <Prefix>/<export task identifier>/.
+// RDS writes the export with IAMRoleARN, a role allowed to write to the bucket, and encrypts it with KMSKeyID.
+// ExportOnly limits the export to the given databases, schemas or tables, e.g. annotations or annotations.annotation.
+// Retention is the number of most recent exports of each cluster preserved under the prefix, 0 keeps them all.
+type ExportDestination struct {
+	Bucket     string
+	Prefix     string
+	IAMRoleARN string
+	KMSKeyID   string
+	ExportOnly []string
+	Retention  int
+}
+
+// ExportResult describes the export of a snapshot to S3.
+// Deleted lists the identifiers of the older exports deleted by the retention of the destination.
+// On failure it holds whatever was known before the error occurred.
+type ExportResult struct {
+	SnapshotID  string
+	TaskID      string
+	S3URI       string
+	ExtractedGB int64
+	Duration    time.Duration
+	Deleted     []string
+	Err         error
+}
+
+// WithExport makes the service export every new snapshot to S3 once it is available,
+// waiting for the export to complete, and then apply the retention of the exports.
+func WithExport(destination ExportDestination) Option {
+	return func(svc *auroraBackupService) {
+		svc.export = &destination
+	}
+}
+
+// WithS3Client makes the service use the given client for the retention of the exports
+// instead of creating an AWS S3 client for the configured region.
+func WithS3Client(client S3Client) Option {
+	return func(svc *auroraBackupService) {
+		svc.s3Client = client
+	}
+}
+
+func newS3Service(region string) (S3Client, error) {
+	sess, err := session.NewSession(aws.NewConfig().WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
+}
+
+// Export exports an available snapshot to S3, or the most recent available backup snapshot
+// if snapshotID is LatestSnapshot, and then applies the retention of the exports.
+func (svc *auroraBackupService) Export(ctx context.Context, snapshotID string) (*ExportResult, error) {
+	if svc.export == nil {
+		return nil, errors.New("no export bucket configured")
+	}
+	var clusterID string
+	snapshot, err := svc.availableSnapshot(ctx, snapshotID, &clusterID)
+	if err != nil {
+		return nil, err
+	}
+	result := svc.exportSnapshot(ctx, snapshot, clusterID, nil)
+	if result.Err != nil {
+		return result, &ExportError{SnapshotID: result.SnapshotID, TaskID: result.TaskID, Err: result.Err}
+	}
+	return result, nil
+}
+
+// exportBackup exports the snapshot of a backup to S3. A reused snapshot is not exported again
+// if it already has an export task to the bucket that did not fail, e.g. started by the run that created it:
+// the export waits for that task instead, as every export task is billed and counts toward the retention.
+func (svc *auroraBackupService) exportBackup(ctx context.Context, snapshot *rds.DBClusterSnapshot, clusterID string, reused bool) *ExportResult {
+	if !reused {
+		return svc.exportSnapshot(ctx, snapshot, clusterID, nil)
+	}
+	task, err := svc.existingExport(ctx, snapshot)
+	if err != nil {
+		log.WithField("snapshotID", aws.StringValue(snapshot.DBClusterSnapshotIdentifier)).
+			WithError(err).
+			Error("Error in looking for an export of the reused snapshot")
+		return &ExportResult{SnapshotID: aws.StringValue(snapshot.DBClusterSnapshotIdentifier), Err: err}
+	}
+	return svc.exportSnapshot(ctx, snapshot, clusterID, task)
+}
+
+// existingExport returns an export task of the snapshot to the bucket of the export destination
+// that is neither failed nor cancelled, or nil if there is none.
+func (svc *auroraBackupService) existingExport(ctx context.Context, snapshot *rds.DBClusterSnapshot) (*rds.ExportTask, error) {
+	input := new(rds.DescribeExportTasksInput)
+	input.SetSourceArn(aws.StringValue(snapshot.DBClusterSnapshotArn))
+	for {
+		output, err := svc.DescribeExportTasksWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range output.ExportTasks {
+			status := aws.StringValue(task.Status)
+			if aws.StringValue(task.S3Bucket) == svc.export.Bucket && status != exportStatusFailed && status != exportStatusCanceled {
+				return task, nil
+			}
+		}
+		if aws.StringValue(output.Marker) == "" {
+			return nil, nil
+		}
+		input.SetMarker(aws.StringValue(output.Marker))
+	}
+}
+
+// exportSnapshot starts the export of the snapshot of a cluster, or resumes the given export task of it if not nil,
+// waits for it to complete, and deletes the exports of the cluster beyond the retention.
+func (svc *auroraBackupService) exportSnapshot(ctx context.Context, snapshot *rds.DBClusterSnapshot, clusterID string, task *rds.ExportTask) *ExportResult {
+	start := time.Now()
+	taskIDPrefix := svc.exportTaskIDPrefix(clusterID)
+	result := &ExportResult{
+		SnapshotID: aws.StringValue(snapshot.DBClusterSnapshotIdentifier),
+		TaskID:     taskIDPrefix + "-" + start.UTC().Format(exportTaskIDDateFormat),
+	}
+	if task != nil {
+		result.TaskID = aws.StringValue(task.ExportTaskIdentifier)
+	}
+	result.S3URI = "s3://" + svc.export.Bucket + "/" + svc.exportFolder(result.TaskID)
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	logEntry := log.WithField("snapshotID", result.SnapshotID).WithField("exportTaskID", result.TaskID)
+	if task != nil {
+		logEntry.WithField("status", aws.StringValue(task.Status)).Info("Reusing the export of the reused snapshot instead of exporting it again")
+		return svc.completeExport(ctx, result, taskIDPrefix, logEntry)
+	}
+	logEntry.WithField("s3URI", result.S3URI).Info("Exporting snapshot to S3")
+	input := new(rds.StartExportTaskInput)
+	input.SetExportTaskIdentifier(result.TaskID)
+	input.SetSourceArn(aws.StringValue(snapshot.DBClusterSnapshotArn))
+	input.SetS3BucketName(svc.export.Bucket)
+	if svc.export.Prefix != "" {
+		input.SetS3Prefix(strings.TrimSuffix(svc.export.Prefix, "/"))
+	}
+	input.SetIamRoleArn(svc.export.IAMRoleARN)
+	input.SetKmsKeyId(svc.export.KMSKeyID)
+	if len(svc.export.ExportOnly) > 0 {
+		input.SetExportOnly(aws.StringSlice(svc.export.ExportOnly))
+	}
+	if _, err := svc.StartExportTaskWithContext(ctx, input); err != nil {
+		logEntry.WithError(err).Error("Error in starting the snapshot export")
+		result.Err = err
+		return result
+	}
+	return svc.completeExport(ctx, result, taskIDPrefix, logEntry)
+}
+
+// completeExport waits for the export task of the result to complete, and then deletes the exports
+// with the task identifier prefix beyond the retention.
+func (svc *auroraBackupService) completeExport(ctx context.Context, result *ExportResult, taskIDPrefix string, logEntry *log.Entry) *ExportResult {
+	task, err := svc.waitForExport(ctx, result.TaskID)
+	if err != nil {
+		logEntry.WithError(err).Error("Error in snapshot export check")
+		result.Err = err
+		return result
+	}
+	result.ExtractedGB = aws.Int64Value(task.TotalExtractedDataInGB)
+	logEntry.Info("Snapshot successfully exported to S3")
+
+	if svc.export.Retention > 0 {
+		result.Deleted, result.Err = svc.cleanUpExports(ctx, taskIDPrefix)
+		if result.Err != nil {
+			logEntry.WithError(result.Err).Error("Error in deleting old snapshot exports")
+		}
+	}
+	return result
+}
+
+// waitForExport waits until the export task is complete, failing if it fails or is cancelled.
+func (svc *auroraBackupService) waitForExport(ctx context.Context, taskID string) (*rds.ExportTask, error) {
+	input := new(rds.DescribeExportTasksInput)
+	input.SetExportTaskIdentifier(taskID)
+
+	for attempt := 0; attempt < svc.statusCheckAttempts; attempt++ {
+		if err := svc.pause(ctx, attempt); err != nil {
+			return nil, &InterruptedError{SnapshotID: taskID, Operation: "export", Err: err}
+		}
+		result, err := svc.DescribeExportTasksWithContext(ctx, input)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, &InterruptedError{SnapshotID: taskID, Operation: "export", Err: ctx.Err()}
+			}
+			return nil, err
+		}
+		if len(result.ExportTasks) < 1 {
+			return nil, errors.New("export task not found")
+		}
+		task := result.ExportTasks[0]
+		switch aws.StringValue(task.Status) {
+		case exportStatusComplete:
+			return task, nil
+		case exportStatusFailed, exportStatusCanceled:
+			return nil, fmt.Errorf("export task %v: %v", strings.ToLower(aws.StringValue(task.Status)), aws.StringValue(task.FailureCause))
+		}
+	}
+	return nil, &SnapshotTimeoutError{SnapshotID: taskID, Operation: "export"}
+}
+
+// cleanUpExports deletes the objects of the exports with the given task identifier prefix
+// beyond the retention of the destination, the most recent first, and returns their identifiers.
+func (svc *auroraBackupService) cleanUpExports(ctx context.Context, taskIDPrefix string) ([]string, error) {
+	taskIDs, err := svc.listExports(ctx, taskIDPrefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(taskIDs) <= svc.export.Retention {
+		return nil, nil
+	}
+	var deleted []string
+	for _, taskID := range taskIDs[svc.export.Retention:] {
+		if err := svc.deleteS3Objects(ctx, svc.exportFolder(taskID)); err != nil {
+			return deleted, fmt.Errorf("deleting export %v: %w", taskID, err)
+		}
+		log.WithField("exportTaskID", taskID).Info("Old snapshot export deleted")
+		deleted = append(deleted, taskID)
+	}
+	return deleted, nil
+}
+
+// listExports returns the identifiers of the exports under the prefix of the destination
+// created with the given task identifier prefix, from the most recent to the oldest.
+func (svc *auroraBackupService) listExports(ctx context.Context, taskIDPrefix string) ([]string, error) {
+	folder := svc.exportFolder("")
+	input := new(s3.ListObjectsV2Input)
+	input.SetBucket(svc.export.Bucket)
+	input.SetPrefix(folder + taskIDPrefix + "-")
+	input.SetDelimiter("/")
+	var taskIDs []string
+	for {
+		result, err := svc.s3Client.ListObjectsV2WithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, prefix := range result.CommonPrefixes {
+			taskID := strings.TrimSuffix(strings.TrimPrefix(aws.StringValue(prefix.Prefix), folder), "/")
+			if _, err := time.Parse(exportTaskIDDateFormat, strings.TrimPrefix(taskID, taskIDPrefix+"-")); err == nil {
+				taskIDs = append(taskIDs, taskID)
+			}
+		}
+		if !aws.BoolValue(result.IsTruncated) {
+			break
+		}
+		input.SetContinuationToken(aws.StringValue(result.NextContinuationToken))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(taskIDs)))
+	return taskIDs, nil
+}
+
+// deleteS3Objects deletes every object of the export bucket under the given prefix,
+// listing again from the start after each deletion, as a listing page never exceeds
+// the number of objects a DeleteObjects call accepts.
+func (svc *auroraBackupService) deleteS3Objects(ctx context.Context, prefix string) error {
+	input := new(s3.ListObjectsV2Input)
+	input.SetBucket(svc.export.Bucket)
+	input.SetPrefix(prefix)
+	input.SetMaxKeys(maxDeleteObjects)
+	for {
+		result, err := svc.s3Client.ListObjectsV2WithContext(ctx, input)
+		if err != nil {
+			return err
+		}
+		if len(result.Contents) == 0 {
+			return nil
+		}
+		var objects []*s3.ObjectIdentifier
+		for _, object := range result.Contents {
+			objects = append(objects, &s3.ObjectIdentifier{Key: object.Key})
+		}
+		deleteInput := new(s3.DeleteObjectsInput)
+		deleteInput.SetBucket(svc.export.Bucket)
+		deleteInput.SetDelete(&s3.Delete{Objects: objects, Quiet: aws.Bool(true)})
+		output, err := svc.s3Client.DeleteObjectsWithContext(ctx, deleteInput)
+		if err != nil {
+			return err
+		}
+		if len(output.Errors) > 0 {
+			return fmt.Errorf("deleting object %v: %v", aws.StringValue(output.Errors[0].Key), aws.StringValue(output.Errors[0].Message))
+		}
+	}
+}
+
+// exportTaskIDPrefix returns the prefix of the identifiers of the export tasks of a cluster:
+// its snapshot identifier prefix, shortened to leave room for the creation time of the export.
+func (svc *auroraBackupService) exportTaskIDPrefix(clusterID string) string {
+	prefix := svc.snapshotIDPrefixFor(clusterID)
+	if maxLength := maxExportTaskIDLength - len(exportTaskIDDateFormat) - 1; len(prefix) > maxLength {
+		prefix = strings.TrimRight(prefix[:maxLength], "-")
+	}
+	return prefix
+}
+
+// exportFolder returns the key prefix of the objects of an export in the bucket,
+// or of all the exports if taskID is empty.
+func (svc *auroraBackupService) exportFolder(taskID string) string {
+	folder := ""
+	if prefix := strings.TrimSuffix(svc.export.Prefix, "/"); prefix != "" {
+		folder = prefix + "/"
+	}
+	if taskID != "" {
+		folder += taskID + "/"
+	}
+	return folder
+}
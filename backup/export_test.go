@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testExportBucket = "pac-aurora-exports"
+
+func testExportDestination(retention int) ExportDestination {
+	return ExportDestination{
+		Bucket:     testExportBucket,
+		Prefix:     "analytics/aurora/",
+		IAMRoleARN: "arn:aws:iam::123456789012:role/pac-aurora-export",
+		KMSKeyID:   "arn:aws:kms:eu-west-1:123456789012:key/export",
+		ExportOnly: []string{"annotations.annotation"},
+		Retention:  retention,
+	}
+}
+
+func newFakeExport() (*awsfake.RDS, *awsfake.S3) {
+	fake := awsfake.NewRDS()
+	fake.S3 = awsfake.NewS3()
+	fake.S3.AddBucket(testExportBucket)
+	return fake, fake.S3
+}
+
+func TestMakeBackupExportsSnapshot(t *testing.T) {
+	fake, bucket := newFakeExport()
+	fake.ExportPolls = 2
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	for _, key := range []string{
+		"analytics/aurora/" + testSnapshotIDPrefix + "-20240101030000/export_info.json",
+		"analytics/aurora/" + testSnapshotIDPrefix + "-20240102030000/export_info.json",
+		"analytics/aurora/" + testSnapshotIDPrefix + "-20240103030000/annotations/annotation/part-00000.parquet",
+		"analytics/aurora/" + testSnapshotIDPrefix + "-manual/export_info.json",
+		"analytics/aurora/another-cluster-backup-20240101030000/export_info.json",
+	} {
+		bucket.AddObject(testExportBucket, key, []byte("x"))
+	}
+	svc := newFakeBackupService(t, fake, 0, WithExport(testExportDestination(2)), WithS3Client(bucket))
+
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	export := results[0].Export
+	require.NotNil(t, export)
+	assert.NoError(t, export.Err)
+	assert.Equal(t, results[0].SnapshotID, export.SnapshotID)
+	assert.True(t, strings.HasPrefix(export.TaskID, testSnapshotIDPrefix+"-"), export.TaskID)
+	assert.Equal(t, "s3://"+testExportBucket+"/analytics/aurora/"+export.TaskID+"/", export.S3URI)
+	assert.Equal(t, int64(1), export.ExtractedGB)
+	assert.Equal(t, []string{testSnapshotIDPrefix + "-20240102030000", testSnapshotIDPrefix + "-20240101030000"}, export.Deleted)
+
+	tasks := fake.ExportTasks()
+	require.Len(t, tasks, 1)
+	assert.Equal(t, results[0].SnapshotARN, *tasks[0].SourceArn)
+	assert.Equal(t, "analytics/aurora", *tasks[0].S3Prefix)
+	assert.Equal(t, []string{"annotations.annotation"}, aws.StringValueSlice(tasks[0].ExportOnly))
+
+	assert.Equal(t, []string{
+		"analytics/aurora/another-cluster-backup-20240101030000/export_info.json",
+		"analytics/aurora/" + testSnapshotIDPrefix + "-20240103030000/annotations/annotation/part-00000.parquet",
+		"analytics/aurora/" + export.TaskID + "/annotations/annotation/part-00000.parquet",
+		"analytics/aurora/" + export.TaskID + "/export_info_" + export.TaskID + ".json",
+		"analytics/aurora/" + testSnapshotIDPrefix + "-manual/export_info.json",
+	}, bucket.Keys(testExportBucket, ""))
+}
+
+func TestMakeBackupExportFailure(t *testing.T) {
+	fake, bucket := newFakeExport()
+	fake.ExportFailureCause = "The IAM role does not have access to the S3 bucket"
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0, WithExport(testExportDestination(2)), WithS3Client(bucket))
+
+	results, err := svc.MakeBackup(context.Background())
+
+	var exportErr *ExportError
+	require.True(t, errors.As(err, &exportErr), "unexpected error: %v", err)
+	assert.Equal(t, results[0].SnapshotID, exportErr.SnapshotID)
+	assert.ErrorContains(t, err, "export task failed: The IAM role does not have access to the S3 bucket")
+	require.Len(t, results, 1)
+	assert.NotEmpty(t, results[0].SnapshotARN)
+	assert.Error(t, results[0].Export.Err)
+	assert.Equal(t, 0, bucket.Calls("ListObjectsV2"))
+}
+
+func TestExportLatestSnapshot(t *testing.T) {
+	fake, bucket := newFakeExport()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	for _, day := range []int{1, 2} {
+		createTime := time.Date(2024, 1, day, 3, 0, 0, 0, time.UTC)
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + createTime.Format(snapshotIDDateFormat)),
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			SnapshotCreateTime:          aws.Time(createTime),
+		})
+	}
+	svc := newFakeBackupService(t, fake, 0, WithExport(testExportDestination(0)), WithS3Client(bucket))
+
+	result, err := svc.Export(context.Background(), LatestSnapshot)
+	require.NoError(t, err)
+	assert.Equal(t, testSnapshotIDPrefix+"-2024-01-02-03-00-00", result.SnapshotID)
+	assert.Empty(t, result.Deleted)
+	assert.Equal(t, 0, bucket.Calls("ListObjectsV2"))
+	assert.Len(t, bucket.Keys(testExportBucket, "analytics/aurora/"+result.TaskID+"/"), 2)
+}
+
+func TestExportNotConfigured(t *testing.T) {
+	svc := newFakeBackupService(t, awsfake.NewRDS(), 0)
+
+	_, err := svc.Export(context.Background(), LatestSnapshot)
+	assert.EqualError(t, err, "no export bucket configured")
+}
+
+func TestExportTaskIDPrefixFitsTaskIDLength(t *testing.T) {
+	svc := newFakeBackupService(t, awsfake.NewRDS(), 0, WithExport(testExportDestination(0)), WithS3Client(awsfake.NewS3()))
+	assert.Equal(t, testSnapshotIDPrefix, svc.exportTaskIDPrefix(""))
+
+	svc.snapshotIDPrefix = "pac-aurora-production-eu-west-annotations-backup"
+	prefix := svc.exportTaskIDPrefix("")
+	assert.Equal(t, "pac-aurora-production-eu-west-annotations-bac", prefix)
+	assert.Equal(t, maxExportTaskIDLength, len(prefix)+len("-"+exportTaskIDDateFormat))
+
+	svc.snapshotIDPrefix = "pac-aurora-production-eu-west-annotations-ab-backup"
+	assert.Equal(t, "pac-aurora-production-eu-west-annotations-ab", svc.exportTaskIDPrefix(""))
+}
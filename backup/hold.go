@@ -0,0 +1,256 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tags holding a snapshot, e.g. for a legal hold or an incident investigation. The cleanup never deletes
+// a snapshot tagged with HoldReasonTagKey, unless HoldUntilTagKey is set to a time in the past,
+// and does not count it toward the retention of the backups.
+const (
+	HoldReasonTagKey = "hold-reason"
+	HoldOwnerTagKey  = "hold-owner"
+	HoldUntilTagKey  = "hold-until"
+)
+
+// maxTagValueLength is the maximum length of an RDS tag value.
+const maxTagValueLength = 256
+
+// tagValuePattern matches the characters allowed in RDS tag values.
+var tagValuePattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// Hold describes why and by whom a snapshot is held. Until is zero for a hold without expiry.
+type Hold struct {
+	Reason string
+	Owner  string
+	Until  time.Time
+}
+
+// PinRequest describes the hold to put on a backup snapshot.
+type PinRequest struct {
+	// SnapshotID is the snapshot to hold, or LatestSnapshot for the most recent available backup snapshot of the cluster.
+	SnapshotID string
+	Hold
+}
+
+// PinResult describes the snapshot pinned or unpinned, with the regions where it or its copy was tagged.
+type PinResult struct {
+	SnapshotID string
+	Regions    []string
+	Hold       *Hold
+}
+
+func (h *Hold) String() string {
+	s := fmt.Sprintf("held by %v: %v", h.Owner, h.Reason)
+	if !h.Until.IsZero() {
+		s += fmt.Sprintf(" (until %v)", h.Until.Format(time.RFC3339))
+	}
+	return s
+}
+
+// active reports whether the hold still exempts the snapshot from the cleanup at the given time.
+func (h *Hold) active(now time.Time) bool {
+	return h.Until.IsZero() || h.Until.After(now)
+}
+
+func (h *Hold) validate(now time.Time) error {
+	if h.Reason == "" {
+		return errors.New("the hold reason is required")
+	}
+	if h.Owner == "" {
+		return errors.New("the hold owner is required")
+	}
+	for name, value := range map[string]string{"reason": h.Reason, "owner": h.Owner} {
+		if len(value) > maxTagValueLength {
+			return fmt.Errorf("the hold %v is longer than %d characters", name, maxTagValueLength)
+		}
+		if !tagValuePattern.MatchString(value) {
+			return fmt.Errorf("the hold %v can only contain letters, digits, spaces and the characters _.:/=+-@: %q", name, value)
+		}
+	}
+	if !h.Until.IsZero() && !h.Until.After(now) {
+		return fmt.Errorf("the hold expiry is in the past: %v", h.Until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (h *Hold) tags() []*rds.Tag {
+	tags := []*rds.Tag{
+		{Key: aws.String(HoldReasonTagKey), Value: aws.String(h.Reason)},
+		{Key: aws.String(HoldOwnerTagKey), Value: aws.String(h.Owner)},
+	}
+	if !h.Until.IsZero() {
+		tags = append(tags, &rds.Tag{Key: aws.String(HoldUntilTagKey), Value: aws.String(h.Until.UTC().Format(time.RFC3339))})
+	}
+	return tags
+}
+
+// snapshotHold returns the hold of a snapshot, or nil when the snapshot is not tagged with a hold reason.
+// A hold expiry that cannot be parsed is ignored, so that the snapshot stays held.
+func snapshotHold(snapshot *rds.DBClusterSnapshot) *Hold {
+	var hold *Hold
+	var until string
+	for _, tag := range snapshot.TagList {
+		switch aws.StringValue(tag.Key) {
+		case HoldReasonTagKey:
+			if hold == nil {
+				hold = new(Hold)
+			}
+			hold.Reason = aws.StringValue(tag.Value)
+		case HoldOwnerTagKey:
+			if hold == nil {
+				hold = new(Hold)
+			}
+			hold.Owner = aws.StringValue(tag.Value)
+		case HoldUntilTagKey:
+			until = aws.StringValue(tag.Value)
+		}
+	}
+	if hold == nil || hold.Reason == "" {
+		return nil
+	}
+	if until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			log.WithError(err).
+				WithField("snapshotID", aws.StringValue(snapshot.DBClusterSnapshotIdentifier)).
+				Warn("Ignoring invalid hold expiry of snapshot, holding it indefinitely")
+		} else {
+			hold.Until = t
+		}
+	}
+	return hold
+}
+
+// splitHeld separates the snapshots with an active hold at the given time from the others.
+func splitHeld(snapshots []*rds.DBClusterSnapshot, now time.Time) (notHeld, held []*rds.DBClusterSnapshot) {
+	for _, snapshot := range snapshots {
+		if hold := snapshotHold(snapshot); hold != nil && hold.active(now) {
+			held = append(held, snapshot)
+		} else {
+			notHeld = append(notHeld, snapshot)
+		}
+	}
+	return notHeld, held
+}
+
+// Pin holds a backup snapshot, and its copies with the same identifier in the copy regions,
+// so that the cleanup keeps them until they are unpinned or the hold expires.
+// Pinning a held snapshot replaces its hold.
+func (svc *auroraBackupService) Pin(ctx context.Context, req PinRequest) (*PinResult, error) {
+	if err := req.Hold.validate(time.Now()); err != nil {
+		return nil, err
+	}
+	var clusterID string
+	snapshot, err := svc.availableSnapshot(ctx, req.SnapshotID, &clusterID)
+	if err != nil {
+		log.WithError(err).
+			WithField("snapshotID", req.SnapshotID).
+			Error("Error in fetching the snapshot to pin")
+		return nil, err
+	}
+
+	hold := req.Hold
+	result := &PinResult{SnapshotID: aws.StringValue(snapshot.DBClusterSnapshotIdentifier), Hold: &hold}
+	err = svc.forEachSnapshotCopy(ctx, snapshot, func(client RDSClient, region string, snapshot *rds.DBClusterSnapshot) error {
+		input := new(rds.AddTagsToResourceInput)
+		input.SetResourceName(aws.StringValue(snapshot.DBClusterSnapshotArn))
+		input.SetTags(hold.tags())
+		if _, err := client.AddTagsToResourceWithContext(ctx, input); err != nil {
+			return err
+		}
+		if hold.Until.IsZero() {
+			// The tags are merged, so the expiry of a previous hold must be removed for the hold to be indefinite.
+			untilInput := new(rds.RemoveTagsFromResourceInput)
+			untilInput.SetResourceName(aws.StringValue(snapshot.DBClusterSnapshotArn))
+			untilInput.SetTagKeys(aws.StringSlice([]string{HoldUntilTagKey}))
+			if _, err := client.RemoveTagsFromResourceWithContext(ctx, untilInput); err != nil {
+				return err
+			}
+		}
+		log.WithField("snapshotID", result.SnapshotID).
+			WithField("region", region).
+			WithField("reason", hold.Reason).
+			WithField("owner", hold.Owner).
+			Info("Snapshot pinned")
+		result.Regions = append(result.Regions, region)
+		return nil
+	})
+	return result, err
+}
+
+// Unpin removes the hold of a backup snapshot and of its copies with the same identifier in the copy regions,
+// so that the cleanup applies the retention to them again.
+func (svc *auroraBackupService) Unpin(ctx context.Context, snapshotID string) (*PinResult, error) {
+	snapshot, err := describeSnapshot(ctx, svc.RDSClient, snapshotID)
+	if err != nil {
+		log.WithError(err).
+			WithField("snapshotID", snapshotID).
+			Error("Error in fetching the snapshot to unpin")
+		return nil, err
+	}
+
+	result := &PinResult{SnapshotID: snapshotID, Hold: snapshotHold(snapshot)}
+	err = svc.forEachSnapshotCopy(ctx, snapshot, func(client RDSClient, region string, snapshot *rds.DBClusterSnapshot) error {
+		input := new(rds.RemoveTagsFromResourceInput)
+		input.SetResourceName(aws.StringValue(snapshot.DBClusterSnapshotArn))
+		input.SetTagKeys(aws.StringSlice([]string{HoldReasonTagKey, HoldOwnerTagKey, HoldUntilTagKey}))
+		if _, err := client.RemoveTagsFromResourceWithContext(ctx, input); err != nil {
+			return err
+		}
+		log.WithField("snapshotID", snapshotID).
+			WithField("region", region).
+			Info("Snapshot unpinned")
+		result.Regions = append(result.Regions, region)
+		return nil
+	})
+	return result, err
+}
+
+// forEachSnapshotCopy calls fn with the snapshot in the source region, then with its copy in each copy region
+// where it exists, stopping at the first error.
+func (svc *auroraBackupService) forEachSnapshotCopy(ctx context.Context, snapshot *rds.DBClusterSnapshot, fn func(client RDSClient, region string, snapshot *rds.DBClusterSnapshot) error) error {
+	snapshotID := aws.StringValue(snapshot.DBClusterSnapshotIdentifier)
+	if err := fn(svc.RDSClient, svc.region, snapshot); err != nil {
+		return fmt.Errorf("snapshot %v in %v: %w", snapshotID, svc.region, err)
+	}
+	for _, destination := range svc.copyDestinations {
+		client := svc.copyClients[destination.Region]
+		snapshotCopy, err := describeSnapshot(ctx, client, snapshotID)
+		if isAWSErrorCode(err, rds.ErrCodeDBClusterSnapshotNotFoundFault) {
+			log.WithField("snapshotID", snapshotID).
+				WithField("region", destination.Region).
+				Info("No copy of the snapshot in region")
+			continue
+		}
+		if err == nil {
+			err = fn(client, destination.Region, snapshotCopy)
+		}
+		if err != nil {
+			return fmt.Errorf("copy of snapshot %v in %v: %w", snapshotID, destination.Region, err)
+		}
+	}
+	return nil
+}
+
+// describeSnapshot returns the snapshot with the given identifier whatever its status.
+func describeSnapshot(ctx context.Context, client RDSClient, snapshotID string) (*rds.DBClusterSnapshot, error) {
+	input := new(rds.DescribeDBClusterSnapshotsInput)
+	input.SetDBClusterSnapshotIdentifier(snapshotID)
+	output, err := client.DescribeDBClusterSnapshotsWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(output.DBClusterSnapshots) == 0 {
+		return nil, fmt.Errorf("snapshot %v not found", snapshotID)
+	}
+	return output.DBClusterSnapshots[0], nil
+}
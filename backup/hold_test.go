@@ -0,0 +1,161 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addManagedSnapshots adds, for each of the given days ago, an available managed backup snapshot.
+func addManagedSnapshots(fake *awsfake.RDS, now time.Time, days ...int) []string {
+	var snapshotIDs []string
+	for _, day := range days {
+		snapshotID := testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -day).Format(snapshotIDDateFormat)
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(snapshotID),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -day)),
+			Status:                      aws.String(statusAvailable),
+			TagList:                     testManagedTags,
+		})
+		snapshotIDs = append(snapshotIDs, snapshotID)
+	}
+	return snapshotIDs
+}
+
+func TestCleanUpKeepsPinnedSnapshots(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	snapshotIDs := addManagedSnapshots(fake, now, 1, 2, 3, 4)
+	svc := newFakeBackupService(t, fake, 2)
+
+	_, err := svc.Pin(context.Background(), PinRequest{SnapshotID: snapshotIDs[3], Hold: Hold{Reason: "INC-1234", Owner: "jane.doe@ft.com"}})
+	require.NoError(t, err)
+	_, err = svc.Pin(context.Background(), PinRequest{SnapshotID: snapshotIDs[2], Hold: Hold{Reason: "INC-1234", Owner: "jane.doe@ft.com", Until: now.Add(time.Hour)}})
+	require.NoError(t, err)
+
+	plan, err := svc.Plan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, plan.Cleanups, 1)
+	assert.ElementsMatch(t, snapshotIDs[2:], plan.Cleanups[0].Held)
+
+	infos, err := svc.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, infos, 4)
+	assert.True(t, infos[3].Keep)
+	assert.Equal(t, "held by jane.doe@ft.com: INC-1234", infos[3].Reason)
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, snapshotIDs[:2], results[0].Retained, "the pinned snapshots should not count toward the retention")
+	assert.ElementsMatch(t, snapshotIDs[2:], results[0].Held)
+	assert.Empty(t, results[0].Deleted)
+	require.Len(t, results[0].Decisions, 4)
+	assert.Equal(t, snapshotIDs[3], results[0].Decisions[3].SnapshotID)
+	assert.Equal(t, "held by jane.doe@ft.com: INC-1234", results[0].Decisions[3].Reason)
+	assert.Equal(t, "keep-last", results[0].Decisions[0].Reason)
+
+	_, err = svc.Unpin(context.Background(), snapshotIDs[3])
+	require.NoError(t, err)
+	results, err = svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{snapshotIDs[3]}, results[0].Deleted)
+	assert.Equal(t, []string{snapshotIDs[2]}, results[0].Held)
+}
+
+func TestCleanUpDeletesSnapshotsWithExpiredHold(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	snapshotIDs := addManagedSnapshots(fake, now, 1, 2)
+	_, err := fake.AddTagsToResource(&rds.AddTagsToResourceInput{
+		ResourceName: fake.Snapshot(snapshotIDs[1]).DBClusterSnapshotArn,
+		Tags:         (&Hold{Reason: "INC-1234", Owner: "jane.doe@ft.com", Until: now.Add(-time.Hour)}).tags(),
+	})
+	require.NoError(t, err)
+	require.NotNil(t, snapshotHold(fake.Snapshot(snapshotIDs[1])))
+	svc := newFakeBackupService(t, fake, 1)
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, results[0].Held)
+	assert.Equal(t, []string{snapshotIDs[1]}, results[0].Deleted)
+}
+
+func TestRepinWithoutExpiryHoldsIndefinitely(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	snapshotIDs := addManagedSnapshots(fake, now, 1, 2)
+	svc := newFakeBackupService(t, fake, 1)
+	until := now.Truncate(time.Second).Add(2 * time.Second)
+
+	_, err := svc.Pin(context.Background(), PinRequest{SnapshotID: snapshotIDs[1], Hold: Hold{Reason: "INC-1234", Owner: "jane.doe@ft.com", Until: until}})
+	require.NoError(t, err)
+	_, err = svc.Pin(context.Background(), PinRequest{SnapshotID: snapshotIDs[1], Hold: Hold{Reason: "legal hold", Owner: "legal"}})
+	require.NoError(t, err)
+	assert.Equal(t, &Hold{Reason: "legal hold", Owner: "legal"}, snapshotHold(fake.Snapshot(snapshotIDs[1])))
+
+	time.Sleep(time.Until(until) + 10*time.Millisecond)
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{snapshotIDs[1]}, results[0].Held, "the expiry of the previous hold should be removed")
+	assert.Empty(t, results[0].Deleted)
+}
+
+func TestPinTagsCopies(t *testing.T) {
+	source, destinations := newFakeRegions("us-east-1", "eu-central-1")
+	source.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	snapshotIDs := addManagedSnapshots(source, now, 1, 2)
+	addManagedSnapshots(destinations["us-east-1"], now, 1)
+	svc := newFakeBackupService(t, source, 1,
+		WithClientFactory(fakeClientFactory(destinations)),
+		WithCopyDestinations(CopyDestination{Region: "us-east-1", Retention: 1}, CopyDestination{Region: "eu-central-1", Retention: 1}))
+	until := now.AddDate(0, 0, 90).Truncate(time.Second)
+
+	result, err := svc.Pin(context.Background(), PinRequest{SnapshotID: LatestSnapshot, Hold: Hold{Reason: "legal hold", Owner: "legal", Until: until}})
+	require.NoError(t, err)
+	assert.Equal(t, snapshotIDs[0], result.SnapshotID)
+	assert.Equal(t, []string{source.Region, "us-east-1"}, result.Regions)
+	assert.Equal(t, &Hold{Reason: "legal hold", Owner: "legal", Until: until}, snapshotHold(source.Snapshot(snapshotIDs[0])))
+	assert.Equal(t, &Hold{Reason: "legal hold", Owner: "legal", Until: until}, snapshotHold(destinations["us-east-1"].Snapshot(snapshotIDs[0])))
+
+	result, err = svc.Unpin(context.Background(), snapshotIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{source.Region, "us-east-1"}, result.Regions)
+	assert.Equal(t, "legal hold", result.Hold.Reason)
+	assert.Nil(t, snapshotHold(source.Snapshot(snapshotIDs[0])))
+	assert.Nil(t, snapshotHold(destinations["us-east-1"].Snapshot(snapshotIDs[0])))
+	assert.True(t, isManaged(source.Snapshot(snapshotIDs[0])))
+}
+
+func TestPinInvalidHold(t *testing.T) {
+	fake := awsfake.NewRDS()
+	snapshotIDs := addManagedSnapshots(fake, time.Now().UTC(), 1)
+	svc := newFakeBackupService(t, fake, 1)
+
+	for name, test := range map[string]struct {
+		hold          Hold
+		expectedError string
+	}{
+		"no reason":      {Hold{Owner: "jane.doe@ft.com"}, "the hold reason is required"},
+		"no owner":       {Hold{Reason: "INC-1234"}, "the hold owner is required"},
+		"invalid reason": {Hold{Reason: "INC-1234, do not delete", Owner: "jane.doe@ft.com"}, `the hold reason can only contain letters, digits, spaces and the characters _.:/=+-@: "INC-1234, do not delete"`},
+		"expired":        {Hold{Reason: "INC-1234", Owner: "jane.doe@ft.com", Until: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, "the hold expiry is in the past: 2024-01-01T00:00:00Z"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.Pin(context.Background(), PinRequest{SnapshotID: snapshotIDs[0], Hold: test.hold})
+			assert.EqualError(t, err, test.expectedError)
+		})
+	}
+	assert.Equal(t, 0, fake.Calls("AddTagsToResource"))
+}
@@ -0,0 +1,50 @@
+package backup
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// WithMinBackupInterval makes MakeBackup reuse the most recent managed snapshot of a cluster created less than interval ago,
+// waiting for it if it is still being created, instead of creating another one. This makes the runs idempotent,
+// e.g. when the CronJob of the other delivery cluster already backed up the cluster, or when a Job retried
+// after a crash resumes waiting for the snapshot of its first attempt. A zero interval always creates a snapshot.
+func WithMinBackupInterval(interval time.Duration) Option {
+	return func(svc *auroraBackupService) {
+		svc.minBackupInterval = interval
+	}
+}
+
+// recentSnapshot returns the most recent available or creating managed snapshot of the cluster
+// created less than the minimum backup interval before now, or nil if there is none.
+// A snapshot being created without a creation time yet counts as recent.
+func (svc *auroraBackupService) recentSnapshot(ctx context.Context, clusterID string, now time.Time) (*rds.DBClusterSnapshot, error) {
+	if svc.minBackupInterval <= 0 {
+		return nil, nil
+	}
+	snapshots, err := svc.listClusterSnapshots(ctx, svc.RDSClient, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, _ = splitManaged(snapshots)
+	sortSnapshotsNewestFirst(snapshots)
+	for _, snapshot := range snapshots {
+		status := aws.StringValue(snapshot.Status)
+		if status != statusAvailable && status != statusCreating {
+			continue
+		}
+		if snapshot.SnapshotCreateTime == nil {
+			if status == statusCreating {
+				return snapshot, nil
+			}
+			continue
+		}
+		if now.Sub(*snapshot.SnapshotCreateTime) < svc.minBackupInterval {
+			return snapshot, nil
+		}
+	}
+	return nil, nil
+}
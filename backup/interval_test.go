@@ -0,0 +1,167 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeBackupReusesSnapshotBeingCreated(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	snapshotID := testSnapshotIDPrefix + "-" + now.Add(-time.Hour).Format(snapshotIDDateFormat)
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String(snapshotID),
+		SnapshotCreateTime:          aws.Time(now.Add(-time.Hour)),
+		Status:                      aws.String(statusCreating),
+		TagList:                     testManagedTags,
+	})
+	svc := newFakeBackupService(t, fake, 0, WithMinBackupInterval(12*time.Hour))
+
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Reused)
+	assert.Equal(t, snapshotID, results[0].SnapshotID)
+	assert.Equal(t, *fake.Snapshot(snapshotID).DBClusterSnapshotArn, results[0].SnapshotARN)
+	assert.Equal(t, statusAvailable, *fake.Snapshot(snapshotID).Status)
+	assert.Zero(t, fake.Calls("CreateDBClusterSnapshot"))
+}
+
+func TestMakeBackupIgnoresOldUnmanagedAndFailedSnapshots(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	addManagedSnapshots(fake, now, 1)
+	for status, tags := range map[string][]*rds.Tag{statusAvailable: nil, "failed": testManagedTags} {
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + status),
+			SnapshotCreateTime:          aws.Time(now.Add(-time.Hour)),
+			Status:                      aws.String(status),
+			TagList:                     tags,
+		})
+	}
+	svc := newFakeBackupService(t, fake, 0, WithMinBackupInterval(12*time.Hour))
+
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Reused)
+	assert.Equal(t, 1, fake.Calls("CreateDBClusterSnapshot"))
+	assert.NotNil(t, fake.Snapshot(results[0].SnapshotID))
+}
+
+func TestMakeBackupRetryReusesSnapshotAndCopies(t *testing.T) {
+	source, destinations := newFakeRegions("us-east-1")
+	source.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, source, 0,
+		WithMinBackupInterval(time.Hour),
+		WithClientFactory(fakeClientFactory(destinations)),
+		WithCopyDestinations(CopyDestination{Region: "us-east-1", Retention: 3}))
+
+	first, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	retry, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, retry, 1)
+	assert.True(t, retry[0].Reused)
+	assert.Equal(t, first[0].SnapshotID, retry[0].SnapshotID)
+	require.Len(t, retry[0].Copies, 1)
+	assert.NoError(t, retry[0].Copies[0].Err)
+	assert.Equal(t, first[0].Copies[0].SnapshotARN, retry[0].Copies[0].SnapshotARN)
+	assert.Len(t, source.Snapshots(), 1)
+	assert.Len(t, destinations["us-east-1"].Snapshots(), 1)
+}
+
+func TestMakeBackupRetryReusesExport(t *testing.T) {
+	fake, bucket := newFakeExport()
+	fake.ExportPolls = 1
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0,
+		WithMinBackupInterval(time.Hour),
+		WithExport(testExportDestination(3)),
+		WithS3Client(bucket))
+
+	first, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, first[0].Export)
+	assert.NotZero(t, first[0].CreationDuration)
+	retry, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, retry, 1)
+	assert.True(t, retry[0].Reused)
+	assert.Zero(t, retry[0].CreationDuration, "the creation of a reused snapshot is not measured")
+	require.NotNil(t, retry[0].Export)
+	assert.NoError(t, retry[0].Export.Err)
+	assert.Equal(t, first[0].Export.TaskID, retry[0].Export.TaskID)
+	assert.Equal(t, 1, fake.Calls("StartExportTask"))
+	assert.Len(t, fake.ExportTasks(), 1)
+}
+
+func TestMakeBackupExportsReusedSnapshotAfterFailedExport(t *testing.T) {
+	fake, bucket := newFakeExport()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	first, err := newFakeBackupService(t, fake, 0).MakeBackup(context.Background())
+	require.NoError(t, err)
+	destination := testExportDestination(3)
+	fake.ExportPolls = 0
+	fake.ExportFailureCause = "S3 bucket not accessible"
+	_, err = fake.StartExportTask(&rds.StartExportTaskInput{
+		ExportTaskIdentifier: aws.String("failed-export"),
+		SourceArn:            aws.String(first[0].SnapshotARN),
+		S3BucketName:         aws.String(destination.Bucket),
+		IamRoleArn:           aws.String(destination.IAMRoleARN),
+		KmsKeyId:             aws.String(destination.KMSKeyID),
+	})
+	require.NoError(t, err)
+	_, err = fake.DescribeExportTasks(&rds.DescribeExportTasksInput{})
+	require.NoError(t, err)
+	fake.ExportFailureCause = ""
+	svc := newFakeBackupService(t, fake, 0,
+		WithMinBackupInterval(time.Hour),
+		WithExport(destination),
+		WithS3Client(bucket))
+
+	retry, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+
+	assert.True(t, retry[0].Reused)
+	assert.NotEqual(t, "failed-export", retry[0].Export.TaskID, "a failed export should not be reused")
+	assert.Equal(t, 2, fake.Calls("StartExportTask"))
+}
+
+func TestPlanShowsReusedSnapshot(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	snapshotIDs := addManagedSnapshots(fake, now, 0, 1, 2)
+	svc := newFakeBackupService(t, fake, 2, WithMinBackupInterval(12*time.Hour))
+
+	plan, err := svc.Plan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, plan.Backups, 1)
+	assert.True(t, plan.Backups[0].Reused)
+	assert.Equal(t, snapshotIDs[0], plan.Backups[0].SnapshotID)
+	assert.Empty(t, plan.Backups[0].PreflightConditions)
+	require.Len(t, plan.Cleanups, 1)
+	assert.Empty(t, plan.Cleanups[0].NewSnapshotID)
+	require.Len(t, plan.Cleanups[0].Snapshots, 3)
+	assert.False(t, plan.Cleanups[0].Snapshots[2].Keep, "the reused snapshot should count toward the retention")
+}
+
+func TestNewBackupServiceRejectsNegativeMinBackupInterval(t *testing.T) {
+	_, err := NewBackupService("eu-west-1", testClusterIDPrefix, testSnapshotIDPrefix, 0, testStatusCheckAttempts, 0,
+		WithRDSClient(awsfake.NewRDS()), WithMinBackupInterval(-time.Hour))
+	assert.EqualError(t, err, "minimum backup interval cannot be negative: -1h0m0s")
+}
@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotInfo describes a backup snapshot of the source region listed by List,
+// with the decision the cleanup would currently take for it.
+type SnapshotInfo struct {
+	SnapshotID string
+	ClusterID  string
+	Status     string
+	CreateTime time.Time
+	Age        time.Duration
+	// AllocatedStorage is the storage allocated to the snapshot in GiB.
+	AllocatedStorage int64
+	Encrypted        bool
+	KmsKeyID         string
+	Keep             bool
+	Reason           string
+}
+
+// List returns the backup snapshots of the source region, from the most recent to the oldest one for each cluster,
+// with whether the retention policy and the age limits would keep or delete them.
+// The snapshots not tagged as managed and the pinned snapshots are listed as kept, as the cleanup ignores them.
+// Unlike Plan, it does not take into account the snapshot a run would create.
+func (svc *auroraBackupService) List(ctx context.Context) ([]*SnapshotInfo, error) {
+	clusterIDs, err := svc.getCleanupClusterIDs(ctx)
+	if err != nil {
+		log.WithError(err).Error("Error in fetching DB cluster information from AWS")
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var infos []*SnapshotInfo
+	for _, clusterID := range clusterIDs {
+		snapshots, err := svc.getDBSnapshotsByPrefix(ctx, svc.snapshotIDPrefixFor(clusterID))
+		if err != nil {
+			return nil, err
+		}
+		if clusterID != "" {
+			snapshots = filterSnapshotsByCluster(snapshots, clusterID)
+		}
+		byID := make(map[string]*rds.DBClusterSnapshot, len(snapshots))
+		for _, snapshot := range snapshots {
+			byID[aws.StringValue(snapshot.DBClusterSnapshotIdentifier)] = snapshot
+		}
+		managed, unmanaged := splitManaged(snapshots)
+		managed, held := splitHeld(managed, now)
+		decisions := decideRetention(managed, svc.retentionPolicy, now, svc.minBackupAge, svc.maxBackupAge)
+		decisions = withIgnoredDecisions(decisions, unmanaged, held, now)
+		for _, decision := range decisions {
+			snapshot := byID[decision.SnapshotID]
+			infos = append(infos, &SnapshotInfo{
+				SnapshotID:       decision.SnapshotID,
+				ClusterID:        aws.StringValue(snapshot.DBClusterIdentifier),
+				Status:           aws.StringValue(snapshot.Status),
+				CreateTime:       decision.CreateTime,
+				Age:              decision.Age,
+				AllocatedStorage: aws.Int64Value(snapshot.AllocatedStorage),
+				Encrypted:        aws.BoolValue(snapshot.StorageEncrypted),
+				KmsKeyID:         aws.StringValue(snapshot.KmsKeyId),
+				Keep:             decision.Keep,
+				Reason:           decision.Reason,
+			})
+		}
+	}
+	return infos, nil
+}
+
+// withIgnoredDecisions adds to the retention decisions those keeping the unmanaged and the pinned snapshots,
+// which the retention ignores, sorting them all from the most recent snapshot to the oldest one.
+func withIgnoredDecisions(decisions []SnapshotDecision, unmanaged, held []*rds.DBClusterSnapshot, now time.Time) []SnapshotDecision {
+	for _, snapshot := range unmanaged {
+		decisions = append(decisions, keptDecision(snapshot, now, unmanagedReason))
+	}
+	for _, snapshot := range held {
+		decisions = append(decisions, keptDecision(snapshot, now, snapshotHold(snapshot).String()))
+	}
+	sort.SliceStable(decisions, func(i, j int) bool { return decisions[i].CreateTime.After(decisions[j].CreateTime) })
+	return decisions
+}
+
+// keptDecision returns the decision keeping a snapshot ignored by the retention for the given reason.
+func keptDecision(snapshot *rds.DBClusterSnapshot, now time.Time, reason string) SnapshotDecision {
+	decision := SnapshotDecision{SnapshotID: aws.StringValue(snapshot.DBClusterSnapshotIdentifier), Keep: true, Reason: reason}
+	if snapshot.SnapshotCreateTime != nil {
+		decision.CreateTime = *snapshot.SnapshotCreateTime
+		decision.Age = now.Sub(decision.CreateTime)
+	}
+	return decision
+}
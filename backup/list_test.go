@@ -0,0 +1,69 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListWithFakeRDS(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	for i := 1; i <= 3; i++ {
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+			TagList:                     testManagedTags,
+			AllocatedStorage:            aws.Int64(int64(10 * i)),
+			StorageEncrypted:            aws.Bool(true),
+			KmsKeyId:                    aws.String("arn:aws:kms:eu-west-1:123456789012:key/backup"),
+		})
+	}
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String("manual-before-upgrade"),
+		SnapshotCreateTime:          aws.Time(now),
+	})
+	svc := newFakeBackupService(t, fake, 2)
+
+	infos, err := svc.List(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, infos, 3)
+	latest := infos[0]
+	assert.Equal(t, testSnapshotIDPrefix+"-"+now.AddDate(0, 0, -1).Format(snapshotIDDateFormat), latest.SnapshotID)
+	assert.Equal(t, testClusterIDPrefix+"-eu", latest.ClusterID)
+	assert.Equal(t, statusAvailable, latest.Status)
+	assert.WithinDuration(t, now.AddDate(0, 0, -1), latest.CreateTime, time.Second)
+	assert.InDelta(t, 24*time.Hour, latest.Age, float64(time.Minute))
+	assert.Equal(t, int64(10), latest.AllocatedStorage)
+	assert.True(t, latest.Encrypted)
+	assert.Equal(t, "arn:aws:kms:eu-west-1:123456789012:key/backup", latest.KmsKeyID)
+	assert.True(t, latest.Keep)
+	assert.Equal(t, "keep-last", latest.Reason)
+
+	assert.True(t, infos[1].Keep)
+	assert.False(t, infos[2].Keep)
+	assert.Equal(t, "not selected by retention policy keep-last=2", infos[2].Reason)
+	assert.Equal(t, 0, fake.Calls("DeleteDBClusterSnapshot"))
+}
+
+func TestListListingError(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.FailNext("DescribeDBClusterSnapshots", awserr.New("Throttling", "Rate exceeded", nil))
+	svc := newFakeBackupService(t, fake, 2)
+
+	_, err := svc.List(context.Background())
+
+	assertAWSErrorCode(t, "Throttling", err)
+}
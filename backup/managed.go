@@ -0,0 +1,195 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tags of the snapshots created by the service. The cleanup only considers the snapshots tagged
+// with ManagedByTagKey=ManagedByTagValue, so that it never deletes a snapshot made by hand
+// with an identifier starting with the snapshot identifier prefix.
+const (
+	ManagedByTagKey   = "managed-by"
+	ManagedByTagValue = "pac-aurora-backup"
+	EnvironmentTagKey = "environment"
+	SystemCodeTagKey  = "system-code"
+	AppVersionTagKey  = "app-version"
+)
+
+const unmanagedReason = "not tagged " + ManagedByTagKey + "=" + ManagedByTagValue
+
+// AdoptResult describes the adoption of the untagged snapshots of a cluster in a region by Adopt.
+// Skipped lists the untagged snapshots with the prefix whose identifier does not end with a backup creation time,
+// most likely made by hand, which are never adopted.
+type AdoptResult struct {
+	Region           string
+	ClusterID        string
+	SnapshotIDPrefix string
+	Adopted          []string
+	Skipped          []string
+	Failed           []SnapshotFailure
+	Err              error
+}
+
+// WithManagedTags makes the service tag every new snapshot with the environment, system code and version of the app
+// next to the managed-by tag. Empty values are not tagged.
+func WithManagedTags(environment, systemCode, version string) Option {
+	return func(svc *auroraBackupService) {
+		svc.managedTags = make(map[string]string)
+		for key, value := range map[string]string{EnvironmentTagKey: environment, SystemCodeTagKey: systemCode, AppVersionTagKey: version} {
+			if value != "" {
+				svc.managedTags[key] = value
+			}
+		}
+	}
+}
+
+// WithEnvironment replaces the environment given to WithManagedTags, which is tagged on every new snapshot
+// and is the {env} of the snapshot identifier template, e.g. with the environment of a target of the config file.
+func WithEnvironment(environment string) Option {
+	return func(svc *auroraBackupService) {
+		if svc.managedTags == nil {
+			svc.managedTags = make(map[string]string)
+		}
+		if environment == "" {
+			delete(svc.managedTags, EnvironmentTagKey)
+			return
+		}
+		svc.managedTags[EnvironmentTagKey] = environment
+	}
+}
+
+// newSnapshotTags returns the tags of a new snapshot sorted by key:
+// the configured tags, overridden by the managed-by tag and the other managed tags.
+func (svc *auroraBackupService) newSnapshotTags() []*rds.Tag {
+	tags := make(map[string]string, len(svc.snapshotTags)+len(svc.managedTags)+1)
+	for key, value := range svc.snapshotTags {
+		tags[key] = value
+	}
+	for key, value := range svc.managedTags {
+		tags[key] = value
+	}
+	tags[ManagedByTagKey] = ManagedByTagValue
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rdsTags := make([]*rds.Tag, 0, len(keys))
+	for _, key := range keys {
+		rdsTags = append(rdsTags, &rds.Tag{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return rdsTags
+}
+
+// isManaged reports whether a snapshot is tagged as managed by this app.
+func isManaged(snapshot *rds.DBClusterSnapshot) bool {
+	for _, tag := range snapshot.TagList {
+		if aws.StringValue(tag.Key) == ManagedByTagKey && aws.StringValue(tag.Value) == ManagedByTagValue {
+			return true
+		}
+	}
+	return false
+}
+
+// splitManaged separates the snapshots managed by this app from the others.
+func splitManaged(snapshots []*rds.DBClusterSnapshot) (managed, unmanaged []*rds.DBClusterSnapshot) {
+	for _, snapshot := range snapshots {
+		if isManaged(snapshot) {
+			managed = append(managed, snapshot)
+		} else {
+			unmanaged = append(unmanaged, snapshot)
+		}
+	}
+	return managed, unmanaged
+}
+
+// Adopt tags as managed the untagged snapshots of the clusters, in the source region and in the copy regions,
+// whose identifier is the snapshot identifier prefix followed by a creation time, as the app named its backups
+// before tagging them, so that the cleanup applies the retention to them again.
+// With dryRun, it only reports the snapshots it would adopt.
+func (svc *auroraBackupService) Adopt(ctx context.Context, dryRun bool) ([]*AdoptResult, error) {
+	clusterIDs, err := svc.getCleanupClusterIDs(ctx)
+	if err != nil {
+		log.WithError(err).Error("Error in fetching DB cluster information from AWS for adoption")
+		return nil, err
+	}
+
+	results := make([]*AdoptResult, len(clusterIDs)*(1+len(svc.copyDestinations)))
+	svc.forEachCluster(clusterIDs, func(i int, clusterID string) {
+		offset := i * (1 + len(svc.copyDestinations))
+		results[offset] = svc.adoptSnapshots(ctx, svc.RDSClient, svc.region, clusterID, dryRun)
+		for j, destination := range svc.copyDestinations {
+			results[offset+1+j] = svc.adoptSnapshots(ctx, svc.copyClients[destination.Region], destination.Region, clusterID, dryRun)
+		}
+	})
+
+	var errs []error
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		if result.ClusterID == "" {
+			errs = append(errs, result.Err)
+		} else {
+			errs = append(errs, &ClusterError{ClusterID: result.ClusterID, Err: result.Err})
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (svc *auroraBackupService) adoptSnapshots(ctx context.Context, client RDSClient, region, clusterID string, dryRun bool) *AdoptResult {
+	result := &AdoptResult{Region: region, ClusterID: clusterID, SnapshotIDPrefix: svc.snapshotIDPrefixFor(clusterID)}
+
+	snapshots, err := svc.listClusterSnapshots(ctx, client, clusterID)
+	if err != nil {
+		log.WithError(err).
+			WithField("region", region).
+			Error("Error in fetching DB cluster snapshots for adoption")
+		result.Err = err
+		return result
+	}
+	_, unmanaged := splitManaged(snapshots)
+	sortSnapshotsNewestFirst(unmanaged)
+
+	input := new(rds.AddTagsToResourceInput)
+	input.SetTags(svc.newSnapshotTags())
+	for _, snapshot := range unmanaged {
+		snapshotID := aws.StringValue(snapshot.DBClusterSnapshotIdentifier)
+		if _, ok := svc.snapshotIDTime(snapshotID, result.SnapshotIDPrefix); !ok {
+			log.WithField("snapshotID", snapshotID).
+				WithField("region", region).
+				Warn("Snapshot is not named like a backup, not adopting it")
+			result.Skipped = append(result.Skipped, snapshotID)
+			continue
+		}
+		if dryRun {
+			result.Adopted = append(result.Adopted, snapshotID)
+			continue
+		}
+		input.SetResourceName(aws.StringValue(snapshot.DBClusterSnapshotArn))
+		if _, err := client.AddTagsToResourceWithContext(ctx, input); err != nil {
+			log.WithError(err).
+				WithField("snapshotID", snapshotID).
+				WithField("region", region).
+				Error("Error in tagging snapshot for adoption")
+			result.Failed = append(result.Failed, SnapshotFailure{SnapshotID: snapshotID, Err: err})
+			continue
+		}
+		log.WithField("snapshotID", snapshotID).
+			WithField("region", region).
+			Info("Snapshot adopted")
+		result.Adopted = append(result.Adopted, snapshotID)
+	}
+
+	if len(result.Failed) > 0 {
+		result.Err = &AdoptionError{Failures: result.Failed}
+	}
+	return result
+}
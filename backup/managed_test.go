@@ -0,0 +1,131 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testManagedTags = []*rds.Tag{{Key: aws.String(ManagedByTagKey), Value: aws.String(ManagedByTagValue)}}
+
+// addLegacySnapshots adds, for each of the given days ago, an untagged snapshot named like a backup,
+// and a snapshot made by hand before a migration with the same prefix.
+func addLegacySnapshots(fake *awsfake.RDS, now time.Time, days ...int) []string {
+	var snapshotIDs []string
+	for _, day := range days {
+		snapshotID := testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -day).Format(snapshotIDDateFormat)
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(snapshotID),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -day)),
+		})
+		snapshotIDs = append(snapshotIDs, snapshotID)
+	}
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-before-migration"),
+		SnapshotCreateTime:          aws.Time(now.AddDate(0, -6, 0)),
+	})
+	return snapshotIDs
+}
+
+func TestCleanUpIgnoresUnmanagedSnapshots(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -2).Format(snapshotIDDateFormat)),
+		SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -2)),
+		TagList:                     testManagedTags,
+	})
+	legacy := addLegacySnapshots(fake, now, 10, 20)
+	svc := newFakeBackupService(t, fake, 1)
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -2).Format(snapshotIDDateFormat)}, results[0].Retained)
+	assert.Empty(t, results[0].Deleted)
+	assert.ElementsMatch(t, append(legacy, testSnapshotIDPrefix+"-before-migration"), results[0].Unmanaged)
+	require.Len(t, results[0].Decisions, 4)
+	assert.Equal(t, unmanagedReason, results[0].Decisions[1].Reason)
+	assert.Len(t, fake.Snapshots(), 4)
+
+	plan, err := svc.Plan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, plan.Cleanups, 1)
+	assert.ElementsMatch(t, results[0].Unmanaged, plan.Cleanups[0].Unmanaged)
+	assert.Len(t, plan.Cleanups[0].Snapshots, 2)
+
+	infos, err := svc.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, infos, 4)
+	assert.Equal(t, legacy[0], infos[1].SnapshotID)
+	assert.True(t, infos[1].Keep)
+	assert.Equal(t, "not tagged managed-by=pac-aurora-backup", infos[1].Reason)
+}
+
+func TestAdoptTagsLegacySnapshots(t *testing.T) {
+	source, destinations := newFakeRegions("us-east-1")
+	now := time.Now().UTC()
+	legacy := addLegacySnapshots(source, now, 2, 3)
+	legacyCopies := addLegacySnapshots(destinations["us-east-1"], now, 3)
+	svc := newFakeBackupService(t, source, 1,
+		WithClientFactory(fakeClientFactory(destinations)),
+		WithCopyDestinations(CopyDestination{Region: "us-east-1", Retention: 1}),
+		WithManagedTags("pac-staging-eu", "pac-aurora-backup", "v1.2.3"))
+
+	results, err := svc.Adopt(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, legacy, results[0].Adopted)
+	assert.Equal(t, []string{testSnapshotIDPrefix + "-before-migration"}, results[0].Skipped)
+	assert.Equal(t, "us-east-1", results[1].Region)
+	assert.Equal(t, legacyCopies, results[1].Adopted)
+	assert.Equal(t, 0, source.Calls("AddTagsToResource"))
+
+	results, err = svc.Adopt(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, legacy, results[0].Adopted)
+	assert.Equal(t, legacyCopies, results[1].Adopted)
+	snapshot := source.Snapshot(legacy[0])
+	assert.True(t, isManaged(snapshot))
+	assert.Contains(t, snapshot.TagList, &rds.Tag{Key: aws.String(AppVersionTagKey), Value: aws.String("v1.2.3")})
+	assert.False(t, isManaged(source.Snapshot(testSnapshotIDPrefix+"-before-migration")))
+
+	results, err = svc.Adopt(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, results[0].Adopted)
+	assert.Equal(t, 2, source.Calls("AddTagsToResource"))
+
+	cleanups, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{legacy[1]}, cleanups[0].Deleted)
+	assert.NotNil(t, source.Snapshot(testSnapshotIDPrefix+"-before-migration"))
+}
+
+func TestAdoptTaggingError(t *testing.T) {
+	fake := awsfake.NewRDS()
+	now := time.Now().UTC()
+	legacy := addLegacySnapshots(fake, now, 1, 2)
+	fake.FailNext("AddTagsToResource", awserr.New("Throttling", "Rate exceeded", nil))
+	svc := newFakeBackupService(t, fake, 1)
+
+	results, err := svc.Adopt(context.Background(), false)
+
+	var adoptionErr *AdoptionError
+	require.True(t, errors.As(err, &adoptionErr), "unexpected error: %v", err)
+	require.Len(t, adoptionErr.Failures, 1)
+	assert.Equal(t, legacy[0], adoptionErr.Failures[0].SnapshotID)
+	assert.Equal(t, []string{legacy[1]}, results[0].Adopted)
+	assert.EqualError(t, adoptionErr, "failed to adopt 1 snapshot(s): "+legacy[0])
+}
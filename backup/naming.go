@@ -0,0 +1,240 @@
+package backup
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// DefaultSnapshotIDTemplate names the snapshots after the snapshot identifier prefix and their creation time in UTC,
+// e.g. pac-aurora-prod-backup-2024-01-05-03-04-05.
+const DefaultSnapshotIDTemplate = "{prefix}-{yyyy}-{MM}-{dd}-{HH}-{mm}-{ss}"
+
+// legacySnapshotIDDateFormat is the creation time at the end of the identifiers of the snapshots
+// made by the first versions of the app, e.g. pac-aurora-staging-backup-20180112.
+const legacySnapshotIDDateFormat = "20060102"
+
+// maxSnapshotIDLength is the maximum length of a DB cluster snapshot identifier.
+const maxSnapshotIDLength = 255
+
+// snapshotIDPattern matches the DB cluster snapshot identifiers accepted by RDS: letters, digits and hyphens,
+// starting with a letter, without two consecutive hyphens nor a trailing one.
+var snapshotIDPattern = regexp.MustCompile(`^[A-Za-z](-?[A-Za-z0-9])*$`)
+
+// snapshotIDPlaceholders maps the placeholders of a snapshot identifier template to the pattern of their values
+// when reading an identifier back. The date components are the creation time in UTC.
+var snapshotIDPlaceholders = map[string]string{
+	"{prefix}":  `[A-Za-z0-9-]+?`,
+	"{cluster}": `[A-Za-z0-9-]+?`,
+	"{env}":     `[A-Za-z0-9-]+?`,
+	"{run-id}":  `[A-Za-z0-9-]+?`,
+	"{label}":   `[A-Za-z0-9-]+?`,
+	"{yyyy}":    `(?P<yyyy>[0-9]{4})`,
+	"{MM}":      `(?P<MM>[0-9]{2})`,
+	"{dd}":      `(?P<dd>[0-9]{2})`,
+	"{HH}":      `(?P<HH>[0-9]{2})`,
+	"{mm}":      `(?P<mm>[0-9]{2})`,
+	"{ss}":      `(?P<ss>[0-9]{2})`,
+}
+
+var (
+	placeholderPattern      = regexp.MustCompile(`\{[^{}]*\}`)
+	templateLiteralPattern  = regexp.MustCompile(`^[A-Za-z0-9-]*$`)
+	defaultSnapshotIDFormat = mustParseSnapshotIDTemplate(DefaultSnapshotIDTemplate)
+)
+
+// ValidateSnapshotID checks a DB cluster snapshot identifier against the rules of RDS:
+// 1 to 255 letters, digits and hyphens, starting with a letter, without two consecutive hyphens nor a trailing one.
+func ValidateSnapshotID(snapshotID string) error {
+	if len(snapshotID) > maxSnapshotIDLength {
+		return fmt.Errorf("snapshot identifier is longer than %d characters: %q", maxSnapshotIDLength, snapshotID)
+	}
+	if !snapshotIDPattern.MatchString(snapshotID) {
+		return fmt.Errorf("snapshot identifier must start with a letter and only contain letters, digits and single hyphens: %q", snapshotID)
+	}
+	return nil
+}
+
+// SnapshotIDFields are the values of the placeholders of a snapshot identifier template,
+// other than the date components of the creation time.
+type SnapshotIDFields struct {
+	// Prefix is the snapshot identifier prefix of the cluster, {prefix}.
+	Prefix string
+	// ClusterID is the identifier of the cluster, {cluster}.
+	ClusterID string
+	// Environment is the PAC environment, {env}.
+	Environment string
+	// RunID identifies the run making the snapshot, {run-id}.
+	RunID string
+	// Label is a free label of the snapshots, {label}, e.g. pre-migration.
+	Label string
+}
+
+// SnapshotIDTemplate names the new snapshots, e.g. {prefix}-{label}-{yyyy}{MM}{dd}-{run-id}.
+// It starts with {prefix}, so that the cleanup finds the snapshots it made, and contains at least {yyyy}, {MM} and {dd},
+// so that the creation time of a snapshot can be read back from its identifier. Each placeholder appears at most once.
+type SnapshotIDTemplate struct {
+	text    string
+	pattern *regexp.Regexp
+}
+
+// ParseSnapshotIDTemplate parses and validates a snapshot identifier template.
+func ParseSnapshotIDTemplate(text string) (*SnapshotIDTemplate, error) {
+	if !strings.HasPrefix(text, "{prefix}") {
+		return nil, fmt.Errorf("snapshot identifier template must start with {prefix}, so that the cleanup finds the snapshots: %q", text)
+	}
+	seen := make(map[string]bool)
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	literals := placeholderPattern.Split(text, -1)
+	for i, placeholder := range placeholderPattern.FindAllString(text, -1) {
+		if !templateLiteralPattern.MatchString(literals[i]) {
+			return nil, fmt.Errorf("snapshot identifier template can only contain letters, digits and hyphens outside placeholders: %q", text)
+		}
+		valuePattern, ok := snapshotIDPlaceholders[placeholder]
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder %v in snapshot identifier template: %q", placeholder, text)
+		}
+		if seen[placeholder] {
+			return nil, fmt.Errorf("placeholder %v appears more than once in snapshot identifier template: %q", placeholder, text)
+		}
+		seen[placeholder] = true
+		pattern.WriteString(regexp.QuoteMeta(literals[i]))
+		pattern.WriteString(valuePattern)
+	}
+	last := literals[len(literals)-1]
+	if !templateLiteralPattern.MatchString(last) {
+		return nil, fmt.Errorf("snapshot identifier template can only contain letters, digits and hyphens outside placeholders: %q", text)
+	}
+	pattern.WriteString(regexp.QuoteMeta(last))
+	pattern.WriteString("$")
+	if !seen["{yyyy}"] || !seen["{MM}"] || !seen["{dd}"] {
+		return nil, fmt.Errorf("snapshot identifier template must contain {yyyy}, {MM} and {dd}, so that the creation time can be read from the identifiers: %q", text)
+	}
+	return &SnapshotIDTemplate{text: text, pattern: regexp.MustCompile(pattern.String())}, nil
+}
+
+func mustParseSnapshotIDTemplate(text string) *SnapshotIDTemplate {
+	t, err := ParseSnapshotIDTemplate(text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t *SnapshotIDTemplate) String() string {
+	return t.text
+}
+
+// Execute returns the identifier of a snapshot created at the given time, failing if it is not a valid identifier,
+// e.g. because a placeholder has an empty value.
+func (t *SnapshotIDTemplate) Execute(fields SnapshotIDFields, created time.Time) (string, error) {
+	created = created.UTC()
+	replacer := strings.NewReplacer(
+		"{prefix}", fields.Prefix,
+		"{cluster}", fields.ClusterID,
+		"{env}", fields.Environment,
+		"{run-id}", fields.RunID,
+		"{label}", fields.Label,
+		"{yyyy}", fmt.Sprintf("%04d", created.Year()),
+		"{MM}", fmt.Sprintf("%02d", int(created.Month())),
+		"{dd}", fmt.Sprintf("%02d", created.Day()),
+		"{HH}", fmt.Sprintf("%02d", created.Hour()),
+		"{mm}", fmt.Sprintf("%02d", created.Minute()),
+		"{ss}", fmt.Sprintf("%02d", created.Second()),
+	)
+	snapshotID := replacer.Replace(t.text)
+	if err := ValidateSnapshotID(snapshotID); err != nil {
+		return "", fmt.Errorf("snapshot identifier template %v: %w", t.text, err)
+	}
+	return snapshotID, nil
+}
+
+// parseTime returns the creation time read from a snapshot identifier named after the template.
+// The time components missing from the template are zero.
+func (t *SnapshotIDTemplate) parseTime(snapshotID string) (time.Time, bool) {
+	match := t.pattern.FindStringSubmatch(snapshotID)
+	if match == nil {
+		return time.Time{}, false
+	}
+	components := map[string]int{}
+	for i, name := range t.pattern.SubexpNames() {
+		if name != "" {
+			components[name], _ = strconv.Atoi(match[i])
+		}
+	}
+	created := time.Date(components["yyyy"], time.Month(components["MM"]), components["dd"], components["HH"], components["mm"], components["ss"], 0, time.UTC)
+	if created.Month() != time.Month(components["MM"]) || created.Day() != components["dd"] ||
+		components["HH"] > 23 || components["mm"] > 59 || components["ss"] > 59 {
+		return time.Time{}, false
+	}
+	return created, true
+}
+
+// WithSnapshotIDTemplate makes the service name the new snapshots after the given template
+// instead of DefaultSnapshotIDTemplate. The environment is the one of WithManagedTags.
+func WithSnapshotIDTemplate(template *SnapshotIDTemplate) Option {
+	return func(svc *auroraBackupService) {
+		svc.snapshotIDTemplate = template
+	}
+}
+
+// WithSnapshotIDFields sets the values of the {run-id} and {label} placeholders of the snapshot identifier template.
+func WithSnapshotIDFields(runID, label string) Option {
+	return func(svc *auroraBackupService) {
+		svc.runID = runID
+		svc.snapshotLabel = label
+	}
+}
+
+// newSnapshotID returns the identifier of a new snapshot of the cluster created at the given time.
+func (svc *auroraBackupService) newSnapshotID(clusterID string, t time.Time) (string, error) {
+	return svc.snapshotIDTemplate.Execute(SnapshotIDFields{
+		Prefix:      svc.snapshotIDPrefixFor(clusterID),
+		ClusterID:   clusterID,
+		Environment: svc.managedTags[EnvironmentTagKey],
+		RunID:       svc.runID,
+		Label:       svc.snapshotLabel,
+	}, t)
+}
+
+// snapshotIDTime returns the creation time read from the identifier of a snapshot with the given prefix,
+// named after the template of the service, the default template, or the legacy <prefix>-20060102 style.
+func (svc *auroraBackupService) snapshotIDTime(snapshotID, prefix string) (time.Time, bool) {
+	if !strings.HasPrefix(snapshotID, prefix) {
+		return time.Time{}, false
+	}
+	for _, template := range []*SnapshotIDTemplate{svc.snapshotIDTemplate, defaultSnapshotIDFormat} {
+		if created, ok := template.parseTime(snapshotID); ok {
+			return created, true
+		}
+	}
+	if created, err := time.Parse(legacySnapshotIDDateFormat, strings.TrimPrefix(snapshotID, prefix+"-")); err == nil {
+		return created, true
+	}
+	return time.Time{}, false
+}
+
+// withIDTimes completes the snapshots without a creation time, other than those being created,
+// with the creation time read from their identifiers, so that the listing and the retention apply to them.
+// It returns a new slice, in which the completed snapshots are copies, leaving the given snapshots unmodified.
+func (svc *auroraBackupService) withIDTimes(snapshots []*rds.DBClusterSnapshot, prefix string) []*rds.DBClusterSnapshot {
+	completed := make([]*rds.DBClusterSnapshot, len(snapshots))
+	for i, snapshot := range snapshots {
+		completed[i] = snapshot
+		if snapshot.SnapshotCreateTime != nil || aws.StringValue(snapshot.Status) == statusCreating {
+			continue
+		}
+		if created, ok := svc.snapshotIDTime(aws.StringValue(snapshot.DBClusterSnapshotIdentifier), prefix); ok {
+			withTime := *snapshot
+			withTime.SnapshotCreateTime = aws.Time(created)
+			completed[i] = &withTime
+		}
+	}
+	return completed
+}
@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSnapshotIDTemplateErrors(t *testing.T) {
+	for template, expected := range map[string]string{
+		"backup-{yyyy}-{MM}-{dd}":          "snapshot identifier template must start with {prefix}",
+		"{prefix}-{date}":                  "unknown placeholder {date} in snapshot identifier template",
+		"{prefix}-{yyyy}-{MM}-{dd}-{yyyy}": "placeholder {yyyy} appears more than once in snapshot identifier template",
+		"{prefix}_{yyyy}-{MM}-{dd}":        "snapshot identifier template can only contain letters, digits and hyphens outside placeholders",
+		"{prefix}-{yyyy}-{MM}-{dd}.":       "snapshot identifier template can only contain letters, digits and hyphens outside placeholders",
+		"{prefix}-{yyyy}-{MM}-{dd}-{HH":    "snapshot identifier template can only contain letters, digits and hyphens outside placeholders",
+		"{prefix}-{yyyy}-{MM}-{run-id}":    "snapshot identifier template must contain {yyyy}, {MM} and {dd}",
+	} {
+		_, err := ParseSnapshotIDTemplate(template)
+		assert.ErrorContains(t, err, expected, template)
+	}
+}
+
+func TestSnapshotIDTemplateExecute(t *testing.T) {
+	template, err := ParseSnapshotIDTemplate("{prefix}-{env}-{label}-{yyyy}{MM}{dd}-{HH}{mm}-{run-id}")
+	require.NoError(t, err)
+	assert.Equal(t, "{prefix}-{env}-{label}-{yyyy}{MM}{dd}-{HH}{mm}-{run-id}", template.String())
+	fields := SnapshotIDFields{Prefix: "pac-aurora-prod-backup", ClusterID: "pac-aurora-prod", Environment: "pac-prod-eu", RunID: "1a2b3c4d", Label: "pre-migration"}
+	created := time.Date(2024, 1, 5, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+
+	snapshotID, err := template.Execute(fields, created)
+	require.NoError(t, err)
+	assert.Equal(t, "pac-aurora-prod-backup-pac-prod-eu-pre-migration-20240105-0204-1a2b3c4d", snapshotID)
+
+	parsed, ok := template.parseTime(snapshotID)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 5, 2, 4, 0, 0, time.UTC), parsed)
+
+	fields.Label = ""
+	_, err = template.Execute(fields, created)
+	assert.ErrorContains(t, err, `snapshot identifier must start with a letter and only contain letters, digits and single hyphens: "pac-aurora-prod-backup-pac-prod-eu--20240105-0204-1a2b3c4d"`)
+
+	fields.Label = strings.Repeat("a", maxSnapshotIDLength)
+	_, err = template.Execute(fields, created)
+	assert.ErrorContains(t, err, "snapshot identifier is longer than 255 characters")
+}
+
+func TestSnapshotIDTime(t *testing.T) {
+	template, err := ParseSnapshotIDTemplate("{prefix}-{label}-{yyyy}{MM}{dd}")
+	require.NoError(t, err)
+	fake := awsfake.NewRDS()
+	svc := newFakeBackupService(t, fake, 0, WithSnapshotIDTemplate(template), WithSnapshotIDFields("", "daily"))
+
+	for snapshotID, expected := range map[string]time.Time{
+		testSnapshotIDPrefix + "-daily-20240105":             time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		testSnapshotIDPrefix + "-2024-01-05-03-04-05":        time.Date(2024, 1, 5, 3, 4, 5, 0, time.UTC),
+		testSnapshotIDPrefix + "-20180112":                   time.Date(2018, 1, 12, 0, 0, 0, 0, time.UTC),
+		testSnapshotIDPrefix + "-before-migration":           {},
+		testSnapshotIDPrefix + "-2024-13-05-03-04-05":        {},
+		"other-backup-2024-01-05-03-04-05":                   {},
+		testSnapshotIDPrefix + "-2024-01-05-03-04-05-manual": {},
+	} {
+		created, ok := svc.snapshotIDTime(snapshotID, testSnapshotIDPrefix)
+		assert.Equal(t, !expected.IsZero(), ok, snapshotID)
+		assert.Equal(t, expected, created, snapshotID)
+	}
+}
+
+func TestWithIDTimesLeavesSnapshotsUnmodified(t *testing.T) {
+	svc := newFakeBackupService(t, awsfake.NewRDS(), 0)
+	snapshotID := testSnapshotIDPrefix + "-2024-01-05-03-04-05"
+	snapshots := []*rds.DBClusterSnapshot{{DBClusterSnapshotIdentifier: aws.String(snapshotID), Status: aws.String(statusAvailable)}}
+	original := snapshots[0]
+
+	completed := svc.withIDTimes(snapshots, testSnapshotIDPrefix)
+	require.Len(t, completed, 1)
+	assert.Equal(t, time.Date(2024, 1, 5, 3, 4, 5, 0, time.UTC), aws.TimeValue(completed[0].SnapshotCreateTime))
+	assert.Same(t, original, snapshots[0], "the given slice should not be modified")
+	assert.Nil(t, original.SnapshotCreateTime)
+}
+
+func TestMakeBackupNamesSnapshotAfterTemplate(t *testing.T) {
+	template, err := ParseSnapshotIDTemplate("{prefix}-{env}-{run-id}-{yyyy}-{MM}-{dd}")
+	require.NoError(t, err)
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0,
+		WithManagedTags("pac-prod-eu", "pac-aurora-backup", "v1.2.3"),
+		WithSnapshotIDTemplate(template),
+		WithSnapshotIDFields("job-1", ""))
+
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, testSnapshotIDPrefix+"-pac-prod-eu-job-1-"+time.Now().UTC().Format("2006-01-02"), results[0].SnapshotID)
+	assert.NotNil(t, fake.Snapshot(results[0].SnapshotID))
+}
+
+func TestWithEnvironmentReplacesManagedEnvironment(t *testing.T) {
+	template, err := ParseSnapshotIDTemplate("{prefix}-{env}-{yyyy}-{MM}-{dd}")
+	require.NoError(t, err)
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0,
+		WithManagedTags("", "pac-aurora-backup", "v1.2.3"),
+		WithEnvironment("prod-eu"),
+		WithSnapshotIDTemplate(template))
+
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, testSnapshotIDPrefix+"-prod-eu-"+time.Now().UTC().Format("2006-01-02"), results[0].SnapshotID)
+	assert.Contains(t, fake.Snapshot(results[0].SnapshotID).TagList, &rds.Tag{Key: aws.String(EnvironmentTagKey), Value: aws.String("prod-eu")})
+}
+
+func TestNewBackupServiceRejectsInvalidSnapshotIDs(t *testing.T) {
+	template, err := ParseSnapshotIDTemplate("{prefix}-{label}-{yyyy}-{MM}-{dd}")
+	require.NoError(t, err)
+
+	_, err = NewBackupService("eu-west-1", testClusterIDPrefix, testSnapshotIDPrefix, 0, testStatusCheckAttempts, 0,
+		WithRDSClient(awsfake.NewRDS()), WithSnapshotIDTemplate(template))
+	assert.ErrorContains(t, err, "snapshot identifier template {prefix}-{label}-{yyyy}-{MM}-{dd}: snapshot identifier must start with a letter")
+}
+
+func TestCleanUpReadsCreationTimeFromIdentifiers(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	snapshotIDs := addManagedSnapshots(fake, now, 1, 2, 3)
+	legacyID := testSnapshotIDPrefix + "-20180112"
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String(legacyID),
+		TagList:                     testManagedTags,
+	})
+	for _, snapshotID := range append(snapshotIDs, legacyID) {
+		fake.UpdateSnapshot(snapshotID, func(s *rds.DBClusterSnapshot) { s.SnapshotCreateTime = nil })
+	}
+	svc := newFakeBackupService(t, fake, 2)
+
+	infos, err := svc.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, infos, 4)
+	assert.Equal(t, legacyID, infos[3].SnapshotID)
+	assert.Equal(t, time.Date(2018, 1, 12, 0, 0, 0, 0, time.UTC), infos[3].CreateTime)
+	assert.False(t, infos[3].Keep)
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, snapshotIDs[:2], results[0].Retained)
+	assert.ElementsMatch(t, []string{snapshotIDs[2], legacyID}, results[0].Deleted)
+}
+
+func TestAdoptLegacySnapshotIdentifiers(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	for _, snapshotID := range []string{testSnapshotIDPrefix + "-20180112", testSnapshotIDPrefix + "-before-migration"} {
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(snapshotID),
+		})
+	}
+	svc := newFakeBackupService(t, fake, 2)
+
+	results, err := svc.Adopt(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{testSnapshotIDPrefix + "-20180112"}, results[0].Adopted)
+	assert.Equal(t, []string{testSnapshotIDPrefix + "-before-migration"}, results[0].Skipped)
+}
@@ -0,0 +1,172 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+// Plan describes what a backup run would do, without creating or deleting any snapshot.
+type Plan struct {
+	Backups  []*BackupPlan
+	Cleanups []*CleanupPlan
+}
+
+// BackupPlan describes the snapshot MakeBackup would create for a DB cluster, or reuse when Reused is true,
+// and where it would be shared and copied.
+// PreflightConditions lists the conditions that would currently prevent the snapshot from being created.
+type BackupPlan struct {
+	ClusterID           string
+	SnapshotID          string
+	Reused              bool
+	PreflightConditions []string
+	SharedWith          []string
+	CopyRegions         []string
+	VaultAccountID      string
+}
+
+// CleanupPlan describes which snapshots CleanUpOldBackups would keep and delete in a region.
+// The decisions include the snapshot the backup would create just before the cleanup, identified by NewSnapshotID.
+type CleanupPlan struct {
+	Region           string
+	ClusterID        string
+	SnapshotIDPrefix string
+	Policy           RetentionPolicy
+	NewSnapshotID    string
+	Snapshots        []SnapshotDecision
+	// Unmanaged lists the snapshots with the prefix ignored by the cleanup as they are not tagged as managed.
+	Unmanaged []string
+	// Held lists the snapshots kept as pinned, which do not count toward the retention.
+	Held []string
+	Err  error
+}
+
+// Plan discovers the clusters and lists their snapshots to decide what MakeBackup and CleanUpOldBackups would do,
+// only calling read-only AWS APIs.
+func (svc *auroraBackupService) Plan(ctx context.Context) (*Plan, error) {
+	clusterIDs, err := svc.getBackupClusterIDs(ctx)
+	if err != nil {
+		log.WithError(err).Error("Error in fetching DB cluster information from AWS")
+		return nil, err
+	}
+	cleanupClusterIDs, err := svc.getCleanupClusterIDs(ctx)
+	if err != nil {
+		log.WithError(err).Error("Error in fetching DB cluster information from AWS for cleanup")
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	plan := new(Plan)
+	var errs []error
+	for _, clusterID := range clusterIDs {
+		snapshotID, err := svc.newSnapshotID(clusterID, now)
+		if err != nil {
+			log.WithField("clusterID", clusterID).
+				WithError(err).
+				Error("Error in naming the new snapshot")
+			errs = append(errs, &ClusterError{ClusterID: clusterID, Err: err})
+		}
+		backupPlan := &BackupPlan{
+			ClusterID:  clusterID,
+			SnapshotID: snapshotID,
+			SharedWith: svc.sharingAccountIDs(),
+		}
+		recent, err := svc.recentSnapshot(ctx, clusterID, now)
+		if err != nil {
+			log.WithField("clusterID", clusterID).
+				WithError(err).
+				Error("Error in looking for a recent snapshot of the cluster")
+			errs = append(errs, &ClusterError{ClusterID: clusterID, Err: err})
+		}
+		if recent != nil {
+			backupPlan.SnapshotID = aws.StringValue(recent.DBClusterSnapshotIdentifier)
+			backupPlan.Reused = true
+		} else {
+			backupPlan.PreflightConditions, err = svc.checkPreflightConditions(ctx, clusterID)
+			if err != nil {
+				log.WithField("clusterID", clusterID).
+					WithError(err).
+					Error("Error in pre-flight checks")
+				errs = append(errs, &ClusterError{ClusterID: clusterID, Err: err})
+			}
+		}
+		for _, destination := range svc.copyDestinations {
+			backupPlan.CopyRegions = append(backupPlan.CopyRegions, destination.Region)
+		}
+		if svc.vault != nil {
+			backupPlan.VaultAccountID = svc.vault.AccountID
+		}
+		plan.Backups = append(plan.Backups, backupPlan)
+	}
+
+	plan.Cleanups = make([]*CleanupPlan, len(cleanupClusterIDs)*(1+len(svc.copyDestinations)))
+	svc.forEachCluster(cleanupClusterIDs, func(i int, clusterID string) {
+		newSnapshotID := ""
+		for _, backupPlan := range plan.Backups {
+			if clusterID == "" || backupPlan.ClusterID == clusterID {
+				if !backupPlan.Reused {
+					newSnapshotID = backupPlan.SnapshotID
+				}
+				break
+			}
+		}
+		offset := i * (1 + len(svc.copyDestinations))
+		plan.Cleanups[offset] = svc.planCleanup(ctx, svc.RDSClient, svc.region, clusterID, svc.retentionPolicy, newSnapshotID, now)
+		for j, destination := range svc.copyDestinations {
+			plan.Cleanups[offset+1+j] = svc.planCleanup(ctx, svc.copyClients[destination.Region], destination.Region, clusterID, KeepLast(destination.Retention), newSnapshotID, now)
+		}
+	})
+
+	for _, cleanup := range plan.Cleanups {
+		if cleanup.Err == nil {
+			continue
+		}
+		if cleanup.ClusterID == "" {
+			errs = append(errs, cleanup.Err)
+		} else {
+			errs = append(errs, &ClusterError{ClusterID: cleanup.ClusterID, Err: cleanup.Err})
+		}
+	}
+	return plan, errors.Join(errs...)
+}
+
+func (svc *auroraBackupService) planCleanup(ctx context.Context, client RDSClient, region, clusterID string, policy RetentionPolicy, newSnapshotID string, now time.Time) *CleanupPlan {
+	cleanup := &CleanupPlan{
+		Region:           region,
+		ClusterID:        clusterID,
+		SnapshotIDPrefix: svc.snapshotIDPrefixFor(clusterID),
+		Policy:           policy,
+		NewSnapshotID:    newSnapshotID,
+	}
+
+	snapshots, err := svc.listClusterSnapshots(ctx, client, clusterID)
+	if err != nil {
+		log.WithError(err).
+			WithField("region", region).
+			Error("Error in fetching DB cluster snapshots for cleanup plan")
+		cleanup.Err = err
+		return cleanup
+	}
+	snapshots, unmanaged := splitManaged(snapshots)
+	for _, snapshot := range unmanaged {
+		cleanup.Unmanaged = append(cleanup.Unmanaged, aws.StringValue(snapshot.DBClusterSnapshotIdentifier))
+	}
+	snapshots, held := splitHeld(snapshots, now)
+	for _, snapshot := range held {
+		cleanup.Held = append(cleanup.Held, aws.StringValue(snapshot.DBClusterSnapshotIdentifier))
+	}
+	if newSnapshotID != "" {
+		snapshots = append(snapshots, &rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(clusterID),
+			DBClusterSnapshotIdentifier: aws.String(newSnapshotID),
+			SnapshotCreateTime:          aws.Time(now),
+			Status:                      aws.String(statusAvailable),
+		})
+	}
+	cleanup.Snapshots = decideRetention(snapshots, policy, now, svc.minBackupAge, svc.maxBackupAge)
+	return cleanup
+}
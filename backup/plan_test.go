@@ -0,0 +1,157 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanDoesNotCreateOrDeleteSnapshots(t *testing.T) {
+	source, destinations := newFakeRegions("us-east-1")
+	source.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	for i := 1; i <= 5; i++ {
+		source.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+			TagList:                     testManagedTags,
+		})
+	}
+	svc := newFakeBackupService(t, source, 3,
+		WithClientFactory(fakeClientFactory(destinations)),
+		WithCopyDestinations(CopyDestination{Region: "us-east-1", Retention: 2}),
+		WithSnapshotSharing("111111111111"))
+
+	plan, err := svc.Plan(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, plan.Backups, 1)
+	backupPlan := plan.Backups[0]
+	assert.Equal(t, testClusterIDPrefix+"-eu", backupPlan.ClusterID)
+	assert.Contains(t, backupPlan.SnapshotID, testSnapshotIDPrefix+"-")
+	assert.Equal(t, []string{"111111111111"}, backupPlan.SharedWith)
+	assert.Equal(t, []string{"us-east-1"}, backupPlan.CopyRegions)
+	assert.Empty(t, backupPlan.PreflightConditions)
+
+	require.Len(t, plan.Cleanups, 2)
+	sourceCleanup := plan.Cleanups[0]
+	assert.Equal(t, source.Region, sourceCleanup.Region)
+	assert.Equal(t, backupPlan.SnapshotID, sourceCleanup.NewSnapshotID)
+	assert.Equal(t, KeepLast(3), sourceCleanup.Policy)
+	retained, expired := splitDecisions(sourceCleanup.Snapshots)
+	assert.Equal(t, []string{
+		backupPlan.SnapshotID,
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -1).Format(snapshotIDDateFormat),
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -2).Format(snapshotIDDateFormat),
+	}, retained)
+	assert.Equal(t, []string{
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -3).Format(snapshotIDDateFormat),
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -4).Format(snapshotIDDateFormat),
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -5).Format(snapshotIDDateFormat),
+	}, expired)
+	for _, decision := range sourceCleanup.Snapshots {
+		assert.NotEmpty(t, decision.Reason)
+	}
+
+	copyCleanup := plan.Cleanups[1]
+	assert.Equal(t, "us-east-1", copyCleanup.Region)
+	assert.Equal(t, KeepLast(2), copyCleanup.Policy)
+	require.Len(t, copyCleanup.Snapshots, 1)
+	assert.Equal(t, backupPlan.SnapshotID, copyCleanup.Snapshots[0].SnapshotID)
+	assert.True(t, copyCleanup.Snapshots[0].Keep)
+
+	assert.Len(t, source.Snapshots(), 5)
+	for _, op := range []string{"CreateDBClusterSnapshot", "DeleteDBClusterSnapshot", "CopyDBClusterSnapshot", "ModifyDBClusterSnapshotAttribute"} {
+		assert.Equal(t, 0, source.Calls(op), op)
+		assert.Equal(t, 0, destinations["us-east-1"].Calls(op), op)
+	}
+}
+
+func TestPlanMatchesCleanUpOldBackups(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.AddCluster(testClusterIDPrefix + "-us")
+	now := time.Now().UTC()
+	for _, clusterID := range []string{testClusterIDPrefix + "-eu", testClusterIDPrefix + "-us"} {
+		for i := 1; i <= 40; i++ {
+			fake.AddSnapshot(&rds.DBClusterSnapshot{
+				DBClusterIdentifier:         aws.String(clusterID),
+				DBClusterSnapshotIdentifier: aws.String(clusterID + "-test-backup-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+				SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+				TagList:                     testManagedTags,
+			})
+		}
+	}
+	svc := newFakeBackupService(t, fake, 0,
+		WithClusterDiscovery(2),
+		WithRetentionPolicy(RetentionPolicy{Daily: 7, Weekly: 4}),
+		WithBackupAgeLimits(36*time.Hour, 0))
+
+	plan, err := svc.Plan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, plan.Cleanups, 2)
+
+	_, err = svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	for i, cleanup := range plan.Cleanups {
+		assert.Equal(t, results[i].ClusterID, cleanup.ClusterID)
+		_, expired := splitDecisions(cleanup.Snapshots)
+		assert.NotEmpty(t, expired)
+		assert.Equal(t, expired, results[i].Deleted)
+	}
+}
+
+func TestPlanMissingDBCluster(t *testing.T) {
+	fake := awsfake.NewRDS()
+	svc := newFakeBackupService(t, fake, 3)
+
+	_, err := svc.Plan(context.Background())
+
+	var notFound *ClusterNotFoundError
+	require.True(t, errors.As(err, &notFound), "unexpected error: %v", err)
+}
+
+func TestPlanListingError(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	// The first failure is returned to the pre-flight checks and the second one to the listing for the cleanup.
+	fake.FailNext("DescribeDBClusterSnapshots", awserr.New("Throttling", "rate exceeded", nil))
+	fake.FailNext("DescribeDBClusterSnapshots", awserr.New("Throttling", "rate exceeded", nil))
+	svc := newFakeBackupService(t, fake, 3)
+
+	plan, err := svc.Plan(context.Background())
+
+	assertAWSErrorCode(t, "Throttling", err)
+	require.Len(t, plan.Backups, 1)
+	require.Len(t, plan.Cleanups, 1)
+	assertAWSErrorCode(t, "Throttling", plan.Cleanups[0].Err)
+	assert.Empty(t, plan.Cleanups[0].Snapshots)
+	var clusterErr *ClusterError
+	require.True(t, errors.As(err, &clusterErr), "unexpected error: %v", err)
+	assert.Equal(t, testClusterIDPrefix+"-eu", clusterErr.ClusterID)
+}
+
+func TestPlanReportsPreflightConditions(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu").SetStatus("backing-up")
+	svc := newFakeBackupService(t, fake, 3)
+
+	plan, err := svc.Plan(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, plan.Backups, 1)
+	assert.Equal(t, []string{"cluster status is backing-up"}, plan.Backups[0].PreflightConditions)
+}
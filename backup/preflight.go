@@ -0,0 +1,184 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+const minutesPerDay = 24 * 60
+
+var weekdays = map[string]int{"mon": 0, "tue": 1, "wed": 2, "thu": 3, "fri": 4, "sat": 5, "sun": 6}
+
+// WithPreflightWait makes MakeBackup wait up to the given duration for the pre-flight checks of a cluster
+// to pass, checking again with the status check backoff, instead of failing as soon as a check fails.
+func WithPreflightWait(wait time.Duration) Option {
+	return func(svc *auroraBackupService) {
+		svc.preflightWait = wait
+	}
+}
+
+// waitForPreflightChecks checks that a snapshot of the cluster can be created,
+// waiting for the blocking conditions to clear until the pre-flight wait expires.
+func (svc *auroraBackupService) waitForPreflightChecks(ctx context.Context, clusterID string) error {
+	deadline := time.Now().Add(svc.preflightWait)
+	for attempt := 0; ; attempt++ {
+		conditions, err := svc.checkPreflightConditions(ctx, clusterID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return &InterruptedError{ClusterID: clusterID, Operation: "pre-flight checks", Err: ctx.Err()}
+			}
+			return err
+		}
+		if len(conditions) == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return &PreflightError{ClusterID: clusterID, Conditions: conditions}
+		}
+		log.WithField("clusterID", clusterID).
+			WithField("conditions", conditions).
+			Info("Waiting for pre-flight checks to pass")
+		if err := svc.pause(ctx, attempt); err != nil {
+			return &InterruptedError{ClusterID: clusterID, Operation: "pre-flight checks", Err: err}
+		}
+	}
+}
+
+// checkPreflightConditions describes the cluster and its snapshots and returns the conditions
+// preventing a snapshot from being created now.
+func (svc *auroraBackupService) checkPreflightConditions(ctx context.Context, clusterID string) ([]string, error) {
+	clusterInput := new(rds.DescribeDBClustersInput)
+	clusterInput.SetDBClusterIdentifier(clusterID)
+	clusters, err := svc.DescribeDBClustersWithContext(ctx, clusterInput)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters.DBClusters) == 0 {
+		return nil, &ClusterNotFoundError{ClusterIDPrefix: clusterID}
+	}
+
+	var snapshots []*rds.DBClusterSnapshot
+	snapshotsInput := new(rds.DescribeDBClusterSnapshotsInput)
+	snapshotsInput.SetDBClusterIdentifier(clusterID)
+	for {
+		result, err := svc.DescribeDBClusterSnapshotsWithContext(ctx, snapshotsInput)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, result.DBClusterSnapshots...)
+		if result.Marker == nil {
+			break
+		}
+		snapshotsInput.SetMarker(*result.Marker)
+	}
+
+	return preflightConditions(clusters.DBClusters[0], snapshots, time.Now()), nil
+}
+
+// preflightConditions returns the conditions preventing a snapshot of the cluster from being created at the given time:
+// the cluster is not available, another snapshot of the cluster is being created,
+// or the time falls in the preferred backup or maintenance window of the cluster.
+func preflightConditions(cluster *rds.DBCluster, snapshots []*rds.DBClusterSnapshot, now time.Time) []string {
+	var conditions []string
+	if status := aws.StringValue(cluster.Status); status != statusAvailable {
+		conditions = append(conditions, fmt.Sprintf("cluster status is %v", status))
+	}
+	for _, snapshot := range snapshots {
+		if aws.StringValue(snapshot.Status) == statusCreating {
+			conditions = append(conditions, fmt.Sprintf("snapshot %v is being created", aws.StringValue(snapshot.DBClusterSnapshotIdentifier)))
+		}
+	}
+	if window := aws.StringValue(cluster.PreferredBackupWindow); window != "" {
+		if in, err := inBackupWindow(window, now); err != nil {
+			log.WithError(err).WithField("window", window).Warn("Error in parsing the preferred backup window")
+		} else if in {
+			conditions = append(conditions, fmt.Sprintf("in backup window %v", window))
+		}
+	}
+	if window := aws.StringValue(cluster.PreferredMaintenanceWindow); window != "" {
+		if in, err := inMaintenanceWindow(window, now); err != nil {
+			log.WithError(err).WithField("window", window).Warn("Error in parsing the preferred maintenance window")
+		} else if in {
+			conditions = append(conditions, fmt.Sprintf("in maintenance window %v", window))
+		}
+	}
+	return conditions
+}
+
+// inBackupWindow reports whether the time falls in a daily UTC window in the hh24:mi-hh24:mi format.
+func inBackupWindow(window string, t time.Time) (bool, error) {
+	start, end, found := strings.Cut(window, "-")
+	if !found {
+		return false, fmt.Errorf("backup window is not in the hh24:mi-hh24:mi format: %v", window)
+	}
+	startMinute, err := parseWindowTime(start)
+	if err != nil {
+		return false, err
+	}
+	endMinute, err := parseWindowTime(end)
+	if err != nil {
+		return false, err
+	}
+	t = t.UTC()
+	return inCircularRange(t.Hour()*60+t.Minute(), startMinute, endMinute), nil
+}
+
+// inMaintenanceWindow reports whether the time falls in a weekly UTC window in the ddd:hh24:mi-ddd:hh24:mi format.
+func inMaintenanceWindow(window string, t time.Time) (bool, error) {
+	start, end, found := strings.Cut(window, "-")
+	if !found {
+		return false, fmt.Errorf("maintenance window is not in the ddd:hh24:mi-ddd:hh24:mi format: %v", window)
+	}
+	startMinute, err := parseWindowWeekTime(start)
+	if err != nil {
+		return false, err
+	}
+	endMinute, err := parseWindowWeekTime(end)
+	if err != nil {
+		return false, err
+	}
+	t = t.UTC()
+	weekday := (int(t.Weekday()) + 6) % 7
+	return inCircularRange(weekday*minutesPerDay+t.Hour()*60+t.Minute(), startMinute, endMinute), nil
+}
+
+// parseWindowTime returns the minute of the day of a time in the hh24:mi format.
+func parseWindowTime(s string) (int, error) {
+	hours, minutes, found := strings.Cut(s, ":")
+	h, hErr := strconv.Atoi(hours)
+	m, mErr := strconv.Atoi(minutes)
+	if !found || hErr != nil || mErr != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("window time is not in the hh24:mi format: %v", s)
+	}
+	return h*60 + m, nil
+}
+
+// parseWindowWeekTime returns the minute of the week, starting on Monday, of a time in the ddd:hh24:mi format.
+func parseWindowWeekTime(s string) (int, error) {
+	day, clock, found := strings.Cut(s, ":")
+	weekday, known := weekdays[strings.ToLower(day)]
+	if !found || !known {
+		return 0, fmt.Errorf("window time is not in the ddd:hh24:mi format: %v", s)
+	}
+	minute, err := parseWindowTime(clock)
+	if err != nil {
+		return 0, err
+	}
+	return weekday*minutesPerDay + minute, nil
+}
+
+// inCircularRange reports whether value is in [start, end), where the range can wrap around,
+// e.g. a window from 23:30 to 00:30.
+func inCircularRange(value, start, end int) bool {
+	if start <= end {
+		return value >= start && value < end
+	}
+	return value >= start || value < end
+}
@@ -0,0 +1,163 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInBackupWindow(t *testing.T) {
+	tests := []struct {
+		window   string
+		at       string
+		expected bool
+	}{
+		{"03:00-03:30", "2024-01-03T02:59:59Z", false},
+		{"03:00-03:30", "2024-01-03T03:00:00Z", true},
+		{"03:00-03:30", "2024-01-03T03:29:59Z", true},
+		{"03:00-03:30", "2024-01-03T03:30:00Z", false},
+		{"23:45-00:15", "2024-01-03T23:50:00Z", true},
+		{"23:45-00:15", "2024-01-04T00:10:00Z", true},
+		{"23:45-00:15", "2024-01-04T00:15:00Z", false},
+		{"23:45-00:15", "2024-01-03T12:00:00Z", false},
+		{"03:00-03:30", "2024-01-03T04:15:00+01:00", true},
+	}
+	for _, test := range tests {
+		at, err := time.Parse(time.RFC3339, test.at)
+		require.NoError(t, err)
+		in, err := inBackupWindow(test.window, at)
+		require.NoError(t, err, test.window)
+		assert.Equal(t, test.expected, in, "%v at %v", test.window, test.at)
+	}
+}
+
+func TestInMaintenanceWindow(t *testing.T) {
+	// 2024-01-01 is a Monday and 2024-01-07 a Sunday.
+	tests := []struct {
+		window   string
+		at       string
+		expected bool
+	}{
+		{"mon:04:00-mon:04:30", "2024-01-01T04:10:00Z", true},
+		{"mon:04:00-mon:04:30", "2024-01-02T04:10:00Z", false},
+		{"Sun:23:30-Mon:00:30", "2024-01-07T23:45:00Z", true},
+		{"sun:23:30-mon:00:30", "2024-01-08T00:15:00Z", true},
+		{"sun:23:30-mon:00:30", "2024-01-08T00:30:00Z", false},
+		{"sat:22:00-sun:02:00", "2024-01-07T01:00:00Z", true},
+		{"sat:22:00-sun:02:00", "2024-01-06T21:59:00Z", false},
+	}
+	for _, test := range tests {
+		at, err := time.Parse(time.RFC3339, test.at)
+		require.NoError(t, err)
+		in, err := inMaintenanceWindow(test.window, at)
+		require.NoError(t, err, test.window)
+		assert.Equal(t, test.expected, in, "%v at %v", test.window, test.at)
+	}
+}
+
+func TestWindowParsingErrors(t *testing.T) {
+	for _, window := range []string{"", "03:00", "3-4", "24:00-01:00", "03:60-04:00", "03:00-mon:04:00"} {
+		_, err := inBackupWindow(window, time.Now())
+		assert.Error(t, err, window)
+	}
+	for _, window := range []string{"", "mon:04:00", "04:00-05:00", "xyz:04:00-mon:05:00", "mon:25:00-mon:26:00"} {
+		_, err := inMaintenanceWindow(window, time.Now())
+		assert.Error(t, err, window)
+	}
+}
+
+func TestPreflightConditions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 4, 10, 0, 0, time.UTC)
+	cluster := &rds.DBCluster{
+		Status:                     aws.String("modifying"),
+		PreferredBackupWindow:      aws.String("04:00-04:30"),
+		PreferredMaintenanceWindow: aws.String("mon:04:05-mon:04:35"),
+	}
+	snapshots := []*rds.DBClusterSnapshot{
+		{DBClusterSnapshotIdentifier: aws.String("rds:automated"), Status: aws.String(statusCreating)},
+		{DBClusterSnapshotIdentifier: aws.String("old"), Status: aws.String(statusAvailable)},
+	}
+
+	assert.Equal(t, []string{
+		"cluster status is modifying",
+		"snapshot rds:automated is being created",
+		"in backup window 04:00-04:30",
+		"in maintenance window mon:04:05-mon:04:35",
+	}, preflightConditions(cluster, snapshots, now))
+
+	cluster.SetStatus(statusAvailable)
+	assert.Empty(t, preflightConditions(cluster, snapshots[1:], now.Add(time.Hour)))
+
+	cluster.SetPreferredBackupWindow("invalid")
+	assert.Empty(t, preflightConditions(cluster, nil, now.Add(time.Hour)))
+}
+
+func TestMakeBackupWithFakeRDSPreflightFailure(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.CreationPolls = 5
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String("rds:" + testClusterIDPrefix + "-eu-2024-01-01-03-00"),
+		SnapshotType:                aws.String("automated"),
+		Status:                      aws.String(statusCreating),
+	})
+	svc := newFakeBackupService(t, fake, 0)
+
+	results, err := svc.MakeBackup(context.Background())
+
+	var preflightErr *PreflightError
+	require.True(t, errors.As(err, &preflightErr), "unexpected error: %v", err)
+	assert.Equal(t, testClusterIDPrefix+"-eu", preflightErr.ClusterID)
+	assert.Equal(t, []string{"snapshot rds:" + testClusterIDPrefix + "-eu-2024-01-01-03-00 is being created"}, preflightErr.Conditions)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].SnapshotID)
+	assert.Equal(t, 0, fake.Calls("CreateDBClusterSnapshot"))
+}
+
+func TestMakeBackupWithFakeRDSPreflightWindow(t *testing.T) {
+	fake := awsfake.NewRDS()
+	now := time.Now().UTC()
+	window := now.Add(-time.Minute).Format("15:04") + "-" + now.Add(10*time.Minute).Format("15:04")
+	fake.AddCluster(testClusterIDPrefix + "-eu").SetPreferredBackupWindow(window)
+	svc := newFakeBackupService(t, fake, 0)
+
+	_, err := svc.MakeBackup(context.Background())
+
+	var preflightErr *PreflightError
+	require.True(t, errors.As(err, &preflightErr), "unexpected error: %v", err)
+	assert.Equal(t, []string{"in backup window " + window}, preflightErr.Conditions)
+	assert.True(t, strings.HasSuffix(err.Error(), "pre-flight checks failed for cluster pac-aurora-staging-eu: in backup window "+window), err.Error())
+}
+
+func TestMakeBackupWithFakeRDSPreflightWait(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.CreationPolls = 3
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String("another-backup"),
+	})
+	input := new(rds.CreateDBClusterSnapshotInput)
+	input.SetDBClusterIdentifier(testClusterIDPrefix + "-eu")
+	input.SetDBClusterSnapshotIdentifier("in-progress-backup")
+	_, err := fake.CreateDBClusterSnapshot(input)
+	require.NoError(t, err)
+	svc := newFakeBackupService(t, fake, 0, WithPreflightWait(time.Minute))
+
+	results, err := svc.MakeBackup(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.NotEmpty(t, results[0].SnapshotARN)
+	assert.Equal(t, statusAvailable, *fake.Snapshot("in-progress-backup").Status)
+	assert.Equal(t, 2, fake.Calls("CreateDBClusterSnapshot"))
+}
@@ -0,0 +1,300 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+// LatestSnapshot is the snapshot identifier restoring the most recent available backup snapshot.
+const LatestSnapshot = "latest"
+
+// restoreFailureStatuses are the statuses of a DB cluster or instance that a restore never recovers from.
+var restoreFailureStatuses = map[string]bool{
+	"failed":                              true,
+	"inaccessible-encryption-credentials": true,
+	"incompatible-network":                true,
+	"incompatible-parameters":             true,
+	"incompatible-restore":                true,
+	"restore-error":                       true,
+	"storage-full":                        true,
+}
+
+// RestoreRequest describes the DB cluster to rebuild from a backup snapshot.
+type RestoreRequest struct {
+	// SnapshotID is the snapshot to restore, or LatestSnapshot for the most recent
+	// available snapshot with the snapshot identifier prefix of the source cluster.
+	SnapshotID string
+	// SourceClusterID is the cluster whose configuration and instances are copied.
+	// By default it is the cluster of the snapshot, or the first cluster matching
+	// the cluster identifier prefix when restoring the latest snapshot.
+	SourceClusterID string
+	// TargetClusterID is the identifier of the new cluster.
+	TargetClusterID string
+	// InstanceClass overrides the class of the instances copied from the source cluster.
+	InstanceClass string
+	// SingleInstance restricts the restored cluster to a copy of the first instance of the source cluster.
+	SingleInstance bool
+	// Engines restricts the restore to the snapshots of the given engines, e.g. aurora-mysql.
+	// By default a snapshot of any engine is restored.
+	Engines []string
+}
+
+// RestoreResult describes the DB cluster created by Restore.
+type RestoreResult struct {
+	ClusterID       string
+	SourceClusterID string
+	SnapshotID      string
+	// SnapshotCreateTime is the time when the restored snapshot was taken.
+	SnapshotCreateTime time.Time
+	Endpoint           string
+	ReaderEndpoint     string
+	InstanceIDs        []string
+	Duration           time.Duration
+}
+
+// Restore creates a new DB cluster from a backup snapshot with the VPC security groups, subnet group,
+// parameter group, engine version and KMS key of the source cluster, creates instances matching
+// those of the source cluster, and waits until the new cluster and its instances are available.
+func (svc *auroraBackupService) Restore(ctx context.Context, req RestoreRequest) (*RestoreResult, error) {
+	start := time.Now()
+	if req.TargetClusterID == "" {
+		return nil, errors.New("target cluster identifier is required")
+	}
+
+	snapshot, err := svc.availableSnapshot(ctx, req.SnapshotID, &req.SourceClusterID)
+	if err != nil {
+		return nil, err
+	}
+	if engine := aws.StringValue(snapshot.Engine); len(req.Engines) > 0 && !slices.Contains(req.Engines, engine) {
+		return nil, &UnsupportedEngineError{SnapshotID: aws.StringValue(snapshot.DBClusterSnapshotIdentifier), Engine: engine, Engines: req.Engines}
+	}
+	result := &RestoreResult{
+		ClusterID:          req.TargetClusterID,
+		SourceClusterID:    req.SourceClusterID,
+		SnapshotID:         aws.StringValue(snapshot.DBClusterSnapshotIdentifier),
+		SnapshotCreateTime: aws.TimeValue(snapshot.SnapshotCreateTime),
+	}
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	source, err := svc.describeCluster(ctx, req.SourceClusterID)
+	if err != nil {
+		return nil, err
+	}
+	sourceInstances, err := svc.describeClusterInstances(ctx, req.SourceClusterID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sourceInstances) == 0 && req.InstanceClass == "" {
+		return nil, fmt.Errorf("source cluster %v has no instances: an instance class is required", req.SourceClusterID)
+	}
+
+	log.WithField("snapshotID", result.SnapshotID).
+		WithField("sourceClusterID", req.SourceClusterID).
+		WithField("clusterID", req.TargetClusterID).
+		Info("Restoring DB cluster from snapshot")
+	input := new(rds.RestoreDBClusterFromSnapshotInput)
+	input.SetDBClusterIdentifier(req.TargetClusterID)
+	input.SetSnapshotIdentifier(aws.StringValue(snapshot.DBClusterSnapshotArn))
+	input.SetEngine(aws.StringValue(snapshot.Engine))
+	input.EngineVersion = source.EngineVersion
+	input.DBSubnetGroupName = source.DBSubnetGroup
+	input.DBClusterParameterGroupName = source.DBClusterParameterGroup
+	if aws.BoolValue(source.StorageEncrypted) {
+		input.KmsKeyId = source.KmsKeyId
+	}
+	for _, group := range source.VpcSecurityGroups {
+		input.VpcSecurityGroupIds = append(input.VpcSecurityGroupIds, group.VpcSecurityGroupId)
+	}
+	if _, err := svc.RestoreDBClusterFromSnapshotWithContext(ctx, input); err != nil {
+		return nil, err
+	}
+
+	for i, instanceInput := range restoreInstanceInputs(req, snapshot, sourceInstances) {
+		log.WithField("clusterID", req.TargetClusterID).
+			WithField("instanceID", aws.StringValue(instanceInput.DBInstanceIdentifier)).
+			WithField("instanceClass", aws.StringValue(instanceInput.DBInstanceClass)).
+			Info("Creating DB instance for restored cluster")
+		if _, err := svc.CreateDBInstanceWithContext(ctx, instanceInput); err != nil {
+			return result, fmt.Errorf("creating instance %d of restored cluster %v: %w", i+1, req.TargetClusterID, err)
+		}
+		result.InstanceIDs = append(result.InstanceIDs, aws.StringValue(instanceInput.DBInstanceIdentifier))
+	}
+
+	log.WithField("clusterID", req.TargetClusterID).Info("Checking for restored cluster successfully created")
+	cluster, err := svc.waitForRestore(ctx, req.TargetClusterID, len(result.InstanceIDs))
+	if err != nil {
+		return result, err
+	}
+	result.Endpoint = aws.StringValue(cluster.Endpoint)
+	result.ReaderEndpoint = aws.StringValue(cluster.ReaderEndpoint)
+	return result, nil
+}
+
+// availableSnapshot returns the available snapshot with the given identifier, or for LatestSnapshot the most recent
+// available backup snapshot of the cluster, setting the cluster to the cluster of the snapshot when it is empty.
+func (svc *auroraBackupService) availableSnapshot(ctx context.Context, snapshotID string, clusterID *string) (*rds.DBClusterSnapshot, error) {
+	if snapshotID == LatestSnapshot {
+		if *clusterID == "" {
+			id, err := svc.getDBClusterID(ctx)
+			if err != nil {
+				return nil, err
+			}
+			*clusterID = id
+		}
+		snapshots, err := svc.listClusterSnapshots(ctx, svc.RDSClient, *clusterID)
+		if err != nil {
+			return nil, err
+		}
+		var latest *rds.DBClusterSnapshot
+		for _, snapshot := range snapshots {
+			if aws.StringValue(snapshot.Status) != statusAvailable || snapshot.SnapshotCreateTime == nil {
+				continue
+			}
+			if latest == nil || snapshot.SnapshotCreateTime.After(*latest.SnapshotCreateTime) {
+				latest = snapshot
+			}
+		}
+		if latest == nil {
+			return nil, fmt.Errorf("no available snapshot of cluster %v with identifier prefix %v", *clusterID, svc.snapshotIDPrefixFor(*clusterID))
+		}
+		return latest, nil
+	}
+
+	input := new(rds.DescribeDBClusterSnapshotsInput)
+	input.SetDBClusterSnapshotIdentifier(snapshotID)
+	result, err := svc.DescribeDBClusterSnapshotsWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.DBClusterSnapshots) == 0 {
+		return nil, fmt.Errorf("snapshot %v not found", snapshotID)
+	}
+	snapshot := result.DBClusterSnapshots[0]
+	if status := aws.StringValue(snapshot.Status); status != statusAvailable {
+		return nil, &UnexpectedStatusError{SnapshotID: snapshotID, Status: status}
+	}
+	if *clusterID == "" {
+		*clusterID = aws.StringValue(snapshot.DBClusterIdentifier)
+	}
+	return snapshot, nil
+}
+
+func (svc *auroraBackupService) describeCluster(ctx context.Context, clusterID string) (*rds.DBCluster, error) {
+	input := new(rds.DescribeDBClustersInput)
+	input.SetDBClusterIdentifier(clusterID)
+	result, err := svc.DescribeDBClustersWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.DBClusters) == 0 {
+		return nil, &ClusterNotFoundError{ClusterIDPrefix: clusterID}
+	}
+	return result.DBClusters[0], nil
+}
+
+func (svc *auroraBackupService) describeClusterInstances(ctx context.Context, clusterID string) ([]*rds.DBInstance, error) {
+	var instances []*rds.DBInstance
+	input := new(rds.DescribeDBInstancesInput)
+	input.SetFilters([]*rds.Filter{{Name: aws.String("db-cluster-id"), Values: aws.StringSlice([]string{clusterID})}})
+	for {
+		result, err := svc.DescribeDBInstancesWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, result.DBInstances...)
+		if result.Marker == nil {
+			return instances, nil
+		}
+		input.SetMarker(*result.Marker)
+	}
+}
+
+// restoreInstanceInputs returns the instances to create in the restored cluster: one per instance
+// of the source cluster with the same class and parameter group, unless the request overrides the class,
+// or a single instance of the requested class when the source cluster has none.
+// A request for a single instance only copies the first instance of the source cluster.
+// The instances are named after the target cluster, e.g. pac-aurora-restored-1.
+func restoreInstanceInputs(req RestoreRequest, snapshot *rds.DBClusterSnapshot, sourceInstances []*rds.DBInstance) []*rds.CreateDBInstanceInput {
+	if len(sourceInstances) == 0 {
+		sourceInstances = []*rds.DBInstance{{}}
+	}
+	if req.SingleInstance {
+		sourceInstances = sourceInstances[:1]
+	}
+	var inputs []*rds.CreateDBInstanceInput
+	for i, source := range sourceInstances {
+		input := new(rds.CreateDBInstanceInput)
+		input.SetDBClusterIdentifier(req.TargetClusterID)
+		input.SetDBInstanceIdentifier(fmt.Sprintf("%v-%d", req.TargetClusterID, i+1))
+		input.SetEngine(aws.StringValue(snapshot.Engine))
+		input.DBInstanceClass = source.DBInstanceClass
+		if req.InstanceClass != "" {
+			input.SetDBInstanceClass(req.InstanceClass)
+		}
+		if len(source.DBParameterGroups) > 0 {
+			input.DBParameterGroupName = source.DBParameterGroups[0].DBParameterGroupName
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs
+}
+
+// waitForRestore waits until the restored cluster and its instances are available.
+func (svc *auroraBackupService) waitForRestore(ctx context.Context, clusterID string, instanceCount int) (*rds.DBCluster, error) {
+	for attempt := 0; attempt < svc.statusCheckAttempts; attempt++ {
+		if err := svc.pause(ctx, attempt); err != nil {
+			return nil, &InterruptedError{ClusterID: clusterID, Operation: "restore", Err: err}
+		}
+		cluster, available, err := svc.checkRestore(ctx, clusterID, instanceCount)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, &InterruptedError{ClusterID: clusterID, Operation: "restore", Err: ctx.Err()}
+			}
+			return nil, err
+		}
+		if available {
+			return cluster, nil
+		}
+	}
+	return nil, &ClusterTimeoutError{ClusterID: clusterID, Operation: "restore"}
+}
+
+// checkRestore reports whether the restored cluster and all its instances are available.
+func (svc *auroraBackupService) checkRestore(ctx context.Context, clusterID string, instanceCount int) (*rds.DBCluster, bool, error) {
+	cluster, err := svc.describeCluster(ctx, clusterID)
+	if err != nil {
+		return nil, false, err
+	}
+	status := aws.StringValue(cluster.Status)
+	if restoreFailureStatuses[status] {
+		return nil, false, &UnexpectedClusterStatusError{ResourceID: clusterID, Status: status}
+	}
+	if status != statusAvailable {
+		return cluster, false, nil
+	}
+
+	instances, err := svc.describeClusterInstances(ctx, clusterID)
+	if err != nil {
+		return nil, false, err
+	}
+	available := len(instances) >= instanceCount
+	for _, instance := range instances {
+		status := aws.StringValue(instance.DBInstanceStatus)
+		if restoreFailureStatuses[status] {
+			return nil, false, &UnexpectedClusterStatusError{ResourceID: aws.StringValue(instance.DBInstanceIdentifier), Status: status}
+		}
+		if status != statusAvailable {
+			available = false
+		}
+	}
+	return cluster, available, nil
+}
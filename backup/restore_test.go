@@ -0,0 +1,158 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRestoreFake returns a fake with an encrypted source cluster of two instances and three backup snapshots,
+// the most recent one being still in creation.
+func newRestoreFake(t *testing.T) (*awsfake.RDS, time.Time) {
+	fake := awsfake.NewRDS()
+	fake.RestorePolls = 2
+	source := fake.AddCluster(testClusterIDPrefix + "-eu")
+	source.SetEngineVersion("8.0.mysql_aurora.3.04.0")
+	source.SetDBSubnetGroup("pac-aurora-subnets")
+	source.SetDBClusterParameterGroup("pac-aurora-cluster-params")
+	source.SetStorageEncrypted(true)
+	source.SetKmsKeyId("arn:aws:kms:eu-west-1:123456789012:key/source")
+	source.SetVpcSecurityGroups([]*rds.VpcSecurityGroupMembership{{VpcSecurityGroupId: aws.String("sg-1")}, {VpcSecurityGroupId: aws.String("sg-2")}})
+	writer := fake.AddInstance(testClusterIDPrefix+"-eu", testClusterIDPrefix+"-eu-writer", "db.r6g.large")
+	writer.SetDBParameterGroups([]*rds.DBParameterGroupStatus{{DBParameterGroupName: aws.String("pac-aurora-params")}})
+	fake.AddInstance(testClusterIDPrefix+"-eu", testClusterIDPrefix+"-eu-reader", "db.r6g.xlarge")
+
+	fake.CreationPolls = 100
+	now := time.Now().UTC()
+	for i, status := range []string{statusCreating, statusAvailable, statusAvailable} {
+		created := now.AddDate(0, 0, -i)
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + created.Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(created),
+			Engine:                      aws.String("aurora-mysql"),
+			Status:                      aws.String(status),
+		})
+	}
+	return fake, now
+}
+
+func TestRestoreWithFakeRDS(t *testing.T) {
+	fake, now := newRestoreFake(t)
+	svc := newFakeBackupService(t, fake, 0)
+	snapshotID := testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -2).Format(snapshotIDDateFormat)
+
+	result, err := svc.Restore(context.Background(), RestoreRequest{SnapshotID: snapshotID, TargetClusterID: "pac-aurora-restored"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "pac-aurora-restored", result.ClusterID)
+	assert.Equal(t, testClusterIDPrefix+"-eu", result.SourceClusterID)
+	assert.Equal(t, snapshotID, result.SnapshotID)
+	assert.Equal(t, "pac-aurora-restored.cluster-fake.eu-west-1.rds.amazonaws.com", result.Endpoint)
+	assert.Equal(t, "pac-aurora-restored.cluster-ro-fake.eu-west-1.rds.amazonaws.com", result.ReaderEndpoint)
+	assert.Equal(t, []string{"pac-aurora-restored-1", "pac-aurora-restored-2"}, result.InstanceIDs)
+
+	cluster := fake.Cluster("pac-aurora-restored")
+	require.NotNil(t, cluster)
+	assert.Equal(t, statusAvailable, *cluster.Status)
+	assert.Equal(t, "aurora-mysql", *cluster.Engine)
+	assert.Equal(t, "8.0.mysql_aurora.3.04.0", *cluster.EngineVersion)
+	assert.Equal(t, "pac-aurora-subnets", *cluster.DBSubnetGroup)
+	assert.Equal(t, "pac-aurora-cluster-params", *cluster.DBClusterParameterGroup)
+	assert.Equal(t, "arn:aws:kms:eu-west-1:123456789012:key/source", *cluster.KmsKeyId)
+	require.Len(t, cluster.VpcSecurityGroups, 2)
+	assert.Equal(t, "sg-1", *cluster.VpcSecurityGroups[0].VpcSecurityGroupId)
+	assert.Equal(t, "sg-2", *cluster.VpcSecurityGroups[1].VpcSecurityGroupId)
+
+	writer := fake.Instance("pac-aurora-restored-1")
+	require.NotNil(t, writer)
+	assert.Equal(t, statusAvailable, *writer.DBInstanceStatus)
+	assert.Equal(t, "db.r6g.large", *writer.DBInstanceClass)
+	assert.Equal(t, "pac-aurora-params", *writer.DBParameterGroups[0].DBParameterGroupName)
+	reader := fake.Instance("pac-aurora-restored-2")
+	require.NotNil(t, reader)
+	assert.Equal(t, "db.r6g.xlarge", *reader.DBInstanceClass)
+}
+
+func TestRestoreWithFakeRDSLatestSnapshot(t *testing.T) {
+	fake, now := newRestoreFake(t)
+	svc := newFakeBackupService(t, fake, 0)
+
+	result, err := svc.Restore(context.Background(), RestoreRequest{SnapshotID: LatestSnapshot, TargetClusterID: "pac-aurora-restored", InstanceClass: "db.t4g.medium"})
+	require.NoError(t, err)
+
+	assert.Equal(t, testSnapshotIDPrefix+"-"+now.AddDate(0, 0, -1).Format(snapshotIDDateFormat), result.SnapshotID)
+	assert.Equal(t, testClusterIDPrefix+"-eu", result.SourceClusterID)
+	for _, instanceID := range result.InstanceIDs {
+		assert.Equal(t, "db.t4g.medium", *fake.Instance(instanceID).DBInstanceClass)
+	}
+}
+
+func TestRestoreWithFakeRDSSnapshotNotAvailable(t *testing.T) {
+	fake, now := newRestoreFake(t)
+	svc := newFakeBackupService(t, fake, 0)
+	snapshotID := testSnapshotIDPrefix + "-" + now.Format(snapshotIDDateFormat)
+
+	_, err := svc.Restore(context.Background(), RestoreRequest{SnapshotID: snapshotID, TargetClusterID: "pac-aurora-restored"})
+
+	var statusErr *UnexpectedStatusError
+	require.True(t, errors.As(err, &statusErr), "unexpected error: %v", err)
+	assert.Equal(t, statusCreating, statusErr.Status)
+	assert.Equal(t, 0, fake.Calls("RestoreDBClusterFromSnapshot"))
+}
+
+func TestRestoreWithFakeRDSNoSnapshot(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0)
+
+	_, err := svc.Restore(context.Background(), RestoreRequest{SnapshotID: LatestSnapshot, TargetClusterID: "pac-aurora-restored"})
+
+	require.Error(t, err)
+	assert.Equal(t, "no available snapshot of cluster pac-aurora-staging-eu with identifier prefix pac-aurora-staging-test-backup", err.Error())
+}
+
+func TestRestoreWithFakeRDSTimeout(t *testing.T) {
+	fake, now := newRestoreFake(t)
+	fake.RestorePolls = testStatusCheckAttempts
+	svc := newFakeBackupService(t, fake, 0)
+	snapshotID := testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -1).Format(snapshotIDDateFormat)
+
+	result, err := svc.Restore(context.Background(), RestoreRequest{SnapshotID: snapshotID, TargetClusterID: "pac-aurora-restored"})
+
+	var timeout *ClusterTimeoutError
+	require.True(t, errors.As(err, &timeout), "unexpected error: %v", err)
+	assert.Equal(t, "pac-aurora-restored", timeout.ClusterID)
+	assert.Len(t, result.InstanceIDs, 2)
+	assert.Empty(t, result.Endpoint)
+}
+
+func TestRestoreWithFakeRDSExistingTarget(t *testing.T) {
+	fake, now := newRestoreFake(t)
+	svc := newFakeBackupService(t, fake, 0)
+	snapshotID := testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -1).Format(snapshotIDDateFormat)
+
+	_, err := svc.Restore(context.Background(), RestoreRequest{SnapshotID: snapshotID, TargetClusterID: testClusterIDPrefix + "-eu"})
+
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterAlreadyExistsFault, err)
+	assert.Equal(t, 0, fake.Calls("CreateDBInstance"))
+}
+
+func TestRestoreInstanceInputs(t *testing.T) {
+	snapshot := &rds.DBClusterSnapshot{Engine: aws.String("aurora-mysql")}
+
+	inputs := restoreInstanceInputs(RestoreRequest{TargetClusterID: "restored", InstanceClass: "db.t4g.medium"}, snapshot, nil)
+	require.Len(t, inputs, 1)
+	assert.Equal(t, "restored-1", *inputs[0].DBInstanceIdentifier)
+	assert.Equal(t, "restored", *inputs[0].DBClusterIdentifier)
+	assert.Equal(t, "db.t4g.medium", *inputs[0].DBInstanceClass)
+	assert.Equal(t, "aurora-mysql", *inputs[0].Engine)
+	assert.Nil(t, inputs[0].DBParameterGroupName)
+}
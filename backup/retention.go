@@ -0,0 +1,268 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// RetentionPolicy selects the snapshots preserved by the cleanup.
+// Last keeps the most recent snapshots, whatever their age.
+// Each of the other buckets keeps the most recent snapshot of each of the given number of
+// most recent hours, days, ISO weeks, months and years that have a snapshot, computed in UTC.
+// A snapshot is preserved if any bucket selects it.
+type RetentionPolicy struct {
+	Last    int
+	Hourly  int
+	Daily   int
+	Weekly  int
+	Monthly int
+	Yearly  int
+}
+
+// KeepLast returns a policy preserving the n most recent snapshots.
+func KeepLast(n int) RetentionPolicy {
+	return RetentionPolicy{Last: n}
+}
+
+// ParseRetentionPolicy parses a comma-separated list of <bucket>=<count> pairs,
+// e.g. keep-daily=14,keep-weekly=8,keep-monthly=12.
+// The buckets are keep-last, keep-hourly, keep-daily, keep-weekly, keep-monthly and keep-yearly.
+func ParseRetentionPolicy(s string) (RetentionPolicy, error) {
+	var policy RetentionPolicy
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, found := strings.Cut(pair, "=")
+		if !found {
+			return RetentionPolicy{}, fmt.Errorf("retention rule is not in the <bucket>=<count> format: %v", pair)
+		}
+		name = strings.TrimSpace(name)
+		count, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || count < 0 {
+			return RetentionPolicy{}, fmt.Errorf("retention count is not a non-negative integer: %v", pair)
+		}
+		if seen[name] {
+			return RetentionPolicy{}, fmt.Errorf("retention bucket is set more than once: %v", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "keep-last":
+			policy.Last = count
+		case "keep-hourly":
+			policy.Hourly = count
+		case "keep-daily":
+			policy.Daily = count
+		case "keep-weekly":
+			policy.Weekly = count
+		case "keep-monthly":
+			policy.Monthly = count
+		case "keep-yearly":
+			policy.Yearly = count
+		default:
+			return RetentionPolicy{}, fmt.Errorf("unknown retention bucket: %v", name)
+		}
+	}
+	if policy == (RetentionPolicy{}) {
+		return RetentionPolicy{}, fmt.Errorf("retention policy does not preserve any snapshot: %v", s)
+	}
+	return policy, nil
+}
+
+func (p RetentionPolicy) String() string {
+	var rules []string
+	for _, rule := range []struct {
+		name  string
+		count int
+	}{
+		{"keep-last", p.Last},
+		{"keep-hourly", p.Hourly},
+		{"keep-daily", p.Daily},
+		{"keep-weekly", p.Weekly},
+		{"keep-monthly", p.Monthly},
+		{"keep-yearly", p.Yearly},
+	} {
+		if rule.count > 0 {
+			rules = append(rules, fmt.Sprintf("%v=%d", rule.name, rule.count))
+		}
+	}
+	return strings.Join(rules, ",")
+}
+
+// retentionBucket maps a creation time to the period it belongs to.
+type retentionBucket func(t time.Time) string
+
+func hourBucket(t time.Time) string {
+	return t.Format("2006-01-02T15")
+}
+
+func dayBucket(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func weekBucket(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+func monthBucket(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+func yearBucket(t time.Time) string {
+	return t.Format("2006")
+}
+
+// SnapshotDecision records whether the cleanup keeps a snapshot and why.
+// CreateTime is zero for snapshots still being created.
+type SnapshotDecision struct {
+	SnapshotID string
+	Status     string
+	CreateTime time.Time
+	Age        time.Duration
+	Keep       bool
+	Reason     string
+}
+
+// Skipped reports whether the cleanup leaves in place a snapshot that is not kept,
+// as it is neither available nor already being deleted, e.g. a failed snapshot.
+func (d SnapshotDecision) Skipped() bool {
+	return !d.Keep && d.Status != statusAvailable && d.Status != statusDeleting
+}
+
+// decideRetention decides which snapshots are preserved by the policy and the age limits at the given time.
+// Snapshots younger than minAge are always preserved and snapshots older than maxAge are always deleted,
+// minAge winning over maxAge. A zero duration disables the corresponding limit.
+// Only available snapshots are selected by the policy: snapshots being created are always preserved,
+// as are the snapshots without a creation time, while failed or deleting snapshots are not.
+// The decisions are sorted from the most recent snapshot to the oldest one.
+func decideRetention(snapshots []*rds.DBClusterSnapshot, policy RetentionPolicy, now time.Time, minAge, maxAge time.Duration) []SnapshotDecision {
+	sorted := append([]*rds.DBClusterSnapshot(nil), snapshots...)
+	sortSnapshotsNewestFirst(sorted)
+	reasons := retentionReasons(sorted, policy)
+
+	decisions := make([]SnapshotDecision, len(sorted))
+	for i, snapshot := range sorted {
+		status := aws.StringValue(snapshot.Status)
+		decision := SnapshotDecision{SnapshotID: *snapshot.DBClusterSnapshotIdentifier, Status: status}
+		if status == statusCreating || snapshot.SnapshotCreateTime == nil {
+			decision.Keep = true
+			decision.Reason = "being created"
+			if status != statusCreating {
+				decision.Reason = "creation time unknown"
+			}
+			if snapshot.SnapshotCreateTime != nil {
+				decision.CreateTime = *snapshot.SnapshotCreateTime
+				decision.Age = now.Sub(decision.CreateTime)
+			}
+			decisions[i] = decision
+			continue
+		}
+		decision.CreateTime = *snapshot.SnapshotCreateTime
+		decision.Age = now.Sub(decision.CreateTime)
+		switch {
+		case status == statusDeleting:
+			decision.Reason = "being deleted"
+		case status != statusAvailable:
+			decision.Reason = fmt.Sprintf("%v, not counted by retention policy %v", status, policy)
+		case minAge > 0 && decision.Age < minAge:
+			decision.Keep = true
+			decision.Reason = fmt.Sprintf("younger than min-backup-age %v", minAge)
+		case maxAge > 0 && decision.Age > maxAge:
+			decision.Reason = fmt.Sprintf("older than max-backup-age %v", maxAge)
+		case len(reasons[i]) > 0:
+			decision.Keep = true
+			decision.Reason = strings.Join(reasons[i], ",")
+		default:
+			decision.Reason = fmt.Sprintf("not selected by retention policy %v", policy)
+		}
+		decisions[i] = decision
+	}
+	return decisions
+}
+
+// retentionReasons returns, for each of the snapshots sorted from the most recent to the oldest,
+// the buckets of the policy selecting it. Only the available snapshots with a creation time fill the buckets.
+func retentionReasons(sorted []*rds.DBClusterSnapshot, policy RetentionPolicy) [][]string {
+	reasons := make([][]string, len(sorted))
+	var dated []int
+	for i, snapshot := range sorted {
+		if snapshot.SnapshotCreateTime != nil && aws.StringValue(snapshot.Status) == statusAvailable {
+			dated = append(dated, i)
+		}
+	}
+
+	for n, i := range dated {
+		if n >= policy.Last {
+			break
+		}
+		reasons[i] = append(reasons[i], "keep-last")
+	}
+	for _, rule := range []struct {
+		name   string
+		count  int
+		bucket retentionBucket
+	}{
+		{"keep-hourly", policy.Hourly, hourBucket},
+		{"keep-daily", policy.Daily, dayBucket},
+		{"keep-weekly", policy.Weekly, weekBucket},
+		{"keep-monthly", policy.Monthly, monthBucket},
+		{"keep-yearly", policy.Yearly, yearBucket},
+	} {
+		seen := make(map[string]bool)
+		for _, i := range dated {
+			if len(seen) >= rule.count {
+				break
+			}
+			key := rule.bucket(sorted[i].SnapshotCreateTime.UTC())
+			if !seen[key] {
+				seen[key] = true
+				reasons[i] = append(reasons[i], rule.name)
+			}
+		}
+	}
+	return reasons
+}
+
+// ParseBackupAge parses a duration which can also be expressed in days, e.g. 30d.
+// An empty string is a zero duration.
+func ParseBackupAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if days, found := strings.CutSuffix(s, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("backup age is invalid: %v", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("backup age is invalid: %v", s)
+	}
+	return d, nil
+}
+
+// sortSnapshotsNewestFirst sorts the snapshots from the most recent to the oldest,
+// keeping the snapshots still being created first.
+func sortSnapshotsNewestFirst(snapshots []*rds.DBClusterSnapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if snapshots[j].SnapshotCreateTime == nil {
+			return false
+		}
+		if snapshots[i].SnapshotCreateTime == nil {
+			return true
+		}
+		return snapshots[i].SnapshotCreateTime.After(*snapshots[j].SnapshotCreateTime)
+	})
+}
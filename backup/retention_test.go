@@ -0,0 +1,398 @@
+package backup
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected RetentionPolicy
+	}{
+		{"keep-last=35", RetentionPolicy{Last: 35}},
+		{"keep-daily=14,keep-weekly=8,keep-monthly=12", RetentionPolicy{Daily: 14, Weekly: 8, Monthly: 12}},
+		{" keep-hourly = 24 , keep-yearly=3,", RetentionPolicy{Hourly: 24, Yearly: 3}},
+		{"keep-last=0,keep-daily=7", RetentionPolicy{Daily: 7}},
+		{"keep-last=1,keep-hourly=2,keep-daily=3,keep-weekly=4,keep-monthly=5,keep-yearly=6", RetentionPolicy{1, 2, 3, 4, 5, 6}},
+	}
+	for _, test := range tests {
+		policy, err := ParseRetentionPolicy(test.in)
+		require.NoError(t, err, test.in)
+		assert.Equal(t, test.expected, policy, test.in)
+	}
+}
+
+func TestParseRetentionPolicyErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"keep-daily",
+		"keep-daily=",
+		"keep-daily=-1",
+		"keep-daily=seven",
+		"keep-fortnightly=2",
+		"keep-daily=7,keep-daily=14",
+		"keep-last=0",
+	} {
+		_, err := ParseRetentionPolicy(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestParseBackupAge(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"36h", 36 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"2d", 48 * time.Hour},
+		{"0d", 0},
+	}
+	for _, test := range tests {
+		age, err := ParseBackupAge(test.in)
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.expected, age, test.in)
+	}
+
+	for _, in := range []string{"d", "1.5d", "-1d", "-1h", "a week"} {
+		_, err := ParseBackupAge(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestRetentionPolicyString(t *testing.T) {
+	assert.Equal(t, "keep-last=35", KeepLast(35).String())
+	assert.Equal(t, "keep-daily=14,keep-weekly=8,keep-monthly=12", RetentionPolicy{Daily: 14, Weekly: 8, Monthly: 12}.String())
+	assert.Equal(t, "", RetentionPolicy{}.String())
+
+	policy, err := ParseRetentionPolicy(RetentionPolicy{1, 2, 3, 4, 5, 6}.String())
+	require.NoError(t, err)
+	assert.Equal(t, RetentionPolicy{1, 2, 3, 4, 5, 6}, policy)
+}
+
+func TestDecideRetentionPolicy(t *testing.T) {
+	// Monday 2024-01-01 is the first day of ISO week 2024-W01,
+	// while Sunday 2023-12-31 belongs to ISO week 2023-W52.
+	at := func(s string) time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	times := []string{
+		"2024-01-02T10:00:00Z",
+		"2024-01-02T09:30:00Z",
+		"2024-01-02T09:00:00Z",
+		"2024-01-01T23:00:00Z",
+		"2024-01-01T01:00:00Z",
+		"2023-12-31T23:00:00Z",
+		"2023-12-25T12:00:00Z",
+		"2023-12-24T12:00:00Z",
+		"2023-11-30T12:00:00Z",
+		"2023-11-01T12:00:00Z",
+		"2022-06-15T12:00:00Z",
+		"2021-01-01T00:00:00Z",
+	}
+
+	tests := []struct {
+		name     string
+		policy   RetentionPolicy
+		failed   []string
+		retained []string
+	}{
+		{
+			name:     "nothing",
+			policy:   RetentionPolicy{},
+			retained: nil,
+		},
+		{
+			name:     "keep last",
+			policy:   KeepLast(3),
+			retained: times[:3],
+		},
+		{
+			name:     "keep last skips failed snapshots",
+			policy:   KeepLast(3),
+			failed:   times[1:2],
+			retained: []string{times[0], times[2], times[3]},
+		},
+		{
+			name:     "keep daily skips failed snapshots",
+			policy:   RetentionPolicy{Daily: 2},
+			failed:   times[:1],
+			retained: []string{times[1], times[3]},
+		},
+		{
+			name:     "keep last more than available",
+			policy:   KeepLast(100),
+			retained: times,
+		},
+		{
+			name:   "keep hourly",
+			policy: RetentionPolicy{Hourly: 3},
+			retained: []string{
+				"2024-01-02T10:00:00Z",
+				"2024-01-02T09:30:00Z",
+				"2024-01-01T23:00:00Z",
+			},
+		},
+		{
+			name:   "keep daily",
+			policy: RetentionPolicy{Daily: 4},
+			retained: []string{
+				"2024-01-02T10:00:00Z",
+				"2024-01-01T23:00:00Z",
+				"2023-12-31T23:00:00Z",
+				"2023-12-25T12:00:00Z",
+			},
+		},
+		{
+			name:   "keep weekly uses ISO weeks",
+			policy: RetentionPolicy{Weekly: 4},
+			retained: []string{
+				"2024-01-02T10:00:00Z",
+				"2023-12-31T23:00:00Z",
+				"2023-12-24T12:00:00Z",
+				"2023-11-30T12:00:00Z",
+			},
+		},
+		{
+			name:   "keep monthly",
+			policy: RetentionPolicy{Monthly: 3},
+			retained: []string{
+				"2024-01-02T10:00:00Z",
+				"2023-12-31T23:00:00Z",
+				"2023-11-30T12:00:00Z",
+			},
+		},
+		{
+			name:   "keep yearly",
+			policy: RetentionPolicy{Yearly: 10},
+			retained: []string{
+				"2024-01-02T10:00:00Z",
+				"2023-12-31T23:00:00Z",
+				"2022-06-15T12:00:00Z",
+				"2021-01-01T00:00:00Z",
+			},
+		},
+		{
+			name:   "buckets overlap",
+			policy: RetentionPolicy{Last: 1, Daily: 2, Monthly: 4},
+			retained: []string{
+				"2024-01-02T10:00:00Z",
+				"2024-01-01T23:00:00Z",
+				"2023-12-31T23:00:00Z",
+				"2023-11-30T12:00:00Z",
+				"2022-06-15T12:00:00Z",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The snapshots are given out of order to check the selection does not depend on it.
+			var snapshots []*rds.DBClusterSnapshot
+			for i := len(times) - 1; i >= 0; i-- {
+				status := statusAvailable
+				if contains(test.failed, times[i]) {
+					status = "failed"
+				}
+				snapshots = append(snapshots, &rds.DBClusterSnapshot{
+					DBClusterSnapshotIdentifier: aws.String(times[i]),
+					Status:                      aws.String(status),
+					SnapshotCreateTime:          aws.Time(at(times[i])),
+				})
+			}
+
+			retained, expired := splitDecisions(decideRetention(snapshots, test.policy, at(times[0]), 0, 0))
+
+			var expectedExpired []string
+			for _, id := range times {
+				if !contains(test.retained, id) {
+					expectedExpired = append(expectedExpired, id)
+				}
+			}
+			assert.Equal(t, test.retained, retained)
+			assert.Equal(t, expectedExpired, expired)
+		})
+	}
+}
+
+func TestDecideRetentionUsesUTC(t *testing.T) {
+	paris := time.FixedZone("CET", 60*60)
+	snapshots := []*rds.DBClusterSnapshot{
+		// 2024-01-02T00:30 in Paris is still 2024-01-01 in UTC.
+		{DBClusterSnapshotIdentifier: aws.String("late"), Status: aws.String(statusAvailable), SnapshotCreateTime: aws.Time(time.Date(2024, 1, 2, 0, 30, 0, 0, paris))},
+		{DBClusterSnapshotIdentifier: aws.String("early"), Status: aws.String(statusAvailable), SnapshotCreateTime: aws.Time(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))},
+	}
+
+	retained, expired := splitDecisions(decideRetention(snapshots, RetentionPolicy{Daily: 2}, time.Now(), 0, 0))
+
+	assert.Equal(t, []string{"late"}, retained)
+	assert.Equal(t, []string{"early"}, expired)
+}
+
+func TestDecideRetentionKeepsSnapshotsBeingCreated(t *testing.T) {
+	snapshots := []*rds.DBClusterSnapshot{
+		{DBClusterSnapshotIdentifier: aws.String("old"), Status: aws.String(statusAvailable), SnapshotCreateTime: aws.Time(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))},
+		{DBClusterSnapshotIdentifier: aws.String("creating"), Status: aws.String(statusCreating)},
+	}
+
+	decisions := decideRetention(snapshots, RetentionPolicy{}, time.Now(), 0, time.Nanosecond)
+
+	retained, expired := splitDecisions(decisions)
+	assert.Equal(t, []string{"creating"}, retained)
+	assert.Equal(t, []string{"old"}, expired)
+	assert.True(t, decisions[0].CreateTime.IsZero())
+	assert.Equal(t, "being created", decisions[0].Reason)
+}
+
+func TestDecideRetentionDoesNotCountFailedSnapshots(t *testing.T) {
+	snapshots := []*rds.DBClusterSnapshot{
+		{DBClusterSnapshotIdentifier: aws.String("failed"), Status: aws.String("failed"), SnapshotCreateTime: aws.Time(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))},
+		{DBClusterSnapshotIdentifier: aws.String("available"), Status: aws.String(statusAvailable), SnapshotCreateTime: aws.Time(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))},
+	}
+
+	decisions := decideRetention(snapshots, KeepLast(1), time.Now(), 0, 0)
+
+	retained, expired := splitDecisions(decisions)
+	assert.Equal(t, []string{"available"}, retained)
+	assert.Equal(t, []string{"failed"}, expired)
+	assert.Equal(t, "failed, not counted by retention policy keep-last=1", decisions[0].Reason)
+}
+
+func TestDecideRetentionAgeLimits(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name             string
+		ages             []int
+		policy           RetentionPolicy
+		minAge           time.Duration
+		maxAge           time.Duration
+		expectedRetained []string
+		expectedExpired  []string
+	}{
+		{
+			name:             "no limits",
+			ages:             []int{0, 1, 2, 40},
+			policy:           KeepLast(2),
+			expectedRetained: []string{"0d", "1d"},
+			expectedExpired:  []string{"2d", "40d"},
+		},
+		{
+			name:             "min age keeps young expired snapshots",
+			ages:             []int{0, 1, 2, 3},
+			policy:           KeepLast(1),
+			minAge:           3 * day,
+			expectedRetained: []string{"0d", "1d", "2d"},
+			expectedExpired:  []string{"3d"},
+		},
+		{
+			name:             "max age expires old retained snapshots",
+			ages:             []int{0, 10, 20, 31, 365},
+			policy:           KeepLast(4),
+			maxAge:           30 * day,
+			expectedRetained: []string{"0d", "10d", "20d"},
+			expectedExpired:  []string{"31d", "365d"},
+		},
+		{
+			name:             "min age wins over max age",
+			ages:             []int{0, 1, 5},
+			policy:           KeepLast(2),
+			minAge:           7 * day,
+			maxAge:           2 * day,
+			expectedRetained: []string{"0d", "1d", "5d"},
+		},
+		{
+			name:             "retention zero with min age",
+			ages:             []int{0, 1, 2},
+			policy:           KeepLast(0),
+			minAge:           day + time.Hour,
+			expectedRetained: []string{"0d", "1d"},
+			expectedExpired:  []string{"2d"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var snapshots []*rds.DBClusterSnapshot
+			for _, age := range test.ages {
+				snapshots = append(snapshots, &rds.DBClusterSnapshot{
+					DBClusterSnapshotIdentifier: aws.String(fmt.Sprintf("%dd", age)),
+					Status:                      aws.String(statusAvailable),
+					SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -age)),
+				})
+			}
+
+			retained, expired := splitDecisions(decideRetention(snapshots, test.policy, now, test.minAge, test.maxAge))
+
+			assert.Equal(t, test.expectedRetained, retained)
+			assert.Equal(t, test.expectedExpired, expired)
+		})
+	}
+}
+
+func TestDecideRetentionReasons(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	var snapshots []*rds.DBClusterSnapshot
+	for _, age := range []int{0, 1, 2, 40, 400} {
+		snapshots = append(snapshots, &rds.DBClusterSnapshot{
+			DBClusterSnapshotIdentifier: aws.String(fmt.Sprintf("%dd", age)),
+			Status:                      aws.String(statusAvailable),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -age)),
+		})
+	}
+	policy := RetentionPolicy{Last: 1, Monthly: 2}
+
+	decisions := decideRetention(snapshots, policy, now, 36*time.Hour, 365*24*time.Hour)
+
+	require.Len(t, decisions, 5)
+	var reasons []string
+	for _, decision := range decisions {
+		reasons = append(reasons, decision.Reason)
+	}
+	assert.Equal(t, []string{
+		"younger than min-backup-age 36h0m0s",
+		"younger than min-backup-age 36h0m0s",
+		"not selected by retention policy keep-last=1,keep-monthly=2",
+		"keep-monthly",
+		"older than max-backup-age 8760h0m0s",
+	}, reasons)
+	assert.Equal(t, 40*24*time.Hour, decisions[3].Age)
+	assert.Equal(t, now.AddDate(0, 0, -40), decisions[3].CreateTime)
+
+	decisions = decideRetention(snapshots, policy, now, 0, 0)
+	assert.Equal(t, "keep-last,keep-monthly", decisions[0].Reason)
+}
+
+func splitDecisions(decisions []SnapshotDecision) (retained, expired []string) {
+	for _, decision := range decisions {
+		if decision.Keep {
+			retained = append(retained, decision.SnapshotID)
+		} else {
+			expired = append(expired, decision.SnapshotID)
+		}
+	}
+	return retained, expired
+}
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
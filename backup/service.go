@@ -1,15 +1,14 @@
 package backup
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/rds"
 	log "github.com/sirupsen/logrus"
 )
@@ -21,184 +20,510 @@ const statusCreating = "creating"
 const statusDeleting = "deleting"
 const statusDeleted = "deleted"
 
+// bytesPerGiB converts the allocated storage of snapshots, reported by AWS in GiB, to bytes.
+const bytesPerGiB = 1 << 30
+
 type Service interface {
-	MakeBackup()
-	CleanUpOldBackups()
+	MakeBackup(ctx context.Context) ([]*BackupResult, error)
+	CleanUpOldBackups(ctx context.Context) ([]*CleanupResult, error)
+	Plan(ctx context.Context) (*Plan, error)
+	List(ctx context.Context) ([]*SnapshotInfo, error)
+	Status(ctx context.Context) ([]*ClusterStatus, error)
+	CopySnapshot(ctx context.Context, snapshotID string) ([]*CopyResult, error)
+	Restore(ctx context.Context, req RestoreRequest) (*RestoreResult, error)
+	Verify(ctx context.Context, req VerifyRequest, check CheckFunc) (*VerifyResult, error)
+	Export(ctx context.Context, snapshotID string) (*ExportResult, error)
+	Adopt(ctx context.Context, dryRun bool) ([]*AdoptResult, error)
+	Pin(ctx context.Context, req PinRequest) (*PinResult, error)
+	Unpin(ctx context.Context, snapshotID string) (*PinResult, error)
+}
+
+// BackupResult describes the snapshot created by MakeBackup for a DB cluster.
+// On failure it holds whatever was known before the error occurred.
+type BackupResult struct {
+	ClusterID   string
+	SnapshotID  string
+	SnapshotARN string
+	StartTime   time.Time
+	Duration    time.Duration
+	// CreationDuration is the time elapsed from the snapshot creation request until the snapshot was available.
+	// It is zero for a reused snapshot, whose creation was not requested by this run.
+	CreationDuration time.Duration
+	// Reused is true when the snapshot was not created by this run but is a recent one reused as the backup,
+	// see WithMinBackupInterval.
+	Reused     bool
+	SharedWith []string
+	Copies     []*CopyResult
+	Export     *ExportResult
+	Err        error
+}
+
+// CleanupResult describes the outcome of CleanUpOldBackups for a snapshot identifier prefix in a region.
+// ClusterID is empty when the cleanup is not restricted to the snapshots of a single cluster.
+type CleanupResult struct {
+	Region           string
+	ClusterID        string
+	SnapshotIDPrefix string
+	Policy           RetentionPolicy
+	Retained         []string
+	// Unmanaged lists the snapshots with the prefix ignored by the cleanup as they are not tagged as managed.
+	Unmanaged []string
+	// Held lists the snapshots kept as pinned, which do not count toward the retention.
+	Held []string
+	// Skipped lists the snapshots not kept but left in place as they are neither available nor being deleted.
+	Skipped []string
+	// RetainedBytes is the storage allocated to the retained snapshots.
+	RetainedBytes int64
+	// Decisions records why each snapshot with the prefix was kept or deleted, from the most recent to the oldest.
+	Decisions []SnapshotDecision
+	Deleted   []string
+	Failed    []SnapshotFailure
+	Duration  time.Duration
+	Err       error
 }
 
 type auroraBackupService struct {
-	*rds.RDS
-	clusterIDPrefix     string
-	snapshotIDPrefix    string
-	statusCheckInterval time.Duration
-	statusCheckAttempts int
-	backupsRetention    int
+	RDSClient
+	region                 string
+	clusterIDPrefix        string
+	snapshotIDPrefix       string
+	statusCheckInterval    time.Duration
+	statusCheckAttempts    int
+	retentionPolicy        RetentionPolicy
+	minBackupAge           time.Duration
+	maxBackupAge           time.Duration
+	discoverClusters       bool
+	concurrency            int
+	newClient              ClientFactory
+	copyDestinations       []CopyDestination
+	copyClients            map[string]RDSClient
+	shareWithAccounts      []string
+	vault                  *VaultCopy
+	vaultClient            RDSClient
+	preflightWait          time.Duration
+	maxStatusCheckInterval time.Duration
+	snapshotTags           map[string]string
+	managedTags            map[string]string
+	deletionConcurrency    int
+	deletionRate           float64
+	minBackupInterval      time.Duration
+	snapshotIDTemplate     *SnapshotIDTemplate
+	runID                  string
+	snapshotLabel          string
+	export                 *ExportDestination
+	s3Client               S3Client
 }
 
-func NewBackupService(region, clusterIDPrefix, snapshotIDPrefix string, statusCheckInterval time.Duration, statusCheckAttempts, backupsRetention int) (Service, error) {
-	svc, err := newRDSService(region)
-	if err != nil {
-		return nil, err
+// Option customises the backup service returned by NewBackupService.
+type Option func(*auroraBackupService)
+
+// WithRDSClient makes the service use the given client instead of creating
+// an AWS RDS client for the configured region.
+func WithRDSClient(client RDSClient) Option {
+	return func(svc *auroraBackupService) {
+		svc.RDSClient = client
 	}
-	return &auroraBackupService{
-		svc,
-		clusterIDPrefix,
-		snapshotIDPrefix,
-		statusCheckInterval,
-		statusCheckAttempts,
-		backupsRetention,
-	}, nil
 }
 
-func newRDSService(region string) (*rds.RDS, error) {
-	sess, err := session.NewSession(aws.NewConfig().WithRegion(region))
-	if err != nil {
-		return nil, err
+// WithRetentionPolicy makes the cleanup preserve the snapshots selected by the given policy
+// instead of only the backupsRetention most recent ones.
+func WithRetentionPolicy(policy RetentionPolicy) Option {
+	return func(svc *auroraBackupService) {
+		svc.retentionPolicy = policy
+	}
+}
+
+// WithBackupAgeLimits makes the cleanup never delete snapshots younger than minAge
+// and always delete snapshots older than maxAge, whatever the retention policy.
+// minAge takes precedence over maxAge, and a zero duration disables the corresponding limit.
+// Age limits apply to the snapshots of the source region and of the copy regions.
+func WithBackupAgeLimits(minAge, maxAge time.Duration) Option {
+	return func(svc *auroraBackupService) {
+		svc.minBackupAge = minAge
+		svc.maxBackupAge = maxAge
+	}
+}
+
+// WithClusterDiscovery makes the service back up and clean up every DB cluster
+// matching the cluster identifier prefix, processing at most concurrency clusters at a time.
+// The snapshots of each cluster are identified by a prefix derived from the cluster identifier,
+// so that retention is applied per cluster.
+func WithClusterDiscovery(concurrency int) Option {
+	return func(svc *auroraBackupService) {
+		svc.discoverClusters = true
+		svc.concurrency = concurrency
 	}
+}
 
-	return rds.New(sess), nil
+// WithSnapshotTags makes the service tag every new snapshot with the given tags,
+// which the snapshot copies inherit. The managed tags take precedence over them.
+func WithSnapshotTags(tags map[string]string) Option {
+	return func(svc *auroraBackupService) {
+		svc.snapshotTags = tags
+	}
 }
 
-func (svc *auroraBackupService) MakeBackup() {
+func NewBackupService(region, clusterIDPrefix, snapshotIDPrefix string, statusCheckInterval time.Duration, statusCheckAttempts, backupsRetention int, opts ...Option) (Service, error) {
+	svc := &auroraBackupService{
+		region:                 region,
+		newClient:              newRDSService,
+		clusterIDPrefix:        clusterIDPrefix,
+		snapshotIDPrefix:       snapshotIDPrefix,
+		statusCheckInterval:    statusCheckInterval,
+		statusCheckAttempts:    statusCheckAttempts,
+		retentionPolicy:        KeepLast(backupsRetention),
+		maxStatusCheckInterval: 4 * statusCheckInterval,
+		snapshotIDTemplate:     defaultSnapshotIDFormat,
+	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	if svc.minBackupAge < 0 || svc.maxBackupAge < 0 {
+		return nil, fmt.Errorf("backup age limits cannot be negative: min %v, max %v", svc.minBackupAge, svc.maxBackupAge)
+	}
+	if svc.minBackupInterval < 0 {
+		return nil, fmt.Errorf("minimum backup interval cannot be negative: %v", svc.minBackupInterval)
+	}
+	if svc.maxBackupAge > 0 && svc.minBackupAge > svc.maxBackupAge {
+		return nil, fmt.Errorf("minimum backup age %v is greater than maximum backup age %v", svc.minBackupAge, svc.maxBackupAge)
+	}
+	if _, err := svc.newSnapshotID(clusterIDPrefix, time.Now()); err != nil {
+		return nil, err
+	}
+	if svc.RDSClient == nil {
+		client, err := newRDSService(region, "")
+		if err != nil {
+			return nil, err
+		}
+		svc.RDSClient = client
+	}
+	svc.copyClients = make(map[string]RDSClient)
+	for _, destination := range svc.copyDestinations {
+		client, err := svc.newClient(destination.Region, "")
+		if err != nil {
+			return nil, err
+		}
+		svc.copyClients[destination.Region] = client
+	}
+	if svc.vault != nil {
+		client, err := svc.newClient(svc.vault.Region, svc.vault.RoleARN)
+		if err != nil {
+			return nil, err
+		}
+		svc.vaultClient = client
+	}
+	if svc.export != nil && svc.s3Client == nil {
+		client, err := newS3Service(region)
+		if err != nil {
+			return nil, err
+		}
+		svc.s3Client = client
+	}
+	return svc, nil
+}
+
+func (svc *auroraBackupService) MakeBackup(ctx context.Context) ([]*BackupResult, error) {
 	log.Info("Getting DB cluster ID")
-	clusterID, err := svc.getDBClusterID()
+	clusterIDs, err := svc.getBackupClusterIDs(ctx)
 	if err != nil {
 		log.WithError(err).Error("Error in fetching DB cluster information from AWS")
-		return
+		return nil, err
 	}
 
-	log.WithField("clusterID", clusterID).
-		Info("Making snapshot for cluster")
-	snapshotID, err := svc.makeDBSnapshots(clusterID)
+	results := make([]*BackupResult, len(clusterIDs))
+	svc.forEachCluster(clusterIDs, func(i int, clusterID string) {
+		results[i] = svc.backupCluster(ctx, clusterID)
+	})
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, &ClusterError{ClusterID: result.ClusterID, Err: result.Err})
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (svc *auroraBackupService) backupCluster(ctx context.Context, clusterID string) *BackupResult {
+	result := &BackupResult{ClusterID: clusterID, StartTime: time.Now().UTC()}
+	defer func() {
+		result.Duration = time.Since(result.StartTime)
+	}()
+
+	recent, err := svc.recentSnapshot(ctx, clusterID, time.Now())
 	if err != nil {
-		log.WithError(err).Error("Error in creating DB snapshot")
-		return
+		log.WithField("clusterID", clusterID).
+			WithError(err).
+			Error("Error in looking for a recent snapshot of the cluster")
+		result.Err = err
+		return result
+	}
+
+	var creationStart time.Time
+	var snapshotID string
+	if recent != nil {
+		snapshotID = *recent.DBClusterSnapshotIdentifier
+		result.Reused = true
+		log.WithField("clusterID", clusterID).
+			WithField("snapshotID", snapshotID).
+			WithField("status", aws.StringValue(recent.Status)).
+			Info("Reusing a recent snapshot of the cluster instead of making a new one")
+	} else {
+		log.WithField("clusterID", clusterID).
+			Info("Running pre-flight checks for cluster")
+		if err := svc.waitForPreflightChecks(ctx, clusterID); err != nil {
+			log.WithField("clusterID", clusterID).
+				WithError(err).
+				Error("Error in pre-flight checks")
+			result.Err = err
+			return result
+		}
+
+		log.WithField("clusterID", clusterID).
+			Info("Making snapshot for cluster")
+		creationStart = time.Now()
+		snapshotID, err = svc.makeDBSnapshots(ctx, clusterID)
+		if err != nil {
+			log.WithField("clusterID", clusterID).
+				WithError(err).
+				Error("Error in creating DB snapshot")
+			result.Err = err
+			return result
+		}
 	}
+	result.SnapshotID = snapshotID
 
 	log.WithField("snapshotID", snapshotID).
 		Info("Checking for snapshot successfully created")
-	err = svc.checkSnapshotCreation(snapshotID)
+	snapshot, err := svc.checkSnapshotCreation(ctx, snapshotID)
 	if err != nil {
 		log.WithField("snapshotID", snapshotID).
 			WithError(err).
 			Error("Error in snapshot creation check")
-		return
+		result.Err = err
+		return result
+	}
+	result.SnapshotARN = *snapshot.DBClusterSnapshotArn
+	if !result.Reused {
+		result.CreationDuration = time.Since(creationStart)
 	}
 
 	log.WithField("snapshotID", snapshotID).Info("PAC aurora backup successfully created")
-}
 
-func (svc *auroraBackupService) getDBClusterID() (string, error) {
-	clusterIdentifierPrefix := svc.clusterIDPrefix
-	isLastPage := false
-	input := new(rds.DescribeDBClustersInput)
-	for !isLastPage {
-		result, err := svc.DescribeDBClusters(input)
-		if err != nil {
-			return "", err
+	var errs []error
+	shared := false
+	if accountIDs := svc.sharingAccountIDs(); len(accountIDs) > 0 {
+		if err := svc.shareSnapshot(ctx, snapshotID, accountIDs); err != nil {
+			errs = append(errs, &ShareError{SnapshotID: snapshotID, AccountIDs: accountIDs, Err: err})
+		} else {
+			result.SharedWith = accountIDs
+			shared = true
 		}
-		for _, cluster := range result.DBClusters {
-			if strings.HasPrefix(*cluster.DBClusterIdentifier, clusterIdentifierPrefix) {
-				return *cluster.DBClusterIdentifier, nil
-			}
+	}
+
+	result.Copies = svc.copySnapshotToDestinations(ctx, snapshot, svc.vault != nil && shared)
+	for _, c := range result.Copies {
+		if c.Err != nil {
+			errs = append(errs, &CopyError{Region: c.Region, AccountID: c.AccountID, SnapshotID: snapshotID, Err: c.Err})
 		}
-		if result.Marker != nil {
-			input.SetMarker(*result.Marker)
-		} else {
-			isLastPage = true
+	}
+
+	if svc.export != nil {
+		result.Export = svc.exportBackup(ctx, snapshot, clusterID, result.Reused)
+		if result.Export.Err != nil {
+			errs = append(errs, &ExportError{SnapshotID: snapshotID, TaskID: result.Export.TaskID, Err: result.Export.Err})
 		}
 	}
-	return "", fmt.Errorf("DB cluster not found with identifier prefix %v", clusterIdentifierPrefix)
+	result.Err = errors.Join(errs...)
+	return result
 }
 
-func (svc *auroraBackupService) makeDBSnapshots(clusterID string) (string, error) {
+func (svc *auroraBackupService) makeDBSnapshots(ctx context.Context, clusterID string) (string, error) {
 	input := new(rds.CreateDBClusterSnapshotInput)
 	input.SetDBClusterIdentifier(clusterID)
-	timestamp := time.Now().UTC().Format(snapshotIDDateFormat)
-	snapshotIdentifier := svc.snapshotIDPrefix + "-" + timestamp
+	snapshotIdentifier, err := svc.newSnapshotID(clusterID, time.Now())
+	if err != nil {
+		return "", err
+	}
 	input.SetDBClusterSnapshotIdentifier(snapshotIdentifier)
+	input.SetTags(svc.newSnapshotTags())
 
-	_, err := svc.CreateDBClusterSnapshot(input)
+	_, err = svc.CreateDBClusterSnapshotWithContext(ctx, input)
 
 	return snapshotIdentifier, err
 }
 
-func (svc *auroraBackupService) checkSnapshotCreation(snapshotID string) error {
+func (svc *auroraBackupService) checkSnapshotCreation(ctx context.Context, snapshotID string) (*rds.DBClusterSnapshot, error) {
+	return svc.waitForSnapshotCreation(ctx, svc.RDSClient, snapshotID)
+}
+
+func (svc *auroraBackupService) waitForSnapshotCreation(ctx context.Context, client RDSClient, snapshotID string) (*rds.DBClusterSnapshot, error) {
 	input := new(rds.DescribeDBClusterSnapshotsInput)
 	input.SetDBClusterSnapshotIdentifier(snapshotID)
 
 	for attempt := 0; attempt < svc.statusCheckAttempts; attempt++ {
-		time.Sleep(svc.statusCheckInterval)
-		result, err := svc.DescribeDBClusterSnapshots(input)
+		if err := svc.pause(ctx, attempt); err != nil {
+			return nil, &InterruptedError{SnapshotID: snapshotID, Operation: "creation", Err: err}
+		}
+		result, err := client.DescribeDBClusterSnapshotsWithContext(ctx, input)
 		if err != nil {
-			return err
+			if ctx.Err() != nil {
+				return nil, &InterruptedError{SnapshotID: snapshotID, Operation: "creation", Err: ctx.Err()}
+			}
+			return nil, err
 		}
 		if len(result.DBClusterSnapshots) < 1 {
-			return errors.New("snapshot not found")
+			return nil, errors.New("snapshot not found")
 		}
-		if *result.DBClusterSnapshots[0].Status != statusCreating {
-			if *result.DBClusterSnapshots[0].Status == statusAvailable {
-				return nil
+		snapshot := result.DBClusterSnapshots[0]
+		if *snapshot.Status != statusCreating {
+			if *snapshot.Status == statusAvailable {
+				return snapshot, nil
 			} else {
-				return fmt.Errorf("unexpected snapshot status %v", *result.DBClusterSnapshots[0].Status)
+				return nil, &UnexpectedStatusError{SnapshotID: snapshotID, Status: *snapshot.Status}
 			}
 		}
 	}
-	return errors.New("check for snapshot creation time out")
+	return nil, &SnapshotTimeoutError{SnapshotID: snapshotID, Operation: "creation"}
 }
 
-func (svc *auroraBackupService) CleanUpOldBackups() {
-	log.Info("Getting list of snapshot to be cleaned up")
-	snapshots, err := svc.getDBSnapshotsByPrefix()
+func (svc *auroraBackupService) CleanUpOldBackups(ctx context.Context) ([]*CleanupResult, error) {
+	clusterIDs, err := svc.getCleanupClusterIDs(ctx)
+	if err != nil {
+		log.WithError(err).Error("Error in fetching DB cluster information from AWS for cleanup")
+		return nil, err
+	}
+
+	results := make([]*CleanupResult, len(clusterIDs)*(1+len(svc.copyDestinations)))
+	svc.forEachCluster(clusterIDs, func(i int, clusterID string) {
+		offset := i * (1 + len(svc.copyDestinations))
+		results[offset] = svc.cleanUpSnapshots(ctx, svc.RDSClient, svc.region, clusterID, svc.retentionPolicy)
+		for j, destination := range svc.copyDestinations {
+			results[offset+1+j] = svc.cleanUpSnapshots(ctx, svc.copyClients[destination.Region], destination.Region, clusterID, KeepLast(destination.Retention))
+		}
+	})
+
+	var errs []error
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		if result.ClusterID == "" {
+			errs = append(errs, result.Err)
+		} else {
+			errs = append(errs, &ClusterError{ClusterID: result.ClusterID, Err: result.Err})
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// cleanUpSnapshots deletes the snapshots of the given cluster in a region which are not preserved by the retention policy.
+// An empty cluster ID cleans up every snapshot with the configured prefix, whatever its cluster.
+func (svc *auroraBackupService) cleanUpSnapshots(ctx context.Context, client RDSClient, region, clusterID string, policy RetentionPolicy) *CleanupResult {
+	start := time.Now()
+	result := &CleanupResult{Region: region, ClusterID: clusterID, SnapshotIDPrefix: svc.snapshotIDPrefixFor(clusterID), Policy: policy}
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	log.WithField("snapshotIDPrefix", result.SnapshotIDPrefix).
+		WithField("region", region).
+		Info("Getting list of snapshot to be cleaned up")
+	snapshots, err := svc.listClusterSnapshots(ctx, client, clusterID)
 	if err != nil {
 		log.WithError(err).Error("Error in fetching DB cluster snapshots for cleanup")
-		return
+		result.Err = err
+		return result
+	}
+	snapshots, unmanaged := splitManaged(snapshots)
+	for _, snapshot := range unmanaged {
+		result.Unmanaged = append(result.Unmanaged, aws.StringValue(snapshot.DBClusterSnapshotIdentifier))
+	}
+	if len(result.Unmanaged) > 0 {
+		log.WithField("snapshotIDPrefix", result.SnapshotIDPrefix).
+			WithField("region", region).
+			WithField("unmanaged", result.Unmanaged).
+			Warn("Ignoring snapshots not tagged as managed by the app, adopt them if they are backups")
+	}
+	now := time.Now()
+	snapshots, held := splitHeld(snapshots, now)
+	for _, snapshot := range held {
+		snapshotID := aws.StringValue(snapshot.DBClusterSnapshotIdentifier)
+		log.WithField("snapshotID", snapshotID).
+			WithField("region", region).
+			WithField("hold", snapshotHold(snapshot).String()).
+			Info("Keeping pinned snapshot")
+		result.Held = append(result.Held, snapshotID)
 	}
 
-	if len(snapshots) > svc.backupsRetention {
-		sort.Slice(snapshots, func(i, j int) bool {
-			return snapshots[i].SnapshotCreateTime.After(*snapshots[j].SnapshotCreateTime)
-		})
-		snapshots = snapshots[svc.backupsRetention:]
+	allocatedStorage := make(map[string]int64, len(snapshots))
+	for _, snapshot := range snapshots {
+		allocatedStorage[aws.StringValue(snapshot.DBClusterSnapshotIdentifier)] = aws.Int64Value(snapshot.AllocatedStorage)
+	}
 
-		for _, snapshot := range snapshots {
-			log.WithField("snapshotID", *snapshot.DBClusterSnapshotIdentifier).
+	decisions := decideRetention(snapshots, policy, now, svc.minBackupAge, svc.maxBackupAge)
+	var toDelete, deleting []string
+	for _, decision := range decisions {
+		switch {
+		case decision.Keep:
+			result.Retained = append(result.Retained, decision.SnapshotID)
+			result.RetainedBytes += allocatedStorage[decision.SnapshotID] * bytesPerGiB
+		case decision.Skipped():
+			result.Skipped = append(result.Skipped, decision.SnapshotID)
+		case decision.Status == statusDeleting:
+			toDelete = append(toDelete, decision.SnapshotID)
+			deleting = append(deleting, decision.SnapshotID)
+		default:
+			log.WithField("snapshotID", decision.SnapshotID).
+				WithField("reason", decision.Reason).
 				Info("Deleting snapshot for cleanup")
-			input := new(rds.DeleteDBClusterSnapshotInput)
-			input.SetDBClusterSnapshotIdentifier(*snapshot.DBClusterSnapshotIdentifier)
-			_, err = svc.DeleteDBClusterSnapshot(input)
-			if err != nil {
-				log.WithError(err).
-					WithField("snapshotID", *snapshot.DBClusterSnapshotIdentifier).
-					Error("Error in deleting DB cluster snapshot for cleanup")
-			} else {
-				log.WithField("snapshotID", *snapshot.DBClusterSnapshotIdentifier).
-					Info("Checking for snapshot successfully deleted")
-				err = svc.checkSnapshotDeletion(*snapshot.DBClusterSnapshotIdentifier)
-				if err != nil {
-					log.WithError(err).
-						WithField("snapshotID", *snapshot.DBClusterSnapshotIdentifier).
-						Error("Error in checking DB cluster snapshot deletion for cleanup")
-				}
-				log.WithField("snapshotID", *snapshot.DBClusterSnapshotIdentifier).
-					Info("Deleted old snapshot for cleanup")
-			}
+			toDelete = append(toDelete, decision.SnapshotID)
 		}
 	}
+	if len(result.Skipped) > 0 {
+		log.WithField("snapshotIDPrefix", result.SnapshotIDPrefix).
+			WithField("region", region).
+			WithField("skipped", result.Skipped).
+			Warn("Not deleting snapshots neither available nor being deleted")
+	}
+	result.Decisions = withIgnoredDecisions(decisions, unmanaged, held, now)
+	result.Deleted, result.Failed = svc.deleteSnapshots(ctx, client, region, result.SnapshotIDPrefix, toDelete, deleting)
+
+	if len(result.Failed) > 0 {
+		result.Err = &DeletionError{Failures: result.Failed}
+	}
+	return result
 }
 
-func (svc *auroraBackupService) getDBSnapshotsByPrefix() ([]*rds.DBClusterSnapshot, error) {
+// listClusterSnapshots lists the snapshots with the prefix of the given cluster in a region.
+// An empty cluster ID lists every snapshot with the configured prefix, whatever its cluster.
+func (svc *auroraBackupService) listClusterSnapshots(ctx context.Context, client RDSClient, clusterID string) ([]*rds.DBClusterSnapshot, error) {
+	snapshots, err := svc.listSnapshotsByPrefix(ctx, client, svc.snapshotIDPrefixFor(clusterID))
+	if err != nil {
+		return nil, err
+	}
+	if clusterID != "" {
+		snapshots = filterSnapshotsByCluster(snapshots, clusterID)
+	}
+	return snapshots, nil
+}
+
+func (svc *auroraBackupService) getDBSnapshotsByPrefix(ctx context.Context, snapshotIDPrefix string) ([]*rds.DBClusterSnapshot, error) {
+	return svc.listSnapshotsByPrefix(ctx, svc.RDSClient, snapshotIDPrefix)
+}
+
+func (svc *auroraBackupService) listSnapshotsByPrefix(ctx context.Context, client RDSClient, snapshotIDPrefix string) ([]*rds.DBClusterSnapshot, error) {
 	var snapshots []*rds.DBClusterSnapshot
 	isLastPage := false
 	input := new(rds.DescribeDBClusterSnapshotsInput)
 	input.SetSnapshotType("manual")
 	for !isLastPage {
-		result, err := svc.DescribeDBClusterSnapshots(input)
+		result, err := client.DescribeDBClusterSnapshotsWithContext(ctx, input)
 		if err != nil {
 			return nil, err
 		}
 		for _, snapshot := range result.DBClusterSnapshots {
-			if strings.HasPrefix(*snapshot.DBClusterSnapshotIdentifier, svc.snapshotIDPrefix) {
+			if strings.HasPrefix(*snapshot.DBClusterSnapshotIdentifier, snapshotIDPrefix) {
 				snapshots = append(snapshots, snapshot)
 			}
 		}
@@ -208,17 +533,26 @@ func (svc *auroraBackupService) getDBSnapshotsByPrefix() ([]*rds.DBClusterSnapsh
 			isLastPage = true
 		}
 	}
-	return snapshots, nil
+	return svc.withIDTimes(snapshots, snapshotIDPrefix), nil
 }
 
-func (svc *auroraBackupService) checkSnapshotDeletion(snapshotID string) error {
+func (svc *auroraBackupService) checkSnapshotDeletion(ctx context.Context, snapshotID string) error {
+	return svc.waitForSnapshotDeletion(ctx, svc.RDSClient, snapshotID)
+}
+
+func (svc *auroraBackupService) waitForSnapshotDeletion(ctx context.Context, client RDSClient, snapshotID string) error {
 	input := new(rds.DescribeDBClusterSnapshotsInput)
 	input.SetDBClusterSnapshotIdentifier(snapshotID)
 
 	for attempt := 0; attempt < svc.statusCheckAttempts; attempt++ {
-		time.Sleep(svc.statusCheckInterval)
-		result, err := svc.DescribeDBClusterSnapshots(input)
+		if err := svc.pause(ctx, attempt); err != nil {
+			return &InterruptedError{SnapshotID: snapshotID, Operation: "deletion", Err: err}
+		}
+		result, err := client.DescribeDBClusterSnapshotsWithContext(ctx, input)
 		if err != nil {
+			if ctx.Err() != nil {
+				return &InterruptedError{SnapshotID: snapshotID, Operation: "deletion", Err: ctx.Err()}
+			}
 			switch err.(type) {
 			case awserr.Error:
 				if err.(awserr.Error).Code() == rds.ErrCodeDBClusterSnapshotNotFoundFault {
@@ -237,9 +571,9 @@ func (svc *auroraBackupService) checkSnapshotDeletion(snapshotID string) error {
 			if *result.DBClusterSnapshots[0].Status == statusDeleted {
 				return nil
 			} else {
-				return fmt.Errorf("unexpected snapshot status %v", *result.DBClusterSnapshots[0].Status)
+				return &UnexpectedStatusError{SnapshotID: snapshotID, Status: *result.DBClusterSnapshots[0].Status}
 			}
 		}
 	}
-	return errors.New("check for snapshot deletion time out")
+	return &SnapshotTimeoutError{SnapshotID: snapshotID, Operation: "deletion"}
 }
@@ -1,10 +1,16 @@
 package backup
 
 import (
+	"context"
+	"errors"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/rds"
 	log "github.com/sirupsen/logrus"
 	testLog "github.com/sirupsen/logrus/hooks/test"
@@ -27,7 +33,10 @@ func TestHappyBackup(t *testing.T) {
 	require.NoError(t, err)
 
 	backupTime := time.Now().UTC()
-	svc.MakeBackup()
+	results, err := svc.MakeBackup(context.Background())
+	assert.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.NotEmpty(t, results[0].SnapshotARN)
 
 	assertCorrectBackup(t, testSnapshotIDPrefix, backupTime)
 	cleanUpTestSnapshots(t, testSnapshotIDPrefix)
@@ -45,8 +54,9 @@ func TestUnhappyBackupDueMissingDBCluster(t *testing.T) {
 	svc, err := NewBackupService(region, testClusterIDPrefix+"-that-does-not-exist", testSnapshotIDPrefix, testStatusCheckInterval, testStatusCheckAttempts, 0)
 	require.NoError(t, err)
 
-	svc.MakeBackup()
+	_, err = svc.MakeBackup(context.Background())
 
+	assert.IsType(t, &ClusterNotFoundError{}, err)
 	assert.Equal(t, log.ErrorLevel, hook.LastEntry().Level)
 	assert.Equal(t, "Error in fetching DB cluster information from AWS", hook.LastEntry().Message)
 	assert.EqualError(t, hook.LastEntry().Data["error"].(error), "DB cluster not found with identifier prefix pac-aurora-staging-that-does-not-exist")
@@ -66,7 +76,9 @@ func TestUnhappyBackupDueDBClusterCreationError(t *testing.T) {
 	svc, err := NewBackupService(region, testClusterIDPrefix, "", testStatusCheckInterval, testStatusCheckAttempts, 0)
 	require.NoError(t, err)
 
-	svc.MakeBackup()
+	_, err = svc.MakeBackup(context.Background())
+
+	assert.Error(t, err)
 
 	assert.Equal(t, log.ErrorLevel, hook.LastEntry().Level)
 	assert.Equal(t, "Error in creating DB snapshot", hook.LastEntry().Message)
@@ -88,27 +100,28 @@ func TestBackupCleanupSnapshotsHigherRetention(t *testing.T) {
 
 	require.NoError(t, err)
 
-	clusterID, err := svc.(*auroraBackupService).getDBClusterID()
+	clusterID, err := svc.(*auroraBackupService).getDBClusterID(context.Background())
 	require.NoError(t, err)
 
 	var expectedSnapshotsIDs []string
 
 	for i := 0; i < totalSnapshots; i++ {
 		log.Infof("Waiting for cluster to to be ready for snapshot %v", i+1)
-		waitForClusterToBeReady(t, svc.(*auroraBackupService).RDS, clusterID)
+		waitForClusterToBeReady(t, svc.(*auroraBackupService).RDSClient, clusterID)
 		log.Infof("Creating snapshot %v", i+1)
-		snapshotID, err := svc.(*auroraBackupService).makeDBSnapshots(clusterID)
+		snapshotID, err := svc.(*auroraBackupService).makeDBSnapshots(context.Background(), clusterID)
 		require.NoError(t, err)
 		log.Infof("Waiting for snapshot %v to be ready", i+1)
-		err = svc.(*auroraBackupService).checkSnapshotCreation(snapshotID)
+		_, err = svc.(*auroraBackupService).checkSnapshotCreation(context.Background(), snapshotID)
 		require.NoError(t, err)
 		if i >= totalSnapshots-backupsRetention {
 			expectedSnapshotsIDs = append(expectedSnapshotsIDs, snapshotID)
 		}
 	}
 
-	svc.CleanUpOldBackups()
-	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix()
+	_, err = svc.CleanUpOldBackups(context.Background())
+	assert.NoError(t, err)
+	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix(context.Background(), testSnapshotIDPrefix)
 	assert.NoError(t, err)
 	assert.Len(t, snapshots, backupsRetention)
 	for _, snapshot := range snapshots {
@@ -131,25 +144,26 @@ func TestBackupCleanupSnapshotsLowerRetention(t *testing.T) {
 	svc, err := NewBackupService(region, testClusterIDPrefix, testSnapshotIDPrefix, testStatusCheckInterval, testStatusCheckAttempts, backupsRetention)
 	require.NoError(t, err)
 
-	clusterID, err := svc.(*auroraBackupService).getDBClusterID()
+	clusterID, err := svc.(*auroraBackupService).getDBClusterID(context.Background())
 	require.NoError(t, err)
 
 	var expectedSnapshotsIDs []string
 
 	for i := 0; i < totalSnapshots; i++ {
 		log.Infof("Waiting for cluster to to be ready for snapshot %v", i+1)
-		waitForClusterToBeReady(t, svc.(*auroraBackupService).RDS, clusterID)
+		waitForClusterToBeReady(t, svc.(*auroraBackupService).RDSClient, clusterID)
 		log.Infof("Creating snapshot %v", i+1)
-		snapshotID, err := svc.(*auroraBackupService).makeDBSnapshots(clusterID)
+		snapshotID, err := svc.(*auroraBackupService).makeDBSnapshots(context.Background(), clusterID)
 		require.NoError(t, err)
 		log.Infof("Waiting for snapshot %v to be ready", i+1)
-		err = svc.(*auroraBackupService).checkSnapshotCreation(snapshotID)
+		_, err = svc.(*auroraBackupService).checkSnapshotCreation(context.Background(), snapshotID)
 		require.NoError(t, err)
 		expectedSnapshotsIDs = append(expectedSnapshotsIDs, snapshotID)
 	}
 
-	svc.CleanUpOldBackups()
-	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix()
+	_, err = svc.CleanUpOldBackups(context.Background())
+	assert.NoError(t, err)
+	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix(context.Background(), testSnapshotIDPrefix)
 	assert.NoError(t, err)
 	assert.Len(t, snapshots, totalSnapshots)
 	for _, snapshot := range snapshots {
@@ -166,16 +180,17 @@ func TestCheckSnapshotCreationNotFoundError(t *testing.T) {
 	}
 
 	region := getAWSAccessConfig(t)
-	rdsSvc, err := newRDSService(region)
+	rdsSvc, err := newRDSService(region, "")
 	require.NoError(t, err)
 
 	svc := auroraBackupService{
-		RDS:                 rdsSvc,
+		RDSClient:           rdsSvc,
 		statusCheckInterval: testStatusCheckInterval,
 		statusCheckAttempts: testStatusCheckAttempts,
 	}
 
-	svc.checkSnapshotCreation("a-snapshot-that-does-not-exist")
+	_, err = svc.checkSnapshotCreation(context.Background(), "a-snapshot-that-does-not-exist")
+	assert.Error(t, err)
 }
 
 func TestCheckSnapshotDeletionUnexpectedStatusError(t *testing.T) {
@@ -184,39 +199,475 @@ func TestCheckSnapshotDeletionUnexpectedStatusError(t *testing.T) {
 	}
 
 	region := getAWSAccessConfig(t)
-	rdsSvc, err := newRDSService(region)
+	rdsSvc, err := newRDSService(region, "")
 	require.NoError(t, err)
 
 	svc := auroraBackupService{
-		RDS:                 rdsSvc,
+		RDSClient:           rdsSvc,
 		snapshotIDPrefix:    testSnapshotIDPrefix,
 		clusterIDPrefix:     testClusterIDPrefix,
 		statusCheckInterval: testStatusCheckInterval,
 		statusCheckAttempts: testStatusCheckAttempts,
 	}
 
-	clusterId, err := svc.getDBClusterID()
+	clusterId, err := svc.getDBClusterID(context.Background())
 	require.NoError(t, err)
 
-	snapshotID, err := svc.makeDBSnapshots(clusterId)
+	snapshotID, err := svc.makeDBSnapshots(context.Background(), clusterId)
 	require.NoError(t, err)
 
-	err = svc.checkSnapshotCreation(snapshotID)
+	_, err = svc.checkSnapshotCreation(context.Background(), snapshotID)
 	require.NoError(t, err)
 
-	err = svc.checkSnapshotDeletion(snapshotID)
+	err = svc.checkSnapshotDeletion(context.Background(), snapshotID)
 	assert.EqualError(t, err, "unexpected snapshot status available")
 
 	cleanUpTestSnapshots(t, testSnapshotIDPrefix)
 }
 
+func TestMakeBackupWithFakeRDS(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.CreationPolls = 3
+	fake.AddCluster("another-cluster")
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0)
+
+	backupTime := time.Now().UTC()
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	result := results[0]
+
+	snapshots := fake.Snapshots()
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, statusAvailable, *snapshots[0].Status)
+	assert.Equal(t, testClusterIDPrefix+"-eu", result.ClusterID)
+	assert.Equal(t, *snapshots[0].DBClusterSnapshotIdentifier, result.SnapshotID)
+	assert.Equal(t, *snapshots[0].DBClusterSnapshotArn, result.SnapshotARN)
+	assert.WithinDuration(t, backupTime, result.StartTime, 3*time.Second)
+	assert.True(t, result.CreationDuration > 0 && result.CreationDuration <= result.Duration, result.CreationDuration)
+	assert.Equal(t, testClusterIDPrefix+"-eu", *snapshots[0].DBClusterIdentifier)
+	backupTimeLabel, err := time.Parse(testSnapshotIDPrefix+"-"+snapshotIDDateFormat, *snapshots[0].DBClusterSnapshotIdentifier)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, backupTime, backupTimeLabel, 3*time.Second)
+	assert.Equal(t, 5, fake.Calls("DescribeDBClusterSnapshots"))
+}
+
+func TestMakeBackupWithFakeRDSSnapshotTags(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0,
+		WithSnapshotTags(map[string]string{"team": "pac", "environment": "staging", ManagedByTagKey: "someone"}),
+		WithManagedTags("pac-staging-eu", "pac-aurora-backup", ""))
+
+	_, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+
+	snapshots := fake.Snapshots()
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, []*rds.Tag{
+		{Key: aws.String("environment"), Value: aws.String("pac-staging-eu")},
+		{Key: aws.String("managed-by"), Value: aws.String("pac-aurora-backup")},
+		{Key: aws.String("system-code"), Value: aws.String("pac-aurora-backup")},
+		{Key: aws.String("team"), Value: aws.String("pac")},
+	}, snapshots[0].TagList)
+}
+
+func TestMakeBackupWithFakeRDSPaginatedClusters(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.PageSize = 2
+	for _, id := range []string{"cluster-a", "cluster-b", "cluster-c", "cluster-d"} {
+		fake.AddCluster(id)
+	}
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0)
+
+	clusterID, err := svc.getDBClusterID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, testClusterIDPrefix+"-eu", clusterID)
+	assert.Equal(t, 3, fake.Calls("DescribeDBClusters"))
+}
+
+func TestMakeBackupWithFakeRDSMissingDBCluster(t *testing.T) {
+	hook := testLog.NewGlobal()
+	fake := awsfake.NewRDS()
+	fake.AddCluster("another-cluster")
+	svc := newFakeBackupService(t, fake, 0)
+
+	_, err := svc.MakeBackup(context.Background())
+
+	assert.Equal(t, &ClusterNotFoundError{ClusterIDPrefix: testClusterIDPrefix}, err)
+	assert.Equal(t, log.ErrorLevel, hook.LastEntry().Level)
+	assert.Equal(t, "Error in fetching DB cluster information from AWS", hook.LastEntry().Message)
+	assert.EqualError(t, hook.LastEntry().Data["error"].(error), "DB cluster not found with identifier prefix "+testClusterIDPrefix)
+	assert.Empty(t, fake.Snapshots())
+}
+
+func TestMakeBackupWithFakeRDSCreationError(t *testing.T) {
+	hook := testLog.NewGlobal()
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.FailNext("CreateDBClusterSnapshot", awserr.New(rds.ErrCodeSnapshotQuotaExceededFault, "quota exceeded", nil))
+	svc := newFakeBackupService(t, fake, 0)
+
+	results, err := svc.MakeBackup(context.Background())
+
+	assertAWSErrorCode(t, rds.ErrCodeSnapshotQuotaExceededFault, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, testClusterIDPrefix+"-eu", results[0].ClusterID)
+	assert.Empty(t, results[0].SnapshotARN)
+	assertAWSErrorCode(t, rds.ErrCodeSnapshotQuotaExceededFault, results[0].Err)
+	assert.Equal(t, log.ErrorLevel, hook.LastEntry().Level)
+	assert.Equal(t, "Error in creating DB snapshot", hook.LastEntry().Message)
+	assert.Empty(t, fake.Snapshots())
+}
+
+func TestCheckSnapshotCreationWithFakeRDSTimeout(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.CreationPolls = testStatusCheckAttempts
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0)
+
+	snapshotID, err := svc.makeDBSnapshots(context.Background(), testClusterIDPrefix+"-eu")
+	require.NoError(t, err)
+
+	_, err = svc.checkSnapshotCreation(context.Background(), snapshotID)
+	assert.Equal(t, &SnapshotTimeoutError{SnapshotID: snapshotID, Operation: "creation"}, err)
+}
+
+func TestCheckSnapshotCreationWithFakeRDSNotFound(t *testing.T) {
+	fake := awsfake.NewRDS()
+	svc := newFakeBackupService(t, fake, 0)
+
+	_, err := svc.checkSnapshotCreation(context.Background(), "a-snapshot-that-does-not-exist")
+	assertAWSErrorCode(t, rds.ErrCodeDBClusterSnapshotNotFoundFault, err)
+}
+
+func TestCheckSnapshotDeletionWithFakeRDSUnexpectedStatus(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-2018-01-12-12-00-00"),
+	})
+	svc := newFakeBackupService(t, fake, 0)
+
+	err := svc.checkSnapshotDeletion(context.Background(), testSnapshotIDPrefix+"-2018-01-12-12-00-00")
+	assert.Equal(t, &UnexpectedStatusError{SnapshotID: testSnapshotIDPrefix + "-2018-01-12-12-00-00", Status: statusAvailable}, err)
+	assert.EqualError(t, err, "unexpected snapshot status available")
+}
+
+func TestCleanUpOldBackupsWithFakeRDS(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.PageSize = 3
+	fake.DeletionPolls = 2
+	now := time.Now().UTC()
+	for i := 0; i < 7; i++ {
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+			TagList:                     testManagedTags,
+			AllocatedStorage:            aws.Int64(int64(i + 1)),
+		})
+	}
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String("manual-before-migration"),
+		SnapshotCreateTime:          aws.Time(now.AddDate(-1, 0, 0)),
+	})
+	svc := newFakeBackupService(t, fake, 4)
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	result := results[0]
+	assert.Equal(t, testSnapshotIDPrefix, result.SnapshotIDPrefix)
+	assert.Len(t, result.Retained, 4)
+	assert.Equal(t, int64(1+2+3+4)<<30, result.RetainedBytes)
+	assert.ElementsMatch(t, []string{
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -4).Format(snapshotIDDateFormat),
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -5).Format(snapshotIDDateFormat),
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -6).Format(snapshotIDDateFormat),
+	}, result.Deleted)
+	assert.Empty(t, result.Failed)
+
+	var snapshotIDs []string
+	for _, snapshot := range fake.Snapshots() {
+		snapshotIDs = append(snapshotIDs, *snapshot.DBClusterSnapshotIdentifier)
+	}
+	assert.ElementsMatch(t, []string{
+		testSnapshotIDPrefix + "-" + now.Format(snapshotIDDateFormat),
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -1).Format(snapshotIDDateFormat),
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -2).Format(snapshotIDDateFormat),
+		testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -3).Format(snapshotIDDateFormat),
+		"manual-before-migration",
+	}, snapshotIDs)
+	assert.Equal(t, 3, fake.Calls("DeleteDBClusterSnapshot"))
+}
+
+func TestCleanUpOldBackupsWithFakeRDSRetentionPolicy(t *testing.T) {
+	fake := awsfake.NewRDS()
+	now := time.Now().UTC()
+	for i := 0; i < 60; i++ {
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+			TagList:                     testManagedTags,
+		})
+	}
+	policy := RetentionPolicy{Daily: 7, Monthly: 3}
+	svc := newFakeBackupService(t, fake, 35, WithRetentionPolicy(policy))
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	result := results[0]
+	assert.Equal(t, policy, result.Policy)
+
+	retained, expired := splitDecisions(decideRetention(fake.Snapshots(), policy, time.Now(), 0, 0))
+	assert.Empty(t, expired)
+	assert.Equal(t, retained, result.Retained)
+	assert.Len(t, result.Deleted, 60-len(result.Retained))
+	for i := 0; i < 7; i++ {
+		assert.Contains(t, result.Retained, testSnapshotIDPrefix+"-"+now.AddDate(0, 0, -i).Format(snapshotIDDateFormat))
+	}
+	assert.True(t, len(result.Retained) > 7, "expected monthly snapshots to be retained: %v", result.Retained)
+}
+
+func TestCleanUpOldBackupsWithFakeRDSBackupAgeLimits(t *testing.T) {
+	fake := awsfake.NewRDS()
+	now := time.Now().UTC()
+	for _, age := range []time.Duration{time.Hour, 20 * time.Hour, 30 * time.Hour, 10 * 24 * time.Hour, 40 * 24 * time.Hour} {
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.Add(-age).Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(now.Add(-age)),
+			TagList:                     testManagedTags,
+		})
+	}
+	svc := newFakeBackupService(t, fake, 0, WithBackupAgeLimits(24*time.Hour, 30*24*time.Hour))
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{
+		testSnapshotIDPrefix + "-" + now.Add(-time.Hour).Format(snapshotIDDateFormat),
+		testSnapshotIDPrefix + "-" + now.Add(-20*time.Hour).Format(snapshotIDDateFormat),
+	}, results[0].Retained)
+	assert.Len(t, results[0].Deleted, 3)
+
+	svc = newFakeBackupService(t, fake, 5, WithBackupAgeLimits(0, 12*time.Hour))
+	results, err = svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{testSnapshotIDPrefix + "-" + now.Add(-time.Hour).Format(snapshotIDDateFormat)}, results[0].Retained)
+	assert.Len(t, fake.Snapshots(), 1)
+}
+
+func TestNewBackupServiceInvalidBackupAgeLimits(t *testing.T) {
+	for _, limits := range [][2]time.Duration{
+		{-time.Hour, 0},
+		{0, -time.Hour},
+		{48 * time.Hour, 24 * time.Hour},
+	} {
+		_, err := NewBackupService("eu-west-1", testClusterIDPrefix, testSnapshotIDPrefix, 0, testStatusCheckAttempts, 1,
+			WithRDSClient(awsfake.NewRDS()), WithBackupAgeLimits(limits[0], limits[1]))
+		assert.Error(t, err, "limits %v", limits)
+	}
+}
+
+func TestCleanUpOldBackupsWithFakeRDSDeletionError(t *testing.T) {
+	hook := testLog.NewGlobal()
+	fake := awsfake.NewRDS()
+	now := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+			TagList:                     testManagedTags,
+		})
+	}
+	fake.FailNext("DeleteDBClusterSnapshot", awserr.New(rds.ErrCodeInvalidDBClusterSnapshotStateFault, "snapshot is in use", nil))
+	svc := newFakeBackupService(t, fake, 1)
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+
+	var deletionErr *DeletionError
+	require.True(t, errors.As(err, &deletionErr), "unexpected error: %v", err)
+	require.Len(t, results, 1)
+	result := results[0]
+	assert.Equal(t, result.Failed, deletionErr.Failures)
+	require.Len(t, result.Failed, 1)
+	assert.Equal(t, testSnapshotIDPrefix+"-"+now.AddDate(0, 0, -1).Format(snapshotIDDateFormat), result.Failed[0].SnapshotID)
+	assert.Equal(t, []string{testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -2).Format(snapshotIDDateFormat)}, result.Deleted)
+	assert.Len(t, fake.Snapshots(), 2)
+	var errorEntries int
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == log.ErrorLevel {
+			errorEntries++
+			assert.Equal(t, "Error in deleting DB cluster snapshot for cleanup", entry.Message)
+		}
+	}
+	assert.Equal(t, 1, errorEntries)
+}
+
+func TestCleanUpOldBackupsWithFakeRDSListingError(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.FailNext("DescribeDBClusterSnapshots", awserr.New("Throttling", "rate exceeded", nil))
+	svc := newFakeBackupService(t, fake, 1)
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+
+	assertAWSErrorCode(t, "Throttling", err)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Retained)
+	assertAWSErrorCode(t, "Throttling", results[0].Err)
+}
+
+func TestMakeBackupWithFakeRDSIgnoresClustersSharingPrefixWithoutHyphen(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "2")
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0)
+
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, testClusterIDPrefix+"-eu", results[0].ClusterID)
+}
+
+func TestMakeBackupWithFakeRDSClusterDiscovery(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.PageSize = 2
+	fake.CreationPolls = 2
+	clusterIDs := []string{testClusterIDPrefix, testClusterIDPrefix + "-eu", testClusterIDPrefix + "-us", testClusterIDPrefix + "-eu-2"}
+	for _, clusterID := range clusterIDs {
+		fake.AddCluster(clusterID)
+	}
+	fake.AddCluster(testClusterIDPrefix + "2")
+	svc := newFakeBackupService(t, fake, 0, WithClusterDiscovery(2))
+
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, len(clusterIDs))
+
+	for i, result := range results {
+		assert.Equal(t, clusterIDs[i], result.ClusterID)
+		assert.NoError(t, result.Err)
+		snapshot := fake.Snapshot(result.SnapshotID)
+		require.NotNil(t, snapshot)
+		assert.Equal(t, clusterIDs[i], *snapshot.DBClusterIdentifier)
+		assert.Equal(t, statusAvailable, *snapshot.Status)
+		assert.Regexp(t, "^"+clusterIDs[i]+"-test-backup-[0-9-]+$", result.SnapshotID)
+	}
+	assert.Len(t, fake.Snapshots(), len(clusterIDs))
+}
+
+func TestMakeBackupWithFakeRDSClusterDiscoveryPartialFailure(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.AddCluster(testClusterIDPrefix + "-us").SetStatus("stopped")
+	svc := newFakeBackupService(t, fake, 0, WithClusterDiscovery(1))
+
+	results, err := svc.MakeBackup(context.Background())
+
+	require.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.NotEmpty(t, results[0].SnapshotARN)
+	var preflightErr *PreflightError
+	require.True(t, errors.As(results[1].Err, &preflightErr), "unexpected error: %v", results[1].Err)
+	assert.Equal(t, []string{"cluster status is stopped"}, preflightErr.Conditions)
+	assert.Empty(t, results[1].SnapshotID)
+	var clusterErr *ClusterError
+	require.True(t, errors.As(err, &clusterErr), "unexpected error: %v", err)
+	assert.Equal(t, testClusterIDPrefix+"-us", clusterErr.ClusterID)
+}
+
+func TestCleanUpOldBackupsWithFakeRDSClusterDiscovery(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	fake.AddCluster(testClusterIDPrefix + "-us")
+	now := time.Now().UTC()
+	for _, clusterID := range []string{testClusterIDPrefix + "-eu", testClusterIDPrefix + "-us"} {
+		for i := 0; i < 4; i++ {
+			fake.AddSnapshot(&rds.DBClusterSnapshot{
+				DBClusterIdentifier:         aws.String(clusterID),
+				DBClusterSnapshotIdentifier: aws.String(clusterID + "-test-backup-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+				SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+				TagList:                     testManagedTags,
+			})
+		}
+	}
+	legacySnapshotID := testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -10).Format(snapshotIDDateFormat)
+	fake.AddSnapshot(&rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+		DBClusterSnapshotIdentifier: aws.String(legacySnapshotID),
+		SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -10)),
+	})
+	svc := newFakeBackupService(t, fake, 2, WithClusterDiscovery(2))
+
+	results, err := svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	for _, result := range results {
+		assert.Equal(t, result.ClusterID+"-test-backup", result.SnapshotIDPrefix)
+		assert.Equal(t, []string{
+			result.ClusterID + "-test-backup-" + now.Format(snapshotIDDateFormat),
+			result.ClusterID + "-test-backup-" + now.AddDate(0, 0, -1).Format(snapshotIDDateFormat),
+		}, result.Retained)
+		assert.Len(t, result.Deleted, 2)
+	}
+	assert.Len(t, fake.Snapshots(), 5)
+	assert.NotNil(t, fake.Snapshot(legacySnapshotID))
+}
+
+func TestForEachClusterBoundsConcurrency(t *testing.T) {
+	svc := &auroraBackupService{concurrency: 3}
+	var mu sync.Mutex
+	var running, maxRunning int
+	visited := make([]bool, 10)
+
+	svc.forEachCluster(make([]string, 10), func(i int, _ string) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		visited[i] = true
+		mu.Unlock()
+	})
+
+	assert.Equal(t, 3, maxRunning)
+	for _, v := range visited {
+		assert.True(t, v)
+	}
+}
+
+func newFakeBackupService(t *testing.T, fake *awsfake.RDS, backupsRetention int, opts ...Option) *auroraBackupService {
+	svc, err := NewBackupService(fake.Region, testClusterIDPrefix, testSnapshotIDPrefix, 0, testStatusCheckAttempts, backupsRetention, append(opts, WithRDSClient(fake))...)
+	require.NoError(t, err)
+	return svc.(*auroraBackupService)
+}
+
+func assertAWSErrorCode(t *testing.T, expectedCode string, err error) {
+	var awsErr awserr.Error
+	require.True(t, errors.As(err, &awsErr), "unexpected error: %v", err)
+	assert.Equal(t, expectedCode, awsErr.Code())
+}
+
 func assertCorrectBackup(t *testing.T, snapshotIDPrefix string, expectedBackupTime time.Time) {
 	region := getAWSAccessConfig(t)
 
 	svc, err := NewBackupService(region, testClusterIDPrefix, snapshotIDPrefix, testStatusCheckInterval, testStatusCheckAttempts, 0)
 	require.NoError(t, err)
 
-	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix()
+	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix(context.Background(), snapshotIDPrefix)
 	assert.NoError(t, err)
 	assert.Len(t, snapshots, 1)
 
@@ -231,7 +682,7 @@ func cleanUpTestSnapshots(t *testing.T, snapshotIDPrefix string) {
 	svc, err := NewBackupService(region, testClusterIDPrefix, snapshotIDPrefix, testStatusCheckInterval, testStatusCheckAttempts, 0)
 	require.NoError(t, err)
 
-	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix()
+	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix(context.Background(), snapshotIDPrefix)
 	require.NoError(t, err)
 
 	for _, snapshot := range snapshots {
@@ -239,18 +690,18 @@ func cleanUpTestSnapshots(t *testing.T, snapshotIDPrefix string) {
 			Info("cleaning up test snapshot")
 		input := new(rds.DeleteDBClusterSnapshotInput)
 		input.SetDBClusterSnapshotIdentifier(*snapshot.DBClusterSnapshotIdentifier)
-		_, err = svc.(*auroraBackupService).RDS.DeleteDBClusterSnapshot(input)
+		_, err = svc.(*auroraBackupService).RDSClient.DeleteDBClusterSnapshotWithContext(context.Background(), input)
 		require.NoError(t, err)
-		err = svc.(*auroraBackupService).checkSnapshotDeletion(*snapshot.DBClusterSnapshotIdentifier)
+		err = svc.(*auroraBackupService).checkSnapshotDeletion(context.Background(), *snapshot.DBClusterSnapshotIdentifier)
 		require.NoError(t, err)
 	}
 }
 
-func waitForClusterToBeReady(t *testing.T, svc *rds.RDS, clusterID string) {
+func waitForClusterToBeReady(t *testing.T, svc RDSClient, clusterID string) {
 	for i := 0; i < 20; i++ {
 		input := new(rds.DescribeDBClustersInput)
 		input.SetDBClusterIdentifier(clusterID)
-		result, err := svc.DescribeDBClusters(input)
+		result, err := svc.DescribeDBClustersWithContext(context.Background(), input)
 		require.NoError(t, err)
 
 		require.Len(t, result.DBClusters, 1)
@@ -269,7 +720,7 @@ func assertBackupNotExist(t *testing.T, snapshotIDPrefix string) {
 	svc, err := NewBackupService(region, testClusterIDPrefix, snapshotIDPrefix, testStatusCheckInterval, testStatusCheckAttempts, 0)
 	require.NoError(t, err)
 
-	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix()
+	snapshots, err := svc.(*auroraBackupService).getDBSnapshotsByPrefix(context.Background(), snapshotIDPrefix)
 	require.NoError(t, err)
 
 	assert.Empty(t, snapshots)
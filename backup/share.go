@@ -0,0 +1,68 @@
+package backup
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+const snapshotAttributeRestore = "restore"
+
+// VaultCopy is a backup vault AWS account where every new snapshot is copied,
+// so that the backups survive the compromise of the account owning the cluster.
+// The copy is made by assuming RoleARN in the vault account and is encrypted with
+// KMSKeyID, a key owned by the vault account, when the cluster is encrypted.
+type VaultCopy struct {
+	AccountID string
+	RoleARN   string
+	Region    string
+	KMSKeyID  string
+}
+
+// WithSnapshotSharing makes the service share every new snapshot with the given AWS accounts.
+func WithSnapshotSharing(accountIDs ...string) Option {
+	return func(svc *auroraBackupService) {
+		svc.shareWithAccounts = append(svc.shareWithAccounts, accountIDs...)
+	}
+}
+
+// WithVaultCopy makes the service share every new snapshot with the vault account
+// and copy it there, waiting for the copy to become available.
+func WithVaultCopy(vault VaultCopy) Option {
+	return func(svc *auroraBackupService) {
+		svc.vault = &vault
+	}
+}
+
+// sharingAccountIDs returns the accounts a new snapshot is shared with, including the vault account.
+func (svc *auroraBackupService) sharingAccountIDs() []string {
+	accountIDs := append([]string(nil), svc.shareWithAccounts...)
+	if svc.vault == nil {
+		return accountIDs
+	}
+	for _, accountID := range accountIDs {
+		if accountID == svc.vault.AccountID {
+			return accountIDs
+		}
+	}
+	return append(accountIDs, svc.vault.AccountID)
+}
+
+func (svc *auroraBackupService) shareSnapshot(ctx context.Context, snapshotID string, accountIDs []string) error {
+	log.WithField("snapshotID", snapshotID).
+		WithField("accountIDs", accountIDs).
+		Info("Sharing snapshot with AWS accounts")
+	input := new(rds.ModifyDBClusterSnapshotAttributeInput)
+	input.SetDBClusterSnapshotIdentifier(snapshotID)
+	input.SetAttributeName(snapshotAttributeRestore)
+	input.SetValuesToAdd(aws.StringSlice(accountIDs))
+	_, err := svc.ModifyDBClusterSnapshotAttributeWithContext(ctx, input)
+	if err != nil {
+		log.WithField("snapshotID", snapshotID).
+			WithError(err).
+			Error("Error in sharing snapshot with AWS accounts")
+	}
+	return err
+}
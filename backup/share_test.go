@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testVaultAccountID = "210987654321"
+const testVaultRoleARN = "arn:aws:iam::" + testVaultAccountID + ":role/pac-aurora-backup-vault"
+
+func TestMakeBackupSharesSnapshotAndCopiesItToVault(t *testing.T) {
+	source, vault := newFakeVault()
+	source.AddCluster(testClusterIDPrefix + "-eu")
+	var assumedRoleARN string
+	svc := newFakeBackupService(t, source, 0,
+		WithClientFactory(func(region, roleARN string) (RDSClient, error) {
+			assumedRoleARN = roleARN
+			return vault, nil
+		}),
+		WithSnapshotSharing("111111111111"),
+		WithVaultCopy(VaultCopy{AccountID: testVaultAccountID, RoleARN: testVaultRoleARN, Region: "eu-west-1", KMSKeyID: "a-vault-key"}))
+
+	results, err := svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	result := results[0]
+
+	assert.Equal(t, testVaultRoleARN, assumedRoleARN)
+	assert.Equal(t, []string{"111111111111", testVaultAccountID}, result.SharedWith)
+	assert.Equal(t, []string{"111111111111", testVaultAccountID}, source.SharedWith(result.SnapshotID))
+
+	require.Len(t, result.Copies, 1)
+	assert.NoError(t, result.Copies[0].Err)
+	assert.Equal(t, testVaultAccountID, result.Copies[0].AccountID)
+	vaultCopy := vault.Snapshot(result.SnapshotID)
+	require.NotNil(t, vaultCopy)
+	assert.Equal(t, statusAvailable, *vaultCopy.Status)
+	assert.Equal(t, "a-vault-key", *vaultCopy.KmsKeyId)
+	assert.Equal(t, result.SnapshotARN, *vaultCopy.SourceDBClusterSnapshotArn)
+	assert.Equal(t, "arn:aws:rds:eu-west-1:"+testVaultAccountID+":cluster-snapshot:"+result.SnapshotID, result.Copies[0].SnapshotARN)
+}
+
+func TestMakeBackupSharingFailureSkipsVaultCopy(t *testing.T) {
+	source, vault := newFakeVault()
+	source.AddCluster(testClusterIDPrefix + "-eu")
+	source.FailNext("ModifyDBClusterSnapshotAttribute", awserr.New("SharedSnapshotQuotaExceeded", "too many accounts", nil))
+	svc := newFakeBackupService(t, source, 0,
+		WithClientFactory(func(region, roleARN string) (RDSClient, error) { return vault, nil }),
+		WithVaultCopy(VaultCopy{AccountID: testVaultAccountID, RoleARN: testVaultRoleARN, Region: "eu-west-1"}))
+
+	results, err := svc.MakeBackup(context.Background())
+
+	var shareErr *ShareError
+	require.True(t, errors.As(err, &shareErr), "unexpected error: %v", err)
+	assert.Equal(t, []string{testVaultAccountID}, shareErr.AccountIDs)
+	require.Len(t, results, 1)
+	assert.NotEmpty(t, results[0].SnapshotARN)
+	assert.Empty(t, results[0].SharedWith)
+	assert.Empty(t, results[0].Copies)
+	assert.Empty(t, vault.Snapshots())
+}
+
+func TestMakeBackupVaultCopyNeedsSharing(t *testing.T) {
+	source, vault := newFakeVault()
+	source.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, source, 0,
+		WithClientFactory(func(region, roleARN string) (RDSClient, error) { return vault, nil }),
+		WithVaultCopy(VaultCopy{AccountID: testVaultAccountID, RoleARN: testVaultRoleARN, Region: "eu-west-1"}))
+	svc.vault.AccountID = "111111111111"
+
+	results, err := svc.MakeBackup(context.Background())
+
+	var copyErr *CopyError
+	require.True(t, errors.As(err, &copyErr), "unexpected error: %v", err)
+	assert.Equal(t, "111111111111", copyErr.AccountID)
+	assert.Equal(t, []string{"111111111111"}, results[0].SharedWith)
+	assert.Empty(t, vault.Snapshots())
+}
+
+func newFakeVault() (*awsfake.RDS, *awsfake.RDS) {
+	source := awsfake.NewRDS()
+	vault := awsfake.NewRDS()
+	vault.AccountID = testVaultAccountID
+	vault.AddPeer(source)
+	return source, vault
+}
@@ -0,0 +1,80 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// ClusterStatus describes the backups of a DB cluster reported by Status.
+// On failure it holds whatever was known before the error occurred.
+type ClusterStatus struct {
+	ClusterID     string
+	ClusterStatus string
+	// Snapshots is the number of backup snapshots of the cluster in the source region.
+	Snapshots          int
+	LatestSnapshotID   string
+	LatestSnapshotTime time.Time
+	// InProgress lists the backup snapshots being created or deleted.
+	InProgress []string
+	// PreflightConditions lists the conditions that would currently prevent a backup.
+	PreflightConditions []string
+	Err                 error
+}
+
+// Status reports, for every cluster to back up, its latest available backup snapshot,
+// the backup snapshots in progress and whether a backup could start now, only calling read-only AWS APIs.
+func (svc *auroraBackupService) Status(ctx context.Context) ([]*ClusterStatus, error) {
+	clusterIDs, err := svc.getBackupClusterIDs(ctx)
+	if err != nil {
+		log.WithError(err).Error("Error in fetching DB cluster information from AWS")
+		return nil, err
+	}
+
+	statuses := make([]*ClusterStatus, len(clusterIDs))
+	svc.forEachCluster(clusterIDs, func(i int, clusterID string) {
+		statuses[i] = svc.clusterStatus(ctx, clusterID)
+	})
+
+	var errs []error
+	for _, status := range statuses {
+		if status.Err != nil {
+			errs = append(errs, &ClusterError{ClusterID: status.ClusterID, Err: status.Err})
+		}
+	}
+	return statuses, errors.Join(errs...)
+}
+
+func (svc *auroraBackupService) clusterStatus(ctx context.Context, clusterID string) *ClusterStatus {
+	status := &ClusterStatus{ClusterID: clusterID}
+	cluster, err := svc.describeCluster(ctx, clusterID)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	status.ClusterStatus = aws.StringValue(cluster.Status)
+
+	snapshots, err := svc.listClusterSnapshots(ctx, svc.RDSClient, clusterID)
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	status.Snapshots = len(snapshots)
+	for _, snapshot := range snapshots {
+		switch aws.StringValue(snapshot.Status) {
+		case statusAvailable:
+			if snapshot.SnapshotCreateTime != nil && snapshot.SnapshotCreateTime.After(status.LatestSnapshotTime) {
+				status.LatestSnapshotID = aws.StringValue(snapshot.DBClusterSnapshotIdentifier)
+				status.LatestSnapshotTime = *snapshot.SnapshotCreateTime
+			}
+		case statusCreating, statusDeleting:
+			status.InProgress = append(status.InProgress, aws.StringValue(snapshot.DBClusterSnapshotIdentifier))
+		}
+	}
+
+	status.PreflightConditions, status.Err = svc.checkPreflightConditions(ctx, clusterID)
+	return status
+}
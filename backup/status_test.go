@@ -0,0 +1,56 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusWithFakeRDS(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.CreationPolls = 100
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	now := time.Now().UTC()
+	for i, status := range []string{statusCreating, statusAvailable, statusAvailable} {
+		fake.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String(testClusterIDPrefix + "-eu"),
+			DBClusterSnapshotIdentifier: aws.String(testSnapshotIDPrefix + "-" + now.AddDate(0, 0, -i).Format(snapshotIDDateFormat)),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+			Status:                      aws.String(status),
+		})
+	}
+	svc := newFakeBackupService(t, fake, 0)
+
+	statuses, err := svc.Status(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, statuses, 1)
+	status := statuses[0]
+	assert.Equal(t, testClusterIDPrefix+"-eu", status.ClusterID)
+	assert.Equal(t, statusAvailable, status.ClusterStatus)
+	assert.Equal(t, 3, status.Snapshots)
+	assert.Equal(t, testSnapshotIDPrefix+"-"+now.AddDate(0, 0, -1).Format(snapshotIDDateFormat), status.LatestSnapshotID)
+	assert.WithinDuration(t, now.AddDate(0, 0, -1), status.LatestSnapshotTime, time.Second)
+	assert.Equal(t, []string{testSnapshotIDPrefix + "-" + now.Format(snapshotIDDateFormat)}, status.InProgress)
+	assert.Equal(t, []string{"snapshot " + status.InProgress[0] + " is being created"}, status.PreflightConditions)
+}
+
+func TestStatusWithFakeRDSNoBackup(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0)
+
+	statuses, err := svc.Status(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, statuses, 1)
+	assert.Zero(t, statuses[0].Snapshots)
+	assert.Empty(t, statuses[0].LatestSnapshotID)
+	assert.True(t, statuses[0].LatestSnapshotTime.IsZero())
+}
@@ -0,0 +1,166 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	log "github.com/sirupsen/logrus"
+)
+
+// verificationClusterInfix separates the cluster identifier prefix from the creation time
+// in the identifiers of the temporary clusters restored by Verify, e.g. pac-aurora-prod-verify-20240105120000.
+const verificationClusterInfix = "-verify-"
+
+const verificationClusterIDDateFormat = "20060102150405"
+
+// VerifyRequest describes the temporary DB cluster restored by Verify.
+type VerifyRequest struct {
+	// SourceClusterID is the cluster whose latest backup snapshot is verified,
+	// by default the first cluster matching the cluster identifier prefix.
+	SourceClusterID string
+	// InstanceClass overrides the class of the instance of the temporary cluster.
+	InstanceClass string
+	// Engines lists the engines the check can connect to. A snapshot of another engine is not restored.
+	Engines []string
+}
+
+// VerifyResult describes the outcome of Verify.
+// On failure it holds whatever was known before the error occurred.
+type VerifyResult struct {
+	// ClusterID is the identifier of the temporary cluster.
+	ClusterID string
+	Restore   *RestoreResult
+	Duration  time.Duration
+	// TeardownErr is the error that prevented the deletion of the temporary cluster.
+	TeardownErr error
+}
+
+// CheckFunc checks the data of a restored cluster.
+type CheckFunc func(ctx context.Context, restored *RestoreResult) error
+
+// Verify restores the latest backup snapshot into a temporary cluster with a single instance,
+// runs check against it once available, and then deletes the temporary cluster and its instance,
+// whether the restore and the check succeeded or not. The deletion is not interrupted by ctx,
+// so that a cancelled run does not leave a temporary cluster behind.
+func (svc *auroraBackupService) Verify(ctx context.Context, req VerifyRequest, check CheckFunc) (*VerifyResult, error) {
+	start := time.Now()
+	result := &VerifyResult{ClusterID: svc.verificationClusterID(start)}
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	restored, err := svc.Restore(ctx, RestoreRequest{
+		SnapshotID:      LatestSnapshot,
+		SourceClusterID: req.SourceClusterID,
+		TargetClusterID: result.ClusterID,
+		InstanceClass:   req.InstanceClass,
+		SingleInstance:  true,
+		Engines:         req.Engines,
+	})
+	result.Restore = restored
+	if err == nil {
+		log.WithField("clusterID", result.ClusterID).
+			WithField("snapshotID", restored.SnapshotID).
+			Info("Checking data of restored cluster")
+		if err = check(ctx, restored); err != nil {
+			err = fmt.Errorf("checking restored cluster %v: %w", result.ClusterID, err)
+		}
+	}
+
+	log.WithField("clusterID", result.ClusterID).Info("Deleting temporary verification cluster")
+	result.TeardownErr = svc.deleteVerificationCluster(context.WithoutCancel(ctx), result.ClusterID)
+	if result.TeardownErr != nil {
+		log.WithError(result.TeardownErr).WithField("clusterID", result.ClusterID).Error("Temporary verification cluster not deleted")
+	}
+	return result, errors.Join(err, result.TeardownErr)
+}
+
+// verificationClusterID returns the identifier of a temporary verification cluster created at the given time.
+func (svc *auroraBackupService) verificationClusterID(t time.Time) string {
+	return svc.clusterIDPrefix + verificationClusterInfix + t.UTC().Format(verificationClusterIDDateFormat)
+}
+
+// isVerificationCluster reports whether the cluster is a temporary verification cluster,
+// so that it is never discovered as a cluster to back up.
+func (svc *auroraBackupService) isVerificationCluster(clusterID string) bool {
+	return strings.HasPrefix(clusterID, svc.clusterIDPrefix+verificationClusterInfix)
+}
+
+// deleteVerificationCluster deletes a temporary verification cluster and its instances without final snapshot,
+// and waits until the cluster is gone. Instances and clusters still being created are deleted once they
+// are available, and a cluster that does not exist is considered deleted.
+func (svc *auroraBackupService) deleteVerificationCluster(ctx context.Context, clusterID string) error {
+	if !svc.isVerificationCluster(clusterID) {
+		return fmt.Errorf("refusing to delete cluster %v: not a verification cluster", clusterID)
+	}
+	for attempt := 0; attempt < svc.statusCheckAttempts; attempt++ {
+		deleted, err := svc.deleteClusterStep(ctx, clusterID)
+		if err != nil || deleted {
+			return err
+		}
+		if err := svc.pause(ctx, attempt); err != nil {
+			return &InterruptedError{ClusterID: clusterID, Operation: "deletion", Err: err}
+		}
+	}
+	return &ClusterTimeoutError{ClusterID: clusterID, Operation: "deletion"}
+}
+
+// deleteClusterStep requests the deletion of the instances and then of the cluster as soon as
+// their status allows it, and reports whether the cluster no longer exists.
+func (svc *auroraBackupService) deleteClusterStep(ctx context.Context, clusterID string) (bool, error) {
+	cluster, err := svc.describeCluster(ctx, clusterID)
+	if isAWSErrorCode(err, rds.ErrCodeDBClusterNotFoundFault) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if aws.StringValue(cluster.Status) == statusDeleting {
+		return false, nil
+	}
+
+	instances, err := svc.describeClusterInstances(ctx, clusterID)
+	if err != nil {
+		return false, err
+	}
+	pending := aws.StringValue(cluster.Status) == statusCreating
+	for _, instance := range instances {
+		switch aws.StringValue(instance.DBInstanceStatus) {
+		case statusCreating:
+			pending = true
+			continue
+		case statusDeleting:
+			continue
+		}
+		input := new(rds.DeleteDBInstanceInput)
+		input.SetDBInstanceIdentifier(aws.StringValue(instance.DBInstanceIdentifier))
+		if _, err := svc.DeleteDBInstanceWithContext(ctx, input); err != nil && !isAWSErrorCode(err, rds.ErrCodeDBInstanceNotFoundFault) {
+			return false, fmt.Errorf("deleting instance %v: %w", aws.StringValue(instance.DBInstanceIdentifier), err)
+		}
+	}
+	if pending {
+		return false, nil
+	}
+
+	input := new(rds.DeleteDBClusterInput)
+	input.SetDBClusterIdentifier(clusterID)
+	input.SetSkipFinalSnapshot(true)
+	if _, err := svc.DeleteDBClusterWithContext(ctx, input); err != nil {
+		if isAWSErrorCode(err, rds.ErrCodeDBClusterNotFoundFault) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+func isAWSErrorCode(err error, code string) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == code
+}
@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyWithFakeRDS(t *testing.T) {
+	fake, now := newRestoreFake(t)
+	svc := newFakeBackupService(t, fake, 0)
+
+	var checked *RestoreResult
+	result, err := svc.Verify(context.Background(), VerifyRequest{InstanceClass: "db.t4g.medium"}, func(ctx context.Context, restored *RestoreResult) error {
+		checked = restored
+		assert.Equal(t, statusAvailable, *fake.Cluster(restored.ClusterID).Status)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^pac-aurora-staging-verify-\d{14}$`, result.ClusterID)
+	require.NotNil(t, checked)
+	assert.Same(t, result.Restore, checked)
+	assert.Equal(t, result.ClusterID, checked.ClusterID)
+	assert.Equal(t, testSnapshotIDPrefix+"-"+now.AddDate(0, 0, -1).Format(snapshotIDDateFormat), checked.SnapshotID)
+	assert.WithinDuration(t, now.AddDate(0, 0, -1), checked.SnapshotCreateTime, time.Second)
+	assert.Equal(t, []string{result.ClusterID + "-1"}, checked.InstanceIDs)
+	assert.NoError(t, result.TeardownErr)
+
+	assert.Nil(t, fake.Cluster(result.ClusterID))
+	assert.Nil(t, fake.Instance(result.ClusterID+"-1"))
+	assert.NotNil(t, fake.Cluster(testClusterIDPrefix+"-eu"))
+	assert.Equal(t, 1, fake.Calls("DeleteDBCluster"))
+}
+
+func TestVerifyWithFakeRDSCheckFailure(t *testing.T) {
+	fake, _ := newRestoreFake(t)
+	svc := newFakeBackupService(t, fake, 0)
+	checkErr := errors.New("connection refused")
+
+	result, err := svc.Verify(context.Background(), VerifyRequest{}, func(context.Context, *RestoreResult) error {
+		return checkErr
+	})
+
+	assert.ErrorIs(t, err, checkErr)
+	assert.NoError(t, result.TeardownErr)
+	assert.Nil(t, fake.Cluster(result.ClusterID))
+}
+
+func TestVerifyWithFakeRDSRestoreFailure(t *testing.T) {
+	fake, _ := newRestoreFake(t)
+	fake.FailNext("CreateDBInstance", awserr.New("InsufficientDBInstanceCapacity", "no capacity", nil))
+	svc := newFakeBackupService(t, fake, 0)
+
+	result, err := svc.Verify(context.Background(), VerifyRequest{}, func(context.Context, *RestoreResult) error {
+		t.Error("check called after a failed restore")
+		return nil
+	})
+
+	assertAWSErrorCode(t, "InsufficientDBInstanceCapacity", err)
+	assert.NoError(t, result.TeardownErr)
+	assert.Nil(t, fake.Cluster(result.ClusterID))
+	assert.Equal(t, 1, fake.Calls("DeleteDBCluster"))
+}
+
+func TestVerifyWithFakeRDSUnsupportedEngine(t *testing.T) {
+	fake, _ := newRestoreFake(t)
+	svc := newFakeBackupService(t, fake, 0)
+
+	result, err := svc.Verify(context.Background(), VerifyRequest{Engines: []string{"aurora-postgresql"}}, func(context.Context, *RestoreResult) error {
+		t.Error("check called for a snapshot of another engine")
+		return nil
+	})
+
+	var engineErr *UnsupportedEngineError
+	require.True(t, errors.As(err, &engineErr), "unexpected error: %v", err)
+	assert.Equal(t, "aurora-mysql", engineErr.Engine)
+	assert.Nil(t, result.Restore)
+	assert.Equal(t, 0, fake.Calls("RestoreDBClusterFromSnapshot"))
+}
+
+func TestVerifyWithFakeRDSNoSnapshot(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0)
+
+	result, err := svc.Verify(context.Background(), VerifyRequest{}, func(context.Context, *RestoreResult) error {
+		return nil
+	})
+
+	require.Error(t, err)
+	assert.Nil(t, result.Restore)
+	assert.NoError(t, result.TeardownErr)
+	assert.Equal(t, 0, fake.Calls("DeleteDBCluster"))
+}
+
+func TestDeleteVerificationClusterRefusesOtherClusters(t *testing.T) {
+	fake, _ := newRestoreFake(t)
+	svc := newFakeBackupService(t, fake, 0)
+
+	err := svc.deleteVerificationCluster(context.Background(), testClusterIDPrefix+"-eu")
+
+	require.Error(t, err)
+	assert.NotNil(t, fake.Cluster(testClusterIDPrefix+"-eu"))
+	assert.Equal(t, 0, fake.Calls("DeleteDBInstance"))
+}
+
+func TestGetDBClusterIDsSkipsVerificationClusters(t *testing.T) {
+	fake := awsfake.NewRDS()
+	fake.AddCluster(testClusterIDPrefix + "-verify-20240105120000")
+	fake.AddCluster(testClusterIDPrefix + "-eu")
+	svc := newFakeBackupService(t, fake, 0)
+
+	clusterIDs, err := svc.getDBClusterIDs(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{testClusterIDPrefix + "-eu"}, clusterIDs)
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/metrics"
+	cli "github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+// commandEnv holds what the subcommands share, built from the global options of the app.
+type commandEnv struct {
+	// newContext returns the context of a command, cancelled on SIGTERM or SIGINT and after the timeout.
+	newContext func() (context.Context, context.CancelFunc)
+	// newService returns the backup service configured by the global options.
+	newService func() backup.Service
+	// lockRun takes the lock of the run, if one is configured, returning the context of the run,
+	// cancelled if the lock is lost, and the function releasing the lock.
+	// It returns false when another run holds the lock, after logging that a backup is already in progress elsewhere.
+	lockRun    func(ctx context.Context) (context.Context, func(), bool)
+	dryRun     *bool
+	configFile *string
+	// pushRun pushes the metrics of a run of the given command to the Pushgateway, if one is configured.
+	pushRun func(command string, run *metrics.Run)
+	// writeReport writes the report of a run of the given command, which failed with err, if a destination is configured.
+	writeReport func(command string, run *metrics.Run, err error)
+	// pushVerification pushes the metrics of a verification to the Pushgateway, if one is configured.
+	pushVerification func(*metrics.Verification)
+}
+
+// registerCommands adds the subcommands of the app.
+func registerCommands(app *cli.Cli, env *commandEnv) {
+	app.Command("run", "Back up the DB clusters and then clean up the old backups, as the CronJob does", func(cmd *cli.Cmd) {
+		cmd.Action = func() { runBackupAndCleanup(env) }
+	})
+	app.Command("backup", "Back up the DB clusters without cleaning up the old backups", func(cmd *cli.Cmd) {
+		backupCommand(cmd, env)
+	})
+	app.Command("cleanup", "Clean up the old backups without taking a new one", func(cmd *cli.Cmd) {
+		cleanupCommand(cmd, env)
+	})
+	app.Command("list", "List the backup snapshots and whether the cleanup would keep or delete them", func(cmd *cli.Cmd) {
+		listCommand(cmd, env)
+	})
+	app.Command("status", "Show the latest backup of the DB clusters and whether a backup could start now", func(cmd *cli.Cmd) {
+		statusCommand(cmd, env)
+	})
+	app.Command("adopt", "Tag the backup snapshots created before the managed tags, so that the cleanup manages them", func(cmd *cli.Cmd) {
+		adoptCommand(cmd, env)
+	})
+	app.Command("pin", "Hold a backup snapshot so that the cleanup keeps it, e.g. for a legal hold", func(cmd *cli.Cmd) {
+		pinCommand(cmd, env)
+	})
+	app.Command("unpin", "Remove the hold of a backup snapshot so that the cleanup manages it again", func(cmd *cli.Cmd) {
+		unpinCommand(cmd, env)
+	})
+	app.Command("copy", "Copy an existing backup snapshot into the copy regions and the vault account", func(cmd *cli.Cmd) {
+		copyCommand(cmd, env)
+	})
+	app.Command("export", "Export an existing backup snapshot to the export bucket in Parquet", func(cmd *cli.Cmd) {
+		exportCommand(cmd, env)
+	})
+	app.Command("restore", "Restore a DB cluster from a backup snapshot", func(cmd *cli.Cmd) {
+		restoreCommand(cmd, env)
+	})
+	app.Command("validate-config", "Check the config file and print its targets without calling AWS", func(cmd *cli.Cmd) {
+		validateConfigCommand(cmd, env)
+	})
+	app.Command("verify", "Check the data of the latest backup snapshot restored into a temporary DB cluster", func(cmd *cli.Cmd) {
+		verifyCommand(cmd, env)
+	})
+}
+
+// runBackupAndCleanup backs up the DB clusters and then cleans up the old backups,
+// unless the backup was interrupted. With the dry run option it only prints the plan of the run.
+func runBackupAndCleanup(env *commandEnv) {
+	runStart := time.Now()
+	ctx, cancel := env.newContext()
+	defer cancel()
+	svc := env.newService()
+
+	if *env.dryRun {
+		plan, err := svc.Plan(ctx)
+		if plan != nil {
+			if printErr := printPlan(os.Stdout, plan); printErr != nil {
+				log.WithError(printErr).Error("Error in printing the dry run plan")
+			}
+		}
+		if code := runExitCode(ctx, err); code != exitCodeSuccess {
+			log.WithError(err).WithField("exitCode", code).Error("PAC aurora backup dry run failed")
+			cli.Exit(code)
+		}
+		return
+	}
+
+	ctx, unlock, locked := env.lockRun(ctx)
+	if !locked {
+		return
+	}
+	defer unlock()
+
+	backupResults, backupErr := svc.MakeBackup(ctx)
+	logBackupResults(backupResults)
+
+	if ctx.Err() != nil {
+		log.WithError(ctx.Err()).Error("PAC aurora backup run interrupted, skipping cleanup")
+		code := runExitCode(ctx, backupErr)
+		run := &metrics.Run{StartTime: runStart, Duration: time.Since(runStart), ExitCode: code, Backups: backupResults}
+		env.pushRun("", run)
+		env.writeReport("", run, backupErr)
+		cli.Exit(code)
+	}
+
+	cleanupResults, cleanupErr := svc.CleanUpOldBackups(ctx)
+	logCleanupResults(cleanupResults)
+
+	code := runExitCode(ctx, backupErr, cleanupErr)
+	run := &metrics.Run{StartTime: runStart, Duration: time.Since(runStart), ExitCode: code, Backups: backupResults, Cleanups: cleanupResults}
+	env.pushRun("", run)
+	env.writeReport("", run, errors.Join(backupErr, cleanupErr))
+	if code != exitCodeSuccess {
+		log.WithField("exitCode", code).Error("PAC aurora backup run failed")
+		cli.Exit(code)
+	}
+}
+
+// backupCommand configures the backup subcommand, which takes a new backup without cleaning up the old ones.
+func backupCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Action = func() {
+		start := time.Now()
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		if *env.dryRun {
+			plan, err := svc.Plan(ctx)
+			if plan != nil {
+				if printErr := printPlan(os.Stdout, &backup.Plan{Backups: plan.Backups}); printErr != nil {
+					log.WithError(printErr).Error("Error in printing the dry run plan")
+				}
+			}
+			if code := runExitCode(ctx, err); code != exitCodeSuccess {
+				log.WithError(err).WithField("exitCode", code).Error("PAC aurora backup dry run failed")
+				cli.Exit(code)
+			}
+			return
+		}
+
+		ctx, unlock, locked := env.lockRun(ctx)
+		if !locked {
+			return
+		}
+		defer unlock()
+
+		results, err := svc.MakeBackup(ctx)
+		logBackupResults(results)
+		code := runExitCode(ctx, err)
+		run := &metrics.Run{StartTime: start, Duration: time.Since(start), ExitCode: code, Backups: results}
+		env.pushRun("backup", run)
+		env.writeReport("backup", run, err)
+		if code != exitCodeSuccess {
+			log.WithField("exitCode", code).Error("PAC aurora backup failed")
+			cli.Exit(code)
+		}
+	}
+}
+
+// cleanupCommand configures the cleanup subcommand, which deletes the old backups without taking a new one.
+// Its dry run prints the snapshots the cleanup would keep and delete.
+func cleanupCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Action = func() {
+		start := time.Now()
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		if *env.dryRun {
+			err := printCleanupPlan(ctx, os.Stdout, svc)
+			if code := runExitCode(ctx, err); code != exitCodeSuccess {
+				log.WithError(err).WithField("exitCode", code).Error("PAC aurora cleanup dry run failed")
+				cli.Exit(code)
+			}
+			return
+		}
+
+		ctx, unlock, locked := env.lockRun(ctx)
+		if !locked {
+			return
+		}
+		defer unlock()
+
+		results, err := svc.CleanUpOldBackups(ctx)
+		logCleanupResults(results)
+		code := runExitCode(ctx, err)
+		run := &metrics.Run{StartTime: start, Duration: time.Since(start), ExitCode: code, Cleanups: results}
+		env.pushRun("cleanup", run)
+		env.writeReport("cleanup", run, err)
+		if code != exitCodeSuccess {
+			log.WithField("exitCode", code).Error("PAC aurora cleanup failed")
+			cli.Exit(code)
+		}
+	}
+}
+
+// copyCommand configures the copy subcommand, which copies an existing backup snapshot
+// into the copy regions and the vault account, e.g. after a run failed to copy it.
+func copyCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Spec = "[--snapshot-id]"
+
+	snapshotID := cmd.String(cli.StringOpt{
+		Name:  "snapshot-id",
+		Value: backup.LatestSnapshot,
+		Desc:  "The identifier of the snapshot to copy, or latest for the most recent available backup snapshot",
+	})
+
+	cmd.Action = func() {
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		results, err := svc.CopySnapshot(ctx, *snapshotID)
+		for _, c := range results {
+			entry := log.WithField("snapshotID", c.SnapshotID).
+				WithField("region", c.Region).
+				WithField("accountID", c.AccountID).
+				WithField("duration", c.Duration.String())
+			if c.Err != nil {
+				entry.WithError(c.Err).Error("Snapshot copy failed")
+			} else {
+				entry.WithField("snapshotARN", c.SnapshotARN).Info("Snapshot copy completed")
+			}
+		}
+		if code := runExitCode(ctx, err); code != exitCodeSuccess {
+			log.WithError(err).WithField("exitCode", code).Error("PAC aurora snapshot copy failed")
+			cli.Exit(code)
+		}
+	}
+}
+
+// exportCommand configures the export subcommand, which exports an existing backup snapshot to S3,
+// e.g. after a run failed to export it or to export an older snapshot.
+func exportCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Spec = "[--snapshot-id]"
+
+	snapshotID := cmd.String(cli.StringOpt{
+		Name:  "snapshot-id",
+		Value: backup.LatestSnapshot,
+		Desc:  "The identifier of the snapshot to export, or latest for the most recent available backup snapshot",
+	})
+
+	cmd.Action = func() {
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		result, err := svc.Export(ctx, *snapshotID)
+		if result != nil {
+			logExportResult(result)
+		}
+		if code := runExitCode(ctx, err); code != exitCodeSuccess {
+			log.WithError(err).WithField("exitCode", code).Error("PAC aurora snapshot export failed")
+			cli.Exit(code)
+		}
+	}
+}
+
+// statusCommand configures the status subcommand, which prints the latest backup of each DB cluster.
+func statusCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Action = func() {
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		statuses, err := svc.Status(ctx)
+		if statuses != nil {
+			if printErr := printStatus(os.Stdout, statuses, time.Now()); printErr != nil {
+				log.WithError(printErr).Error("Error in printing the backup status")
+			}
+		}
+		if code := runExitCode(ctx, err); code != exitCodeSuccess {
+			log.WithError(err).WithField("exitCode", code).Error("PAC aurora backup status failed")
+			cli.Exit(code)
+		}
+	}
+}
+
+// printStatus writes a human-readable table of the latest backup of each cluster at the given time.
+func printStatus(w io.Writer, statuses []*backup.ClusterStatus, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tSTATUS\tSNAPSHOTS\tLATEST SNAPSHOT\tCREATED\tAGE\tIN PROGRESS\tBLOCKED BY")
+	for _, s := range statuses {
+		if s.Err != nil {
+			fmt.Fprintf(tw, "%v\terror: %v\n", s.ClusterID, s.Err)
+			continue
+		}
+		age := "-"
+		if !s.LatestSnapshotTime.IsZero() {
+			age = formatAge(now.Sub(s.LatestSnapshotTime))
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%d\t%v\t%v\t%v\t%v\t%v\n", s.ClusterID, s.ClusterStatus, s.Snapshots, valueOrDash(s.LatestSnapshotID),
+			formatCreateTime(s.LatestSnapshotTime), age, listOrDash(s.InProgress), listOrDash(s.PreflightConditions))
+	}
+	return tw.Flush()
+}
+
+func logBackupResults(results []*backup.BackupResult) {
+	for _, result := range results {
+		entry := log.WithField("clusterID", result.ClusterID).
+			WithField("snapshotID", result.SnapshotID).
+			WithField("duration", result.Duration.String())
+		if result.Err != nil {
+			entry.WithError(result.Err).Error("Backup failed")
+		} else {
+			entry.WithField("snapshotARN", result.SnapshotARN).
+				WithField("reused", result.Reused).
+				Info("Backup completed")
+		}
+		for _, c := range result.Copies {
+			if c.Err == nil {
+				entry.WithField("region", c.Region).
+					WithField("accountID", c.AccountID).
+					WithField("snapshotARN", c.SnapshotARN).
+					WithField("duration", c.Duration.String()).
+					Info("Snapshot copy completed")
+			}
+		}
+		if result.Export != nil && result.Export.Err == nil {
+			logExportResult(result.Export)
+		}
+	}
+}
+
+func logExportResult(result *backup.ExportResult) {
+	entry := log.WithField("snapshotID", result.SnapshotID).
+		WithField("exportTaskID", result.TaskID).
+		WithField("duration", result.Duration.String())
+	if result.Err != nil {
+		entry.WithError(result.Err).Error("Snapshot export failed")
+		return
+	}
+	entry.WithField("s3URI", result.S3URI).
+		WithField("extractedGB", result.ExtractedGB).
+		WithField("deletedExports", len(result.Deleted)).
+		Info("Snapshot export completed")
+}
+
+func logCleanupResults(results []*backup.CleanupResult) {
+	for _, result := range results {
+		entry := log.WithField("clusterID", result.ClusterID).
+			WithField("region", result.Region).
+			WithField("snapshotIDPrefix", result.SnapshotIDPrefix).
+			WithField("retentionPolicy", result.Policy.String()).
+			WithField("retained", len(result.Retained)).
+			WithField("deleted", len(result.Deleted)).
+			WithField("failed", len(result.Failed)).
+			WithField("skipped", len(result.Skipped)).
+			WithField("unmanaged", len(result.Unmanaged)).
+			WithField("held", len(result.Held)).
+			WithField("duration", result.Duration.String())
+		if result.Err != nil {
+			entry.WithError(result.Err).Error("Cleanup failed")
+		} else {
+			entry.Info("Cleanup completed")
+		}
+	}
+}
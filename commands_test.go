@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintStatus(t *testing.T) {
+	now := time.Date(2024, 1, 6, 13, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	require.NoError(t, printStatus(&out, []*backup.ClusterStatus{{
+		ClusterID:           "pac-aurora-prod-eu",
+		ClusterStatus:       "available",
+		Snapshots:           14,
+		LatestSnapshotID:    "pac-aurora-prod-eu-backup-2024-01-05-12-00-00",
+		LatestSnapshotTime:  time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC),
+		InProgress:          []string{"pac-aurora-prod-eu-backup-2024-01-06-12-00-00"},
+		PreflightConditions: []string{"snapshot pac-aurora-prod-eu-backup-2024-01-06-12-00-00 is being created"},
+	}, {
+		ClusterID:     "pac-aurora-prod-us",
+		ClusterStatus: "available",
+	}, {
+		ClusterID: "pac-aurora-prod-ap",
+		Err:       errors.New("rate exceeded"),
+	}}, now))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"CLUSTER", "STATUS", "SNAPSHOTS", "LATEST", "SNAPSHOT", "CREATED", "AGE", "IN", "PROGRESS", "BLOCKED", "BY"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"pac-aurora-prod-eu", "available", "14", "pac-aurora-prod-eu-backup-2024-01-05-12-00-00", "2024-01-05T12:00:00Z", "1d1h",
+		"pac-aurora-prod-eu-backup-2024-01-06-12-00-00", "snapshot", "pac-aurora-prod-eu-backup-2024-01-06-12-00-00", "is", "being", "created"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"pac-aurora-prod-us", "available", "0", "-", "-", "-", "-", "-"}, strings.Fields(lines[2]))
+	assert.Equal(t, "pac-aurora-prod-ap  error: rate exceeded", strings.TrimSpace(lines[3]))
+}
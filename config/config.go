@@ -0,0 +1,373 @@
+// Package config reads the file describing the backup targets of a deployment,
+// so that a single deployment can back up several Aurora clusters with different policies.
+// The file is YAML, or JSON as a subset of YAML.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/notify"
+	"gopkg.in/yaml.v3"
+)
+
+// Default values of the optional settings of a target, which match the defaults of the command line options.
+const (
+	DefaultBackupsRetention = 35
+	DefaultMinBackupAge     = "24h"
+	DefaultCopyRetention    = 7
+)
+
+// Notification types.
+const (
+	NotificationWebhook = "webhook"
+	NotificationSlack   = "slack"
+	NotificationSNS     = "sns"
+)
+
+// snapshotIDDateSuffixLength is the length of the creation time appended to the snapshot identifier prefix.
+const snapshotIDDateSuffixLength = len("-2006-01-02-15-04-05")
+
+// maxSnapshotIDLength is the maximum length of a DB cluster snapshot identifier.
+const maxSnapshotIDLength = 255
+
+// Sample values of the placeholders of a snapshot identifier template set by the command line options,
+// with which the template of a target is checked: a label and a random run identifier.
+const (
+	sampleSnapshotLabel = "label"
+	sampleRunID         = "0123abcd"
+)
+
+var (
+	namePattern       = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	identifierPattern = regexp.MustCompile(`^[A-Za-z](-?[A-Za-z0-9])*$`)
+	accountIDPattern  = regexp.MustCompile(`^[0-9]{12}$`)
+)
+
+// Config lists the backup targets of a deployment.
+type Config struct {
+	Targets []Target `yaml:"targets"`
+}
+
+// Target is a set of DB clusters backed up with the same policy.
+// The clusters are selected by ClusterIDPrefix as with the pac-environment option:
+// the first matching cluster, or every matching cluster with DiscoverClusters.
+type Target struct {
+	Name             string `yaml:"name"`
+	Region           string `yaml:"region"`
+	ClusterIDPrefix  string `yaml:"clusterIdPrefix"`
+	DiscoverClusters bool   `yaml:"discoverClusters"`
+	// SnapshotIDPrefix defaults to the cluster identifier prefix followed by -backup.
+	SnapshotIDPrefix  string            `yaml:"snapshotIdPrefix"`
+	Retention         Retention         `yaml:"retention"`
+	Copies            []Copy            `yaml:"copies"`
+	ShareWithAccounts []string          `yaml:"shareWithAccounts"`
+	Vault             *Vault            `yaml:"vault"`
+	Export            *Export           `yaml:"export"`
+	Tags              map[string]string `yaml:"tags"`
+	Notifications     []Notification    `yaml:"notifications"`
+
+	// SnapshotIDTemplate overrides the snapshot-id-template option for the target.
+	SnapshotIDTemplate string `yaml:"snapshotIdTemplate"`
+	// Environment is tagged on the new snapshots and is the {env} of the snapshot identifier template.
+	// It defaults to the name of the target.
+	Environment string `yaml:"environment"`
+}
+
+// Retention selects the snapshots of a target preserved by the cleanup.
+// Policy takes precedence over Backups, and the ages accept days, e.g. 90d.
+type Retention struct {
+	Backups int    `yaml:"backups"`
+	Policy  string `yaml:"policy"`
+	MinAge  string `yaml:"minAge"`
+	MaxAge  string `yaml:"maxAge"`
+}
+
+// Copy is a disaster recovery region where every new snapshot of a target is copied.
+type Copy struct {
+	Region    string `yaml:"region"`
+	KMSKeyID  string `yaml:"kmsKeyId"`
+	Retention int    `yaml:"retention"`
+}
+
+// Vault is the backup vault account where every new snapshot of a target is copied.
+// Region defaults to the region of the target.
+type Vault struct {
+	RoleARN  string `yaml:"roleArn"`
+	Region   string `yaml:"region"`
+	KMSKeyID string `yaml:"kmsKeyId"`
+}
+
+// Export is the S3 bucket where every new snapshot of a target is exported in Parquet.
+// Only limits the export to the given databases, schemas or tables,
+// and Retention is the number of most recent exports preserved, 0 keeps them all.
+type Export struct {
+	Bucket    string   `yaml:"bucket"`
+	Prefix    string   `yaml:"prefix"`
+	RoleARN   string   `yaml:"roleArn"`
+	KMSKeyID  string   `yaml:"kmsKeyId"`
+	Only      []string `yaml:"only"`
+	Retention int      `yaml:"retention"`
+}
+
+// Notification is a destination of the outcome of the runs of a target:
+// a generic JSON webhook or a Slack incoming webhook at URL, or the SNS topic TopicARN.
+// Template replaces the default messages, see notify.ParseTemplate.
+type Notification struct {
+	Type     string `yaml:"type"`
+	URL      string `yaml:"url"`
+	TopicARN string `yaml:"topicArn"`
+	Template string `yaml:"template"`
+}
+
+// Load reads, validates and completes with defaults the config file at the given path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses, validates and completes with defaults a YAML or JSON config.
+// Unknown fields are rejected, so that a misspelt setting is not silently ignored.
+func Parse(data []byte) (*Config, error) {
+	config := new(Config)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	config.setDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Target returns the target with the given name.
+func (c *Config) Target(name string) (*Target, error) {
+	for i := range c.Targets {
+		if c.Targets[i].Name == name {
+			return &c.Targets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("target not found in config: %v", name)
+}
+
+func (c *Config) setDefaults() {
+	for i := range c.Targets {
+		target := &c.Targets[i]
+		if target.SnapshotIDPrefix == "" && target.ClusterIDPrefix != "" {
+			target.SnapshotIDPrefix = target.ClusterIDPrefix + "-backup"
+		}
+		if target.Environment == "" {
+			target.Environment = target.Name
+		}
+		if target.Retention.Backups == 0 && target.Retention.Policy == "" {
+			target.Retention.Backups = DefaultBackupsRetention
+		}
+		if target.Retention.MinAge == "" {
+			target.Retention.MinAge = DefaultMinBackupAge
+		}
+		for j := range target.Copies {
+			if target.Copies[j].Retention == 0 {
+				target.Copies[j].Retention = DefaultCopyRetention
+			}
+		}
+		if target.Vault != nil && target.Vault.Region == "" {
+			target.Vault.Region = target.Region
+		}
+	}
+}
+
+// Validate checks every target of the config and reports all the problems found,
+// each prefixed by the name or the position of its target.
+func (c *Config) Validate() error {
+	if len(c.Targets) == 0 {
+		return errors.New("config does not list any target")
+	}
+	var errs []error
+	names := make(map[string]bool)
+	for i := range c.Targets {
+		target := &c.Targets[i]
+		label := fmt.Sprintf("target %d", i+1)
+		if target.Name != "" {
+			label = "target " + target.Name
+		}
+		if names[target.Name] {
+			errs = append(errs, fmt.Errorf("%v: name is not unique", label))
+		}
+		names[target.Name] = true
+		for _, err := range target.validate() {
+			errs = append(errs, fmt.Errorf("%v: %w", label, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Target) validate() []error {
+	var errs []error
+	if !namePattern.MatchString(t.Name) {
+		errs = append(errs, fmt.Errorf("name must be lowercase letters, digits and hyphens: %q", t.Name))
+	}
+	if t.Region == "" {
+		errs = append(errs, errors.New("region is missing"))
+	}
+	if t.ClusterIDPrefix == "" {
+		errs = append(errs, errors.New("clusterIdPrefix is missing"))
+	}
+	if !identifierPattern.MatchString(t.SnapshotIDPrefix) {
+		errs = append(errs, fmt.Errorf("snapshotIdPrefix must start with a letter and only contain letters, digits and single hyphens: %q", t.SnapshotIDPrefix))
+	} else if maxLength := maxSnapshotIDLength - snapshotIDDateSuffixLength; t.SnapshotIDTemplate == "" && len(t.SnapshotIDPrefix) > maxLength {
+		errs = append(errs, fmt.Errorf("snapshotIdPrefix is longer than %d characters: %q", maxLength, t.SnapshotIDPrefix))
+	}
+	if t.SnapshotIDTemplate != "" {
+		if err := t.validateSnapshotIDTemplate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, t.Retention.validate()...)
+
+	regions := make(map[string]bool)
+	for _, c := range t.Copies {
+		switch {
+		case c.Region == "":
+			errs = append(errs, errors.New("copy region is missing"))
+		case c.Region == t.Region:
+			errs = append(errs, fmt.Errorf("copy region is the region of the target: %v", c.Region))
+		case regions[c.Region]:
+			errs = append(errs, fmt.Errorf("copy region is listed more than once: %v", c.Region))
+		}
+		regions[c.Region] = true
+		if c.Retention < 0 {
+			errs = append(errs, fmt.Errorf("retention of copy region %v cannot be negative: %d", c.Region, c.Retention))
+		}
+	}
+	for _, accountID := range t.ShareWithAccounts {
+		if !accountIDPattern.MatchString(accountID) {
+			errs = append(errs, fmt.Errorf("account ID to share with is not 12 digits: %q", accountID))
+		}
+	}
+	if t.Vault != nil && t.Vault.RoleARN == "" {
+		errs = append(errs, errors.New("vault roleArn is missing"))
+	}
+	if t.Export != nil {
+		errs = append(errs, t.Export.validate()...)
+	}
+	keys := make([]string, 0, len(t.Tags))
+	for key := range t.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := t.Tags[key]
+		switch {
+		case key == "" || len(key) > 128:
+			errs = append(errs, fmt.Errorf("tag key must have 1 to 128 characters: %q", key))
+		case strings.HasPrefix(strings.ToLower(key), "aws:"):
+			errs = append(errs, fmt.Errorf("tag key cannot start with aws: %q", key))
+		case key == backup.ManagedByTagKey, key == backup.HoldReasonTagKey, key == backup.HoldOwnerTagKey, key == backup.HoldUntilTagKey:
+			errs = append(errs, fmt.Errorf("tag key is reserved by the app: %q", key))
+		}
+		if len(value) > 256 {
+			errs = append(errs, fmt.Errorf("value of tag %v is longer than 256 characters", key))
+		}
+	}
+	for _, n := range t.Notifications {
+		if err := n.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// validateSnapshotIDTemplate parses the snapshot identifier template of the target and executes it
+// with the fields of the target, so that an empty placeholder or a too long identifier is reported
+// before any backup. The run identifier and the label, set by the command line options, take sample values.
+func (t *Target) validateSnapshotIDTemplate() error {
+	template, err := backup.ParseSnapshotIDTemplate(t.SnapshotIDTemplate)
+	if err != nil {
+		return err
+	}
+	_, err = template.Execute(backup.SnapshotIDFields{
+		Prefix:      t.SnapshotIDPrefix,
+		ClusterID:   t.ClusterIDPrefix,
+		Environment: t.Environment,
+		RunID:       sampleRunID,
+		Label:       sampleSnapshotLabel,
+	}, time.Now())
+	return err
+}
+
+func (r Retention) validate() []error {
+	var errs []error
+	if r.Backups < 0 {
+		errs = append(errs, fmt.Errorf("retention backups cannot be negative: %d", r.Backups))
+	}
+	if r.Policy != "" {
+		if _, err := backup.ParseRetentionPolicy(r.Policy); err != nil {
+			errs = append(errs, fmt.Errorf("retention policy: %w", err))
+		}
+	}
+	minAge, minErr := backup.ParseBackupAge(r.MinAge)
+	if minErr != nil {
+		errs = append(errs, fmt.Errorf("retention minAge: %w", minErr))
+	}
+	maxAge, maxErr := backup.ParseBackupAge(r.MaxAge)
+	if maxErr != nil {
+		errs = append(errs, fmt.Errorf("retention maxAge: %w", maxErr))
+	}
+	if minErr == nil && maxErr == nil && maxAge > 0 && minAge > maxAge {
+		errs = append(errs, fmt.Errorf("retention minAge %v is greater than maxAge %v", r.MinAge, r.MaxAge))
+	}
+	return errs
+}
+
+func (e Export) validate() []error {
+	var errs []error
+	if e.Bucket == "" {
+		errs = append(errs, errors.New("export bucket is missing"))
+	}
+	if e.RoleARN == "" {
+		errs = append(errs, errors.New("export roleArn is missing"))
+	}
+	if e.KMSKeyID == "" {
+		errs = append(errs, errors.New("export kmsKeyId is missing"))
+	}
+	if e.Retention < 0 {
+		errs = append(errs, fmt.Errorf("export retention cannot be negative: %d", e.Retention))
+	}
+	return errs
+}
+
+// Validate checks the type and the destination of the notification, and parses its template.
+func (n Notification) Validate() error {
+	switch n.Type {
+	case NotificationWebhook, NotificationSlack:
+		u, err := url.Parse(n.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%v notification url is not an http or https URL: %q", n.Type, n.URL)
+		}
+	case NotificationSNS:
+		if !strings.HasPrefix(n.TopicARN, "arn:aws:sns:") {
+			return fmt.Errorf("sns notification topicArn is not an SNS topic ARN: %q", n.TopicARN)
+		}
+	default:
+		return fmt.Errorf("notification type must be webhook, slack or sns: %q", n.Type)
+	}
+	if n.Template != "" {
+		if _, err := notify.ParseTemplate(n.Template); err != nil {
+			return fmt.Errorf("%v notification: %w", n.Type, err)
+		}
+	}
+	return nil
+}
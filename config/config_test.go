@@ -0,0 +1,189 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+targets:
+  - name: prod-eu
+    region: eu-west-1
+    clusterIdPrefix: pac-aurora-prod-eu
+    discoverClusters: true
+    retention:
+      policy: keep-daily=14,keep-weekly=8
+      maxAge: 90d
+    copies:
+      - region: eu-central-1
+        kmsKeyId: alias/pac-aurora-backup
+    shareWithAccounts: ["123456789012"]
+    vault:
+      roleArn: arn:aws:iam::210987654321:role/pac-aurora-backup-vault
+    export:
+      bucket: pac-aurora-exports
+      roleArn: arn:aws:iam::123456789012:role/pac-aurora-export
+      kmsKeyId: alias/pac-aurora-export
+      only: [annotations]
+      retention: 3
+    tags:
+      team: pac
+    notifications:
+      - type: slack
+        url: https://hooks.slack.com/services/T0/B0/X
+        template: "{{.Kind}} {{.ClusterID}}"
+  - name: staging-us
+    region: us-east-1
+    clusterIdPrefix: pac-aurora-staging-us
+    snapshotIdPrefix: pac-staging-us-nightly
+    environment: pac-staging-us
+    retention:
+      backups: 7
+      minAge: "0"
+`
+
+func TestParse(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	require.NoError(t, err)
+	require.Len(t, config.Targets, 2)
+
+	prod := config.Targets[0]
+	assert.Equal(t, "prod-eu", prod.Name)
+	assert.Equal(t, "eu-west-1", prod.Region)
+	assert.True(t, prod.DiscoverClusters)
+	assert.Equal(t, "pac-aurora-prod-eu-backup", prod.SnapshotIDPrefix)
+	assert.Equal(t, "prod-eu", prod.Environment, "the environment should default to the name of the target")
+	assert.Equal(t, Retention{Policy: "keep-daily=14,keep-weekly=8", MinAge: DefaultMinBackupAge, MaxAge: "90d"}, prod.Retention)
+	assert.Equal(t, []Copy{{Region: "eu-central-1", KMSKeyID: "alias/pac-aurora-backup", Retention: DefaultCopyRetention}}, prod.Copies)
+	assert.Equal(t, []string{"123456789012"}, prod.ShareWithAccounts)
+	assert.Equal(t, &Vault{RoleARN: "arn:aws:iam::210987654321:role/pac-aurora-backup-vault", Region: "eu-west-1"}, prod.Vault)
+	assert.Equal(t, &Export{Bucket: "pac-aurora-exports", RoleARN: "arn:aws:iam::123456789012:role/pac-aurora-export",
+		KMSKeyID: "alias/pac-aurora-export", Only: []string{"annotations"}, Retention: 3}, prod.Export)
+	assert.Equal(t, map[string]string{"team": "pac"}, prod.Tags)
+	assert.Equal(t, []Notification{{Type: NotificationSlack, URL: "https://hooks.slack.com/services/T0/B0/X", Template: "{{.Kind}} {{.ClusterID}}"}}, prod.Notifications)
+
+	staging := config.Targets[1]
+	assert.Equal(t, "pac-staging-us-nightly", staging.SnapshotIDPrefix)
+	assert.Equal(t, "pac-staging-us", staging.Environment)
+	assert.Equal(t, Retention{Backups: 7, MinAge: "0"}, staging.Retention)
+	assert.False(t, staging.DiscoverClusters)
+}
+
+func TestParseJSON(t *testing.T) {
+	config, err := Parse([]byte(`{"targets": [{"name": "prod", "region": "eu-west-1", "clusterIdPrefix": "pac-aurora-prod"}]}`))
+	require.NoError(t, err)
+	require.Len(t, config.Targets, 1)
+	assert.Equal(t, "pac-aurora-prod-backup", config.Targets[0].SnapshotIDPrefix)
+	assert.Equal(t, DefaultBackupsRetention, config.Targets[0].Retention.Backups)
+}
+
+func TestParseUnknownField(t *testing.T) {
+	_, err := Parse([]byte("targets:\n  - name: prod\n    region: eu-west-1\n    clusterIdPrefix: pac-aurora-prod\n    retension:\n      backups: 7\n"))
+	assert.ErrorContains(t, err, "retension")
+}
+
+func TestParseNoTarget(t *testing.T) {
+	_, err := Parse([]byte("targets: []\n"))
+	assert.EqualError(t, err, "config does not list any target")
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	config := &Config{Targets: []Target{
+		{
+			Name:             "prod",
+			Region:           "eu-west-1",
+			ClusterIDPrefix:  "pac-aurora-prod",
+			SnapshotIDPrefix: "pac-aurora-prod--backup",
+			Retention:        Retention{Policy: "keep-daily", MinAge: "2d", MaxAge: "1d"},
+			Copies:           []Copy{{Region: "eu-west-1"}, {Region: "eu-central-1", Retention: -1}},
+		},
+		{
+			Name:               "prod",
+			Region:             "eu-west-1",
+			ClusterIDPrefix:    "pac-aurora-prod",
+			SnapshotIDPrefix:   "pac-aurora-prod-backup",
+			ShareWithAccounts:  []string{"1234"},
+			Vault:              &Vault{},
+			Export:             &Export{Bucket: "pac-aurora-exports", Retention: -1},
+			Tags:               map[string]string{"aws:team": "pac", "hold-reason": "forever", "managed-by": "terraform"},
+			Notifications:      []Notification{{Type: "email"}, {Type: NotificationWebhook, URL: "ftp://example.com"}, {Type: NotificationSNS, TopicARN: "alerts"}, {Type: NotificationSlack, URL: "https://hooks.slack.com/services/T0/B0/X", Template: "{{.Cluster}}"}},
+			SnapshotIDTemplate: "{prefix}-{date}",
+		},
+		{},
+	}}
+
+	err := config.Validate()
+	require.Error(t, err)
+	for _, expected := range []string{
+		"target prod: snapshotIdPrefix must start with a letter and only contain letters, digits and single hyphens",
+		"target prod: retention policy: retention rule is not in the <bucket>=<count> format: keep-daily",
+		"target prod: retention minAge 2d is greater than maxAge 1d",
+		"target prod: copy region is the region of the target: eu-west-1",
+		"target prod: retention of copy region eu-central-1 cannot be negative: -1",
+		"target prod: name is not unique",
+		`target prod: account ID to share with is not 12 digits: "1234"`,
+		"target prod: vault roleArn is missing",
+		"target prod: export roleArn is missing",
+		"target prod: export kmsKeyId is missing",
+		"target prod: export retention cannot be negative: -1",
+		`target prod: tag key cannot start with aws: "aws:team"`,
+		`target prod: tag key is reserved by the app: "hold-reason"`,
+		`target prod: tag key is reserved by the app: "managed-by"`,
+		`target prod: notification type must be webhook, slack or sns: "email"`,
+		`target prod: webhook notification url is not an http or https URL: "ftp://example.com"`,
+		`target prod: sns notification topicArn is not an SNS topic ARN: "alerts"`,
+		`target prod: slack notification: notification template: template: message:1:2: executing "message" at <.Cluster>: can't evaluate field Cluster`,
+		`target prod: unknown placeholder {date} in snapshot identifier template: "{prefix}-{date}"`,
+		`target 3: name must be lowercase letters, digits and hyphens: ""`,
+		"target 3: region is missing",
+		"target 3: clusterIdPrefix is missing",
+	} {
+		assert.ErrorContains(t, err, expected)
+	}
+}
+
+func TestValidateSnapshotIDPrefixTooLong(t *testing.T) {
+	prefix := "a"
+	for len(prefix) <= maxSnapshotIDLength {
+		prefix += "a"
+	}
+	config := &Config{Targets: []Target{{Name: "prod", Region: "eu-west-1", ClusterIDPrefix: "pac-aurora-prod", SnapshotIDPrefix: prefix}}}
+	assert.ErrorContains(t, config.Validate(), "snapshotIdPrefix is longer than 235 characters")
+}
+
+func TestValidateExecutesSnapshotIDTemplate(t *testing.T) {
+	target := Target{Name: "prod", Region: "eu-west-1", ClusterIDPrefix: "pac-aurora-prod", SnapshotIDPrefix: "pac-aurora-prod-backup",
+		SnapshotIDTemplate: "{prefix}-{env}-{yyyy}-{MM}-{dd}-{label}"}
+	config := &Config{Targets: []Target{target}}
+	assert.ErrorContains(t, config.Validate(), `target prod: snapshot identifier template {prefix}-{env}-{yyyy}-{MM}-{dd}-{label}: snapshot identifier must start with a letter and only contain letters, digits and single hyphens: "pac-aurora-prod-backup--`)
+
+	config.setDefaults()
+	assert.NoError(t, config.Validate(), "the environment should default to the name of the target")
+
+	for len(config.Targets[0].SnapshotIDPrefix) < maxSnapshotIDLength-len("-2024-01-05") {
+		config.Targets[0].SnapshotIDPrefix += "a"
+	}
+	assert.ErrorContains(t, config.Validate(), "snapshot identifier is longer than 255 characters")
+}
+
+func TestLoadAndTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfig), 0o600))
+
+	config, err := Load(path)
+	require.NoError(t, err)
+
+	target, err := config.Target("staging-us")
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", target.Region)
+
+	_, err = config.Target("prod-us")
+	assert.EqualError(t, err, "target not found in config: prod-us")
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	cli "github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+// listFormats maps the values of the format option of the list subcommand to their printer.
+var listFormats = map[string]func(io.Writer, []*backup.SnapshotInfo) error{
+	"table": printSnapshotTable,
+	"json":  printSnapshotJSON,
+	"csv":   printSnapshotCSV,
+}
+
+// listCommand configures the list subcommand, which prints the backup snapshots of the environment.
+func listCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Spec = "[--format]"
+
+	format := cmd.String(cli.StringOpt{
+		Name:  "format",
+		Value: "table",
+		Desc:  "The output format: table, json or csv",
+	})
+
+	cmd.Action = func() {
+		printSnapshots, found := listFormats[*format]
+		if !found {
+			log.WithField("format", *format).Error("Unknown list format, expected table, json or csv")
+			cli.Exit(exitCodeError)
+		}
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		snapshots, err := svc.List(ctx)
+		if err != nil {
+			code := runExitCode(ctx, err)
+			log.WithError(err).WithField("exitCode", code).Error("PAC aurora backup list failed")
+			cli.Exit(code)
+		}
+		if err := printSnapshots(os.Stdout, snapshots); err != nil {
+			log.WithError(err).Error("Error in printing the snapshots")
+			cli.Exit(exitCodeError)
+		}
+	}
+}
+
+// snapshotRecord is the JSON representation of a listed snapshot.
+type snapshotRecord struct {
+	SnapshotID          string     `json:"snapshotId"`
+	ClusterID           string     `json:"clusterId"`
+	Status              string     `json:"status"`
+	CreateTime          *time.Time `json:"createTime,omitempty"`
+	AgeSeconds          int64      `json:"ageSeconds"`
+	AllocatedStorageGiB int64      `json:"allocatedStorageGiB"`
+	Encrypted           bool       `json:"encrypted"`
+	KmsKeyID            string     `json:"kmsKeyId,omitempty"`
+	Action              string     `json:"action"`
+	Reason              string     `json:"reason"`
+}
+
+func newSnapshotRecord(s *backup.SnapshotInfo) snapshotRecord {
+	record := snapshotRecord{
+		SnapshotID:          s.SnapshotID,
+		ClusterID:           s.ClusterID,
+		Status:              s.Status,
+		AgeSeconds:          int64(s.Age.Seconds()),
+		AllocatedStorageGiB: s.AllocatedStorage,
+		Encrypted:           s.Encrypted,
+		KmsKeyID:            s.KmsKeyID,
+		Action:              snapshotAction(s),
+		Reason:              s.Reason,
+	}
+	if !s.CreateTime.IsZero() {
+		createTime := s.CreateTime.UTC()
+		record.CreateTime = &createTime
+	}
+	return record
+}
+
+// printSnapshotTable writes a human-readable table of the snapshots.
+func printSnapshotTable(w io.Writer, snapshots []*backup.SnapshotInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SNAPSHOT\tCLUSTER\tSTATUS\tCREATED\tAGE\tSIZE\tENCRYPTION\tACTION\tREASON")
+	for _, s := range snapshots {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%dGiB\t%v\t%v\t%v\n",
+			s.SnapshotID, s.ClusterID, s.Status, formatCreateTime(s.CreateTime), formatAge(s.Age), s.AllocatedStorage, formatEncryption(s), snapshotAction(s), s.Reason)
+	}
+	fmt.Fprintf(tw, "%d snapshot(s)\n", len(snapshots))
+	return tw.Flush()
+}
+
+// printSnapshotJSON writes the snapshots as a JSON array.
+func printSnapshotJSON(w io.Writer, snapshots []*backup.SnapshotInfo) error {
+	records := make([]snapshotRecord, 0, len(snapshots))
+	for _, s := range snapshots {
+		records = append(records, newSnapshotRecord(s))
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+// printSnapshotCSV writes the snapshots as CSV with a header row.
+func printSnapshotCSV(w io.Writer, snapshots []*backup.SnapshotInfo) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"snapshot_id", "cluster_id", "status", "create_time", "age_seconds", "allocated_storage_gib", "encrypted", "kms_key_id", "action", "reason"}); err != nil {
+		return err
+	}
+	for _, s := range snapshots {
+		r := newSnapshotRecord(s)
+		createTime := ""
+		if r.CreateTime != nil {
+			createTime = r.CreateTime.Format(time.RFC3339)
+		}
+		if err := cw.Write([]string{r.SnapshotID, r.ClusterID, r.Status, createTime, strconv.FormatInt(r.AgeSeconds, 10),
+			strconv.FormatInt(r.AllocatedStorageGiB, 10), strconv.FormatBool(r.Encrypted), r.KmsKeyID, r.Action, r.Reason}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func snapshotAction(s *backup.SnapshotInfo) string {
+	if s.Keep {
+		return "keep"
+	}
+	return "delete"
+}
+
+func formatCreateTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatEncryption(s *backup.SnapshotInfo) string {
+	if !s.Encrypted {
+		return "none"
+	}
+	return valueOrDash(s.KmsKeyID)
+}
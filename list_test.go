@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSnapshotInfos() []*backup.SnapshotInfo {
+	return []*backup.SnapshotInfo{{
+		SnapshotID:       "pac-aurora-prod-backup-2024-01-05-03-04-05",
+		ClusterID:        "pac-aurora-prod",
+		Status:           "available",
+		CreateTime:       time.Date(2024, 1, 5, 3, 4, 5, 0, time.UTC),
+		Age:              26 * time.Hour,
+		AllocatedStorage: 120,
+		Encrypted:        true,
+		KmsKeyID:         "arn:aws:kms:eu-west-1:123456789012:key/backup",
+		Keep:             true,
+		Reason:           "keep-daily",
+	}, {
+		SnapshotID: "pac-aurora-prod-backup-2023-12-01-03-04-05",
+		ClusterID:  "pac-aurora-prod",
+		Status:     "available",
+		CreateTime: time.Date(2023, 12, 1, 3, 4, 5, 0, time.UTC),
+		Age:        36 * 24 * time.Hour,
+		Reason:     "older than max-backup-age 720h0m0s",
+	}, {
+		SnapshotID: "pac-aurora-prod-backup-2024-01-06-03-04-05",
+		ClusterID:  "pac-aurora-prod",
+		Status:     "creating",
+		Keep:       true,
+		Reason:     "being created",
+	}}
+}
+
+func TestPrintSnapshotTable(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printSnapshotTable(&out, testSnapshotInfos()))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, []string{"SNAPSHOT", "CLUSTER", "STATUS", "CREATED", "AGE", "SIZE", "ENCRYPTION", "ACTION", "REASON"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"pac-aurora-prod-backup-2024-01-05-03-04-05", "pac-aurora-prod", "available", "2024-01-05T03:04:05Z", "1d2h", "120GiB",
+		"arn:aws:kms:eu-west-1:123456789012:key/backup", "keep", "keep-daily"}, strings.Fields(lines[1]))
+	assert.Contains(t, lines[2], "none")
+	assert.Contains(t, lines[2], "delete")
+	assert.Equal(t, []string{"pac-aurora-prod-backup-2024-01-06-03-04-05", "pac-aurora-prod", "creating", "-", "0h", "0GiB", "none", "keep", "being", "created"}, strings.Fields(lines[3]))
+	assert.Equal(t, "3 snapshot(s)", lines[4])
+}
+
+func TestPrintSnapshotJSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printSnapshotJSON(&out, testSnapshotInfos()))
+
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &records))
+	require.Len(t, records, 3)
+	assert.Equal(t, map[string]interface{}{
+		"snapshotId":          "pac-aurora-prod-backup-2024-01-05-03-04-05",
+		"clusterId":           "pac-aurora-prod",
+		"status":              "available",
+		"createTime":          "2024-01-05T03:04:05Z",
+		"ageSeconds":          93600.0,
+		"allocatedStorageGiB": 120.0,
+		"encrypted":           true,
+		"kmsKeyId":            "arn:aws:kms:eu-west-1:123456789012:key/backup",
+		"action":              "keep",
+		"reason":              "keep-daily",
+	}, records[0])
+	assert.Equal(t, "delete", records[1]["action"])
+	assert.NotContains(t, records[2], "createTime")
+}
+
+func TestPrintSnapshotJSONEmpty(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printSnapshotJSON(&out, nil))
+	assert.Equal(t, "[]\n", out.String())
+}
+
+func TestPrintSnapshotCSV(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printSnapshotCSV(&out, testSnapshotInfos()))
+
+	assert.Equal(t, "snapshot_id,cluster_id,status,create_time,age_seconds,allocated_storage_gib,encrypted,kms_key_id,action,reason\n"+
+		"pac-aurora-prod-backup-2024-01-05-03-04-05,pac-aurora-prod,available,2024-01-05T03:04:05Z,93600,120,true,arn:aws:kms:eu-west-1:123456789012:key/backup,keep,keep-daily\n"+
+		"pac-aurora-prod-backup-2023-12-01-03-04-05,pac-aurora-prod,available,2023-12-01T03:04:05Z,3110400,0,false,,delete,older than max-backup-age 720h0m0s\n"+
+		"pac-aurora-prod-backup-2024-01-06-03-04-05,pac-aurora-prod,creating,,0,0,false,,keep,being created\n", out.String())
+}
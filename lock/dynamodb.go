@@ -0,0 +1,129 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Attributes of the items of the DynamoDB lock table. The table has the string partition key "lock",
+// and "expires" holds the expiry of the lease in Unix seconds, so that it can be the TTL attribute of the table.
+const (
+	dynamoDBLockAttribute    = "lock"
+	dynamoDBOwnerAttribute   = "owner"
+	dynamoDBExpiresAttribute = "expires"
+)
+
+// DynamoDBClient is the subset of the AWS DynamoDB API used by DynamoDBLocker.
+// It is satisfied by *dynamodb.DynamoDB and by the in-memory fake in the awsfake package.
+type DynamoDBClient interface {
+	PutItemWithContext(aws.Context, *dynamodb.PutItemInput, ...request.Option) (*dynamodb.PutItemOutput, error)
+	GetItemWithContext(aws.Context, *dynamodb.GetItemInput, ...request.Option) (*dynamodb.GetItemOutput, error)
+	DeleteItemWithContext(aws.Context, *dynamodb.DeleteItemInput, ...request.Option) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoDBLocker keeps each lock in an item of a DynamoDB table, taken and renewed with conditional writes,
+// so that only one owner can hold an unexpired lease whatever the number of runs racing for it.
+type DynamoDBLocker struct {
+	client DynamoDBClient
+	table  string
+	now    func() time.Time
+}
+
+// NewDynamoDBLocker returns a locker keeping the locks in the given table.
+func NewDynamoDBLocker(client DynamoDBClient, table string) *DynamoDBLocker {
+	return &DynamoDBLocker{client: client, table: table, now: time.Now}
+}
+
+func (l *DynamoDBLocker) Acquire(ctx context.Context, name, owner string, ttl time.Duration) error {
+	err := l.put(ctx, name, owner, ttl, true)
+	if errors.Is(err, ErrNotHeld) {
+		// The lock was released between the conditional write and the read of its holder.
+		err = l.put(ctx, name, owner, ttl, true)
+	}
+	return err
+}
+
+func (l *DynamoDBLocker) Renew(ctx context.Context, name, owner string, ttl time.Duration) error {
+	return l.put(ctx, name, owner, ttl, false)
+}
+
+func (l *DynamoDBLocker) Release(ctx context.Context, name, owner string) error {
+	input := new(dynamodb.DeleteItemInput)
+	input.SetTableName(l.table)
+	input.SetKey(map[string]*dynamodb.AttributeValue{dynamoDBLockAttribute: {S: aws.String(name)}})
+	input.SetConditionExpression("#owner = :owner")
+	input.SetExpressionAttributeNames(map[string]*string{"#owner": aws.String(dynamoDBOwnerAttribute)})
+	input.SetExpressionAttributeValues(map[string]*dynamodb.AttributeValue{":owner": {S: aws.String(owner)}})
+	_, err := l.client.DeleteItemWithContext(ctx, input)
+	if isConditionalCheckFailed(err) {
+		return ErrNotHeld
+	}
+	return err
+}
+
+// put writes the lease of the owner if the owner holds the lock, or with acquire if the lock is free,
+// reporting the holder of the lock otherwise.
+func (l *DynamoDBLocker) put(ctx context.Context, name, owner string, ttl time.Duration, acquire bool) error {
+	now := l.now()
+	input := new(dynamodb.PutItemInput)
+	input.SetTableName(l.table)
+	input.SetItem(map[string]*dynamodb.AttributeValue{
+		dynamoDBLockAttribute:    {S: aws.String(name)},
+		dynamoDBOwnerAttribute:   {S: aws.String(owner)},
+		dynamoDBExpiresAttribute: {N: aws.String(strconv.FormatInt(now.Add(ttl).Unix(), 10))},
+	})
+	input.SetConditionExpression("#owner = :owner")
+	names := map[string]*string{"#owner": aws.String(dynamoDBOwnerAttribute)}
+	values := map[string]*dynamodb.AttributeValue{":owner": {S: aws.String(owner)}}
+	if acquire {
+		input.SetConditionExpression("attribute_not_exists(#lock) OR #expires < :now OR #owner = :owner")
+		names["#lock"] = aws.String(dynamoDBLockAttribute)
+		names["#expires"] = aws.String(dynamoDBExpiresAttribute)
+		values[":now"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(now.Unix(), 10))}
+	}
+	input.SetExpressionAttributeNames(names)
+	input.SetExpressionAttributeValues(values)
+
+	_, err := l.client.PutItemWithContext(ctx, input)
+	if !isConditionalCheckFailed(err) {
+		return err
+	}
+	return l.holder(ctx, name)
+}
+
+// holder returns a *HeldError describing the holder of the lock, or ErrNotHeld if nobody holds it.
+func (l *DynamoDBLocker) holder(ctx context.Context, name string) error {
+	input := new(dynamodb.GetItemInput)
+	input.SetTableName(l.table)
+	input.SetKey(map[string]*dynamodb.AttributeValue{dynamoDBLockAttribute: {S: aws.String(name)}})
+	input.SetConsistentRead(true)
+	output, err := l.client.GetItemWithContext(ctx, input)
+	if err != nil {
+		return err
+	}
+	if output.Item == nil {
+		return ErrNotHeld
+	}
+	held := &HeldError{Name: name}
+	if owner := output.Item[dynamoDBOwnerAttribute]; owner != nil {
+		held.Owner = aws.StringValue(owner.S)
+	}
+	if expires := output.Item[dynamoDBExpiresAttribute]; expires != nil {
+		if seconds, err := strconv.ParseInt(aws.StringValue(expires.N), 10, 64); err == nil {
+			held.Expires = time.Unix(seconds, 0)
+		}
+	}
+	return held
+}
+
+func isConditionalCheckFailed(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
@@ -0,0 +1,55 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamoDBLocker(t *testing.T) {
+	fake := awsfake.NewDynamoDB()
+	fake.AddTable("pac-aurora-backup-locks", "lock")
+	now := time.Date(2024, 1, 5, 3, 0, 0, 0, time.UTC)
+	locker := NewDynamoDBLocker(fake, "pac-aurora-backup-locks")
+	locker.now = func() time.Time { return now }
+
+	require.NoError(t, locker.Acquire(context.Background(), "pac-aurora-prod", "pod-a", 5*time.Minute))
+	require.NoError(t, locker.Acquire(context.Background(), "pac-aurora-prod", "pod-a", 5*time.Minute), "the owner should be able to take the lock again")
+
+	err := locker.Acquire(context.Background(), "pac-aurora-prod", "pod-b", 5*time.Minute)
+	var held *HeldError
+	require.True(t, errors.As(err, &held), "unexpected error: %v", err)
+	assert.Equal(t, &HeldError{Name: "pac-aurora-prod", Owner: "pod-a", Expires: now.Add(5 * time.Minute).Local()}, held)
+
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, locker.Renew(context.Background(), "pac-aurora-prod", "pod-a", 5*time.Minute))
+	now = now.Add(6 * time.Minute)
+	require.NoError(t, locker.Acquire(context.Background(), "pac-aurora-prod", "pod-b", 5*time.Minute), "an expired lease should be taken over")
+
+	err = locker.Renew(context.Background(), "pac-aurora-prod", "pod-a", 5*time.Minute)
+	require.True(t, errors.As(err, &held), "unexpected error: %v", err)
+	assert.Equal(t, "pod-b", held.Owner)
+	assert.Equal(t, ErrNotHeld, locker.Release(context.Background(), "pac-aurora-prod", "pod-a"))
+
+	require.NoError(t, locker.Release(context.Background(), "pac-aurora-prod", "pod-b"))
+	assert.Nil(t, fake.Item("pac-aurora-backup-locks", "pac-aurora-prod"))
+	assert.ErrorIs(t, locker.Renew(context.Background(), "pac-aurora-prod", "pod-b", 5*time.Minute), ErrNotHeld)
+}
+
+func TestDynamoDBLockerError(t *testing.T) {
+	fake := awsfake.NewDynamoDB()
+	fake.AddTable("pac-aurora-backup-locks", "lock")
+	fake.FailNext("PutItem", awserr.New("ProvisionedThroughputExceededException", "Rate exceeded", nil))
+	locker := NewDynamoDBLocker(fake, "pac-aurora-backup-locks")
+
+	err := locker.Acquire(context.Background(), "pac-aurora-prod", "pod-a", time.Minute)
+	var awsErr awserr.Error
+	require.True(t, errors.As(err, &awsErr), "unexpected error: %v", err)
+	assert.Equal(t, "ProvisionedThroughputExceededException", awsErr.Code())
+}
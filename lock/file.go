@@ -0,0 +1,109 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// guardRetryInterval is how often FileLocker retries to take the guard of a lock file held by another process.
+const guardRetryInterval = 10 * time.Millisecond
+
+// FileLocker keeps each lock in a file of a local directory, whose content is the owner and the expiry of the lease.
+// Every access to a lock file is guarded by the exclusive creation of a companion file, so that runs sharing
+// the directory take the lock in turn. It is meant for tests and for runs on the same host.
+type FileLocker struct {
+	dir string
+}
+
+// NewFileLocker returns a locker keeping the locks in files of the given directory, which must exist.
+func NewFileLocker(dir string) *FileLocker {
+	return &FileLocker{dir: dir}
+}
+
+func (l *FileLocker) Acquire(ctx context.Context, name, owner string, ttl time.Duration) error {
+	return l.guarded(ctx, name, func(path string, holder *HeldError) error {
+		if holder != nil && holder.Owner != owner && holder.Expires.After(time.Now()) {
+			return holder
+		}
+		return writeLockFile(path, owner, ttl)
+	})
+}
+
+func (l *FileLocker) Renew(ctx context.Context, name, owner string, ttl time.Duration) error {
+	return l.guarded(ctx, name, func(path string, holder *HeldError) error {
+		if err := heldBy(holder, owner); err != nil {
+			return err
+		}
+		return writeLockFile(path, owner, ttl)
+	})
+}
+
+func (l *FileLocker) Release(ctx context.Context, name, owner string) error {
+	return l.guarded(ctx, name, func(path string, holder *HeldError) error {
+		if heldBy(holder, owner) != nil {
+			return ErrNotHeld
+		}
+		return os.Remove(path)
+	})
+}
+
+// guarded calls fn with the path of the lock file and its holder, nil if the file does not exist,
+// while holding the guard of the lock file.
+func (l *FileLocker) guarded(ctx context.Context, name string, fn func(path string, holder *HeldError) error) error {
+	path := filepath.Join(l.dir, name+".lock")
+	guard := path + ".guard"
+	for {
+		f, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(guardRetryInterval):
+		}
+	}
+	defer os.Remove(guard)
+
+	holder, err := readLockFile(path, name)
+	if err != nil {
+		return err
+	}
+	return fn(path, holder)
+}
+
+func readLockFile(path, name string) (*HeldError, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	owner, expires, _ := strings.Cut(strings.TrimSpace(string(content)), " ")
+	held := &HeldError{Name: name, Owner: owner}
+	if held.Expires, err = time.Parse(time.RFC3339Nano, expires); err != nil {
+		return nil, fmt.Errorf("invalid lock file %v: %w", path, err)
+	}
+	return held, nil
+}
+
+// writeLockFile replaces the lock file with a new one, so that it is never read half written.
+func writeLockFile(path, owner string, ttl time.Duration) error {
+	tmp := path + ".tmp"
+	content := owner + " " + time.Now().Add(ttl).UTC().Format(time.RFC3339Nano) + "\n"
+	if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,24 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileLockerTakesExpiredLock(t *testing.T) {
+	locker := NewFileLocker(t.TempDir())
+	require.NoError(t, locker.Acquire(context.Background(), "pac-aurora-prod", "pod-a", time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	require.NoError(t, locker.Acquire(context.Background(), "pac-aurora-prod", "pod-b", time.Minute))
+	var held *HeldError
+	err := locker.Renew(context.Background(), "pac-aurora-prod", "pod-a", time.Minute)
+	require.True(t, errors.As(err, &held), "unexpected error: %v", err)
+	assert.Equal(t, ErrNotHeld, locker.Release(context.Background(), "pac-aurora-prod", "pod-a"))
+	assert.ErrorIs(t, locker.Renew(context.Background(), "other", "pod-a", time.Minute), ErrNotHeld)
+}
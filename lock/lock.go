@@ -0,0 +1,129 @@
+// Package lock provides leases on named locks, so that two runs of the app deployed in different delivery clusters
+// never back up or clean up the same DB clusters at the same time.
+// A lease expires after its TTL unless it is renewed, so a run that dies never holds a lock for longer than the TTL.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNotHeld is returned when renewing or releasing a lock that is not held by the owner anymore.
+var ErrNotHeld = errors.New("lock not held by the owner")
+
+// Locker takes leases on named locks.
+// A lock is free when nobody holds it or when the lease of its holder has expired.
+type Locker interface {
+	// Acquire takes the lock for the owner until the TTL elapses, or renews the lease if the owner already holds it.
+	// It returns a *HeldError if another owner holds an unexpired lease.
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) error
+	// Renew extends the lease of the owner by the TTL from now.
+	// It returns a *HeldError if another owner took the lock, or ErrNotHeld if nobody holds it.
+	Renew(ctx context.Context, name, owner string, ttl time.Duration) error
+	// Release frees the lock if the owner holds it.
+	Release(ctx context.Context, name, owner string) error
+}
+
+// HeldError reports that another owner holds a lock.
+type HeldError struct {
+	Name    string
+	Owner   string
+	Expires time.Time
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("backup already in progress elsewhere: lock %v held by %v until %v", e.Name, e.Owner, e.Expires.UTC().Format(time.RFC3339))
+}
+
+// Lease is a lock held by an owner, renewed in the background every third of its TTL until it is released.
+type Lease struct {
+	locker Locker
+	name   string
+	owner  string
+	ttl    time.Duration
+
+	lost     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	lostOnce sync.Once
+}
+
+// Acquire takes the lock with the given name for the owner and starts renewing its lease.
+// It returns a *HeldError if another owner holds the lock.
+func Acquire(ctx context.Context, locker Locker, name, owner string, ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("lock TTL must be positive: %v", ttl)
+	}
+	if err := locker.Acquire(ctx, name, owner, ttl); err != nil {
+		return nil, err
+	}
+	l := &Lease{
+		locker: locker,
+		name:   name,
+		owner:  owner,
+		ttl:    ttl,
+		lost:   make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go l.renew(time.Now().Add(ttl))
+	return l, nil
+}
+
+// Lost returns a channel closed when the lease could not be renewed before it expired or another owner took the lock.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops renewing the lease and frees the lock.
+func (l *Lease) Release(ctx context.Context) error {
+	close(l.stop)
+	<-l.done
+	err := l.locker.Release(ctx, l.name, l.owner)
+	if errors.Is(err, ErrNotHeld) {
+		return nil
+	}
+	return err
+}
+
+// renew extends the lease every third of the TTL, retrying failed renewals until the lease expires.
+func (l *Lease) renew(expires time.Time) {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithDeadline(context.Background(), expires)
+		err := l.locker.Renew(ctx, l.name, l.owner, l.ttl)
+		cancel()
+		var held *HeldError
+		switch {
+		case err == nil:
+			expires = time.Now().Add(l.ttl)
+		case errors.As(err, &held), errors.Is(err, ErrNotHeld):
+			log.WithError(err).WithField("lock", l.name).Error("Lost the lock")
+			l.setLost()
+			return
+		case !time.Now().Before(expires):
+			log.WithError(err).WithField("lock", l.name).Error("Lock lease expired before it could be renewed")
+			l.setLost()
+			return
+		default:
+			log.WithError(err).WithField("lock", l.name).Warn("Error in renewing the lock lease, retrying")
+		}
+	}
+}
+
+func (l *Lease) setLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
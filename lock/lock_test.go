@@ -0,0 +1,62 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaseIsRenewedUntilReleased(t *testing.T) {
+	locker := NewFileLocker(t.TempDir())
+	ttl := 150 * time.Millisecond
+
+	lease, err := Acquire(context.Background(), locker, "pac-aurora-prod", "pod-a", ttl)
+	require.NoError(t, err)
+
+	time.Sleep(3 * ttl)
+	_, err = Acquire(context.Background(), locker, "pac-aurora-prod", "pod-b", ttl)
+	var held *HeldError
+	require.True(t, errors.As(err, &held), "unexpected error: %v", err)
+	assert.Equal(t, "pod-a", held.Owner)
+	assert.Contains(t, err.Error(), "backup already in progress elsewhere: lock pac-aurora-prod held by pod-a until ")
+	select {
+	case <-lease.Lost():
+		t.Fatal("the lease should not be lost")
+	default:
+	}
+
+	require.NoError(t, lease.Release(context.Background()))
+	other, err := Acquire(context.Background(), locker, "pac-aurora-prod", "pod-b", ttl)
+	require.NoError(t, err)
+	require.NoError(t, other.Release(context.Background()))
+}
+
+func TestLeaseLost(t *testing.T) {
+	locker := NewFileLocker(t.TempDir())
+	ttl := 150 * time.Millisecond
+	lease, err := Acquire(context.Background(), locker, "pac-aurora-prod", "pod-a", ttl)
+	require.NoError(t, err)
+
+	require.NoError(t, locker.guarded(context.Background(), "pac-aurora-prod", func(path string, _ *HeldError) error {
+		return writeLockFile(path, "pod-b", time.Hour)
+	}))
+
+	select {
+	case <-lease.Lost():
+	case <-time.After(2 * ttl):
+		t.Fatal("the lease should be lost")
+	}
+	require.NoError(t, lease.Release(context.Background()))
+	holder, err := readLockFile(locker.dir+"/pac-aurora-prod.lock", "pac-aurora-prod")
+	require.NoError(t, err)
+	assert.Equal(t, "pod-b", holder.Owner, "releasing a lost lease should not free the lock of the new owner")
+}
+
+func TestAcquireInvalidTTL(t *testing.T) {
+	_, err := Acquire(context.Background(), NewFileLocker(t.TempDir()), "pac-aurora-prod", "pod-a", 0)
+	assert.EqualError(t, err, "lock TTL must be positive: 0s")
+}
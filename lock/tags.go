@@ -0,0 +1,147 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// lockTagKeyPrefix prefixes the key of the tag holding a lock on a DB cluster, e.g. backup-lock:pac-aurora-prod.
+const lockTagKeyPrefix = "backup-lock:"
+
+// defaultTagSettleDelay is how long ClusterTagLocker waits before checking that its tag was not overwritten by another owner.
+const defaultTagSettleDelay = 5 * time.Second
+
+// RDSClient is the subset of the AWS RDS API used by ClusterTagLocker.
+// It is satisfied by *rds.RDS and by the in-memory fake in the awsfake package.
+type RDSClient interface {
+	DescribeDBClustersWithContext(aws.Context, *rds.DescribeDBClustersInput, ...request.Option) (*rds.DescribeDBClustersOutput, error)
+	AddTagsToResourceWithContext(aws.Context, *rds.AddTagsToResourceInput, ...request.Option) (*rds.AddTagsToResourceOutput, error)
+	RemoveTagsFromResourceWithContext(aws.Context, *rds.RemoveTagsFromResourceInput, ...request.Option) (*rds.RemoveTagsFromResourceOutput, error)
+}
+
+// ClusterTagLocker keeps each lock in a tag of a DB cluster, whose value is the owner and the expiry of the lease.
+// As tags cannot be written conditionally, it tags the cluster and then reads the tag back after a delay,
+// so that the last of two runs racing for the lock wins it and the other backs off.
+// This only works while the runs are not more than the delay apart, which is the case of the CronJob
+// of the delivery clusters, but the DynamoDBLocker should be preferred.
+type ClusterTagLocker struct {
+	client      RDSClient
+	clusterID   string
+	settleDelay time.Duration
+}
+
+// NewClusterTagLocker returns a locker keeping the locks in tags of the given DB cluster.
+func NewClusterTagLocker(client RDSClient, clusterID string) *ClusterTagLocker {
+	return &ClusterTagLocker{client: client, clusterID: clusterID, settleDelay: defaultTagSettleDelay}
+}
+
+func (l *ClusterTagLocker) Acquire(ctx context.Context, name, owner string, ttl time.Duration) error {
+	arn, holder, err := l.holder(ctx, name)
+	if err != nil {
+		return err
+	}
+	if holder != nil && holder.Owner != owner && holder.Expires.After(time.Now()) {
+		return holder
+	}
+	if err := l.tag(ctx, arn, name, owner, ttl); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(l.settleDelay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	}
+	return l.check(ctx, name, owner)
+}
+
+func (l *ClusterTagLocker) Renew(ctx context.Context, name, owner string, ttl time.Duration) error {
+	arn, holder, err := l.holder(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := heldBy(holder, owner); err != nil {
+		return err
+	}
+	return l.tag(ctx, arn, name, owner, ttl)
+}
+
+func (l *ClusterTagLocker) Release(ctx context.Context, name, owner string) error {
+	arn, holder, err := l.holder(ctx, name)
+	if err != nil {
+		return err
+	}
+	if heldBy(holder, owner) != nil {
+		return ErrNotHeld
+	}
+	input := new(rds.RemoveTagsFromResourceInput)
+	input.SetResourceName(arn)
+	input.SetTagKeys(aws.StringSlice([]string{lockTagKeyPrefix + name}))
+	_, err = l.client.RemoveTagsFromResourceWithContext(ctx, input)
+	return err
+}
+
+// check returns nil if the owner holds the lock, a *HeldError if another owner does, or ErrNotHeld if nobody does.
+func (l *ClusterTagLocker) check(ctx context.Context, name, owner string) error {
+	_, holder, err := l.holder(ctx, name)
+	if err != nil {
+		return err
+	}
+	return heldBy(holder, owner)
+}
+
+// heldBy returns nil if the holder is the owner, the holder if it is another owner, or ErrNotHeld if there is no holder.
+func heldBy(holder *HeldError, owner string) error {
+	if holder == nil {
+		return ErrNotHeld
+	}
+	if holder.Owner != owner {
+		return holder
+	}
+	return nil
+}
+
+// holder returns the ARN of the cluster and the holder of the lock, nil if the cluster has no tag for it.
+func (l *ClusterTagLocker) holder(ctx context.Context, name string) (string, *HeldError, error) {
+	input := new(rds.DescribeDBClustersInput)
+	input.SetDBClusterIdentifier(l.clusterID)
+	output, err := l.client.DescribeDBClustersWithContext(ctx, input)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(output.DBClusters) == 0 {
+		return "", nil, fmt.Errorf("DB cluster %v not found", l.clusterID)
+	}
+	cluster := output.DBClusters[0]
+	for _, tag := range cluster.TagList {
+		if aws.StringValue(tag.Key) != lockTagKeyPrefix+name {
+			continue
+		}
+		owner, expires, _ := strings.Cut(aws.StringValue(tag.Value), " ")
+		held := &HeldError{Name: name, Owner: owner}
+		if t, err := time.Parse(time.RFC3339, expires); err == nil {
+			held.Expires = t
+		}
+		return aws.StringValue(cluster.DBClusterArn), held, nil
+	}
+	return aws.StringValue(cluster.DBClusterArn), nil, nil
+}
+
+func (l *ClusterTagLocker) tag(ctx context.Context, arn, name, owner string, ttl time.Duration) error {
+	input := new(rds.AddTagsToResourceInput)
+	input.SetResourceName(arn)
+	input.SetTags([]*rds.Tag{{
+		Key:   aws.String(lockTagKeyPrefix + name),
+		Value: aws.String(owner + " " + time.Now().Add(ttl).UTC().Format(time.RFC3339)),
+	}})
+	_, err := l.client.AddTagsToResourceWithContext(ctx, input)
+	return err
+}
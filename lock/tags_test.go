@@ -0,0 +1,45 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterTagLocker(t *testing.T) {
+	fake := awsfake.NewRDS()
+	cluster := fake.AddCluster("pac-aurora-prod-eu")
+	locker := NewClusterTagLocker(fake, "pac-aurora-prod-eu")
+	locker.settleDelay = 0
+
+	require.NoError(t, locker.Acquire(context.Background(), "pac-aurora-prod", "pod-a", 5*time.Minute))
+	err := locker.Acquire(context.Background(), "pac-aurora-prod", "pod-b", 5*time.Minute)
+	var held *HeldError
+	require.True(t, errors.As(err, &held), "unexpected error: %v", err)
+	assert.Equal(t, "pod-a", held.Owner)
+	assert.WithinDuration(t, time.Now().Add(5*time.Minute), held.Expires, 2*time.Second)
+	require.NoError(t, locker.Renew(context.Background(), "pac-aurora-prod", "pod-a", 5*time.Minute))
+
+	// pod-b overwrites the tag while racing pod-a for the lock.
+	_, err = fake.AddTagsToResource(&rds.AddTagsToResourceInput{
+		ResourceName: cluster.DBClusterArn,
+		Tags:         []*rds.Tag{{Key: aws.String("backup-lock:pac-aurora-prod"), Value: aws.String("pod-b 2099-01-01T00:00:00Z")}},
+	})
+	require.NoError(t, err)
+	err = locker.Renew(context.Background(), "pac-aurora-prod", "pod-a", 5*time.Minute)
+	require.True(t, errors.As(err, &held), "unexpected error: %v", err)
+	assert.Equal(t, "pod-b", held.Owner)
+	assert.Equal(t, ErrNotHeld, locker.Release(context.Background(), "pac-aurora-prod", "pod-a"))
+
+	require.NoError(t, locker.Release(context.Background(), "pac-aurora-prod", "pod-b"))
+	out, err := fake.DescribeDBClusters(&rds.DescribeDBClustersInput{DBClusterIdentifier: aws.String("pac-aurora-prod-eu")})
+	require.NoError(t, err)
+	assert.Empty(t, out.DBClusters[0].TagList)
+}
@@ -0,0 +1,203 @@
+// Package metrics pushes the outcome of a backup run to a Prometheus Pushgateway,
+// as the app runs as a short-lived CronJob that cannot be scraped.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const namespace = "pac_aurora_backup"
+
+// Run describes a backup run whose metrics are pushed.
+type Run struct {
+	StartTime time.Time
+	Duration  time.Duration
+	ExitCode  int
+	Backups   []*backup.BackupResult
+	Cleanups  []*backup.CleanupResult
+}
+
+// Verification describes a verification of the latest backup whose metrics are pushed.
+type Verification struct {
+	StartTime time.Time
+	Duration  time.Duration
+	Passed    bool
+	// SnapshotCreateTime is the time when the verified snapshot was taken, zero if no snapshot was restored.
+	SnapshotCreateTime time.Time
+}
+
+// Pusher pushes the metrics of backup runs to a Pushgateway under a job and grouping labels.
+type Pusher struct {
+	url      string
+	job      string
+	grouping map[string]string
+	client   push.HTTPDoer
+}
+
+// PusherOption configures a Pusher.
+type PusherOption func(*Pusher)
+
+// WithGrouping adds a label identifying the metrics of the app in the Pushgateway, e.g. the environment.
+func WithGrouping(name, value string) PusherOption {
+	return func(p *Pusher) {
+		p.grouping[name] = value
+	}
+}
+
+// WithHTTPClient sets the client used to call the Pushgateway.
+func WithHTTPClient(client push.HTTPDoer) PusherOption {
+	return func(p *Pusher) {
+		p.client = client
+	}
+}
+
+// NewPusher returns a Pusher for the Pushgateway at the given URL.
+func NewPusher(url, job string, opts ...PusherOption) *Pusher {
+	p := &Pusher{url: url, job: job, grouping: map[string]string{}}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Push pushes the metrics of a run, replacing those of the previous run.
+// The metrics of a successful backup are added to a group per cluster, which failed runs leave untouched.
+func (p *Pusher) Push(ctx context.Context, run *Run) error {
+	var errs []error
+	for _, result := range run.Backups {
+		if result.SnapshotARN == "" {
+			continue
+		}
+		if err := p.pusher(clusterRegistry(result)).Grouping("cluster", result.ClusterID).AddContext(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("pushing backup metrics of cluster %v: %w", result.ClusterID, err))
+		}
+	}
+	if err := p.pusher(runRegistry(run)).PushContext(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("pushing run metrics: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// PushVerification pushes the metrics of a verification in a group of their own.
+func (p *Pusher) PushVerification(ctx context.Context, verification *Verification) error {
+	if err := p.pusher(verificationRegistry(verification)).Grouping("command", "verify").PushContext(ctx); err != nil {
+		return fmt.Errorf("pushing verification metrics: %w", err)
+	}
+	return nil
+}
+
+func (p *Pusher) pusher(registry *prometheus.Registry) *push.Pusher {
+	pusher := push.New(p.url, p.job).Gatherer(registry)
+	for name, value := range p.grouping {
+		pusher = pusher.Grouping(name, value)
+	}
+	if p.client != nil {
+		pusher = pusher.Client(p.client)
+	}
+	return pusher
+}
+
+// clusterRegistry returns the metrics of the successful backup of a cluster,
+// without the creation duration of a reused snapshot.
+func clusterRegistry(result *backup.BackupResult) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	lastSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "The time when the last successful backup of the cluster completed.",
+	})
+	lastSuccess.Set(float64(result.StartTime.Add(result.Duration).Unix()))
+	creationDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "snapshot_creation_duration_seconds",
+		Help:      "The time the last snapshot of the cluster took to become available.",
+	})
+	registry.MustRegister(lastSuccess)
+	if !result.Reused {
+		creationDuration.Set(result.CreationDuration.Seconds())
+		registry.MustRegister(creationDuration)
+	}
+	return registry
+}
+
+// runRegistry returns the metrics of the outcome of a run.
+func runRegistry(run *Run) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	newGauge := func(name, help string, value float64) {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help})
+		gauge.Set(value)
+		registry.MustRegister(gauge)
+	}
+	newGauge("last_run_timestamp_seconds", "The time when the last run started.", float64(run.StartTime.Unix()))
+	newGauge("last_run_duration_seconds", "The duration of the last run.", run.Duration.Seconds())
+	newGauge("last_run_exit_code", "The exit code of the last run.", float64(run.ExitCode))
+	success := 0.0
+	if run.ExitCode == 0 {
+		success = 1
+	}
+	newGauge("last_run_success", "Whether the last run succeeded.", success)
+
+	backupSuccess := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "backup_success",
+		Help:      "Whether the last run created an available snapshot of the cluster.",
+	}, []string{"cluster"})
+	for _, result := range run.Backups {
+		value := 0.0
+		if result.SnapshotARN != "" {
+			value = 1
+		}
+		backupSuccess.WithLabelValues(result.ClusterID).Set(value)
+	}
+	registry.MustRegister(backupSuccess)
+
+	cleanupLabels := []string{"region", "snapshot_id_prefix"}
+	newCleanupGauge := func(name, help string) *prometheus.GaugeVec {
+		gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help}, cleanupLabels)
+		registry.MustRegister(gauge)
+		return gauge
+	}
+	deleted := newCleanupGauge("snapshots_deleted", "The number of snapshots deleted by the last cleanup.")
+	failed := newCleanupGauge("snapshots_deletion_failed", "The number of snapshots the last cleanup failed to delete.")
+	retained := newCleanupGauge("snapshots", "The number of manual snapshots retained by the last cleanup.")
+	retainedBytes := newCleanupGauge("snapshots_bytes", "The storage allocated to the manual snapshots retained by the last cleanup.")
+	for _, result := range run.Cleanups {
+		if result.Err != nil && len(result.Failed) == 0 {
+			continue
+		}
+		labels := []string{result.Region, result.SnapshotIDPrefix}
+		deleted.WithLabelValues(labels...).Set(float64(len(result.Deleted)))
+		failed.WithLabelValues(labels...).Set(float64(len(result.Failed)))
+		retained.WithLabelValues(labels...).Set(float64(len(result.Retained)))
+		retainedBytes.WithLabelValues(labels...).Set(float64(result.RetainedBytes))
+	}
+	return registry
+}
+
+// verificationRegistry returns the metrics of the outcome of a verification.
+func verificationRegistry(verification *Verification) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	newGauge := func(name, help string, value float64) {
+		gauge := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help})
+		gauge.Set(value)
+		registry.MustRegister(gauge)
+	}
+	newGauge("last_verification_timestamp_seconds", "The time when the last verification started.", float64(verification.StartTime.Unix()))
+	newGauge("last_verification_duration_seconds", "The duration of the last verification.", verification.Duration.Seconds())
+	success := 0.0
+	if verification.Passed {
+		success = 1
+	}
+	newGauge("last_verification_success", "Whether every check of the last verification passed.", success)
+	if !verification.SnapshotCreateTime.IsZero() {
+		newGauge("last_verified_snapshot_timestamp_seconds", "The time when the snapshot checked by the last verification was taken.", float64(verification.SnapshotCreateTime.Unix()))
+	}
+	return registry
+}
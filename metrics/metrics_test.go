@@ -0,0 +1,253 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pushedGroup struct {
+	method   string
+	families map[string]*dto.MetricFamily
+}
+
+// fakePushgateway records the metric families pushed to each grouping key path,
+// with the job first and then the other grouping labels sorted by name,
+// as the push client orders them randomly.
+// Like the Pushgateway, a PUT replaces the whole group while a POST only replaces the families it pushes.
+type fakePushgateway struct {
+	mu     sync.Mutex
+	groups map[string]pushedGroup
+	status int
+}
+
+func newFakePushgateway(t *testing.T) (*fakePushgateway, *httptest.Server) {
+	gateway := &fakePushgateway{groups: map[string]pushedGroup{}, status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		decoder := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		families := map[string]*dto.MetricFamily{}
+		for {
+			family := new(dto.MetricFamily)
+			if err := decoder.Decode(family); err != nil {
+				if !errors.Is(err, io.EOF) {
+					t.Errorf("decoding pushed metrics: %v", err)
+				}
+				break
+			}
+			families[family.GetName()] = family
+		}
+		gateway.mu.Lock()
+		path := canonicalPath(t, r.URL.Path)
+		if r.Method == http.MethodPost {
+			for name, family := range gateway.groups[path].families {
+				if _, found := families[name]; !found {
+					families[name] = family
+				}
+			}
+		}
+		gateway.groups[path] = pushedGroup{method: r.Method, families: families}
+		status := gateway.status
+		gateway.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return gateway, server
+}
+
+// canonicalPath sorts the grouping labels of a Pushgateway URL path, /metrics/job/<job>/<label>/<value>/...
+func canonicalPath(t *testing.T, path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/metrics/"), "/")
+	if len(segments) < 2 || len(segments)%2 != 0 || segments[0] != "job" {
+		t.Errorf("unexpected path: %v", path)
+		return path
+	}
+	var labels []string
+	for i := 2; i < len(segments); i += 2 {
+		labels = append(labels, segments[i]+"/"+segments[i+1])
+	}
+	sort.Strings(labels)
+	return strings.Join(append([]string{"/metrics/job/" + segments[1]}, labels...), "/")
+}
+
+func (g *fakePushgateway) paths() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var paths []string
+	for path := range g.groups {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func gaugeValue(t *testing.T, group pushedGroup, name string, labels map[string]string) float64 {
+	family, found := group.families[name]
+	require.True(t, found, "metric %v not pushed", name)
+	for _, metric := range family.GetMetric() {
+		matches := len(metric.GetLabel()) == len(labels)
+		for _, label := range metric.GetLabel() {
+			if labels[label.GetName()] != label.GetValue() {
+				matches = false
+			}
+		}
+		if matches {
+			return metric.GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("metric %v with labels %v not pushed", name, labels)
+	return 0
+}
+
+func TestPush(t *testing.T) {
+	gateway, server := newFakePushgateway(t)
+	start := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	run := &Run{
+		StartTime: start,
+		Duration:  10 * time.Minute,
+		ExitCode:  5,
+		Backups: []*backup.BackupResult{{
+			ClusterID:        "pac-aurora-prod-eu",
+			SnapshotARN:      "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:pac-aurora-prod-eu-backup",
+			StartTime:        start,
+			Duration:         6 * time.Minute,
+			CreationDuration: 5 * time.Minute,
+		}, {
+			ClusterID: "pac-aurora-prod-us",
+			StartTime: start,
+			Err:       errors.New("cluster status is stopped"),
+		}},
+		Cleanups: []*backup.CleanupResult{{
+			Region:           "eu-west-1",
+			SnapshotIDPrefix: "pac-aurora-prod-eu-backup",
+			Retained:         []string{"a", "b"},
+			RetainedBytes:    3 << 30,
+			Deleted:          []string{"c"},
+			Failed:           []backup.SnapshotFailure{{SnapshotID: "d"}},
+			Err:              &backup.DeletionError{},
+		}, {
+			Region:           "eu-west-1",
+			SnapshotIDPrefix: "pac-aurora-prod-us-backup",
+			Err:              errors.New("rate exceeded"),
+		}},
+	}
+
+	err := NewPusher(server.URL, "pac-aurora-backup", WithGrouping("environment", "prod")).Push(context.Background(), run)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"/metrics/job/pac-aurora-backup/cluster/pac-aurora-prod-eu/environment/prod",
+		"/metrics/job/pac-aurora-backup/environment/prod",
+	}, gateway.paths())
+
+	clusterGroup := gateway.groups["/metrics/job/pac-aurora-backup/cluster/pac-aurora-prod-eu/environment/prod"]
+	assert.Equal(t, http.MethodPost, clusterGroup.method)
+	assert.Equal(t, float64(start.Add(6*time.Minute).Unix()), gaugeValue(t, clusterGroup, "pac_aurora_backup_last_success_timestamp_seconds", nil))
+	assert.Equal(t, 300.0, gaugeValue(t, clusterGroup, "pac_aurora_backup_snapshot_creation_duration_seconds", nil))
+
+	runGroup := gateway.groups["/metrics/job/pac-aurora-backup/environment/prod"]
+	assert.Equal(t, http.MethodPut, runGroup.method)
+	assert.Equal(t, float64(start.Unix()), gaugeValue(t, runGroup, "pac_aurora_backup_last_run_timestamp_seconds", nil))
+	assert.Equal(t, 600.0, gaugeValue(t, runGroup, "pac_aurora_backup_last_run_duration_seconds", nil))
+	assert.Equal(t, 5.0, gaugeValue(t, runGroup, "pac_aurora_backup_last_run_exit_code", nil))
+	assert.Equal(t, 0.0, gaugeValue(t, runGroup, "pac_aurora_backup_last_run_success", nil))
+	assert.Equal(t, 1.0, gaugeValue(t, runGroup, "pac_aurora_backup_backup_success", map[string]string{"cluster": "pac-aurora-prod-eu"}))
+	assert.Equal(t, 0.0, gaugeValue(t, runGroup, "pac_aurora_backup_backup_success", map[string]string{"cluster": "pac-aurora-prod-us"}))
+	cleanupLabels := map[string]string{"region": "eu-west-1", "snapshot_id_prefix": "pac-aurora-prod-eu-backup"}
+	assert.Equal(t, 1.0, gaugeValue(t, runGroup, "pac_aurora_backup_snapshots_deleted", cleanupLabels))
+	assert.Equal(t, 1.0, gaugeValue(t, runGroup, "pac_aurora_backup_snapshots_deletion_failed", cleanupLabels))
+	assert.Equal(t, 2.0, gaugeValue(t, runGroup, "pac_aurora_backup_snapshots", cleanupLabels))
+	assert.Equal(t, float64(3<<30), gaugeValue(t, runGroup, "pac_aurora_backup_snapshots_bytes", cleanupLabels))
+	assert.Len(t, runGroup.families["pac_aurora_backup_snapshots"].GetMetric(), 1)
+}
+
+func TestPushReusedSnapshotKeepsPreviousCreationDuration(t *testing.T) {
+	gateway, server := newFakePushgateway(t)
+	pusher := NewPusher(server.URL, "pac-aurora-backup")
+	start := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	err := pusher.Push(context.Background(), &Run{
+		StartTime: start,
+		Duration:  6 * time.Minute,
+		Backups: []*backup.BackupResult{{
+			ClusterID:        "pac-aurora-prod-eu",
+			SnapshotARN:      "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:pac-aurora-prod-eu-backup",
+			StartTime:        start,
+			Duration:         6 * time.Minute,
+			CreationDuration: 5 * time.Minute,
+		}},
+	})
+	require.NoError(t, err)
+
+	retry := start.Add(time.Hour)
+	err = pusher.Push(context.Background(), &Run{
+		StartTime: retry,
+		Duration:  time.Minute,
+		Backups: []*backup.BackupResult{{
+			ClusterID:   "pac-aurora-prod-eu",
+			SnapshotARN: "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:pac-aurora-prod-eu-backup",
+			StartTime:   retry,
+			Duration:    time.Minute,
+			Reused:      true,
+		}},
+	})
+	require.NoError(t, err)
+
+	clusterGroup := gateway.groups["/metrics/job/pac-aurora-backup/cluster/pac-aurora-prod-eu"]
+	assert.Equal(t, float64(retry.Add(time.Minute).Unix()), gaugeValue(t, clusterGroup, "pac_aurora_backup_last_success_timestamp_seconds", nil))
+	assert.Equal(t, 300.0, gaugeValue(t, clusterGroup, "pac_aurora_backup_snapshot_creation_duration_seconds", nil))
+}
+
+func TestPushError(t *testing.T) {
+	gateway, server := newFakePushgateway(t)
+	gateway.status = http.StatusInternalServerError
+
+	err := NewPusher(server.URL, "pac-aurora-backup").Push(context.Background(), &Run{StartTime: time.Now()})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pushing run metrics")
+}
+
+func TestPushVerification(t *testing.T) {
+	gateway, server := newFakePushgateway(t)
+	start := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	verification := &Verification{
+		StartTime:          start,
+		Duration:           40 * time.Minute,
+		Passed:             true,
+		SnapshotCreateTime: start.Add(-time.Hour),
+	}
+
+	err := NewPusher(server.URL, "pac-aurora-backup", WithGrouping("environment", "prod")).PushVerification(context.Background(), verification)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/metrics/job/pac-aurora-backup/command/verify/environment/prod"}, gateway.paths())
+	group := gateway.groups["/metrics/job/pac-aurora-backup/command/verify/environment/prod"]
+	assert.Equal(t, http.MethodPut, group.method)
+	assert.Equal(t, float64(start.Unix()), gaugeValue(t, group, "pac_aurora_backup_last_verification_timestamp_seconds", nil))
+	assert.Equal(t, 2400.0, gaugeValue(t, group, "pac_aurora_backup_last_verification_duration_seconds", nil))
+	assert.Equal(t, 1.0, gaugeValue(t, group, "pac_aurora_backup_last_verification_success", nil))
+	assert.Equal(t, float64(start.Add(-time.Hour).Unix()), gaugeValue(t, group, "pac_aurora_backup_last_verified_snapshot_timestamp_seconds", nil))
+}
+
+func TestPushVerificationWithoutSnapshot(t *testing.T) {
+	gateway, server := newFakePushgateway(t)
+
+	err := NewPusher(server.URL, "pac-aurora-backup").PushVerification(context.Background(), &Verification{StartTime: time.Now()})
+	require.NoError(t, err)
+
+	group := gateway.groups["/metrics/job/pac-aurora-backup/command/verify"]
+	assert.Equal(t, 0.0, gaugeValue(t, group, "pac_aurora_backup_last_verification_success", nil))
+	assert.NotContains(t, group.families, "pac_aurora_backup_last_verified_snapshot_timestamp_seconds")
+}
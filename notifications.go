@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/config"
+	"github.com/Financial-Times/pac-aurora-backup/notify"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	log "github.com/sirupsen/logrus"
+)
+
+// notifyTimeout bounds the delivery of the notifications of a backup or a cleanup.
+const notifyTimeout = 30 * time.Second
+
+// parseNotifications parses the destinations of the notify option: webhook:<url>, slack:<url> or sns:<topic ARN>.
+func parseNotifications(specs []string) ([]config.Notification, error) {
+	var notifications []config.Notification
+	for _, spec := range specs {
+		kind, arg, found := strings.Cut(spec, ":")
+		if !found || arg == "" {
+			return nil, fmt.Errorf("notification is not webhook:<url>, slack:<url> or sns:<topic arn>: %q", spec)
+		}
+		n := config.Notification{Type: kind, URL: arg}
+		if kind == config.NotificationSNS {
+			n = config.Notification{Type: kind, TopicARN: arg}
+		}
+		if err := n.Validate(); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, nil
+}
+
+// newNotifier returns the notifier sending to the given destinations, or nil if there is none.
+// The SNS topics are published to in their own region.
+func newNotifier(notifications []config.Notification) (*notify.Notifier, error) {
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+	notifier := new(notify.Notifier)
+	for _, n := range notifications {
+		var sink notify.Sink
+		switch n.Type {
+		case config.NotificationWebhook:
+			sink = notify.NewWebhook(n.URL, nil)
+		case config.NotificationSlack:
+			sink = notify.NewSlack(n.URL, nil)
+		case config.NotificationSNS:
+			topic, err := arn.Parse(n.TopicARN)
+			if err != nil {
+				return nil, fmt.Errorf("sns notification topic ARN is invalid: %v", n.TopicARN)
+			}
+			sess, err := session.NewSession(aws.NewConfig().WithRegion(topic.Region))
+			if err != nil {
+				return nil, err
+			}
+			sink = notify.NewSNS(sns.New(sess), n.TopicARN)
+		default:
+			return nil, fmt.Errorf("notification type must be webhook, slack or sns: %q", n.Type)
+		}
+		var tmpl *template.Template
+		if n.Template != "" {
+			var err error
+			if tmpl, err = notify.ParseTemplate(n.Template); err != nil {
+				return nil, err
+			}
+		}
+		notifier.Add(sink, tmpl)
+	}
+	return notifier, nil
+}
+
+// withNotifications returns the backup service notifying the outcome of its backups and cleanups
+// under the given source, or the service itself without notifier.
+func withNotifications(svc backup.Service, notifier *notify.Notifier, source string, slowCreation time.Duration) backup.Service {
+	if notifier == nil {
+		return svc
+	}
+	return &notifyingService{Service: svc, notifier: notifier, source: source, slowCreation: slowCreation}
+}
+
+// notifyingService notifies the outcome of the backups and cleanups of a backup service.
+type notifyingService struct {
+	backup.Service
+	notifier     *notify.Notifier
+	source       string
+	slowCreation time.Duration
+}
+
+func (s *notifyingService) MakeBackup(ctx context.Context) ([]*backup.BackupResult, error) {
+	results, err := s.Service.MakeBackup(ctx)
+	s.notify(notify.BackupEvents(s.source, results, err, s.slowCreation))
+	return results, err
+}
+
+func (s *notifyingService) CleanUpOldBackups(ctx context.Context) ([]*backup.CleanupResult, error) {
+	results, err := s.Service.CleanUpOldBackups(ctx)
+	s.notify(notify.CleanupEvents(s.source, results, err))
+	return results, err
+}
+
+func (s *notifyingService) notify(events []notify.Event) {
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := s.notifier.Notify(ctx, events); err != nil {
+		log.WithError(err).WithField("source", s.source).Warn("Error in sending notifications")
+		return
+	}
+	log.WithField("source", s.source).WithField("events", len(events)).Info("Notifications sent")
+}
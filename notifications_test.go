@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNotifications(t *testing.T) {
+	notifications, err := parseNotifications([]string{
+		"webhook:https://alerts.example.com/backup",
+		"slack:https://hooks.slack.com/services/T0/B0/X",
+		"sns:arn:aws:sns:eu-west-1:123456789012:pac-aurora-backup",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []config.Notification{
+		{Type: config.NotificationWebhook, URL: "https://alerts.example.com/backup"},
+		{Type: config.NotificationSlack, URL: "https://hooks.slack.com/services/T0/B0/X"},
+		{Type: config.NotificationSNS, TopicARN: "arn:aws:sns:eu-west-1:123456789012:pac-aurora-backup"},
+	}, notifications)
+
+	for spec, expected := range map[string]string{
+		"https://alerts.example.com": `notification type must be webhook, slack or sns: "https"`,
+		"webhook:alerts.example.com": `webhook notification url is not an http or https URL: "alerts.example.com"`,
+		"slack":                      `notification is not webhook:<url>, slack:<url> or sns:<topic arn>: "slack"`,
+		"email:pac@example.com":      `notification type must be webhook, slack or sns: "email"`,
+		"sns:pac-aurora-backup":      `sns notification topicArn is not an SNS topic ARN: "pac-aurora-backup"`,
+	} {
+		_, err := parseNotifications([]string{spec})
+		assert.EqualError(t, err, expected, spec)
+	}
+}
+
+func TestNotifyingServiceSendsOutcomes(t *testing.T) {
+	var mu sync.Mutex
+	var kinds []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Kind      string `json:"kind"`
+			Source    string `json:"source"`
+			ClusterID string `json:"clusterId"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		assert.Equal(t, "pac-prod-eu", payload.Source)
+		mu.Lock()
+		kinds = append(kinds, payload.Kind+" "+payload.ClusterID)
+		mu.Unlock()
+	}))
+	defer server.Close()
+	notifier, err := newNotifier([]config.Notification{{Type: config.NotificationWebhook, URL: server.URL}})
+	require.NoError(t, err)
+
+	fake := awsfake.NewRDS()
+	svc, err := backup.NewBackupService(fake.Region, "pac-aurora-prod", "pac-aurora-prod-backup", 0, 5, 7, backup.WithRDSClient(fake))
+	require.NoError(t, err)
+	svc = withNotifications(svc, notifier, "pac-prod-eu", 0)
+
+	_, err = svc.MakeBackup(context.Background())
+	require.Error(t, err)
+	fake.AddCluster("pac-aurora-prod")
+	_, err = svc.MakeBackup(context.Background())
+	require.NoError(t, err)
+	_, err = svc.CleanUpOldBackups(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"backup-failed ", "backup-succeeded pac-aurora-prod"}, kinds)
+}
+
+func TestWithoutNotifications(t *testing.T) {
+	notifier, err := newNotifier(nil)
+	require.NoError(t, err)
+	svc, err := backup.NewBackupService("eu-west-1", "pac-aurora-prod", "pac-aurora-prod-backup", 0, 5, 7, backup.WithRDSClient(awsfake.NewRDS()))
+	require.NoError(t, err)
+	assert.Same(t, svc, withNotifications(svc, notifier, "pac-prod-eu", 0))
+}
@@ -0,0 +1,195 @@
+// Package notify sends the outcome of the backups and cleanups to a generic JSON webhook,
+// a Slack incoming webhook or an SNS topic, so that a failed or slow backup is noticed without watching the logs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+)
+
+// Kind is the outcome an event notifies.
+type Kind string
+
+// Event kinds.
+const (
+	BackupSucceeded Kind = "backup-succeeded"
+	BackupFailed    Kind = "backup-failed"
+	// SlowCreation is a successful backup whose snapshot took longer than the threshold to be created,
+	// which is notified besides BackupSucceeded.
+	SlowCreation  Kind = "slow-creation"
+	CleanupFailed Kind = "cleanup-failed"
+)
+
+// Event is an outcome of a backup or a cleanup. The fields not relevant to its kind are empty.
+type Event struct {
+	Kind Kind
+	// Source identifies where the event comes from, e.g. the PAC environment or the target of the config file.
+	Source string
+	// ClusterID is empty for a backup failing before any cluster was backed up, e.g. when no cluster is found,
+	// and for a cleanup not restricted to a single cluster.
+	ClusterID        string
+	SnapshotID       string
+	Region           string
+	SnapshotIDPrefix string
+	Duration         time.Duration
+	CreationDuration time.Duration
+	// Threshold is the creation duration over which a snapshot creation is slow.
+	Threshold time.Duration
+	Error     string
+	Time      time.Time
+}
+
+// defaultTemplates are the messages of the events of each kind when the destination has no template of its own.
+var defaultTemplates = map[Kind]string{
+	BackupSucceeded: `{{.Source}}: backup of {{.ClusterID}} succeeded in {{.Duration}}, snapshot {{.SnapshotID}}`,
+	BackupFailed:    `{{.Source}}: backup of {{or .ClusterID "the DB clusters"}} failed{{with .Duration}} after {{.}}{{end}}{{with .SnapshotID}}, snapshot {{.}}{{end}}: {{.Error}}`,
+	SlowCreation:    `{{.Source}}: snapshot {{.SnapshotID}} of {{.ClusterID}} took {{.CreationDuration}} to be created, more than {{.Threshold}}`,
+	CleanupFailed:   `{{.Source}}: cleanup{{with .SnapshotIDPrefix}} of {{.}} snapshots{{end}}{{with .ClusterID}} of {{.}}{{end}}{{with .Region}} in {{.}}{{end}} failed{{with .Duration}} after {{.}}{{end}}: {{.Error}}`,
+}
+
+// ParseTemplate parses a message template, a Go text/template executed with the Event,
+// e.g. `{{.Kind}} {{.ClusterID}} {{.SnapshotID}} {{.Error}}`.
+func ParseTemplate(text string) (*template.Template, error) {
+	t, err := template.New("message").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("notification template: %w", err)
+	}
+	if err := t.Execute(new(bytes.Buffer), Event{}); err != nil {
+		return nil, fmt.Errorf("notification template: %w", err)
+	}
+	return t, nil
+}
+
+// Sink delivers the notifications to a destination.
+type Sink interface {
+	// Send delivers the message rendered for the event.
+	Send(ctx context.Context, event Event, message string) error
+}
+
+type destination struct {
+	sink     Sink
+	template *template.Template
+}
+
+// Notifier sends the events to every destination added to it.
+type Notifier struct {
+	destinations []destination
+}
+
+// Add adds a destination, whose messages are rendered with the template,
+// or with a default template per kind of event if the template is nil.
+func (n *Notifier) Add(sink Sink, template *template.Template) {
+	n.destinations = append(n.destinations, destination{sink: sink, template: template})
+}
+
+// Notify sends every event to every destination, returning the errors of the failed deliveries.
+// A failed delivery does not prevent the others.
+func (n *Notifier) Notify(ctx context.Context, events []Event) error {
+	var errs []error
+	for _, event := range events {
+		for _, d := range n.destinations {
+			message, err := d.render(event)
+			if err == nil {
+				err = d.sink.Send(ctx, event, message)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("sending %v notification: %w", event.Kind, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d destination) render(event Event) (string, error) {
+	t := d.template
+	if t == nil {
+		t = parsedDefaultTemplates[event.Kind]
+	}
+	if t == nil {
+		return "", fmt.Errorf("no message template for %v events", event.Kind)
+	}
+	var message bytes.Buffer
+	if err := t.Execute(&message, event); err != nil {
+		return "", err
+	}
+	return message.String(), nil
+}
+
+// parsedDefaultTemplates are the parsed defaultTemplates.
+var parsedDefaultTemplates = func() map[Kind]*template.Template {
+	templates := make(map[Kind]*template.Template)
+	for kind, text := range defaultTemplates {
+		templates[kind] = template.Must(ParseTemplate(text))
+	}
+	return templates
+}()
+
+// BackupEvents returns the events of the outcome of MakeBackup: a success or a failure per cluster,
+// and a slow creation for the snapshots created by the run that took longer than slowCreation, 0 disabling it.
+// An error not attributed to a cluster, e.g. of the cluster discovery, is a failure without cluster.
+func BackupEvents(source string, results []*backup.BackupResult, err error, slowCreation time.Duration) []Event {
+	now := time.Now().UTC()
+	var events []Event
+	clusterFailed := false
+	for _, r := range results {
+		event := Event{
+			Kind:             BackupSucceeded,
+			Source:           source,
+			ClusterID:        r.ClusterID,
+			SnapshotID:       r.SnapshotID,
+			Duration:         r.Duration.Round(time.Second),
+			CreationDuration: r.CreationDuration.Round(time.Second),
+			Time:             now,
+		}
+		if r.Err != nil {
+			clusterFailed = true
+			event.Kind = BackupFailed
+			event.Error = r.Err.Error()
+			events = append(events, event)
+			continue
+		}
+		events = append(events, event)
+		if slowCreation > 0 && !r.Reused && r.CreationDuration > slowCreation {
+			event.Kind = SlowCreation
+			event.Threshold = slowCreation
+			events = append(events, event)
+		}
+	}
+	if err != nil && !clusterFailed {
+		events = append(events, Event{Kind: BackupFailed, Source: source, Error: err.Error(), Time: now})
+	}
+	return events
+}
+
+// CleanupEvents returns the events of the outcome of CleanUpOldBackups: a failure per failed cleanup,
+// and a failure without cluster nor region for an error not attributed to a cleanup.
+// Successful cleanups are not notified.
+func CleanupEvents(source string, results []*backup.CleanupResult, err error) []Event {
+	now := time.Now().UTC()
+	var events []Event
+	for _, r := range results {
+		if r.Err == nil {
+			continue
+		}
+		events = append(events, Event{
+			Kind:             CleanupFailed,
+			Source:           source,
+			ClusterID:        r.ClusterID,
+			Region:           r.Region,
+			SnapshotIDPrefix: r.SnapshotIDPrefix,
+			Duration:         r.Duration.Round(time.Second),
+			Error:            r.Err.Error(),
+			Time:             now,
+		})
+	}
+	if err != nil && len(events) == 0 {
+		events = append(events, Event{Kind: CleanupFailed, Source: source, Error: err.Error(), Time: now})
+	}
+	return events
+}
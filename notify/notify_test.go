@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink records the messages sent to it, failing with err if it is set.
+type recordingSink struct {
+	messages []string
+	err      error
+}
+
+func (s *recordingSink) Send(ctx context.Context, event Event, message string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func TestBackupEvents(t *testing.T) {
+	results := []*backup.BackupResult{
+		{ClusterID: "pac-aurora-prod-eu", SnapshotID: "pac-aurora-prod-eu-backup-2024-01-05-03-00-00", Duration: 95*time.Minute + 400*time.Millisecond, CreationDuration: 90 * time.Minute},
+		{ClusterID: "pac-aurora-prod-us", SnapshotID: "pac-aurora-prod-us-backup-2024-01-05-03-00-00", Duration: 10 * time.Minute, CreationDuration: 9 * time.Minute},
+		{ClusterID: "pac-aurora-prod-ap", Duration: time.Second, Err: errors.New("cluster is not available")},
+	}
+
+	events := BackupEvents("pac-prod-eu", results, errors.New("cluster is not available"), time.Hour)
+	require.Len(t, events, 4)
+	assert.Equal(t, []Kind{BackupSucceeded, SlowCreation, BackupSucceeded, BackupFailed},
+		[]Kind{events[0].Kind, events[1].Kind, events[2].Kind, events[3].Kind})
+	assert.Equal(t, 95*time.Minute, events[0].Duration)
+	assert.Equal(t, time.Hour, events[1].Threshold)
+	assert.Equal(t, "pac-aurora-prod-ap", events[3].ClusterID)
+	assert.Equal(t, "cluster is not available", events[3].Error)
+
+	events = BackupEvents("pac-prod-eu", results[:2], nil, 0)
+	assert.Len(t, events, 2, "a zero threshold should disable the slow creation events")
+
+	events = BackupEvents("pac-prod-eu", []*backup.BackupResult{{ClusterID: "pac-aurora-prod-eu", Reused: true, CreationDuration: 90 * time.Minute}}, nil, time.Hour)
+	assert.Len(t, events, 1, "a reused snapshot should not be notified as a slow creation")
+
+	events = BackupEvents("pac-prod-eu", nil, errors.New("no cluster found"), time.Hour)
+	require.Len(t, events, 1)
+	assert.Equal(t, BackupFailed, events[0].Kind)
+	assert.Empty(t, events[0].ClusterID)
+	assert.Equal(t, "no cluster found", events[0].Error)
+}
+
+func TestCleanupEvents(t *testing.T) {
+	results := []*backup.CleanupResult{
+		{Region: "eu-west-1", SnapshotIDPrefix: "pac-aurora-prod-backup", Deleted: []string{"a"}},
+		{Region: "us-east-1", SnapshotIDPrefix: "pac-aurora-prod-backup", Duration: 3 * time.Second, Err: errors.New("access denied")},
+	}
+
+	events := CleanupEvents("pac-prod-eu", results, errors.New("access denied"))
+	require.Len(t, events, 1)
+	assert.Equal(t, CleanupFailed, events[0].Kind)
+	assert.Equal(t, "us-east-1", events[0].Region)
+	assert.Equal(t, "access denied", events[0].Error)
+
+	assert.Empty(t, CleanupEvents("pac-prod-eu", results[:1], nil))
+	assert.Len(t, CleanupEvents("pac-prod-eu", nil, errors.New("listing failed")), 1)
+}
+
+func TestNotifyRendersDefaultMessages(t *testing.T) {
+	sink := new(recordingSink)
+	notifier := new(Notifier)
+	notifier.Add(sink, nil)
+	events := []Event{
+		{Kind: BackupSucceeded, Source: "pac-prod-eu", ClusterID: "pac-aurora-prod", SnapshotID: "pac-aurora-prod-backup-2024-01-05", Duration: 95 * time.Minute},
+		{Kind: BackupFailed, Source: "pac-prod-eu", Error: "no cluster found"},
+		{Kind: SlowCreation, Source: "pac-prod-eu", ClusterID: "pac-aurora-prod", SnapshotID: "pac-aurora-prod-backup-2024-01-05", CreationDuration: 90 * time.Minute, Threshold: time.Hour},
+		{Kind: CleanupFailed, Source: "prod", Region: "us-east-1", SnapshotIDPrefix: "pac-aurora-prod-backup", Duration: 3 * time.Second, Error: "access denied"},
+	}
+
+	require.NoError(t, notifier.Notify(context.Background(), events))
+	assert.Equal(t, []string{
+		"pac-prod-eu: backup of pac-aurora-prod succeeded in 1h35m0s, snapshot pac-aurora-prod-backup-2024-01-05",
+		"pac-prod-eu: backup of the DB clusters failed: no cluster found",
+		"pac-prod-eu: snapshot pac-aurora-prod-backup-2024-01-05 of pac-aurora-prod took 1h30m0s to be created, more than 1h0m0s",
+		"prod: cleanup of pac-aurora-prod-backup snapshots in us-east-1 failed after 3s: access denied",
+	}, sink.messages)
+}
+
+func TestNotifyWithTemplateAndFailingSink(t *testing.T) {
+	template, err := ParseTemplate(`[{{.Kind}}] {{.ClusterID}}{{with .Error}}: {{.}}{{end}}`)
+	require.NoError(t, err)
+	failing := &recordingSink{err: errors.New("connection refused")}
+	sink := new(recordingSink)
+	notifier := new(Notifier)
+	notifier.Add(failing, nil)
+	notifier.Add(sink, template)
+
+	err = notifier.Notify(context.Background(), []Event{{Kind: BackupFailed, ClusterID: "pac-aurora-prod", Error: "timeout"}})
+	assert.EqualError(t, err, "sending backup-failed notification: connection refused")
+	assert.Equal(t, []string{"[backup-failed] pac-aurora-prod: timeout"}, sink.messages, "a failed delivery should not prevent the others")
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	_, err := ParseTemplate(`{{.Cluster}`)
+	assert.ErrorContains(t, err, "notification template: template: message:1: bad character")
+	_, err = ParseTemplate(`{{.Cluster}}`)
+	assert.ErrorContains(t, err, "can't evaluate field Cluster")
+}
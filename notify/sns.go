@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sns"
+)
+
+// maxSNSSubjectLength is the maximum length of the subject of an SNS message, used by its email subscriptions.
+const maxSNSSubjectLength = 100
+
+// SNSClient is the subset of the AWS SNS API used by SNS.
+// It is satisfied by *sns.SNS and by the in-memory fake in the awsfake package.
+type SNSClient interface {
+	PublishWithContext(aws.Context, *sns.PublishInput, ...request.Option) (*sns.PublishOutput, error)
+}
+
+// SNS publishes the messages of the events to an SNS topic, with the kind of the event as the kind message attribute,
+// so that the subscriptions can filter the events, e.g. to page only on failures.
+type SNS struct {
+	client   SNSClient
+	topicARN string
+}
+
+// NewSNS returns a sink publishing to the topic with the given ARN.
+func NewSNS(client SNSClient, topicARN string) *SNS {
+	return &SNS{client: client, topicARN: topicARN}
+}
+
+func (s *SNS) Send(ctx context.Context, event Event, message string) error {
+	subject := event.Source + ": " + string(event.Kind)
+	if len(subject) > maxSNSSubjectLength {
+		subject = subject[:maxSNSSubjectLength]
+	}
+	_, err := s.client.PublishWithContext(ctx, &sns.PublishInput{
+		TopicArn: aws.String(s.topicARN),
+		Subject:  aws.String(subject),
+		Message:  aws.String(message),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"kind": {DataType: aws.String("String"), StringValue: aws.String(string(event.Kind))},
+		},
+	})
+	return err
+}
@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSNSPublishesMessage(t *testing.T) {
+	const topicARN = "arn:aws:sns:eu-west-1:123456789012:pac-aurora-backup"
+	fake := awsfake.NewSNS()
+	fake.AddTopic(topicARN)
+	sink := NewSNS(fake, topicARN)
+
+	require.NoError(t, sink.Send(context.Background(), Event{Kind: CleanupFailed, Source: "pac-prod-eu"}, "cleanup failed"))
+	require.NoError(t, sink.Send(context.Background(), Event{Kind: BackupFailed, Source: strings.Repeat("a", 120)}, "backup failed"))
+
+	messages := fake.Messages(topicARN)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "pac-prod-eu: cleanup-failed", aws.StringValue(messages[0].Subject))
+	assert.Equal(t, "cleanup failed", aws.StringValue(messages[0].Message))
+	assert.Equal(t, "cleanup-failed", aws.StringValue(messages[0].MessageAttributes["kind"].StringValue))
+	assert.Len(t, aws.StringValue(messages[1].Subject), maxSNSSubjectLength)
+
+	err := NewSNS(fake, topicARN+"-deleted").Send(context.Background(), Event{Kind: BackupFailed}, "backup failed")
+	assert.ErrorContains(t, err, "NotFound")
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultHTTPTimeout bounds a request to a webhook made with the default client.
+const defaultHTTPTimeout = 10 * time.Second
+
+// HTTPDoer sends the requests of the webhooks, e.g. an *http.Client.
+type HTTPDoer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// WebhookPayload is the JSON body posted by a Webhook: the event, with the durations in seconds, and its message.
+type WebhookPayload struct {
+	Kind                    Kind      `json:"kind"`
+	Source                  string    `json:"source"`
+	ClusterID               string    `json:"clusterId,omitempty"`
+	SnapshotID              string    `json:"snapshotId,omitempty"`
+	Region                  string    `json:"region,omitempty"`
+	SnapshotIDPrefix        string    `json:"snapshotIdPrefix,omitempty"`
+	DurationSeconds         float64   `json:"durationSeconds"`
+	CreationDurationSeconds float64   `json:"creationDurationSeconds,omitempty"`
+	ThresholdSeconds        float64   `json:"thresholdSeconds,omitempty"`
+	Error                   string    `json:"error,omitempty"`
+	Time                    time.Time `json:"time"`
+	Message                 string    `json:"message"`
+}
+
+// Webhook posts the events as a JSON WebhookPayload to a URL.
+type Webhook struct {
+	url    string
+	client HTTPDoer
+}
+
+// NewWebhook returns a sink posting to the webhook at the given URL with the client,
+// or with an http.Client with a 10s timeout if client is nil.
+func NewWebhook(url string, client HTTPDoer) *Webhook {
+	return &Webhook{url: url, client: httpClient(client)}
+}
+
+func (w *Webhook) Send(ctx context.Context, event Event, message string) error {
+	return postJSON(ctx, w.client, w.url, WebhookPayload{
+		Kind:                    event.Kind,
+		Source:                  event.Source,
+		ClusterID:               event.ClusterID,
+		SnapshotID:              event.SnapshotID,
+		Region:                  event.Region,
+		SnapshotIDPrefix:        event.SnapshotIDPrefix,
+		DurationSeconds:         event.Duration.Seconds(),
+		CreationDurationSeconds: event.CreationDuration.Seconds(),
+		ThresholdSeconds:        event.Threshold.Seconds(),
+		Error:                   event.Error,
+		Time:                    event.Time,
+		Message:                 message,
+	})
+}
+
+// Slack posts the messages of the events to a Slack incoming webhook.
+type Slack struct {
+	url    string
+	client HTTPDoer
+}
+
+// NewSlack returns a sink posting to the Slack incoming webhook at the given URL with the client,
+// or with an http.Client with a 10s timeout if client is nil.
+func NewSlack(url string, client HTTPDoer) *Slack {
+	return &Slack{url: url, client: httpClient(client)}
+}
+
+func (s *Slack) Send(ctx context.Context, event Event, message string) error {
+	return postJSON(ctx, s.client, s.url, struct {
+		Text string `json:"text"`
+	}{Text: message})
+}
+
+func httpClient(client HTTPDoer) HTTPDoer {
+	if client == nil {
+		return &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	return client
+}
+
+// postJSON posts the body as JSON, failing unless the response has a 2xx status.
+// The errors only name the host of the URL, as the path of a webhook URL is often its secret.
+func postJSON(ctx context.Context, client HTTPDoer, target string, body interface{}) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return errors.New("invalid webhook URL")
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("webhook at %v: %w", u.Host, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error of the client quotes the URL, which is left out.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("webhook at %v: %w", u.Host, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook at %v responded %v", u.Host, resp.Status)
+	}
+	return nil
+}
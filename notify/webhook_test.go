@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReceiver records the JSON bodies posted to it, responding with status.
+type fakeReceiver struct {
+	mu     sync.Mutex
+	bodies []map[string]interface{}
+	status int
+}
+
+func newFakeReceiver(t *testing.T) (*fakeReceiver, *httptest.Server) {
+	receiver := &fakeReceiver{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		receiver.mu.Lock()
+		receiver.bodies = append(receiver.bodies, body)
+		status := receiver.status
+		receiver.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server
+}
+
+func TestWebhookPostsEvent(t *testing.T) {
+	receiver, server := newFakeReceiver(t)
+	created := time.Date(2024, 1, 5, 3, 0, 0, 0, time.UTC)
+	event := Event{
+		Kind:             SlowCreation,
+		Source:           "pac-prod-eu",
+		ClusterID:        "pac-aurora-prod",
+		SnapshotID:       "pac-aurora-prod-backup-2024-01-05-03-00-00",
+		Duration:         95 * time.Minute,
+		CreationDuration: 90 * time.Minute,
+		Threshold:        time.Hour,
+		Time:             created,
+	}
+
+	require.NoError(t, NewWebhook(server.URL+"/hooks/backup", nil).Send(context.Background(), event, "snapshot took 1h30m0s"))
+	require.Len(t, receiver.bodies, 1)
+	assert.Equal(t, map[string]interface{}{
+		"kind":                    "slow-creation",
+		"source":                  "pac-prod-eu",
+		"clusterId":               "pac-aurora-prod",
+		"snapshotId":              "pac-aurora-prod-backup-2024-01-05-03-00-00",
+		"durationSeconds":         5700.0,
+		"creationDurationSeconds": 5400.0,
+		"thresholdSeconds":        3600.0,
+		"time":                    "2024-01-05T03:00:00Z",
+		"message":                 "snapshot took 1h30m0s",
+	}, receiver.bodies[0])
+}
+
+func TestSlackPostsText(t *testing.T) {
+	receiver, server := newFakeReceiver(t)
+	notifier := new(Notifier)
+	notifier.Add(NewSlack(server.URL+"/services/T0/B0/X", server.Client()), nil)
+
+	err := notifier.Notify(context.Background(), []Event{{Kind: BackupFailed, Source: "pac-prod-eu", ClusterID: "pac-aurora-prod", Error: "timeout"}})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"text": "pac-prod-eu: backup of pac-aurora-prod failed: timeout"}}, receiver.bodies)
+}
+
+func TestWebhookErrorsDoNotQuoteURL(t *testing.T) {
+	receiver, server := newFakeReceiver(t)
+	receiver.status = http.StatusForbidden
+	slack := NewSlack(server.URL+"/services/T0/B0/secret", nil)
+
+	err := slack.Send(context.Background(), Event{Kind: BackupFailed}, "backup failed")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "responded 403 Forbidden")
+	assert.NotContains(t, err.Error(), "secret")
+
+	server.Close()
+	err = slack.Send(context.Background(), Event{Kind: BackupFailed}, "backup failed")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	cli "github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+// pinCommand configures the pin subcommand, which holds a backup snapshot, e.g. for a legal hold,
+// so that the cleanup keeps it until it is unpinned or the hold expires.
+func pinCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Spec = "--snapshot-id --reason --owner [--until]"
+
+	snapshotID := cmd.String(cli.StringOpt{
+		Name: "snapshot-id",
+		Desc: "The identifier of the snapshot to pin, or latest for the most recent available backup snapshot",
+	})
+
+	reason := cmd.String(cli.StringOpt{
+		Name: "reason",
+		Desc: "Why the snapshot is held, e.g. a ticket number, with letters, digits, spaces and _.:/=+-@ only",
+	})
+
+	owner := cmd.String(cli.StringOpt{
+		Name: "owner",
+		Desc: "Who to ask before unpinning the snapshot, e.g. an email address",
+	})
+
+	until := cmd.String(cli.StringOpt{
+		Name: "until",
+		Desc: "When the hold expires, as a date (2006-01-02, held until the end of the day in UTC), an RFC 3339 time, or a duration from now like 90d, by default never",
+	})
+
+	cmd.Action = func() {
+		ctx, cancel := env.newContext()
+		defer cancel()
+
+		untilTime, err := parseHoldUntil(*until, time.Now())
+		if err != nil {
+			log.WithError(err).Error("Invalid hold expiry")
+			cli.Exit(exitCodeError)
+		}
+
+		svc := env.newService()
+		result, err := svc.Pin(ctx, backup.PinRequest{
+			SnapshotID: *snapshotID,
+			Hold:       backup.Hold{Reason: *reason, Owner: *owner, Until: untilTime},
+		})
+		if result != nil {
+			if printErr := printPinResult(os.Stdout, result, true); printErr != nil {
+				log.WithError(printErr).Error("Error in printing the pinned snapshot")
+			}
+		}
+		if code := runExitCode(ctx, err); code != exitCodeSuccess {
+			log.WithError(err).
+				WithField("snapshotID", *snapshotID).
+				WithField("exitCode", code).
+				Error("PAC aurora snapshot pin failed")
+			cli.Exit(code)
+		}
+	}
+}
+
+// unpinCommand configures the unpin subcommand, which removes the hold of a backup snapshot,
+// so that the cleanup applies the retention to it again.
+func unpinCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Spec = "--snapshot-id"
+
+	snapshotID := cmd.String(cli.StringOpt{
+		Name: "snapshot-id",
+		Desc: "The identifier of the snapshot to unpin",
+	})
+
+	cmd.Action = func() {
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		result, err := svc.Unpin(ctx, *snapshotID)
+		if result != nil {
+			if printErr := printPinResult(os.Stdout, result, false); printErr != nil {
+				log.WithError(printErr).Error("Error in printing the unpinned snapshot")
+			}
+		}
+		if code := runExitCode(ctx, err); code != exitCodeSuccess {
+			log.WithError(err).
+				WithField("snapshotID", *snapshotID).
+				WithField("exitCode", code).
+				Error("PAC aurora snapshot unpin failed")
+			cli.Exit(code)
+		}
+	}
+}
+
+// parseHoldUntil parses the expiry of a hold: a date, held until the end of the day in UTC, an RFC 3339 time,
+// or a duration from now in the format of the backup ages. An empty string is a hold without expiry.
+func parseHoldUntil(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	d, err := backup.ParseBackupAge(s)
+	if err != nil || d == 0 {
+		return time.Time{}, fmt.Errorf("hold expiry is neither a date, an RFC 3339 time nor a duration: %v", s)
+	}
+	return now.Add(d).UTC().Truncate(time.Second), nil
+}
+
+// printPinResult writes the snapshot pinned or unpinned and the regions where it was tagged.
+func printPinResult(w io.Writer, result *backup.PinResult, pinned bool) error {
+	action := "Unpinned"
+	if pinned {
+		action = "Pinned"
+	}
+	if _, err := fmt.Fprintf(w, "%v snapshot %v in %v\n", action, result.SnapshotID, strings.Join(result.Regions, ", ")); err != nil {
+		return err
+	}
+	if result.Hold == nil {
+		return nil
+	}
+	prefix := "Hold: "
+	if !pinned {
+		prefix = "Removed hold: "
+	}
+	_, err := fmt.Fprintln(w, prefix+result.Hold.String())
+	return err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHoldUntil(t *testing.T) {
+	now := time.Date(2024, 1, 5, 3, 0, 0, 0, time.UTC)
+	for s, expected := range map[string]time.Time{
+		"":                          {},
+		"2024-03-31":                time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		"2024-03-31T12:00:00+01:00": time.Date(2024, 3, 31, 11, 0, 0, 0, time.UTC),
+		"90d":                       time.Date(2024, 4, 4, 3, 0, 0, 0, time.UTC),
+		"36h":                       time.Date(2024, 1, 6, 15, 0, 0, 0, time.UTC),
+	} {
+		until, err := parseHoldUntil(s, now)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, until, s)
+	}
+
+	for _, s := range []string{"next week", "0d", "-1d", "31/03/2024"} {
+		_, err := parseHoldUntil(s, now)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestPrintPinResult(t *testing.T) {
+	result := &backup.PinResult{
+		SnapshotID: "pac-aurora-prod-backup-2024-01-05-03-00-00",
+		Regions:    []string{"eu-west-1", "us-east-1"},
+		Hold:       &backup.Hold{Reason: "INC-1234", Owner: "jane.doe@ft.com", Until: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printPinResult(&out, result, true))
+	assert.Equal(t, "Pinned snapshot pac-aurora-prod-backup-2024-01-05-03-00-00 in eu-west-1, us-east-1\n"+
+		"Hold: held by jane.doe@ft.com: INC-1234 (until 2024-04-01T00:00:00Z)\n", out.String())
+
+	out.Reset()
+	result.Hold = nil
+	require.NoError(t, printPinResult(&out, result, false))
+	assert.Equal(t, "Unpinned snapshot pac-aurora-prod-backup-2024-01-05-03-00-00 in eu-west-1, us-east-1\n", out.String())
+}
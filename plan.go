@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	log "github.com/sirupsen/logrus"
+)
+
+// printPlan writes a human-readable table of the snapshots a run would create and delete.
+func printPlan(w io.Writer, plan *backup.Plan) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "Snapshots to create:")
+	fmt.Fprintln(tw, "CLUSTER\tSNAPSHOT\tSHARED WITH\tCOPY REGIONS\tVAULT ACCOUNT\tBLOCKED BY")
+	for _, b := range plan.Backups {
+		blockedBy := "-"
+		if len(b.PreflightConditions) > 0 {
+			blockedBy = strings.Join(b.PreflightConditions, "; ")
+		}
+		snapshotID := b.SnapshotID
+		if b.Reused {
+			snapshotID += " (reused)"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\n", b.ClusterID, snapshotID, listOrDash(b.SharedWith), listOrDash(b.CopyRegions), valueOrDash(b.VaultAccountID), blockedBy)
+	}
+
+	for _, c := range plan.Cleanups {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "Cleanup of %v* in %v with %v:\n", c.SnapshotIDPrefix, c.Region, c.Policy)
+		if c.Err != nil {
+			fmt.Fprintf(tw, "error: %v\n", c.Err)
+			continue
+		}
+		var deleted, skipped int
+		fmt.Fprintln(tw, "ACTION\tSNAPSHOT\tCREATED\tAGE\tREASON")
+		for _, s := range c.Snapshots {
+			action := "keep"
+			switch {
+			case s.Skipped():
+				action = "skip"
+				skipped++
+			case !s.Keep:
+				action = "delete"
+				deleted++
+			}
+			snapshotID := s.SnapshotID
+			if snapshotID == c.NewSnapshotID {
+				snapshotID += " (new)"
+			}
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", action, snapshotID, formatCreateTime(s.CreateTime), formatAge(s.Age), s.Reason)
+		}
+		fmt.Fprintf(tw, "%d snapshot(s) to keep, %d to delete\n", len(c.Snapshots)-deleted-skipped, deleted)
+		if skipped > 0 {
+			fmt.Fprintf(tw, "%d snapshot(s) left in place as neither available nor being deleted\n", skipped)
+		}
+		if len(c.Unmanaged) > 0 {
+			fmt.Fprintf(tw, "%d snapshot(s) ignored as not tagged %v=%v: %v\n", len(c.Unmanaged), backup.ManagedByTagKey, backup.ManagedByTagValue, strings.Join(c.Unmanaged, ", "))
+		}
+		if len(c.Held) > 0 {
+			fmt.Fprintf(tw, "%d snapshot(s) kept as pinned: %v\n", len(c.Held), strings.Join(c.Held, ", "))
+		}
+	}
+	return tw.Flush()
+}
+
+// printCleanupPlan writes the cleanups of the plan of the service, in the source and in the copy regions.
+func printCleanupPlan(ctx context.Context, w io.Writer, svc backup.Service) error {
+	plan, err := svc.Plan(ctx)
+	if plan != nil {
+		if printErr := printPlan(w, &backup.Plan{Cleanups: plan.Cleanups}); printErr != nil {
+			log.WithError(printErr).Error("Error in printing the dry run plan")
+		}
+	}
+	return err
+}
+
+// formatAge formats a snapshot age in days and hours, e.g. 3d4h.
+func formatAge(d time.Duration) string {
+	if d <= 0 {
+		return "0h"
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	if days == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd%dh", days, hours)
+}
+
+func listOrDash(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
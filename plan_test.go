@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintPlan(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	plan := &backup.Plan{
+		Backups: []*backup.BackupPlan{{
+			ClusterID:   "pac-aurora-prod",
+			SnapshotID:  "pac-aurora-prod-backup-2024-01-05-03-04-05",
+			CopyRegions: []string{"eu-central-1", "us-east-1"},
+		}, {
+			ClusterID:           "pac-aurora-prod-us",
+			SnapshotID:          "pac-aurora-prod-us-backup-2024-01-05-03-04-05",
+			PreflightConditions: []string{"cluster status is modifying", "in backup window 03:00-03:30"},
+		}},
+		Cleanups: []*backup.CleanupPlan{
+			{
+				Region:           "eu-west-1",
+				SnapshotIDPrefix: "pac-aurora-prod-backup",
+				Policy:           backup.KeepLast(1),
+				NewSnapshotID:    "pac-aurora-prod-backup-2024-01-05-03-04-05",
+				Snapshots: []backup.SnapshotDecision{
+					{SnapshotID: "pac-aurora-prod-backup-2024-01-05-03-04-05", Status: "available", CreateTime: created.AddDate(0, 0, 3), Keep: true, Reason: "keep-last"},
+					{SnapshotID: "pac-aurora-prod-backup-2024-01-02-03-04-05", Status: "available", CreateTime: created, Age: 73 * time.Hour, Reason: "not selected by retention policy keep-last=1"},
+					{SnapshotID: "pac-aurora-prod-backup-2024-01-01-03-04-05", Status: "failed", CreateTime: created.AddDate(0, 0, -1), Age: 97 * time.Hour, Reason: "failed, not counted by retention policy keep-last=1"},
+				},
+				Unmanaged: []string{"pac-aurora-prod-backup-before-migration"},
+				Held:      []string{"pac-aurora-prod-backup-2023-11-02-03-04-05"},
+			},
+			{
+				Region:           "us-east-1",
+				SnapshotIDPrefix: "pac-aurora-prod-backup",
+				Policy:           backup.KeepLast(7),
+				Err:              errors.New("rate exceeded"),
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printPlan(&out, plan))
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "Snapshots to create:", lines[0])
+	assert.Regexp(t, `^CLUSTER +SNAPSHOT +SHARED WITH +COPY REGIONS +VAULT ACCOUNT +BLOCKED BY$`, lines[1])
+	assert.Regexp(t, `^pac-aurora-prod +pac-aurora-prod-backup-2024-01-05-03-04-05 +- +eu-central-1,us-east-1 +- +-$`, lines[2])
+	assert.Regexp(t, `^pac-aurora-prod-us +pac-aurora-prod-us-backup-2024-01-05-03-04-05 +- +- +- +cluster status is modifying; in backup window 03:00-03:30$`, lines[3])
+	assert.Equal(t, "Cleanup of pac-aurora-prod-backup* in eu-west-1 with keep-last=1:", lines[5])
+	assert.Regexp(t, `^keep +pac-aurora-prod-backup-2024-01-05-03-04-05 \(new\) +2024-01-05T03:04:05Z +0h +keep-last$`, lines[7])
+	assert.Regexp(t, `^delete +pac-aurora-prod-backup-2024-01-02-03-04-05 +2024-01-02T03:04:05Z +3d1h +not selected by retention policy keep-last=1$`, lines[8])
+	assert.Regexp(t, `^skip +pac-aurora-prod-backup-2024-01-01-03-04-05 +2024-01-01T03:04:05Z +4d1h +failed, not counted by retention policy keep-last=1$`, lines[9])
+	assert.Equal(t, "1 snapshot(s) to keep, 1 to delete", lines[10])
+	assert.Equal(t, "1 snapshot(s) left in place as neither available nor being deleted", lines[11])
+	assert.Equal(t, "1 snapshot(s) ignored as not tagged managed-by=pac-aurora-backup: pac-aurora-prod-backup-before-migration", lines[12])
+	assert.Equal(t, "1 snapshot(s) kept as pinned: pac-aurora-prod-backup-2023-11-02-03-04-05", lines[13])
+	assert.Equal(t, "Cleanup of pac-aurora-prod-backup* in us-east-1 with keep-last=7:", lines[15])
+	assert.Equal(t, "error: rate exceeded", lines[16])
+}
+
+func TestPrintPlanReusedSnapshot(t *testing.T) {
+	plan := &backup.Plan{
+		Backups: []*backup.BackupPlan{{
+			ClusterID:  "pac-aurora-prod",
+			SnapshotID: "pac-aurora-prod-backup-2024-01-05-03-04-05",
+			Reused:     true,
+		}},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, printPlan(&out, plan))
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Regexp(t, `^pac-aurora-prod +pac-aurora-prod-backup-2024-01-05-03-04-05 \(reused\) +- +- +- +-$`, lines[2])
+}
+
+func TestFormatAge(t *testing.T) {
+	assert.Equal(t, "0h", formatAge(-time.Minute))
+	assert.Equal(t, "0h", formatAge(59*time.Minute))
+	assert.Equal(t, "23h", formatAge(23*time.Hour+59*time.Minute))
+	assert.Equal(t, "1d0h", formatAge(24*time.Hour))
+	assert.Equal(t, "400d12h", formatAge(400*24*time.Hour+12*time.Hour))
+}
+
+func TestPrintCleanupPlanIncludesCopyRegions(t *testing.T) {
+	source := awsfake.NewRDS()
+	source.AddCluster("pac-aurora-prod")
+	copies := awsfake.NewRDS()
+	copies.Region = "us-east-1"
+	now := time.Now().UTC()
+	for i := 1; i <= 2; i++ {
+		copies.AddSnapshot(&rds.DBClusterSnapshot{
+			DBClusterIdentifier:         aws.String("pac-aurora-prod"),
+			DBClusterSnapshotIdentifier: aws.String("pac-aurora-prod-backup-" + now.AddDate(0, 0, -i).Format("2006-01-02-15-04-05")),
+			SnapshotCreateTime:          aws.Time(now.AddDate(0, 0, -i)),
+			TagList:                     []*rds.Tag{{Key: aws.String(backup.ManagedByTagKey), Value: aws.String(backup.ManagedByTagValue)}},
+		})
+	}
+	svc, err := backup.NewBackupService(source.Region, "pac-aurora-prod", "pac-aurora-prod-backup", 0, 5, 7,
+		backup.WithRDSClient(source),
+		backup.WithClientFactory(func(region, roleARN string) (backup.RDSClient, error) { return copies, nil }),
+		backup.WithCopyDestinations(backup.CopyDestination{Region: copies.Region, Retention: 1}))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, printCleanupPlan(context.Background(), &out, svc))
+
+	assert.Contains(t, out.String(), "Cleanup of pac-aurora-prod-backup* in us-east-1 with keep-last=1:")
+	assert.Regexp(t, `(?m)^delete +pac-aurora-prod-backup-`+now.AddDate(0, 0, -2).Format("2006-01-02")+`-`, out.String())
+	assert.Len(t, copies.Snapshots(), 2)
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// S3Client is the subset of the AWS S3 API used to upload the reports.
+// It is satisfied by *s3.S3 and by the in-memory fake in the awsfake package.
+type S3Client interface {
+	PutObjectWithContext(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error)
+}
+
+// Destination is where a report is written: stdout, a local file or an S3 object.
+type Destination struct {
+	// Path is the local file, or - for stdout, when Bucket is empty.
+	Path   string
+	Bucket string
+	Key    string
+}
+
+// ParseDestination parses a destination: - for stdout, s3://<bucket>/<key> for an S3 object, or a file path.
+func ParseDestination(spec string) (Destination, error) {
+	if spec == "" {
+		return Destination{}, errors.New("report destination is empty")
+	}
+	if !strings.HasPrefix(spec, "s3://") {
+		return Destination{Path: spec}, nil
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(spec, "s3://"), "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return Destination{}, fmt.Errorf("report destination is not s3://<bucket>/<key>: %q", spec)
+	}
+	return Destination{Bucket: bucket, Key: key}, nil
+}
+
+// IsS3 reports whether the report is uploaded to S3.
+func (d Destination) IsS3() bool {
+	return d.Bucket != ""
+}
+
+func (d Destination) String() string {
+	if d.IsS3() {
+		return "s3://" + d.Bucket + "/" + d.Key
+	}
+	return d.Path
+}
+
+// Write writes the report as indented JSON to the destination, using stdout for -
+// and the S3 client for an S3 object, which may be nil for the other destinations.
+// A file is replaced at once, so that a job reading it never sees a partial report.
+func (d Destination) Write(ctx context.Context, report *Report, stdout io.Writer, client S3Client) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	switch {
+	case d.IsS3():
+		_, err := client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(d.Bucket),
+			Key:         aws.String(d.Key),
+			Body:        bytes.NewReader(data),
+			ContentType: aws.String("application/json"),
+		})
+		return err
+	case d.Path == "-":
+		_, err := stdout.Write(data)
+		return err
+	default:
+		tmp := d.Path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o644); err != nil {
+			return err
+		}
+		return os.Rename(tmp, d.Path)
+	}
+}
@@ -0,0 +1,67 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDestination(t *testing.T) {
+	for spec, expected := range map[string]Destination{
+		"-":                                 {Path: "-"},
+		"/tmp/report.json":                  {Path: "/tmp/report.json"},
+		"s3://pac-reports/runs/latest.json": {Bucket: "pac-reports", Key: "runs/latest.json"},
+	} {
+		destination, err := ParseDestination(spec)
+		require.NoError(t, err, spec)
+		assert.Equal(t, expected, destination, spec)
+		assert.Equal(t, spec, destination.String())
+	}
+
+	for _, spec := range []string{"s3://pac-reports", "s3://pac-reports/runs/", "s3:///latest.json"} {
+		_, err := ParseDestination(spec)
+		assert.EqualError(t, err, `report destination is not s3://<bucket>/<key>: "`+spec+`"`)
+	}
+	_, err := ParseDestination("")
+	assert.EqualError(t, err, "report destination is empty")
+}
+
+func TestWriteToStdoutAndFile(t *testing.T) {
+	r := New(testRun())
+
+	var stdout bytes.Buffer
+	require.NoError(t, Destination{Path: "-"}.Write(context.Background(), r, &stdout, nil))
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &decoded))
+	assert.Equal(t, "pac-aurora-prod-eu-backup-2024-01-05-03-00-00", decoded["backups"].([]interface{})[0].(map[string]interface{})["snapshotId"])
+	assert.Equal(t, "v1.2.3", decoded["version"])
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, Destination{Path: path}.Write(context.Background(), r, nil, nil))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, stdout.String(), string(content))
+	_, err = os.Stat(path + ".tmp")
+	assert.True(t, os.IsNotExist(err), "the temporary file should be renamed")
+}
+
+func TestWriteToS3(t *testing.T) {
+	fake := awsfake.NewS3()
+	fake.AddBucket("pac-reports")
+	r := New(testRun())
+
+	require.NoError(t, Destination{Bucket: "pac-reports", Key: "runs/latest.json"}.Write(context.Background(), r, nil, fake))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(fake.Object("pac-reports", "runs/latest.json"), &decoded))
+	assert.Equal(t, *r, decoded)
+
+	err := Destination{Bucket: "pac-missing", Key: "runs/latest.json"}.Write(context.Background(), r, nil, fake)
+	assert.ErrorContains(t, err, "NoSuchBucket")
+}
@@ -0,0 +1,258 @@
+// Package report describes a run of the app in a single JSON document, written to stdout, a file or an S3 object
+// at the end of the run, as an audit trail and for the jobs chained on the new snapshots.
+package report
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// Run describes a run of the backup, cleanup or run command.
+// The run backs up the PAC environment, or the targets of the config file.
+type Run struct {
+	Command        string
+	Version        string
+	Environment    string
+	ConfigFile     string
+	Target         string
+	CallerIdentity *CallerIdentity
+	StartTime      time.Time
+	Duration       time.Duration
+	ExitCode       int
+	Backups        []*backup.BackupResult
+	Cleanups       []*backup.CleanupResult
+	Err            error
+}
+
+// Report is the JSON document describing a run. The durations are in seconds.
+type Report struct {
+	Command         string          `json:"command"`
+	Version         string          `json:"version"`
+	Environment     string          `json:"environment,omitempty"`
+	ConfigFile      string          `json:"configFile,omitempty"`
+	Target          string          `json:"target,omitempty"`
+	CallerIdentity  *CallerIdentity `json:"callerIdentity"`
+	StartTime       time.Time       `json:"startTime"`
+	DurationSeconds float64         `json:"durationSeconds"`
+	ExitCode        int             `json:"exitCode"`
+	// Clusters are the DB clusters backed up or cleaned up by the run.
+	Clusters []string  `json:"clusters"`
+	Backups  []Backup  `json:"backups"`
+	Cleanups []Cleanup `json:"cleanups"`
+	Errors   []string  `json:"errors"`
+}
+
+// CallerIdentity is the IAM principal the run acted as.
+type CallerIdentity struct {
+	Account string `json:"account"`
+	ARN     string `json:"arn"`
+	UserID  string `json:"userId"`
+}
+
+// Backup is the backup of a cluster.
+type Backup struct {
+	ClusterID               string    `json:"clusterId"`
+	SnapshotID              string    `json:"snapshotId,omitempty"`
+	SnapshotARN             string    `json:"snapshotArn,omitempty"`
+	Reused                  bool      `json:"reused"`
+	StartTime               time.Time `json:"startTime"`
+	DurationSeconds         float64   `json:"durationSeconds"`
+	CreationDurationSeconds float64   `json:"creationDurationSeconds"`
+	SharedWith              []string  `json:"sharedWith,omitempty"`
+	Copies                  []Copy    `json:"copies,omitempty"`
+	Export                  *Export   `json:"export,omitempty"`
+	Error                   string    `json:"error,omitempty"`
+}
+
+// Copy is a copy of a new snapshot into another region or account.
+type Copy struct {
+	Region          string  `json:"region"`
+	AccountID       string  `json:"accountId,omitempty"`
+	SnapshotID      string  `json:"snapshotId,omitempty"`
+	SnapshotARN     string  `json:"snapshotArn,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// Export is the export of a new snapshot to S3.
+type Export struct {
+	SnapshotID      string   `json:"snapshotId"`
+	TaskID          string   `json:"taskId,omitempty"`
+	S3URI           string   `json:"s3Uri,omitempty"`
+	ExtractedGB     int64    `json:"extractedGb"`
+	DurationSeconds float64  `json:"durationSeconds"`
+	Deleted         []string `json:"deleted,omitempty"`
+	Error           string   `json:"error,omitempty"`
+}
+
+// Cleanup is the cleanup of the snapshots with a prefix in a region.
+type Cleanup struct {
+	Region           string     `json:"region"`
+	ClusterID        string     `json:"clusterId,omitempty"`
+	SnapshotIDPrefix string     `json:"snapshotIdPrefix"`
+	Policy           string     `json:"policy"`
+	Decisions        []Decision `json:"decisions"`
+	Deleted          []string   `json:"deleted"`
+	Skipped          []string   `json:"skipped,omitempty"`
+	Failed           []Failure  `json:"failed,omitempty"`
+	RetainedBytes    int64      `json:"retainedBytes"`
+	DurationSeconds  float64    `json:"durationSeconds"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// Decision records why the cleanup kept or deleted a snapshot.
+// CreateTime is nil for a snapshot being created.
+type Decision struct {
+	SnapshotID string     `json:"snapshotId"`
+	CreateTime *time.Time `json:"createTime"`
+	Keep       bool       `json:"keep"`
+	Reason     string     `json:"reason"`
+}
+
+// Failure is a snapshot the cleanup failed to delete.
+type Failure struct {
+	SnapshotID string `json:"snapshotId"`
+	Error      string `json:"error"`
+}
+
+// New returns the report of a run.
+func New(run Run) *Report {
+	r := &Report{
+		Command:         run.Command,
+		Version:         run.Version,
+		Environment:     run.Environment,
+		ConfigFile:      run.ConfigFile,
+		Target:          run.Target,
+		CallerIdentity:  run.CallerIdentity,
+		StartTime:       run.StartTime.UTC(),
+		DurationSeconds: run.Duration.Seconds(),
+		ExitCode:        run.ExitCode,
+		Clusters:        []string{},
+		Backups:         []Backup{},
+		Cleanups:        []Cleanup{},
+		Errors:          []string{},
+	}
+	clusters := map[string]bool{}
+	for _, b := range run.Backups {
+		clusters[b.ClusterID] = true
+		r.Backups = append(r.Backups, newBackup(b))
+	}
+	for _, c := range run.Cleanups {
+		if c.ClusterID != "" {
+			clusters[c.ClusterID] = true
+		}
+		r.Cleanups = append(r.Cleanups, newCleanup(c))
+	}
+	for clusterID := range clusters {
+		r.Clusters = append(r.Clusters, clusterID)
+	}
+	sort.Strings(r.Clusters)
+	r.Errors = errorMessages(run.Err)
+	return r
+}
+
+func newBackup(b *backup.BackupResult) Backup {
+	report := Backup{
+		ClusterID:               b.ClusterID,
+		SnapshotID:              b.SnapshotID,
+		SnapshotARN:             b.SnapshotARN,
+		Reused:                  b.Reused,
+		StartTime:               b.StartTime.UTC(),
+		DurationSeconds:         b.Duration.Seconds(),
+		CreationDurationSeconds: b.CreationDuration.Seconds(),
+		SharedWith:              b.SharedWith,
+		Error:                   errorMessage(b.Err),
+	}
+	for _, c := range b.Copies {
+		report.Copies = append(report.Copies, Copy{
+			Region:          c.Region,
+			AccountID:       c.AccountID,
+			SnapshotID:      c.SnapshotID,
+			SnapshotARN:     c.SnapshotARN,
+			DurationSeconds: c.Duration.Seconds(),
+			Error:           errorMessage(c.Err),
+		})
+	}
+	if e := b.Export; e != nil {
+		report.Export = &Export{
+			SnapshotID:      e.SnapshotID,
+			TaskID:          e.TaskID,
+			S3URI:           e.S3URI,
+			ExtractedGB:     e.ExtractedGB,
+			DurationSeconds: e.Duration.Seconds(),
+			Deleted:         e.Deleted,
+			Error:           errorMessage(e.Err),
+		}
+	}
+	return report
+}
+
+func newCleanup(c *backup.CleanupResult) Cleanup {
+	report := Cleanup{
+		Region:           c.Region,
+		ClusterID:        c.ClusterID,
+		SnapshotIDPrefix: c.SnapshotIDPrefix,
+		Policy:           c.Policy.String(),
+		Decisions:        []Decision{},
+		Deleted:          append([]string{}, c.Deleted...),
+		Skipped:          c.Skipped,
+		RetainedBytes:    c.RetainedBytes,
+		DurationSeconds:  c.Duration.Seconds(),
+		Error:            errorMessage(c.Err),
+	}
+	for _, d := range c.Decisions {
+		decision := Decision{SnapshotID: d.SnapshotID, Keep: d.Keep, Reason: d.Reason}
+		if !d.CreateTime.IsZero() {
+			createTime := d.CreateTime.UTC()
+			decision.CreateTime = &createTime
+		}
+		report.Decisions = append(report.Decisions, decision)
+	}
+	for _, f := range c.Failed {
+		report.Failed = append(report.Failed, Failure{SnapshotID: f.SnapshotID, Error: errorMessage(f.Err)})
+	}
+	return report
+}
+
+// errorMessages returns the messages of the errors joined into err, e.g. one per failed cluster.
+func errorMessages(err error) []string {
+	if err == nil {
+		return []string{}
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var messages []string
+		for _, e := range joined.Unwrap() {
+			messages = append(messages, errorMessages(e)...)
+		}
+		return messages
+	}
+	return []string{err.Error()}
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// STSClient is the subset of the AWS STS API used to look up the caller identity.
+// It is satisfied by *sts.STS and by the in-memory fake in the awsfake package.
+type STSClient interface {
+	GetCallerIdentityWithContext(aws.Context, *sts.GetCallerIdentityInput, ...request.Option) (*sts.GetCallerIdentityOutput, error)
+}
+
+// LookupCallerIdentity returns the IAM principal the client acts as.
+func LookupCallerIdentity(ctx context.Context, client STSClient) (*CallerIdentity, error) {
+	out, err := client.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+	return &CallerIdentity{Account: aws.StringValue(out.Account), ARN: aws.StringValue(out.Arn), UserID: aws.StringValue(out.UserId)}, nil
+}
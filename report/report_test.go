@@ -0,0 +1,92 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStartTime = time.Date(2024, 1, 5, 3, 0, 0, 0, time.UTC)
+
+func testRun() Run {
+	created := testStartTime.Add(-24 * time.Hour)
+	return Run{
+		Command:     "run",
+		Version:     "v1.2.3",
+		Environment: "pac-prod-eu",
+		StartTime:   testStartTime,
+		Duration:    10 * time.Minute,
+		ExitCode:    1,
+		Backups: []*backup.BackupResult{{
+			ClusterID:        "pac-aurora-prod-eu",
+			SnapshotID:       "pac-aurora-prod-eu-backup-2024-01-05-03-00-00",
+			SnapshotARN:      "arn:aws:rds:eu-west-1:123456789012:cluster-snapshot:pac-aurora-prod-eu-backup-2024-01-05-03-00-00",
+			StartTime:        testStartTime,
+			Duration:         9 * time.Minute,
+			CreationDuration: 8 * time.Minute,
+			Copies:           []*backup.CopyResult{{Region: "eu-central-1", Duration: time.Minute, Err: errors.New("quota exceeded")}},
+			Export:           &backup.ExportResult{SnapshotID: "pac-aurora-prod-eu-backup-2024-01-05-03-00-00", TaskID: "task", Duration: time.Minute},
+		}},
+		Cleanups: []*backup.CleanupResult{{
+			Region:           "eu-west-1",
+			ClusterID:        "pac-aurora-prod-eu",
+			SnapshotIDPrefix: "pac-aurora-prod-eu-backup",
+			Policy:           backup.KeepLast(1),
+			Decisions: []backup.SnapshotDecision{
+				{SnapshotID: "pac-aurora-prod-eu-backup-2024-01-05-03-00-00", Keep: true, Reason: "being created"},
+				{SnapshotID: "pac-aurora-prod-eu-backup-2024-01-04-03-00-00", CreateTime: created, Reason: "not selected by retention policy keep-last=1"},
+			},
+			Failed: []backup.SnapshotFailure{{SnapshotID: "pac-aurora-prod-eu-backup-2024-01-04-03-00-00", Err: errors.New("invalid state")}},
+			Err:    errors.New("failed to delete 1 snapshot"),
+		}},
+		Err: errors.Join(errors.New("copy to eu-central-1 failed"), errors.Join(errors.New("failed to delete 1 snapshot"))),
+	}
+}
+
+func TestNew(t *testing.T) {
+	r := New(testRun())
+
+	assert.Equal(t, "run", r.Command)
+	assert.Equal(t, "pac-prod-eu", r.Environment)
+	assert.Equal(t, 600.0, r.DurationSeconds)
+	assert.Equal(t, []string{"pac-aurora-prod-eu"}, r.Clusters)
+	require.Len(t, r.Backups, 1)
+	assert.Equal(t, 480.0, r.Backups[0].CreationDurationSeconds)
+	assert.Equal(t, []Copy{{Region: "eu-central-1", DurationSeconds: 60, Error: "quota exceeded"}}, r.Backups[0].Copies)
+	assert.Equal(t, "task", r.Backups[0].Export.TaskID)
+	require.Len(t, r.Cleanups, 1)
+	assert.Equal(t, "keep-last=1", r.Cleanups[0].Policy)
+	require.Len(t, r.Cleanups[0].Decisions, 2)
+	assert.Nil(t, r.Cleanups[0].Decisions[0].CreateTime)
+	assert.Equal(t, testStartTime.Add(-24*time.Hour), *r.Cleanups[0].Decisions[1].CreateTime)
+	assert.Equal(t, []Failure{{SnapshotID: "pac-aurora-prod-eu-backup-2024-01-04-03-00-00", Error: "invalid state"}}, r.Cleanups[0].Failed)
+	assert.Equal(t, []string{"copy to eu-central-1 failed", "failed to delete 1 snapshot"}, r.Errors)
+}
+
+func TestNewEmptyRun(t *testing.T) {
+	r := New(Run{Command: "cleanup", StartTime: testStartTime})
+
+	assert.Equal(t, []string{}, r.Clusters)
+	assert.Equal(t, []Backup{}, r.Backups)
+	assert.Equal(t, []Cleanup{}, r.Cleanups)
+	assert.Equal(t, []string{}, r.Errors)
+}
+
+func TestLookupCallerIdentity(t *testing.T) {
+	fake := awsfake.NewSTS("arn:aws:sts::123456789012:assumed-role/pac-aurora-backup/pod", "AROAEXAMPLE:pod")
+
+	identity, err := LookupCallerIdentity(context.Background(), fake)
+	require.NoError(t, err)
+	assert.Equal(t, &CallerIdentity{Account: "123456789012", ARN: "arn:aws:sts::123456789012:assumed-role/pac-aurora-backup/pod", UserID: "AROAEXAMPLE:pod"}, identity)
+
+	fake.FailNext("GetCallerIdentity", awserr.New("ExpiredToken", "The security token included in the request is expired", nil))
+	_, err = LookupCallerIdentity(context.Background(), fake)
+	assert.ErrorContains(t, err, "ExpiredToken")
+}
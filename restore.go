@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	cli "github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+// restoreCommand configures the restore subcommand, which rebuilds a DB cluster from a backup snapshot.
+func restoreCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Spec = "--snapshot-id --target-cluster-id [--source-cluster-id] [--instance-class]"
+
+	snapshotID := cmd.String(cli.StringOpt{
+		Name: "snapshot-id",
+		Desc: "The identifier of the snapshot to restore, or latest for the most recent available backup snapshot of the source cluster",
+	})
+
+	targetClusterID := cmd.String(cli.StringOpt{
+		Name: "target-cluster-id",
+		Desc: "The identifier of the new DB cluster",
+	})
+
+	sourceClusterID := cmd.String(cli.StringOpt{
+		Name: "source-cluster-id",
+		Desc: "The DB cluster whose security groups, subnet group, parameter groups, engine version, KMS key and instances are copied, by default the cluster of the snapshot",
+	})
+
+	instanceClass := cmd.String(cli.StringOpt{
+		Name: "instance-class",
+		Desc: "The class of the instances of the new DB cluster, by default the classes of the instances of the source cluster",
+	})
+
+	cmd.Action = func() {
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		result, err := svc.Restore(ctx, backup.RestoreRequest{
+			SnapshotID:      *snapshotID,
+			SourceClusterID: *sourceClusterID,
+			TargetClusterID: *targetClusterID,
+			InstanceClass:   *instanceClass,
+		})
+		if err != nil {
+			code := runExitCode(ctx, err)
+			log.WithError(err).
+				WithField("snapshotID", *snapshotID).
+				WithField("clusterID", *targetClusterID).
+				WithField("exitCode", code).
+				Error("PAC aurora restore failed")
+			cli.Exit(code)
+		}
+		log.WithField("snapshotID", result.SnapshotID).
+			WithField("sourceClusterID", result.SourceClusterID).
+			WithField("clusterID", result.ClusterID).
+			WithField("endpoint", result.Endpoint).
+			WithField("duration", result.Duration.String()).
+			Info("PAC aurora restore completed")
+		if err := printRestoreResult(os.Stdout, result); err != nil {
+			log.WithError(err).Error("Error in printing the restore result")
+		}
+	}
+}
+
+// printRestoreResult writes the endpoints of the restored cluster.
+func printRestoreResult(w io.Writer, result *backup.RestoreResult) error {
+	_, err := fmt.Fprintf(w, "Cluster %v restored from snapshot %v of %v\nEndpoint: %v\nReader endpoint: %v\n",
+		result.ClusterID, result.SnapshotID, result.SourceClusterID, result.Endpoint, result.ReaderEndpoint)
+	return err
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintRestoreResult(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printRestoreResult(&out, &backup.RestoreResult{
+		ClusterID:       "pac-aurora-restored",
+		SourceClusterID: "pac-aurora-prod",
+		SnapshotID:      "pac-aurora-prod-backup-2024-01-05-12-00-00",
+		Endpoint:        "pac-aurora-restored.cluster-abc.eu-west-1.rds.amazonaws.com",
+		ReaderEndpoint:  "pac-aurora-restored.cluster-ro-abc.eu-west-1.rds.amazonaws.com",
+	}))
+
+	assert.Equal(t, "Cluster pac-aurora-restored restored from snapshot pac-aurora-prod-backup-2024-01-05-12-00-00 of pac-aurora-prod\n"+
+		"Endpoint: pac-aurora-restored.cluster-abc.eu-west-1.rds.amazonaws.com\n"+
+		"Reader endpoint: pac-aurora-restored.cluster-ro-abc.eu-west-1.rds.amazonaws.com\n", out.String())
+}
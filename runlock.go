@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/lock"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/rds"
+	cli "github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+// lockReleaseTimeout bounds the release of the run lock at the end of a run.
+const lockReleaseTimeout = 30 * time.Second
+
+// newLocker returns the locker configured by the lock option: dynamodb:[<region>/]<table>,
+// cluster-tag:[<region>/]<DB cluster identifier> or file:<directory>, or nil when the option is empty.
+// The DynamoDB and tag lockers call AWS in the region of the option, or else in the given region.
+func newLocker(spec, region string) (lock.Locker, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	kind, arg, found := strings.Cut(spec, ":")
+	if !found || arg == "" {
+		return nil, fmt.Errorf("lock is not dynamodb:[<region>/]<table>, cluster-tag:[<region>/]<cluster id> or file:<directory>: %q", spec)
+	}
+	switch kind {
+	case "dynamodb", "cluster-tag":
+		if lockRegion, name, found := strings.Cut(arg, "/"); found {
+			if lockRegion == "" || name == "" {
+				return nil, fmt.Errorf("lock is not dynamodb:[<region>/]<table>, cluster-tag:[<region>/]<cluster id> or file:<directory>: %q", spec)
+			}
+			region, arg = lockRegion, name
+		}
+		sess, err := newRegionSession(region)
+		if err != nil {
+			return nil, fmt.Errorf("lock %q: %w", spec, err)
+		}
+		if kind == "dynamodb" {
+			return lock.NewDynamoDBLocker(dynamodb.New(sess), arg), nil
+		}
+		return lock.NewClusterTagLocker(rds.New(sess), arg), nil
+	case "file":
+		return lock.NewFileLocker(arg), nil
+	}
+	return nil, fmt.Errorf("lock is not dynamodb:[<region>/]<table>, cluster-tag:[<region>/]<cluster id> or file:<directory>: %q", spec)
+}
+
+// defaultLockName names the lock after the cluster identifier prefix of the PAC environment,
+// or after the config file and the selected target, e.g. targets-prod for the prod target of targets.yaml.
+func defaultLockName(configFile, targetName, pacEnvironment string) (string, error) {
+	if configFile != "" {
+		name := strings.TrimSuffix(filepath.Base(configFile), filepath.Ext(configFile))
+		if targetName != "" {
+			name += "-" + targetName
+		}
+		return name, nil
+	}
+	envLevel, err := extractEnvironmentLevel(pacEnvironment)
+	if err != nil {
+		return "", err
+	}
+	return pacAuroraPrefix + envLevel, nil
+}
+
+// lockOwner identifies the run holding the lock by its host name, the pod name on Kubernetes, and its process ID.
+func lockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return host + "/" + strconv.Itoa(os.Getpid())
+}
+
+// newRunLock returns the lockRun function of the commands, taking the lock with the given name for the run.
+// The context of the run is cancelled if the lease of the lock is lost, e.g. to a run that found it expired.
+// Without locker, runs are not locked.
+func newRunLock(locker lock.Locker, name, owner string, ttl time.Duration) func(ctx context.Context) (context.Context, func(), bool) {
+	return func(ctx context.Context) (context.Context, func(), bool) {
+		if locker == nil {
+			return ctx, func() {}, true
+		}
+		lease, err := lock.Acquire(ctx, locker, name, owner, ttl)
+		var held *lock.HeldError
+		if errors.As(err, &held) {
+			log.WithField("lock", name).
+				WithField("holder", held.Owner).
+				WithField("expires", held.Expires.UTC().Format(time.RFC3339)).
+				Warn("Backup already in progress elsewhere, exiting")
+			return ctx, nil, false
+		}
+		if err != nil {
+			log.WithError(err).WithField("lock", name).Error("Error in taking the run lock")
+			cli.Exit(exitCodeError)
+		}
+		log.WithField("lock", name).WithField("owner", owner).Info("Run lock taken")
+
+		ctx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-lease.Lost():
+				log.WithField("lock", name).Error("Run lock lost, interrupting the run")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		return ctx, func() {
+			cancel()
+			releaseCtx, cancelRelease := context.WithTimeout(context.Background(), lockReleaseTimeout)
+			defer cancelRelease()
+			if err := lease.Release(releaseCtx); err != nil {
+				log.WithError(err).WithField("lock", name).Error("Error in releasing the run lock")
+				return
+			}
+			log.WithField("lock", name).Info("Run lock released")
+		}, true
+	}
+}
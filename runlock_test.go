@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/lock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunLock(t *testing.T) {
+	locker := lock.NewFileLocker(t.TempDir())
+	ttl := 150 * time.Millisecond
+
+	ctx, unlock, locked := newRunLock(locker, "pac-aurora-prod", "pod-a", ttl)(context.Background())
+	require.True(t, locked)
+	_, _, locked = newRunLock(locker, "pac-aurora-prod", "pod-b", ttl)(context.Background())
+	assert.False(t, locked, "a second run should not take the lock")
+
+	unlock()
+	assert.Error(t, ctx.Err(), "the context of the run should be cancelled once the lock is released")
+	_, unlock, locked = newRunLock(locker, "pac-aurora-prod", "pod-b", ttl)(context.Background())
+	require.True(t, locked)
+	unlock()
+}
+
+func TestRunLockLost(t *testing.T) {
+	dir := t.TempDir()
+	locker := lock.NewFileLocker(dir)
+	ttl := 150 * time.Millisecond
+	ctx, unlock, locked := newRunLock(locker, "pac-aurora-prod", "pod-a", ttl)(context.Background())
+	require.True(t, locked)
+	defer unlock()
+
+	require.NoError(t, locker.Release(context.Background(), "pac-aurora-prod", "pod-a"))
+	require.NoError(t, locker.Acquire(context.Background(), "pac-aurora-prod", "pod-b", time.Hour))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * ttl):
+		t.Fatal("the run should be interrupted once the lock is lost")
+	}
+}
+
+func TestRunWithoutLock(t *testing.T) {
+	ctx := context.Background()
+	runCtx, unlock, locked := newRunLock(nil, "pac-aurora-prod", "pod-a", time.Minute)(ctx)
+	require.True(t, locked)
+	assert.Equal(t, ctx, runCtx)
+	unlock()
+}
+
+func TestDefaultLockName(t *testing.T) {
+	name, err := defaultLockName("", "", "pac-prod-eu")
+	require.NoError(t, err)
+	assert.Equal(t, "pac-aurora-prod", name)
+
+	name, err = defaultLockName("/config/targets.yaml", "prod", "")
+	require.NoError(t, err)
+	assert.Equal(t, "targets-prod", name)
+
+	name, err = defaultLockName("/config/targets.yaml", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "targets", name)
+}
+
+func TestNewLocker(t *testing.T) {
+	locker, err := newLocker("", "eu-west-1")
+	require.NoError(t, err)
+	assert.Nil(t, locker)
+
+	locker, err = newLocker("file:/tmp", "eu-west-1")
+	require.NoError(t, err)
+	assert.IsType(t, &lock.FileLocker{}, locker)
+
+	locker, err = newLocker("dynamodb:pac-aurora-backup-locks", "eu-west-1")
+	require.NoError(t, err)
+	assert.IsType(t, &lock.DynamoDBLocker{}, locker)
+
+	for _, spec := range []string{"dynamodb", "dynamodb:", "redis:locks", "dynamodb:/pac-aurora-backup-locks", "cluster-tag:us-east-1/"} {
+		_, err = newLocker(spec, "eu-west-1")
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestNewLockerRegion(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_SDK_LOAD_CONFIG", "")
+
+	locker, err := newLocker("dynamodb:us-east-1/pac-aurora-backup-locks", "")
+	require.NoError(t, err)
+	assert.IsType(t, &lock.DynamoDBLocker{}, locker)
+
+	_, err = newLocker("dynamodb:pac-aurora-backup-locks", "")
+	assert.ErrorContains(t, err, "no AWS region")
+
+	locker, err = newLocker("file:/tmp", "")
+	require.NoError(t, err)
+	assert.IsType(t, &lock.FileLocker{}, locker)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/report"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sts"
+	log "github.com/sirupsen/logrus"
+)
+
+// reportTimeout bounds the lookup of the caller identity and the writing of the report at the end of a run.
+const reportTimeout = 30 * time.Second
+
+// writeRunReport writes the report of a run to the destination of the report option, if one is configured.
+func writeRunReport(spec, region string, run report.Run) {
+	if spec == "" {
+		return
+	}
+	destination, err := report.ParseDestination(spec)
+	if err != nil {
+		log.WithError(err).Warn("Error in parsing report parameter")
+		return
+	}
+	config := aws.NewConfig()
+	if region != "" {
+		config = config.WithRegion(region)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		log.WithError(err).Warn("Error in creating the AWS session of the run report")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+	writeReport(ctx, destination, run, sts.New(sess), s3.New(sess), os.Stdout)
+}
+
+// writeReport completes the run with the caller identity and writes its report to the destination.
+// A run whose caller identity cannot be looked up is still reported, without it.
+func writeReport(ctx context.Context, destination report.Destination, run report.Run, stsClient report.STSClient, s3Client report.S3Client, stdout io.Writer) {
+	identity, err := report.LookupCallerIdentity(ctx, stsClient)
+	if err != nil {
+		log.WithError(err).Warn("Error in looking up the caller identity of the run report")
+	}
+	run.CallerIdentity = identity
+	if err := destination.Write(ctx, report.New(run), stdout, s3Client); err != nil {
+		log.WithError(err).WithField("report", destination.String()).Warn("Error in writing the run report")
+		return
+	}
+	log.WithField("report", destination.String()).Info("Run report written")
+}
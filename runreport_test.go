@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/report"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReport(t *testing.T) {
+	stsFake := awsfake.NewSTS("arn:aws:sts::123456789012:assumed-role/pac-aurora-backup/pod", "AROAEXAMPLE:pod")
+	s3Fake := awsfake.NewS3()
+	s3Fake.AddBucket("pac-reports")
+	run := report.Run{
+		Command:   "backup",
+		Version:   "v1.2.3",
+		StartTime: time.Now(),
+		Backups:   []*backup.BackupResult{{ClusterID: "pac-aurora-prod", SnapshotID: "pac-aurora-prod-backup-2024-01-05-03-00-00"}},
+	}
+
+	writeReport(context.Background(), report.Destination{Bucket: "pac-reports", Key: "latest.json"}, run, stsFake, s3Fake, nil)
+	var uploaded report.Report
+	require.NoError(t, json.Unmarshal(s3Fake.Object("pac-reports", "latest.json"), &uploaded))
+	require.NotNil(t, uploaded.CallerIdentity)
+	assert.Equal(t, "123456789012", uploaded.CallerIdentity.Account)
+	assert.Equal(t, "pac-aurora-prod-backup-2024-01-05-03-00-00", uploaded.Backups[0].SnapshotID)
+
+	stsFake.FailNext("GetCallerIdentity", awserr.New("AccessDenied", "not authorized to perform sts:GetCallerIdentity", nil))
+	run.Err = errors.New("snapshot creation timed out")
+	var stdout bytes.Buffer
+	writeReport(context.Background(), report.Destination{Path: "-"}, run, stsFake, nil, &stdout)
+	var printed report.Report
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &printed), "the report should be written without the caller identity")
+	assert.Nil(t, printed.CallerIdentity)
+	assert.Equal(t, []string{"snapshot creation timed out"}, printed.Errors)
+}
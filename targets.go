@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/config"
+	cli "github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+// targetOptions returns the backup service options configured by a target of the config file.
+func targetOptions(target *config.Target) ([]backup.Option, error) {
+	var opts []backup.Option
+	if target.Retention.Policy != "" {
+		policy, err := backup.ParseRetentionPolicy(target.Retention.Policy)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, backup.WithRetentionPolicy(policy))
+	}
+	minAge, err := backup.ParseBackupAge(target.Retention.MinAge)
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := backup.ParseBackupAge(target.Retention.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, backup.WithBackupAgeLimits(minAge, maxAge))
+
+	var destinations []backup.CopyDestination
+	for _, c := range target.Copies {
+		destinations = append(destinations, backup.CopyDestination{Region: c.Region, KMSKeyID: c.KMSKeyID, Retention: c.Retention})
+	}
+	opts = append(opts, backup.WithCopyDestinations(destinations...))
+	opts = append(opts, backup.WithSnapshotSharing(target.ShareWithAccounts...))
+	if target.Vault != nil {
+		vault, err := newVaultCopy(target.Vault.RoleARN, target.Vault.Region, target.Vault.KMSKeyID, target.Region)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, backup.WithVaultCopy(vault))
+	}
+	if target.Export != nil {
+		opts = append(opts, backup.WithExport(backup.ExportDestination{
+			Bucket:     target.Export.Bucket,
+			Prefix:     target.Export.Prefix,
+			IAMRoleARN: target.Export.RoleARN,
+			KMSKeyID:   target.Export.KMSKeyID,
+			ExportOnly: target.Export.Only,
+			Retention:  target.Export.Retention,
+		}))
+	}
+	if len(target.Tags) > 0 {
+		opts = append(opts, backup.WithSnapshotTags(target.Tags))
+	}
+	opts = append(opts, backup.WithEnvironment(target.Environment))
+	if target.SnapshotIDTemplate != "" {
+		template, err := backup.ParseSnapshotIDTemplate(target.SnapshotIDTemplate)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, backup.WithSnapshotIDTemplate(template))
+	}
+	return opts, nil
+}
+
+// newTargetService returns the backup service of a target of the config file,
+// with the given options and notification destinations shared by all the targets,
+// notifying the destinations of the target besides the shared ones.
+func newTargetService(target *config.Target, statusCheckInterval time.Duration, statusCheckAttempts, concurrency int, slowCreation time.Duration, notifications []config.Notification, opts ...backup.Option) (backup.Service, error) {
+	targetOpts, err := targetOptions(target)
+	if err != nil {
+		return nil, fmt.Errorf("target %v: %w", target.Name, err)
+	}
+	opts = append(opts, targetOpts...)
+	if target.DiscoverClusters {
+		opts = append(opts, backup.WithClusterDiscovery(concurrency))
+	}
+	notifier, err := newNotifier(append(append([]config.Notification{}, target.Notifications...), notifications...))
+	if err != nil {
+		return nil, fmt.Errorf("target %v: %w", target.Name, err)
+	}
+	svc, err := backup.NewBackupService(target.Region, target.ClusterIDPrefix, target.SnapshotIDPrefix,
+		statusCheckInterval, statusCheckAttempts, target.Retention.Backups, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("target %v: %w", target.Name, err)
+	}
+	return withNotifications(svc, notifier, target.Name, slowCreation), nil
+}
+
+// selectTargets returns the target of the config file with the given name, or all of them if name is empty.
+func selectTargets(cfg *config.Config, name string) ([]*config.Target, error) {
+	if name != "" {
+		target, err := cfg.Target(name)
+		if err != nil {
+			return nil, err
+		}
+		return []*config.Target{target}, nil
+	}
+	targets := make([]*config.Target, len(cfg.Targets))
+	for i := range cfg.Targets {
+		targets[i] = &cfg.Targets[i]
+	}
+	return targets, nil
+}
+
+// configRegion returns the region of the target of the config file with the given name,
+// or of all its targets if name is empty, or an empty region if they are in several regions.
+func configRegion(path, name string) (string, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return "", err
+	}
+	targets, err := selectTargets(cfg, name)
+	if err != nil {
+		return "", err
+	}
+	var region string
+	for i, target := range targets {
+		if i > 0 && target.Region != region {
+			return "", nil
+		}
+		region = target.Region
+	}
+	return region, nil
+}
+
+// newConfigService returns the backup service of the target of the config file with the given name,
+// or a service running the operations of all its targets if name is empty.
+// The given notification destinations are notified of the outcome of every target.
+func newConfigService(path, name string, statusCheckInterval time.Duration, statusCheckAttempts, concurrency int, slowCreation time.Duration, notifications []config.Notification, opts ...backup.Option) (backup.Service, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := selectTargets(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	multi := new(multiService)
+	for _, target := range targets {
+		svc, err := newTargetService(target, statusCheckInterval, statusCheckAttempts, concurrency, slowCreation, notifications, opts...)
+		if err != nil {
+			return nil, err
+		}
+		multi.names = append(multi.names, target.Name)
+		multi.services = append(multi.services, svc)
+	}
+	if len(multi.services) == 1 {
+		return multi.services[0], nil
+	}
+	return multi, nil
+}
+
+// multiService runs the operations of the backup services of several targets one target after the other,
+// combining their results and errors. The operations on a single snapshot or cluster need a single target.
+type multiService struct {
+	names    []string
+	services []backup.Service
+}
+
+func (m *multiService) MakeBackup(ctx context.Context) ([]*backup.BackupResult, error) {
+	var results []*backup.BackupResult
+	var errs []error
+	for i, svc := range m.services {
+		if ctx.Err() != nil {
+			break
+		}
+		log.WithField("target", m.names[i]).Info("Backing up target")
+		r, err := svc.MakeBackup(ctx)
+		results = append(results, r...)
+		errs = append(errs, err)
+	}
+	return results, errors.Join(errs...)
+}
+
+func (m *multiService) CleanUpOldBackups(ctx context.Context) ([]*backup.CleanupResult, error) {
+	var results []*backup.CleanupResult
+	var errs []error
+	for i, svc := range m.services {
+		if ctx.Err() != nil {
+			break
+		}
+		log.WithField("target", m.names[i]).Info("Cleaning up target")
+		r, err := svc.CleanUpOldBackups(ctx)
+		results = append(results, r...)
+		errs = append(errs, err)
+	}
+	return results, errors.Join(errs...)
+}
+
+func (m *multiService) Adopt(ctx context.Context, dryRun bool) ([]*backup.AdoptResult, error) {
+	var results []*backup.AdoptResult
+	var errs []error
+	for i, svc := range m.services {
+		if ctx.Err() != nil {
+			break
+		}
+		log.WithField("target", m.names[i]).Info("Adopting the snapshots of target")
+		r, err := svc.Adopt(ctx, dryRun)
+		results = append(results, r...)
+		errs = append(errs, err)
+	}
+	return results, errors.Join(errs...)
+}
+
+func (m *multiService) Plan(ctx context.Context) (*backup.Plan, error) {
+	plan := new(backup.Plan)
+	var errs []error
+	for _, svc := range m.services {
+		p, err := svc.Plan(ctx)
+		if p != nil {
+			plan.Backups = append(plan.Backups, p.Backups...)
+			plan.Cleanups = append(plan.Cleanups, p.Cleanups...)
+		}
+		errs = append(errs, err)
+	}
+	return plan, errors.Join(errs...)
+}
+
+func (m *multiService) List(ctx context.Context) ([]*backup.SnapshotInfo, error) {
+	var snapshots []*backup.SnapshotInfo
+	var errs []error
+	for _, svc := range m.services {
+		s, err := svc.List(ctx)
+		snapshots = append(snapshots, s...)
+		errs = append(errs, err)
+	}
+	return snapshots, errors.Join(errs...)
+}
+
+func (m *multiService) Status(ctx context.Context) ([]*backup.ClusterStatus, error) {
+	var statuses []*backup.ClusterStatus
+	var errs []error
+	for _, svc := range m.services {
+		s, err := svc.Status(ctx)
+		statuses = append(statuses, s...)
+		errs = append(errs, err)
+	}
+	return statuses, errors.Join(errs...)
+}
+
+func (m *multiService) CopySnapshot(ctx context.Context, snapshotID string) ([]*backup.CopyResult, error) {
+	return nil, m.singleTargetError()
+}
+
+func (m *multiService) Restore(ctx context.Context, req backup.RestoreRequest) (*backup.RestoreResult, error) {
+	return nil, m.singleTargetError()
+}
+
+func (m *multiService) Verify(ctx context.Context, req backup.VerifyRequest, check backup.CheckFunc) (*backup.VerifyResult, error) {
+	return &backup.VerifyResult{}, m.singleTargetError()
+}
+
+func (m *multiService) Export(ctx context.Context, snapshotID string) (*backup.ExportResult, error) {
+	return nil, m.singleTargetError()
+}
+
+func (m *multiService) Pin(ctx context.Context, req backup.PinRequest) (*backup.PinResult, error) {
+	return nil, m.singleTargetError()
+}
+
+func (m *multiService) Unpin(ctx context.Context, snapshotID string) (*backup.PinResult, error) {
+	return nil, m.singleTargetError()
+}
+
+func (m *multiService) singleTargetError() error {
+	return fmt.Errorf("the config file has %d targets (%v), select one with the target option", len(m.names), strings.Join(m.names, ", "))
+}
+
+// validateConfigCommand configures the validate-config subcommand, which checks the config file
+// without calling AWS, e.g. in the pipeline before deploying a change of the file.
+func validateConfigCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Action = func() {
+		if *env.configFile == "" {
+			log.Error("No config file to validate, set the config option")
+			cli.Exit(exitCodeError)
+		}
+		cfg, err := config.Load(*env.configFile)
+		if err == nil {
+			var errs []error
+			for i := range cfg.Targets {
+				if _, optErr := targetOptions(&cfg.Targets[i]); optErr != nil {
+					errs = append(errs, fmt.Errorf("target %v: %w", cfg.Targets[i].Name, optErr))
+				}
+			}
+			err = errors.Join(errs...)
+		}
+		if err != nil {
+			log.WithError(err).WithField("config", *env.configFile).Error("Config file is invalid")
+			cli.Exit(exitCodeError)
+		}
+		if err := printTargets(os.Stdout, cfg); err != nil {
+			log.WithError(err).Error("Error in printing the targets of the config file")
+		}
+		log.WithField("config", *env.configFile).WithField("targets", len(cfg.Targets)).Info("Config file is valid")
+	}
+}
+
+// printTargets writes a human-readable table of the targets of a config file.
+func printTargets(w io.Writer, cfg *config.Config) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tREGION\tCLUSTERS\tSNAPSHOTS\tRETENTION\tCOPY REGIONS\tVAULT")
+	for _, t := range cfg.Targets {
+		clusters := "first " + t.ClusterIDPrefix
+		if t.DiscoverClusters {
+			clusters = "all " + t.ClusterIDPrefix
+		}
+		retention := t.Retention.Policy
+		if retention == "" {
+			retention = backup.KeepLast(t.Retention.Backups).String()
+		}
+		var regions []string
+		for _, c := range t.Copies {
+			regions = append(regions, c.Region)
+		}
+		vault := "-"
+		if t.Vault != nil {
+			vault = t.Vault.RoleARN
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v-*\t%v\t%v\t%v\n", t.Name, t.Region, clusters, t.SnapshotIDPrefix, retention, listOrDash(regions), vault)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/pac-aurora-backup/awsfake"
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/config"
+	"github.com/Financial-Times/pac-aurora-backup/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `
+targets:
+  - name: prod-eu
+    region: eu-west-1
+    clusterIdPrefix: pac-aurora-prod-eu
+    retention:
+      policy: keep-daily=14
+    copies:
+      - region: eu-central-1
+    vault:
+      roleArn: arn:aws:iam::210987654321:role/pac-aurora-backup-vault
+  - name: prod-us
+    region: us-east-1
+    clusterIdPrefix: pac-aurora-prod-us
+    discoverClusters: true
+`
+
+func TestTargetOptionsInvalidVaultRole(t *testing.T) {
+	_, err := targetOptions(&config.Target{Name: "prod", Region: "eu-west-1", Vault: &config.Vault{RoleARN: "pac-aurora-backup-vault"}})
+	assert.EqualError(t, err, "vault role ARN is invalid: pac-aurora-backup-vault")
+}
+
+func TestNewConfigService(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0o600))
+
+	svc, err := newConfigService(path, "", 0, 1, 2, 0, nil)
+	require.NoError(t, err)
+	multi, ok := svc.(*multiService)
+	require.True(t, ok, "unexpected service: %T", svc)
+	assert.Equal(t, []string{"prod-eu", "prod-us"}, multi.names)
+
+	svc, err = newConfigService(path, "prod-us", 0, 1, 2, 0, nil)
+	require.NoError(t, err)
+	_, ok = svc.(*multiService)
+	assert.False(t, ok)
+
+	_, err = newConfigService(path, "prod-ap", 0, 1, 2, 0, nil)
+	assert.EqualError(t, err, "target not found in config: prod-ap")
+}
+
+func TestConfigRegion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0o600))
+
+	region, err := configRegion(path, "prod-us")
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", region)
+
+	region, err = configRegion(path, "")
+	require.NoError(t, err)
+	assert.Empty(t, region, "the targets are in several regions")
+
+	_, err = configRegion(path, "prod-ap")
+	assert.EqualError(t, err, "target not found in config: prod-ap")
+}
+
+func TestNewConfigServiceNotifiesSharedDestinations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testConfigFile), 0o600))
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer server.Close()
+
+	svc, err := newConfigService(path, "prod-us", 0, 1, 2, 0, []config.Notification{{Type: config.NotificationWebhook, URL: server.URL}})
+	require.NoError(t, err)
+	notifying, ok := svc.(*notifyingService)
+	require.True(t, ok, "the notify option should apply to the targets of the config file: %T", svc)
+	assert.Equal(t, "prod-us", notifying.source)
+
+	notifying.notify([]notify.Event{{Kind: notify.BackupSucceeded, Source: "prod-us", ClusterID: "pac-aurora-prod-us-a"}})
+	assert.Equal(t, 1, received)
+}
+
+func TestMultiServiceCombinesTargets(t *testing.T) {
+	eu := awsfake.NewRDS()
+	eu.AddCluster("pac-aurora-prod-eu")
+	us := awsfake.NewRDS()
+	us.Region = "us-east-1"
+	us.AddCluster("pac-aurora-prod-us-a")
+	us.AddCluster("pac-aurora-prod-us-b")
+
+	euSvc, err := backup.NewBackupService(eu.Region, "pac-aurora-prod-eu", "pac-aurora-prod-eu-backup", 0, 5, 7, backup.WithRDSClient(eu))
+	require.NoError(t, err)
+	usSvc, err := backup.NewBackupService(us.Region, "pac-aurora-prod-us", "pac-aurora-prod-us-backup", 0, 5, 7,
+		backup.WithRDSClient(us), backup.WithClusterDiscovery(2))
+	require.NoError(t, err)
+	multi := &multiService{names: []string{"prod-eu", "prod-us"}, services: []backup.Service{euSvc, usSvc}}
+
+	results, err := multi.MakeBackup(context.Background())
+	require.NoError(t, err)
+	var clusterIDs []string
+	for _, r := range results {
+		clusterIDs = append(clusterIDs, r.ClusterID)
+	}
+	assert.ElementsMatch(t, []string{"pac-aurora-prod-eu", "pac-aurora-prod-us-a", "pac-aurora-prod-us-b"}, clusterIDs)
+	assert.Len(t, eu.Snapshots(), 1)
+	assert.Len(t, us.Snapshots(), 2)
+
+	statuses, err := multi.Status(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, statuses, 3)
+
+	_, err = multi.Restore(context.Background(), backup.RestoreRequest{SnapshotID: backup.LatestSnapshot})
+	assert.EqualError(t, err, "the config file has 2 targets (prod-eu, prod-us), select one with the target option")
+}
+
+func TestPrintTargets(t *testing.T) {
+	cfg, err := config.Parse([]byte(testConfigFile))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, printTargets(&out, cfg))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"TARGET", "REGION", "CLUSTERS", "SNAPSHOTS", "RETENTION", "COPY", "REGIONS", "VAULT"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"prod-eu", "eu-west-1", "first", "pac-aurora-prod-eu", "pac-aurora-prod-eu-backup-*", "keep-daily=14", "eu-central-1",
+		"arn:aws:iam::210987654321:role/pac-aurora-backup-vault"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"prod-us", "us-east-1", "all", "pac-aurora-prod-us", "pac-aurora-prod-us-backup-*", "keep-last=35", "-", "-"}, strings.Fields(lines[2]))
+}
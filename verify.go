@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/metrics"
+	"github.com/Financial-Times/pac-aurora-backup/verify"
+	"github.com/go-sql-driver/mysql"
+	cli "github.com/jawher/mow.cli"
+	log "github.com/sirupsen/logrus"
+)
+
+// dbConnectTimeout bounds the connection to the restored cluster.
+const dbConnectTimeout = 30 * time.Second
+
+// verifyEngines are the engines of the clusters the checks connect to with the MySQL driver.
+var verifyEngines = []string{"aurora-mysql", "aurora"}
+
+// verifyCommand configures the verify subcommand, which checks the data of the latest backup snapshot
+// restored into a temporary DB cluster.
+func verifyCommand(cmd *cli.Cmd, env *commandEnv) {
+	cmd.Spec = "--checks --db-user --db-password [--db-name] [--db-port] [--source-cluster-id] [--instance-class]"
+
+	checksFile := cmd.String(cli.StringOpt{
+		Name:   "checks",
+		Desc:   "The YAML file listing the SQL checks run against the restored cluster",
+		EnvVar: "VERIFY_CHECKS",
+	})
+
+	dbUser := cmd.String(cli.StringOpt{
+		Name:   "db-user",
+		Desc:   "The database user running the checks, usually a read-only user of the source cluster",
+		EnvVar: "VERIFY_DB_USER",
+	})
+
+	dbPassword := cmd.String(cli.StringOpt{
+		Name:      "db-password",
+		Desc:      "The password of the database user",
+		EnvVar:    "VERIFY_DB_PASSWORD",
+		HideValue: true,
+	})
+
+	dbName := cmd.String(cli.StringOpt{
+		Name:   "db-name",
+		Desc:   "The database the checks run against, unless the table names are qualified",
+		EnvVar: "VERIFY_DB_NAME",
+	})
+
+	dbPort := cmd.Int(cli.IntOpt{
+		Name:   "db-port",
+		Value:  3306,
+		Desc:   "The port of the restored cluster",
+		EnvVar: "VERIFY_DB_PORT",
+	})
+
+	sourceClusterID := cmd.String(cli.StringOpt{
+		Name: "source-cluster-id",
+		Desc: "The DB cluster whose latest backup snapshot is verified, by default the first cluster matching the PAC environment",
+	})
+
+	instanceClass := cmd.String(cli.StringOpt{
+		Name:   "instance-class",
+		Desc:   "The class of the instance of the temporary cluster, by default the class of the first instance of the source cluster",
+		EnvVar: "VERIFY_INSTANCE_CLASS",
+	})
+
+	cmd.Action = func() {
+		start := time.Now()
+		config, err := verify.LoadConfig(*checksFile)
+		if err != nil {
+			log.WithError(err).WithField("checks", *checksFile).Error("Error in loading the verification checks")
+			cli.Exit(exitCodeError)
+		}
+		ctx, cancel := env.newContext()
+		defer cancel()
+		svc := env.newService()
+
+		var results []verify.Result
+		result, err := svc.Verify(ctx, backup.VerifyRequest{SourceClusterID: *sourceClusterID, InstanceClass: *instanceClass, Engines: verifyEngines},
+			func(ctx context.Context, restored *backup.RestoreResult) error {
+				db, err := openRestoredDB(restored.Endpoint, *dbPort, *dbUser, *dbPassword, *dbName)
+				if err != nil {
+					return err
+				}
+				defer db.Close()
+				if err := db.PingContext(ctx); err != nil {
+					return fmt.Errorf("connecting to %v: %w", restored.Endpoint, err)
+				}
+				results = verify.Run(ctx, db, config, restored.SnapshotCreateTime)
+				return nil
+			})
+
+		for _, r := range results {
+			entry := log.WithField("check", r.Check).WithField("detail", r.Detail)
+			if r.Passed {
+				entry.Info("Verification check passed")
+			} else {
+				entry.Error("Verification check failed")
+			}
+		}
+		verification := &metrics.Verification{StartTime: start, Duration: time.Since(start), Passed: err == nil && verify.Passed(results)}
+		if result.Restore != nil {
+			verification.SnapshotCreateTime = result.Restore.SnapshotCreateTime
+		}
+		env.pushVerification(verification)
+
+		if err != nil {
+			code := runExitCode(ctx, err)
+			log.WithError(err).
+				WithField("clusterID", result.ClusterID).
+				WithField("exitCode", code).
+				Error("PAC aurora backup verification failed")
+			cli.Exit(code)
+		}
+		if printErr := printVerifyResults(os.Stdout, result, results); printErr != nil {
+			log.WithError(printErr).Error("Error in printing the verification results")
+		}
+		if !verification.Passed {
+			log.WithField("snapshotID", result.Restore.SnapshotID).
+				WithField("exitCode", exitCodeVerificationFailure).
+				Error("PAC aurora backup verification failed")
+			cli.Exit(exitCodeVerificationFailure)
+		}
+		log.WithField("snapshotID", result.Restore.SnapshotID).
+			WithField("duration", result.Duration.String()).
+			Info("PAC aurora backup verification completed")
+	}
+}
+
+// openRestoredDB returns a connection pool to the MySQL endpoint of a restored cluster.
+func openRestoredDB(endpoint string, port int, user, password, dbName string) (*sql.DB, error) {
+	config := mysql.NewConfig()
+	config.Net = "tcp"
+	config.Addr = net.JoinHostPort(endpoint, strconv.Itoa(port))
+	config.User = user
+	config.Passwd = password
+	config.DBName = dbName
+	config.ParseTime = true
+	config.Timeout = dbConnectTimeout
+	config.TLSConfig = "preferred"
+	return sql.Open("mysql", config.FormatDSN())
+}
+
+// printVerifyResults writes the outcome of every check run against the restored snapshot.
+func printVerifyResults(w io.Writer, result *backup.VerifyResult, results []verify.Result) error {
+	if _, err := fmt.Fprintf(w, "Snapshot %v of %v restored into %v\n",
+		result.Restore.SnapshotID, result.Restore.SourceClusterID, result.ClusterID); err != nil {
+		return err
+	}
+	for _, r := range results {
+		outcome := "PASS"
+		if !r.Passed {
+			outcome = "FAIL"
+		}
+		line := fmt.Sprintf("%v  %v", outcome, r.Check)
+		if r.Detail != "" {
+			line += " (" + r.Detail + ")"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
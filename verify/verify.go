@@ -0,0 +1,161 @@
+// Package verify runs SQL assertions against a database restored from a backup snapshot,
+// so that a snapshot is only considered a backup once its data has been checked.
+// The checks only support MySQL-compatible databases.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// TableCheck lists the assertions on a table of the restored database:
+// the table exists, has at least MinRows rows, and the most recent value of
+// FreshnessColumn is at most MaxAge older than the snapshot.
+// Zero values disable the row count and freshness assertions.
+type TableCheck struct {
+	Table           string        `yaml:"table"`
+	MinRows         int64         `yaml:"minRows"`
+	FreshnessColumn string        `yaml:"freshnessColumn"`
+	MaxAge          time.Duration `yaml:"maxAge"`
+}
+
+// Config is the set of assertions run by a verification.
+type Config struct {
+	Tables []TableCheck `yaml:"tables"`
+}
+
+// Result is the outcome of a single assertion.
+type Result struct {
+	Check  string
+	Passed bool
+	Detail string
+}
+
+// LoadConfig reads and validates the assertions of a YAML file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses and validates YAML assertions.
+func ParseConfig(data []byte) (*Config, error) {
+	config := new(Config)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("parsing verification checks: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks that the config has at least one assertion and that table and column names
+// are plain SQL identifiers, as they cannot be passed to the queries as parameters.
+func (c *Config) Validate() error {
+	if len(c.Tables) == 0 {
+		return fmt.Errorf("verification checks do not list any table")
+	}
+	for _, table := range c.Tables {
+		if !identifierPattern.MatchString(table.Table) {
+			return fmt.Errorf("table name is not a valid identifier: %q", table.Table)
+		}
+		if table.MinRows < 0 {
+			return fmt.Errorf("minimum number of rows of table %v cannot be negative: %d", table.Table, table.MinRows)
+		}
+		if table.FreshnessColumn != "" && !identifierPattern.MatchString(table.FreshnessColumn) {
+			return fmt.Errorf("freshness column of table %v is not a valid identifier: %q", table.Table, table.FreshnessColumn)
+		}
+		if (table.FreshnessColumn == "") != (table.MaxAge == 0) {
+			return fmt.Errorf("freshness of table %v needs both a column and a maximum age", table.Table)
+		}
+		if table.MaxAge < 0 {
+			return fmt.Errorf("maximum age of table %v cannot be negative: %v", table.Table, table.MaxAge)
+		}
+	}
+	return nil
+}
+
+// Run runs every assertion of the config against the database, measuring freshness against
+// the creation time of the snapshot. The assertions on a table that does not exist are skipped.
+func Run(ctx context.Context, db *sql.DB, config *Config, snapshotTime time.Time) []Result {
+	var results []Result
+	for _, table := range config.Tables {
+		exists := checkTableExists(ctx, db, table.Table)
+		results = append(results, exists)
+		if !exists.Passed {
+			continue
+		}
+		if table.MinRows > 0 {
+			results = append(results, checkRowCount(ctx, db, table.Table, table.MinRows))
+		}
+		if table.FreshnessColumn != "" {
+			results = append(results, checkFreshness(ctx, db, table, snapshotTime))
+		}
+	}
+	return results
+}
+
+// Passed reports whether every assertion passed.
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return len(results) > 0
+}
+
+func checkTableExists(ctx context.Context, db *sql.DB, table string) Result {
+	result := Result{Check: fmt.Sprintf("table %v exists", table)}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT 1 FROM %v WHERE 1 = 0", table))
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	defer rows.Close()
+	result.Passed = true
+	return result
+}
+
+func checkRowCount(ctx context.Context, db *sql.DB, table string, minRows int64) Result {
+	result := Result{Check: fmt.Sprintf("table %v has at least %d rows", table, minRows)}
+	var count int64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %v", table)).Scan(&count); err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	result.Passed = count >= minRows
+	result.Detail = fmt.Sprintf("%d rows", count)
+	return result
+}
+
+func checkFreshness(ctx context.Context, db *sql.DB, table TableCheck, snapshotTime time.Time) Result {
+	result := Result{Check: fmt.Sprintf("%v.%v is at most %v older than the snapshot", table.Table, table.FreshnessColumn, table.MaxAge)}
+	var latest sql.NullTime
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(%v) FROM %v", table.FreshnessColumn, table.Table)).Scan(&latest); err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	if !latest.Valid {
+		result.Detail = "no rows"
+		return result
+	}
+	age := snapshotTime.Sub(latest.Time)
+	result.Passed = age <= table.MaxAge
+	result.Detail = fmt.Sprintf("latest %v, %v before the snapshot", latest.Time.UTC().Format(time.RFC3339), age.Round(time.Second))
+	return result
+}
@@ -0,0 +1,138 @@
+package verify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+tables:
+  - table: content
+    minRows: 1000
+    freshnessColumn: updated_at
+    maxAge: 48h
+  - table: pac.annotations
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []TableCheck{
+		{Table: "content", MinRows: 1000, FreshnessColumn: "updated_at", MaxAge: 48 * time.Hour},
+		{Table: "pac.annotations"},
+	}, config.Tables)
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`tables: []`,
+		`tables: [{table: "content; DROP TABLE content"}]`,
+		`tables: [{table: content, minRows: -1}]`,
+		`tables: [{table: content, freshnessColumn: "max(x)", maxAge: 1h}]`,
+		`tables: [{table: content, freshnessColumn: updated_at}]`,
+		`tables: [{table: content, maxAge: 1h}]`,
+		`tables: [{table: content, maxAge: -1h, freshnessColumn: updated_at}]`,
+		`tables: [{table: content, minRow: 1}]`,
+	} {
+		_, err := ParseConfig([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestRun(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	snapshotTime := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT 1 FROM content WHERE 1 = 0").WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	mock.ExpectQuery("SELECT COUNT(*) FROM content").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1500))
+	mock.ExpectQuery("SELECT MAX(updated_at) FROM content").WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(snapshotTime.Add(-time.Hour)))
+	mock.ExpectQuery("SELECT 1 FROM annotations WHERE 1 = 0").WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	mock.ExpectQuery("SELECT COUNT(*) FROM annotations").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+	mock.ExpectQuery("SELECT MAX(updated_at) FROM annotations").WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(snapshotTime.Add(-72 * time.Hour)))
+	mock.ExpectQuery("SELECT 1 FROM missing WHERE 1 = 0").WillReturnError(errors.New("Error 1146: Table 'pac.missing' doesn't exist"))
+
+	results := Run(context.Background(), db, &Config{Tables: []TableCheck{
+		{Table: "content", MinRows: 1000, FreshnessColumn: "updated_at", MaxAge: 48 * time.Hour},
+		{Table: "annotations", MinRows: 100, FreshnessColumn: "updated_at", MaxAge: 48 * time.Hour},
+		{Table: "missing", MinRows: 1},
+	}}, snapshotTime)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, []Result{
+		{Check: "table content exists", Passed: true},
+		{Check: "table content has at least 1000 rows", Passed: true, Detail: "1500 rows"},
+		{Check: "content.updated_at is at most 48h0m0s older than the snapshot", Passed: true, Detail: "latest 2024-01-05T11:00:00Z, 1h0m0s before the snapshot"},
+		{Check: "table annotations exists", Passed: true},
+		{Check: "table annotations has at least 100 rows", Passed: false, Detail: "10 rows"},
+		{Check: "annotations.updated_at is at most 48h0m0s older than the snapshot", Passed: false, Detail: "latest 2024-01-02T12:00:00Z, 72h0m0s before the snapshot"},
+		{Check: "table missing exists", Passed: false, Detail: "Error 1146: Table 'pac.missing' doesn't exist"},
+	}, results)
+	assert.False(t, Passed(results))
+	assert.True(t, Passed(results[:4]))
+	assert.False(t, Passed(nil))
+}
+
+func TestRunEmptyTableFreshness(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT 1 FROM content WHERE 1 = 0").WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	mock.ExpectQuery("SELECT MAX(updated_at) FROM content").WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+
+	results := Run(context.Background(), db, &Config{Tables: []TableCheck{
+		{Table: "content", FreshnessColumn: "updated_at", MaxAge: time.Hour},
+	}}, time.Now())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, results, 2)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, "no rows", results[1].Detail)
+}
+
+// TestRunAgainstMySQL runs the checks against the MySQL database of $VERIFY_TEST_MYSQL_DSN,
+// e.g. root:secret@tcp(localhost:3306)/test, and is skipped when it is not set.
+func TestRunAgainstMySQL(t *testing.T) {
+	dsn := os.Getenv("VERIFY_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("VERIFY_TEST_MYSQL_DSN is not set")
+	}
+	config, err := mysql.ParseDSN(dsn)
+	require.NoError(t, err)
+	config.ParseTime = true
+	db, err := sql.Open("mysql", config.FormatDSN())
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	table := fmt.Sprintf("verify_test_%d", time.Now().UnixNano())
+	_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %v (id INT PRIMARY KEY, updated_at DATETIME NOT NULL)", table))
+	require.NoError(t, err)
+	defer db.ExecContext(ctx, fmt.Sprintf("DROP TABLE %v", table))
+	snapshotTime := time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)
+	_, err = db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %v VALUES (1, ?), (2, ?)", table), snapshotTime.Add(-72*time.Hour), snapshotTime.Add(-time.Hour))
+	require.NoError(t, err)
+
+	results := Run(ctx, db, &Config{Tables: []TableCheck{
+		{Table: table, MinRows: 2, FreshnessColumn: "updated_at", MaxAge: 2 * time.Hour},
+		{Table: table, MinRows: 3},
+		{Table: table + "_missing", MinRows: 1},
+	}}, snapshotTime)
+
+	require.Len(t, results, 6)
+	for i, passed := range []bool{true, true, true, true, false, false} {
+		assert.Equal(t, passed, results[i].Passed, "%v: %v", results[i].Check, results[i].Detail)
+	}
+	assert.Equal(t, "2 rows", results[1].Detail)
+}
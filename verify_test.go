@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Financial-Times/pac-aurora-backup/backup"
+	"github.com/Financial-Times/pac-aurora-backup/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintVerifyResults(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printVerifyResults(&out, &backup.VerifyResult{
+		ClusterID: "pac-aurora-prod-verify-20240105130000",
+		Restore: &backup.RestoreResult{
+			SourceClusterID: "pac-aurora-prod",
+			SnapshotID:      "pac-aurora-prod-backup-2024-01-05-12-00-00",
+		},
+	}, []verify.Result{
+		{Check: "table content exists", Passed: true},
+		{Check: "table content has at least 1000 rows", Passed: false, Detail: "10 rows"},
+	}))
+
+	assert.Equal(t, "Snapshot pac-aurora-prod-backup-2024-01-05-12-00-00 of pac-aurora-prod restored into pac-aurora-prod-verify-20240105130000\n"+
+		"PASS  table content exists\n"+
+		"FAIL  table content has at least 1000 rows (10 rows)\n", out.String())
+}
+
+func TestOpenRestoredDB(t *testing.T) {
+	db, err := openRestoredDB("pac-aurora-prod-verify.cluster-abc.eu-west-1.rds.amazonaws.com", 3306, "reader", "secret", "pac")
+	require.NoError(t, err)
+	assert.NoError(t, db.Close())
+}